@@ -0,0 +1,4 @@
+// Package imagestorev1 holds the gRPC api of the image-store service.
+package imagestorev1
+
+//go:generate sh -c "cd ../.. && buf generate"
@@ -0,0 +1,481 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.2.0
+// - protoc             (unknown)
+// source: imagestore/v1/image_store.proto
+
+package imagestorev1
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.32.0 or later.
+const _ = grpc.SupportPackageIsVersion7
+
+// ImageStoreClient is the client API for ImageStore service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type ImageStoreClient interface {
+	CreateAlbum(ctx context.Context, in *CreateAlbumRequest, opts ...grpc.CallOption) (*Album, error)
+	GetAlbum(ctx context.Context, in *GetAlbumRequest, opts ...grpc.CallOption) (*Album, error)
+	DeleteAlbum(ctx context.Context, in *DeleteAlbumRequest, opts ...grpc.CallOption) (*DeleteAlbumResponse, error)
+	ListAlbums(ctx context.Context, in *ListAlbumsRequest, opts ...grpc.CallOption) (ImageStore_ListAlbumsClient, error)
+	// UploadImages creates every image sent on the stream. Each image starts
+	// with an info message followed by zero or more chunk messages.
+	UploadImages(ctx context.Context, opts ...grpc.CallOption) (ImageStore_UploadImagesClient, error)
+	// DownloadImage sends the info of the image followed by its content in
+	// chunks.
+	DownloadImage(ctx context.Context, in *DownloadImageRequest, opts ...grpc.CallOption) (ImageStore_DownloadImageClient, error)
+	ListImages(ctx context.Context, in *ListImagesRequest, opts ...grpc.CallOption) (ImageStore_ListImagesClient, error)
+	DeleteImage(ctx context.Context, in *DeleteImageRequest, opts ...grpc.CallOption) (*DeleteImageResponse, error)
+}
+
+type imageStoreClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewImageStoreClient(cc grpc.ClientConnInterface) ImageStoreClient {
+	return &imageStoreClient{cc}
+}
+
+func (c *imageStoreClient) CreateAlbum(ctx context.Context, in *CreateAlbumRequest, opts ...grpc.CallOption) (*Album, error) {
+	out := new(Album)
+	err := c.cc.Invoke(ctx, "/imagestore.v1.ImageStore/CreateAlbum", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *imageStoreClient) GetAlbum(ctx context.Context, in *GetAlbumRequest, opts ...grpc.CallOption) (*Album, error) {
+	out := new(Album)
+	err := c.cc.Invoke(ctx, "/imagestore.v1.ImageStore/GetAlbum", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *imageStoreClient) DeleteAlbum(ctx context.Context, in *DeleteAlbumRequest, opts ...grpc.CallOption) (*DeleteAlbumResponse, error) {
+	out := new(DeleteAlbumResponse)
+	err := c.cc.Invoke(ctx, "/imagestore.v1.ImageStore/DeleteAlbum", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *imageStoreClient) ListAlbums(ctx context.Context, in *ListAlbumsRequest, opts ...grpc.CallOption) (ImageStore_ListAlbumsClient, error) {
+	stream, err := c.cc.NewStream(ctx, &ImageStore_ServiceDesc.Streams[0], "/imagestore.v1.ImageStore/ListAlbums", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &imageStoreListAlbumsClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type ImageStore_ListAlbumsClient interface {
+	Recv() (*Album, error)
+	grpc.ClientStream
+}
+
+type imageStoreListAlbumsClient struct {
+	grpc.ClientStream
+}
+
+func (x *imageStoreListAlbumsClient) Recv() (*Album, error) {
+	m := new(Album)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *imageStoreClient) UploadImages(ctx context.Context, opts ...grpc.CallOption) (ImageStore_UploadImagesClient, error) {
+	stream, err := c.cc.NewStream(ctx, &ImageStore_ServiceDesc.Streams[1], "/imagestore.v1.ImageStore/UploadImages", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &imageStoreUploadImagesClient{stream}
+	return x, nil
+}
+
+type ImageStore_UploadImagesClient interface {
+	Send(*UploadImagesRequest) error
+	CloseAndRecv() (*UploadImagesResponse, error)
+	grpc.ClientStream
+}
+
+type imageStoreUploadImagesClient struct {
+	grpc.ClientStream
+}
+
+func (x *imageStoreUploadImagesClient) Send(m *UploadImagesRequest) error {
+	return x.ClientStream.SendMsg(m)
+}
+
+func (x *imageStoreUploadImagesClient) CloseAndRecv() (*UploadImagesResponse, error) {
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	m := new(UploadImagesResponse)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *imageStoreClient) DownloadImage(ctx context.Context, in *DownloadImageRequest, opts ...grpc.CallOption) (ImageStore_DownloadImageClient, error) {
+	stream, err := c.cc.NewStream(ctx, &ImageStore_ServiceDesc.Streams[2], "/imagestore.v1.ImageStore/DownloadImage", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &imageStoreDownloadImageClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type ImageStore_DownloadImageClient interface {
+	Recv() (*DownloadImageResponse, error)
+	grpc.ClientStream
+}
+
+type imageStoreDownloadImageClient struct {
+	grpc.ClientStream
+}
+
+func (x *imageStoreDownloadImageClient) Recv() (*DownloadImageResponse, error) {
+	m := new(DownloadImageResponse)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *imageStoreClient) ListImages(ctx context.Context, in *ListImagesRequest, opts ...grpc.CallOption) (ImageStore_ListImagesClient, error) {
+	stream, err := c.cc.NewStream(ctx, &ImageStore_ServiceDesc.Streams[3], "/imagestore.v1.ImageStore/ListImages", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &imageStoreListImagesClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type ImageStore_ListImagesClient interface {
+	Recv() (*ImageInfo, error)
+	grpc.ClientStream
+}
+
+type imageStoreListImagesClient struct {
+	grpc.ClientStream
+}
+
+func (x *imageStoreListImagesClient) Recv() (*ImageInfo, error) {
+	m := new(ImageInfo)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *imageStoreClient) DeleteImage(ctx context.Context, in *DeleteImageRequest, opts ...grpc.CallOption) (*DeleteImageResponse, error) {
+	out := new(DeleteImageResponse)
+	err := c.cc.Invoke(ctx, "/imagestore.v1.ImageStore/DeleteImage", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// ImageStoreServer is the server API for ImageStore service.
+// All implementations must embed UnimplementedImageStoreServer
+// for forward compatibility
+type ImageStoreServer interface {
+	CreateAlbum(context.Context, *CreateAlbumRequest) (*Album, error)
+	GetAlbum(context.Context, *GetAlbumRequest) (*Album, error)
+	DeleteAlbum(context.Context, *DeleteAlbumRequest) (*DeleteAlbumResponse, error)
+	ListAlbums(*ListAlbumsRequest, ImageStore_ListAlbumsServer) error
+	// UploadImages creates every image sent on the stream. Each image starts
+	// with an info message followed by zero or more chunk messages.
+	UploadImages(ImageStore_UploadImagesServer) error
+	// DownloadImage sends the info of the image followed by its content in
+	// chunks.
+	DownloadImage(*DownloadImageRequest, ImageStore_DownloadImageServer) error
+	ListImages(*ListImagesRequest, ImageStore_ListImagesServer) error
+	DeleteImage(context.Context, *DeleteImageRequest) (*DeleteImageResponse, error)
+	mustEmbedUnimplementedImageStoreServer()
+}
+
+// UnimplementedImageStoreServer must be embedded to have forward compatible implementations.
+type UnimplementedImageStoreServer struct {
+}
+
+func (UnimplementedImageStoreServer) CreateAlbum(context.Context, *CreateAlbumRequest) (*Album, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CreateAlbum not implemented")
+}
+func (UnimplementedImageStoreServer) GetAlbum(context.Context, *GetAlbumRequest) (*Album, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetAlbum not implemented")
+}
+func (UnimplementedImageStoreServer) DeleteAlbum(context.Context, *DeleteAlbumRequest) (*DeleteAlbumResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method DeleteAlbum not implemented")
+}
+func (UnimplementedImageStoreServer) ListAlbums(*ListAlbumsRequest, ImageStore_ListAlbumsServer) error {
+	return status.Errorf(codes.Unimplemented, "method ListAlbums not implemented")
+}
+func (UnimplementedImageStoreServer) UploadImages(ImageStore_UploadImagesServer) error {
+	return status.Errorf(codes.Unimplemented, "method UploadImages not implemented")
+}
+func (UnimplementedImageStoreServer) DownloadImage(*DownloadImageRequest, ImageStore_DownloadImageServer) error {
+	return status.Errorf(codes.Unimplemented, "method DownloadImage not implemented")
+}
+func (UnimplementedImageStoreServer) ListImages(*ListImagesRequest, ImageStore_ListImagesServer) error {
+	return status.Errorf(codes.Unimplemented, "method ListImages not implemented")
+}
+func (UnimplementedImageStoreServer) DeleteImage(context.Context, *DeleteImageRequest) (*DeleteImageResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method DeleteImage not implemented")
+}
+func (UnimplementedImageStoreServer) mustEmbedUnimplementedImageStoreServer() {}
+
+// UnsafeImageStoreServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to ImageStoreServer will
+// result in compilation errors.
+type UnsafeImageStoreServer interface {
+	mustEmbedUnimplementedImageStoreServer()
+}
+
+func RegisterImageStoreServer(s grpc.ServiceRegistrar, srv ImageStoreServer) {
+	s.RegisterService(&ImageStore_ServiceDesc, srv)
+}
+
+func _ImageStore_CreateAlbum_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CreateAlbumRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ImageStoreServer).CreateAlbum(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/imagestore.v1.ImageStore/CreateAlbum",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ImageStoreServer).CreateAlbum(ctx, req.(*CreateAlbumRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ImageStore_GetAlbum_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetAlbumRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ImageStoreServer).GetAlbum(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/imagestore.v1.ImageStore/GetAlbum",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ImageStoreServer).GetAlbum(ctx, req.(*GetAlbumRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ImageStore_DeleteAlbum_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DeleteAlbumRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ImageStoreServer).DeleteAlbum(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/imagestore.v1.ImageStore/DeleteAlbum",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ImageStoreServer).DeleteAlbum(ctx, req.(*DeleteAlbumRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ImageStore_ListAlbums_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(ListAlbumsRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(ImageStoreServer).ListAlbums(m, &imageStoreListAlbumsServer{stream})
+}
+
+type ImageStore_ListAlbumsServer interface {
+	Send(*Album) error
+	grpc.ServerStream
+}
+
+type imageStoreListAlbumsServer struct {
+	grpc.ServerStream
+}
+
+func (x *imageStoreListAlbumsServer) Send(m *Album) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func _ImageStore_UploadImages_Handler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(ImageStoreServer).UploadImages(&imageStoreUploadImagesServer{stream})
+}
+
+type ImageStore_UploadImagesServer interface {
+	SendAndClose(*UploadImagesResponse) error
+	Recv() (*UploadImagesRequest, error)
+	grpc.ServerStream
+}
+
+type imageStoreUploadImagesServer struct {
+	grpc.ServerStream
+}
+
+func (x *imageStoreUploadImagesServer) SendAndClose(m *UploadImagesResponse) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func (x *imageStoreUploadImagesServer) Recv() (*UploadImagesRequest, error) {
+	m := new(UploadImagesRequest)
+	if err := x.ServerStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func _ImageStore_DownloadImage_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(DownloadImageRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(ImageStoreServer).DownloadImage(m, &imageStoreDownloadImageServer{stream})
+}
+
+type ImageStore_DownloadImageServer interface {
+	Send(*DownloadImageResponse) error
+	grpc.ServerStream
+}
+
+type imageStoreDownloadImageServer struct {
+	grpc.ServerStream
+}
+
+func (x *imageStoreDownloadImageServer) Send(m *DownloadImageResponse) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func _ImageStore_ListImages_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(ListImagesRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(ImageStoreServer).ListImages(m, &imageStoreListImagesServer{stream})
+}
+
+type ImageStore_ListImagesServer interface {
+	Send(*ImageInfo) error
+	grpc.ServerStream
+}
+
+type imageStoreListImagesServer struct {
+	grpc.ServerStream
+}
+
+func (x *imageStoreListImagesServer) Send(m *ImageInfo) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func _ImageStore_DeleteImage_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DeleteImageRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ImageStoreServer).DeleteImage(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/imagestore.v1.ImageStore/DeleteImage",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ImageStoreServer).DeleteImage(ctx, req.(*DeleteImageRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// ImageStore_ServiceDesc is the grpc.ServiceDesc for ImageStore service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var ImageStore_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "imagestore.v1.ImageStore",
+	HandlerType: (*ImageStoreServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "CreateAlbum",
+			Handler:    _ImageStore_CreateAlbum_Handler,
+		},
+		{
+			MethodName: "GetAlbum",
+			Handler:    _ImageStore_GetAlbum_Handler,
+		},
+		{
+			MethodName: "DeleteAlbum",
+			Handler:    _ImageStore_DeleteAlbum_Handler,
+		},
+		{
+			MethodName: "DeleteImage",
+			Handler:    _ImageStore_DeleteImage_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "ListAlbums",
+			Handler:       _ImageStore_ListAlbums_Handler,
+			ServerStreams: true,
+		},
+		{
+			StreamName:    "UploadImages",
+			Handler:       _ImageStore_UploadImages_Handler,
+			ClientStreams: true,
+		},
+		{
+			StreamName:    "DownloadImage",
+			Handler:       _ImageStore_DownloadImage_Handler,
+			ServerStreams: true,
+		},
+		{
+			StreamName:    "ListImages",
+			Handler:       _ImageStore_ListImages_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "imagestore/v1/image_store.proto",
+}
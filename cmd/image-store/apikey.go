@@ -0,0 +1,80 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	log "github.com/sirupsen/logrus"
+	"githum.com/anupam111/image-store/internal/config"
+	"githum.com/anupam111/image-store/internal/controller"
+	"githum.com/anupam111/image-store/internal/db/dbconnection"
+	"githum.com/anupam111/image-store/internal/db/dbhandler"
+	"githum.com/anupam111/image-store/internal/tenant"
+	"strings"
+	"time"
+)
+
+var errAPIKeyUsage = errors.New("usage: image-store apikey create NAME SCOPE[,SCOPE...] [TENANT|*] | list | revoke ID")
+
+// runAPIKey runs the apikey subcommand against the configured database. It
+// creates the first admin key, the api key endpoints require one. Keys are
+// bound to the default tenant unless TENANT is given, an admin key created
+// with * serves every tenant.
+func runAPIKey(dbConfig *config.DBConfig, args []string) error {
+	if len(args) == 0 {
+		return errAPIKeyUsage
+	}
+
+	dbConnection, err := dbconnection.New(dbConfig)
+	if err != nil {
+		return err
+	}
+
+	defer dbConnection.DB.Close()
+
+	logger := log.StandardLogger()
+	dbHandler := dbhandler.NewDBHandler(logger, dbConnection, nil)
+	apiKeys := controller.NewAPIKeyController(logger, dbHandler, dbHandler)
+	ctx := context.Background()
+
+	switch {
+	case args[0] == "create" && (len(args) == 3 || len(args) == 4):
+		tenantID := ""
+		if len(args) == 4 {
+			tenantID = args[3]
+		}
+
+		key, apiKey, err := apiKeys.CreateAPIKey(ctx, args[1], tenantID, strings.Split(args[2], ","))
+		if err != nil {
+			return err
+		}
+
+		fmt.Printf("id %s\nkey %s\n", apiKey.ID, key)
+	case args[0] == "list" && len(args) == 1:
+		keys, err := apiKeys.ListAPIKeys(ctx)
+		if err != nil {
+			return err
+		}
+
+		for _, apiKey := range keys {
+			state := "active"
+			if apiKey.RevokedAt != nil {
+				state = "revoked " + apiKey.RevokedAt.Format(time.RFC3339)
+			}
+
+			tenantID := apiKey.Tenant
+			if tenantID == "" {
+				tenantID = tenant.Any
+			}
+
+			fmt.Printf("%s\t%s\t%s\t%s\t%s\t%s\n", apiKey.ID, apiKey.Name, tenantID, strings.Join(apiKey.Scopes, ","),
+				apiKey.CreatedAt.Format(time.RFC3339), state)
+		}
+	case args[0] == "revoke" && len(args) == 2:
+		return apiKeys.RevokeAPIKey(ctx, args[1])
+	default:
+		return errAPIKeyUsage
+	}
+
+	return nil
+}
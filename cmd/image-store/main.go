@@ -1,6 +1,7 @@
 package main
 
 import (
+	"flag"
 	"fmt"
 	"github.com/joho/godotenv"
 	log "github.com/sirupsen/logrus"
@@ -8,6 +9,8 @@ import (
 	"githum.com/anupam111/image-store/internal/server"
 	"os"
 	"strconv"
+	"strings"
+	"time"
 )
 
 func main() {
@@ -17,19 +20,135 @@ func main() {
 		log.Fatalf("error occured while reading env data : %v", err)
 	}
 
+	autoMigrate := flag.Bool("auto-migrate", os.Getenv("DB_AUTO_MIGRATE") == "true",
+		"apply pending database migrations before serving")
+	flag.Parse()
+
 	port, err := strconv.Atoi(os.Getenv("PORT"))
 	if err != nil {
 		log.Fatalf("error occured while converting string to int: %v", err)
 	}
 
+	grpcPort, err := strconv.Atoi(os.Getenv("GRPC_PORT"))
+	if err != nil {
+		log.Fatalf("error occured while converting string to int: %v", err)
+	}
+
+	requestTimeout, err := time.ParseDuration(os.Getenv("REQUEST_TIMEOUT"))
+	if err != nil {
+		log.Fatalf("error occured while parsing request timeout: %v", err)
+	}
+
+	var routeTimeouts config.RouteTimeouts
+	if err = routeTimeouts.Decode(os.Getenv("ROUTE_TIMEOUTS")); err != nil {
+		log.Fatalf("error occured while parsing route timeouts: %v", err)
+	}
+
 	dbPort, err := strconv.Atoi(os.Getenv("DB_PORT"))
 	if err != nil {
 		log.Fatalf("error occured while converting string to int: %v", err)
 	}
 
+	connectRetries, err := strconv.Atoi(os.Getenv("DB_CONNECT_RETRIES"))
+	if err != nil {
+		log.Fatalf("error occured while converting string to int: %v", err)
+	}
+
+	connectBackoff, err := time.ParseDuration(os.Getenv("DB_CONNECT_BACKOFF"))
+	if err != nil {
+		log.Fatalf("error occured while parsing db connect backoff: %v", err)
+	}
+
+	connectMaxBackoff, err := time.ParseDuration(os.Getenv("DB_CONNECT_MAX_BACKOFF"))
+	if err != nil {
+		log.Fatalf("error occured while parsing db connect max backoff: %v", err)
+	}
+
+	healthCheckInterval, err := time.ParseDuration(os.Getenv("DB_HEALTH_CHECK_INTERVAL"))
+	if err != nil {
+		log.Fatalf("error occured while parsing db health check interval: %v", err)
+	}
+
+	signedURLMaxTTL := 7 * 24 * time.Hour
+	if raw := os.Getenv("SIGNED_URL_MAX_TTL"); raw != "" {
+		if signedURLMaxTTL, err = time.ParseDuration(raw); err != nil {
+			log.Fatalf("error occured while parsing signed url max ttl: %v", err)
+		}
+	}
+
+	rewrapInterval := time.Hour
+	if raw := os.Getenv("ENCRYPTION_REWRAP_INTERVAL"); raw != "" {
+		if rewrapInterval, err = time.ParseDuration(raw); err != nil {
+			log.Fatalf("error occured while parsing encryption rewrap interval: %v", err)
+		}
+	}
+
+	var rateLimit config.RateLimit
+	if err = rateLimit.Decode(os.Getenv("RATE_LIMIT")); err != nil {
+		log.Fatalf("error occured while parsing rate limit: %v", err)
+	}
+
+	var routeRateLimits config.RouteRateLimits
+	if err = routeRateLimits.Decode(os.Getenv("ROUTE_RATE_LIMITS")); err != nil {
+		log.Fatalf("error occured while parsing route rate limits: %v", err)
+	}
+
+	var ipRateLimit config.RateLimit
+	if err = ipRateLimit.Decode(os.Getenv("IP_RATE_LIMIT")); err != nil {
+		log.Fatalf("error occured while parsing ip rate limit: %v", err)
+	}
+
+	var quotas config.Quotas
+	for name, quota := range map[string]*int64{
+		"QUOTA_TENANT_IMAGES": &quotas.TenantImages,
+		"QUOTA_TENANT_BYTES":  &quotas.TenantBytes,
+		"QUOTA_ALBUM_IMAGES":  &quotas.AlbumImages,
+		"QUOTA_ALBUM_BYTES":   &quotas.AlbumBytes,
+	} {
+		if raw := os.Getenv(name); raw != "" {
+			if *quota, err = strconv.ParseInt(raw, 10, 64); err != nil {
+				log.Fatalf("error occured while parsing %s: %v", name, err)
+			}
+		}
+	}
+
 	serverConfig := config.ServiceConfig{
 		LogLevel: os.Getenv("LOG_LEVEL"),
 		Port:     port,
+		GRPCPort: grpcPort,
+
+		RequestTimeout: requestTimeout,
+		RouteTimeouts:  routeTimeouts,
+		AuthEnabled:    os.Getenv("AUTH_ENABLED") != "false",
+
+		OIDCIssuer:      os.Getenv("OIDC_ISSUER"),
+		OIDCAudience:    os.Getenv("OIDC_AUDIENCE"),
+		OIDCJWKS:        os.Getenv("OIDC_JWKS"),
+		OIDCGroupsClaim: os.Getenv("OIDC_GROUPS_CLAIM"),
+		OIDCTenantClaim: os.Getenv("OIDC_TENANT_CLAIM"),
+
+		SignedURLKey:    os.Getenv("SIGNED_URL_KEY"),
+		SignedURLMaxTTL: signedURLMaxTTL,
+		SignedURLBase:   os.Getenv("SIGNED_URL_BASE"),
+
+		RateLimit:       rateLimit,
+		RouteRateLimits: routeRateLimits,
+		IPRateLimit:     ipRateLimit,
+		RateLimitStore:  os.Getenv("RATE_LIMIT_STORE"),
+
+		EncryptionKeyProvider:    os.Getenv("ENCRYPTION_KEY_PROVIDER"),
+		EncryptionKeyFile:        os.Getenv("ENCRYPTION_KEY_FILE"),
+		EncryptionRewrapInterval: rewrapInterval,
+
+		Quotas: quotas,
+	}
+
+	if serverConfig.OIDCGroupsClaim == "" {
+		serverConfig.OIDCGroupsClaim = "groups"
+	}
+
+	if serverConfig.OIDCTenantClaim == "" {
+		serverConfig.OIDCTenantClaim = "tenant"
 	}
 
 	dbConfig := config.DBConfig{
@@ -39,7 +158,42 @@ func main() {
 		Username:   os.Getenv("DB_USERNAME"),
 		DriverName: os.Getenv("DB_DRIVER"),
 		Port:       dbPort,
+
+		ConnectRetries:      connectRetries,
+		ConnectBackoff:      connectBackoff,
+		ConnectMaxBackoff:   connectMaxBackoff,
+		HealthCheckInterval: healthCheckInterval,
+		AutoMigrate:         *autoMigrate,
+	}
+
+	for _, host := range strings.Split(os.Getenv("DB_REPLICA_HOSTS"), ",") {
+		if host = strings.TrimSpace(host); host != "" {
+			dbConfig.ReplicaHosts = append(dbConfig.ReplicaHosts, host)
+		}
+	}
+
+	for _, proxy := range strings.Split(os.Getenv("TRUSTED_PROXIES"), ",") {
+		if proxy = strings.TrimSpace(proxy); proxy != "" {
+			serverConfig.TrustedProxies = append(serverConfig.TrustedProxies, proxy)
+		}
 	}
+
+	if flag.Arg(0) == "migrate" {
+		if err = runMigrate(&dbConfig, flag.Args()[1:]); err != nil {
+			log.Fatalf("error occured while migrating database: %v", err)
+		}
+
+		return
+	}
+
+	if flag.Arg(0) == "apikey" {
+		if err = runAPIKey(&dbConfig, flag.Args()[1:]); err != nil {
+			log.Fatalf("error occured while managing api keys: %v", err)
+		}
+
+		return
+	}
+
 	server := server.NewAppServer()
 
 	imageStoreServiceConfig := &config.ImageStoreServiceConfig{
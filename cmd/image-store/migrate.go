@@ -0,0 +1,112 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	log "github.com/sirupsen/logrus"
+	"githum.com/anupam111/image-store/internal/config"
+	"githum.com/anupam111/image-store/internal/db/dbconnection"
+	"githum.com/anupam111/image-store/internal/db/migration"
+	"strconv"
+)
+
+var errMigrateUsage = errors.New("usage: image-store migrate up [N] | down [N|all] | status | force VERSION")
+
+// runMigrate runs the migrate subcommand against the configured database.
+func runMigrate(dbConfig *config.DBConfig, args []string) error {
+	if len(args) == 0 || len(args) > 2 {
+		return errMigrateUsage
+	}
+
+	dbConnection, err := dbconnection.New(dbConfig)
+	if err != nil {
+		return err
+	}
+
+	defer dbConnection.DB.Close()
+
+	migrator, err := migration.NewMigrator(log.StandardLogger(), dbConnection.DB)
+	if err != nil {
+		return err
+	}
+
+	defer migrator.Close()
+
+	switch args[0] {
+	case "up":
+		steps, err := migrateSteps(args[1:], 0)
+		if err != nil {
+			return err
+		}
+
+		err = migrator.Up(steps)
+		if err != nil {
+			return err
+		}
+	case "down":
+		steps, err := migrateSteps(args[1:], 1)
+		if err != nil {
+			return err
+		}
+
+		err = migrator.Down(steps)
+		if err != nil {
+			return err
+		}
+	case "force":
+		if len(args) != 2 {
+			return errMigrateUsage
+		}
+
+		version, err := strconv.Atoi(args[1])
+		if err != nil {
+			return errMigrateUsage
+		}
+
+		err = migrator.Force(version)
+		if err != nil {
+			return err
+		}
+	case "status":
+		if len(args) != 1 {
+			return errMigrateUsage
+		}
+	default:
+		return errMigrateUsage
+	}
+
+	version, dirty, err := migrator.Status()
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case version == 0:
+		fmt.Println("no migration applied")
+	case dirty:
+		fmt.Printf("version %d (dirty)\n", version)
+	default:
+		fmt.Printf("version %d\n", version)
+	}
+
+	return nil
+}
+
+// migrateSteps parses the optional step count of up and down. "all" and 0
+// mean every migration.
+func migrateSteps(args []string, defaultSteps int) (int, error) {
+	if len(args) == 0 {
+		return defaultSteps, nil
+	}
+
+	if args[0] == "all" {
+		return 0, nil
+	}
+
+	steps, err := strconv.Atoi(args[0])
+	if err != nil || steps < 0 {
+		return 0, errMigrateUsage
+	}
+
+	return steps, nil
+}
@@ -0,0 +1,109 @@
+package apihandler
+
+import (
+	"errors"
+	"github.com/gin-gonic/gin"
+	"githum.com/anupam111/image-store/internal/controller"
+	"githum.com/anupam111/image-store/internal/db/dbhandler"
+	"githum.com/anupam111/image-store/internal/db/dbmodels"
+	"githum.com/anupam111/image-store/internal/models"
+	"net/http"
+)
+
+// GetAlbumAccess lists who has which role on the album.
+func (a *APIHandler) GetAlbumAccess(ginCtx *gin.Context) {
+	accesses, err := a.imageStore.ListAlbumAccess(ginCtx.Request.Context(), ginCtx.Param("albumName"))
+	if err != nil {
+		writeAlbumAccessError(ginCtx, err)
+
+		return
+	}
+
+	response := make([]models.AlbumAccess, len(accesses))
+	for idx, access := range accesses {
+		response[idx] = models.AlbumAccess{
+			PrincipalType: access.PrincipalType,
+			Principal:     access.Principal,
+			Role:          access.Role,
+		}
+	}
+
+	ginCtx.JSON(http.StatusOK, response)
+}
+
+// GrantAlbumAccess grants the role of the request body to its principal,
+// replacing the role the principal had on the album.
+func (a *APIHandler) GrantAlbumAccess(ginCtx *gin.Context) {
+	var request models.AlbumAccess
+	if err := ginCtx.ShouldBindJSON(&request); err != nil {
+		ginCtx.JSON(http.StatusBadRequest, models.ResponseError{
+			HTTPStatusCode: http.StatusBadRequest,
+			ErrorCode:      "BAD-REQUEST",
+			MessageDetails: err.Error(),
+		})
+
+		return
+	}
+
+	err := a.imageStore.GrantAlbumAccess(ginCtx.Request.Context(), dbmodels.AlbumAccess{
+		AlbumName:     ginCtx.Param("albumName"),
+		PrincipalType: request.PrincipalType,
+		Principal:     request.Principal,
+		Role:          request.Role,
+	})
+	if err != nil {
+		writeAlbumAccessError(ginCtx, err)
+
+		return
+	}
+
+	ginCtx.Status(http.StatusNoContent)
+}
+
+// RevokeAlbumAccess removes the role of the principal given by the
+// principalType and principal query parameters. They are not part of the
+// path as group names may contain slashes.
+func (a *APIHandler) RevokeAlbumAccess(ginCtx *gin.Context) {
+	principalType, principal := ginCtx.Query("principalType"), ginCtx.Query("principal")
+	if principalType == "" || principal == "" {
+		ginCtx.JSON(http.StatusBadRequest, models.ResponseError{
+			HTTPStatusCode: http.StatusBadRequest,
+			ErrorCode:      "BAD-REQUEST",
+			MessageDetails: "principalType or principal is empty in query parameter",
+		})
+
+		return
+	}
+
+	err := a.imageStore.RevokeAlbumAccess(ginCtx.Request.Context(), ginCtx.Param("albumName"), principalType, principal)
+	if err != nil {
+		writeAlbumAccessError(ginCtx, err)
+
+		return
+	}
+
+	ginCtx.Status(http.StatusNoContent)
+}
+
+func writeAlbumAccessError(ginCtx *gin.Context, err error) {
+	status, errorCode := 0, ""
+
+	switch {
+	case errors.Is(err, controller.ErrInvalidAccess):
+		status, errorCode = http.StatusBadRequest, "BAD-REQUEST"
+	case errors.Is(err, dbhandler.ErrNoDataFound):
+		status, errorCode = http.StatusNotFound, "NOT-FOUND"
+	case errors.Is(err, controller.ErrLastOwner):
+		status, errorCode = http.StatusConflict, "CONFLICT"
+	default:
+		writeInternalError(ginCtx, err)
+
+		return
+	}
+
+	ginCtx.JSON(status, models.ResponseError{
+		HTTPStatusCode: status,
+		ErrorCode:      errorCode,
+		MessageDetails: err.Error(),
+	})
+}
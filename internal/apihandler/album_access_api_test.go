@@ -0,0 +1,151 @@
+package apihandler
+
+import (
+	"fmt"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"githum.com/anupam111/image-store/internal/controller"
+	"githum.com/anupam111/image-store/internal/db/dbhandler"
+	"githum.com/anupam111/image-store/internal/db/dbmodels"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func Test_AlbumAccess(t *testing.T) {
+	t.Parallel()
+
+	editors := dbmodels.AlbumAccess{
+		AlbumName:     "holiday",
+		PrincipalType: dbmodels.PrincipalGroup,
+		Principal:     "/org/editors",
+		Role:          dbmodels.RoleEditor,
+	}
+
+	tests := []struct {
+		name         string
+		method       string
+		url          string
+		body         string
+		prepare      func(subs *controller.MockImageStore)
+		statusCode   int
+		expectedBody string
+	}{
+		{
+			name:   "list",
+			method: http.MethodGet,
+			url:    "/album/holiday/access",
+			prepare: func(subs *controller.MockImageStore) {
+				subs.EXPECT().ListAlbumAccess(gomock.Any(), "holiday").Return([]dbmodels.AlbumAccess{editors}, nil)
+			},
+			statusCode:   http.StatusOK,
+			expectedBody: `[{"principalType":"group","principal":"/org/editors","role":"editor"}]`,
+		},
+		{
+			name:   "list_forbidden",
+			method: http.MethodGet,
+			url:    "/album/holiday/access",
+			prepare: func(subs *controller.MockImageStore) {
+				subs.EXPECT().ListAlbumAccess(gomock.Any(), "holiday").
+					Return(nil, fmt.Errorf("error while listing album access, %w", controller.ErrAccessDenied))
+			},
+			statusCode: http.StatusForbidden,
+		},
+		{
+			name:   "grant",
+			method: http.MethodPut,
+			url:    "/album/holiday/access",
+			body:   `{"principalType":"group","principal":"/org/editors","role":"editor"}`,
+			prepare: func(subs *controller.MockImageStore) {
+				subs.EXPECT().GrantAlbumAccess(gomock.Any(), editors).Return(nil)
+			},
+			statusCode: http.StatusNoContent,
+		},
+		{
+			name:   "grant_invalid_role",
+			method: http.MethodPut,
+			url:    "/album/holiday/access",
+			body:   `{"principalType":"group","principal":"/org/editors","role":"admin"}`,
+			prepare: func(subs *controller.MockImageStore) {
+				subs.EXPECT().GrantAlbumAccess(gomock.Any(), gomock.Any()).Return(controller.ErrInvalidAccess)
+			},
+			statusCode: http.StatusBadRequest,
+		},
+		{
+			name:       "grant_invalid_body",
+			method:     http.MethodPut,
+			url:        "/album/holiday/access",
+			body:       `{"principalType":`,
+			statusCode: http.StatusBadRequest,
+		},
+		{
+			name:   "grant_unknown_album",
+			method: http.MethodPut,
+			url:    "/album/holiday/access",
+			body:   `{"principalType":"group","principal":"/org/editors","role":"editor"}`,
+			prepare: func(subs *controller.MockImageStore) {
+				subs.EXPECT().GrantAlbumAccess(gomock.Any(), editors).Return(dbhandler.ErrNoDataFound)
+			},
+			statusCode: http.StatusNotFound,
+		},
+		{
+			name:   "revoke",
+			method: http.MethodDelete,
+			url:    "/album/holiday/access?principalType=group&principal=%2Forg%2Feditors",
+			prepare: func(subs *controller.MockImageStore) {
+				subs.EXPECT().RevokeAlbumAccess(gomock.Any(), "holiday", "group", "/org/editors").Return(nil)
+			},
+			statusCode: http.StatusNoContent,
+		},
+		{
+			name:   "revoke_last_owner",
+			method: http.MethodDelete,
+			url:    "/album/holiday/access?principalType=user&principal=alice",
+			prepare: func(subs *controller.MockImageStore) {
+				subs.EXPECT().RevokeAlbumAccess(gomock.Any(), "holiday", "user", "alice").Return(controller.ErrLastOwner)
+			},
+			statusCode: http.StatusConflict,
+		},
+		{
+			name:       "revoke_without_principal",
+			method:     http.MethodDelete,
+			url:        "/album/holiday/access?principalType=user",
+			statusCode: http.StatusBadRequest,
+		},
+		{
+			name:   "revoke_internal_server_error",
+			method: http.MethodDelete,
+			url:    "/album/holiday/access?principalType=user&principal=bob",
+			prepare: func(subs *controller.MockImageStore) {
+				subs.EXPECT().RevokeAlbumAccess(gomock.Any(), "holiday", "user", "bob").Return(errFake)
+			},
+			statusCode: http.StatusInternalServerError,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			router, controller, apiHandler := setupTestEnv(t)
+			if tt.prepare != nil {
+				tt.prepare(controller)
+			}
+
+			router.GET("/album/:albumName/access", apiHandler.GetAlbumAccess)
+			router.PUT("/album/:albumName/access", apiHandler.GrantAlbumAccess)
+			router.DELETE("/album/:albumName/access", apiHandler.RevokeAlbumAccess)
+
+			req := httptest.NewRequest(tt.method, tt.url, strings.NewReader(tt.body))
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+			assert.Equal(t, tt.statusCode, w.Code)
+
+			if tt.expectedBody != "" {
+				assert.JSONEq(t, tt.expectedBody, w.Body.String())
+			}
+		})
+	}
+}
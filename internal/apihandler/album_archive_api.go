@@ -0,0 +1,207 @@
+package apihandler
+
+import (
+	"archive/zip"
+	"errors"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"githum.com/anupam111/image-store/internal/constants"
+	"githum.com/anupam111/image-store/internal/db/dbhandler"
+	"githum.com/anupam111/image-store/internal/db/dbmodels"
+	"githum.com/anupam111/image-store/internal/models"
+	"io"
+	"net/http"
+	"path"
+	"time"
+)
+
+// ExportAlbum streams every image of the album as a ZIP archive. Images are
+// written to the response as they are read from the database, so the album
+// is never buffered in memory.
+func (a *APIHandler) ExportAlbum(ginCtx *gin.Context) {
+	albumName := ginCtx.Param("albumName")
+	if albumName == "" {
+		ginCtx.JSON(http.StatusBadRequest, models.ResponseError{
+			HTTPStatusCode: http.StatusBadRequest,
+			ErrorCode:      "BAD-REQUEST",
+			MessageDetails: "albumName is empty in request url",
+		})
+
+		return
+	}
+
+	if _, err := a.imageStore.GetImageAlbum(ginCtx.Request.Context(), albumName); err != nil {
+		if errors.Is(err, dbhandler.ErrNoDataFound) {
+			ginCtx.JSON(http.StatusNotFound, models.ResponseError{
+				HTTPStatusCode: http.StatusNotFound,
+				ErrorCode:      "NOT-FOUND",
+				MessageDetails: err.Error(),
+			})
+
+			return
+		}
+
+		writeInternalError(ginCtx, err)
+
+		return
+	}
+
+	a.writeAlbumArchive(ginCtx, albumName)
+}
+
+// writeAlbumArchive streams the images of the album as a ZIP archive.
+func (a *APIHandler) writeAlbumArchive(ginCtx *gin.Context, albumName string) {
+	ginCtx.Header("Content-Type", "application/zip")
+	ginCtx.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", albumName+".zip"))
+	ginCtx.Status(http.StatusOK)
+
+	archive := zip.NewWriter(ginCtx.Writer)
+	err := a.imageStore.ExportAlbumImages(ginCtx.Request.Context(), albumName, func(image dbmodels.Image) error {
+		entry, err := archive.CreateHeader(&zip.FileHeader{
+			Name:     image.ImageName,
+			Method:   zip.Deflate,
+			Modified: time.Now(),
+		})
+		if err != nil {
+			return fmt.Errorf("error while adding %s to archive: %w", image.ImageName, err)
+		}
+
+		if _, err = entry.Write(image.Content()); err != nil {
+			return fmt.Errorf("error while writing %s to archive: %w", image.ImageName, err)
+		}
+
+		ginCtx.Writer.Flush()
+
+		return nil
+	})
+	if err != nil {
+		// The status line is already sent, leave the archive without its
+		// central directory so that the client sees it as truncated.
+		a.log.Errorf("error while exporting album %s: %v", albumName, err)
+		ginCtx.Abort()
+
+		return
+	}
+
+	if err = archive.Close(); err != nil {
+		a.log.Errorf("error while finishing archive of album %s: %v", albumName, err)
+	}
+}
+
+// ImportAlbum creates the album named by the albumName form field, if it does
+// not exist yet, and an image for every file of the ZIP archive uploaded as
+// the file form field. Entries are imported one by one and reported
+// individually.
+func (a *APIHandler) ImportAlbum(ginCtx *gin.Context) {
+	albumName := ginCtx.PostForm("albumName")
+	if albumName == "" {
+		ginCtx.JSON(http.StatusBadRequest, models.ResponseError{
+			HTTPStatusCode: http.StatusBadRequest,
+			ErrorCode:      "BAD-REQUEST",
+			MessageDetails: "albumName is empty in form data",
+		})
+
+		return
+	}
+
+	fileHeader, err := ginCtx.FormFile("file")
+	if err != nil {
+		ginCtx.JSON(http.StatusBadRequest, models.ResponseError{
+			HTTPStatusCode: http.StatusBadRequest,
+			ErrorCode:      "BAD-REQUEST",
+			MessageDetails: err.Error(),
+		})
+
+		return
+	}
+
+	// multipart spools large uploads to disk, so the archive is read from
+	// there rather than from memory.
+	file, err := fileHeader.Open()
+	if err != nil {
+		writeInternalError(ginCtx, err)
+
+		return
+	}
+
+	defer file.Close()
+
+	archive, err := zip.NewReader(file, fileHeader.Size)
+	if err != nil {
+		ginCtx.JSON(http.StatusBadRequest, models.ResponseError{
+			HTTPStatusCode: http.StatusBadRequest,
+			ErrorCode:      "BAD-REQUEST",
+			MessageDetails: fmt.Sprintf("file is not a valid zip archive: %v", err),
+		})
+
+		return
+	}
+
+	err = a.imageStore.CreateImageAlbum(ginCtx.Request.Context(), dbmodels.Album{AlbumName: albumName})
+	if err != nil && !errors.Is(err, dbhandler.ErrDuplicate) {
+		writeInternalError(ginCtx, err)
+
+		return
+	}
+
+	results := []models.BatchItemResult{}
+	for idx, entry := range archive.File {
+		if entry.FileInfo().IsDir() {
+			continue
+		}
+
+		imageName := path.Base(entry.Name)
+
+		content, err := readArchiveEntry(entry)
+		if err != nil {
+			results = append(results, models.BatchItemResult{
+				Index:          idx,
+				ImageName:      imageName,
+				AlbumName:      albumName,
+				HTTPStatusCode: http.StatusBadRequest,
+				ErrorCode:      "BAD-REQUEST",
+				MessageDetails: err.Error(),
+			})
+
+			continue
+		}
+
+		image := dbmodels.Image{
+			ImageName: imageName,
+			AlbumName: albumName,
+		}
+		image.SetContent(content)
+
+		err = a.imageStore.CreateImage(ginCtx.Request.Context(), image)
+		results = append(results, batchItemResult(idx, imageName, albumName, http.StatusCreated, err))
+	}
+
+	writeBatchResponse(ginCtx, http.StatusCreated, results)
+}
+
+func readArchiveEntry(entry *zip.File) ([]byte, error) {
+	if entry.UncompressedSize64 > constants.MaxImageSize {
+		return nil, fmt.Errorf("%s exceeds the maximum image size of %d bytes",
+			entry.Name, constants.MaxImageSize)
+	}
+
+	reader, err := entry.Open()
+	if err != nil {
+		return nil, fmt.Errorf("error while opening %s: %w", entry.Name, err)
+	}
+
+	defer reader.Close()
+
+	// The size in the header is not trusted, the read is bounded as well.
+	content, err := io.ReadAll(io.LimitReader(reader, constants.MaxImageSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("error while reading %s: %w", entry.Name, err)
+	}
+
+	if len(content) > constants.MaxImageSize {
+		return nil, fmt.Errorf("%s exceeds the maximum image size of %d bytes",
+			entry.Name, constants.MaxImageSize)
+	}
+
+	return content, nil
+}
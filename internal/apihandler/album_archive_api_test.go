@@ -0,0 +1,207 @@
+package apihandler
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"githum.com/anupam111/image-store/internal/controller"
+	"githum.com/anupam111/image-store/internal/db/dbhandler"
+	"githum.com/anupam111/image-store/internal/db/dbmodels"
+	"githum.com/anupam111/image-store/internal/models"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func Test_ExportAlbum(t *testing.T) {
+	t.Parallel()
+
+	images := []dbmodels.Image{
+		{AlbumName: "test-album", ImageName: "image-1", Image: base64.StdEncoding.EncodeToString([]byte("first"))},
+		{AlbumName: "test-album", ImageName: "image-2", Image: "not-base64!"},
+	}
+
+	tests := []struct {
+		name    string
+		prepare func(
+			subs *controller.MockImageStore,
+		)
+		statusCode int
+		entries    map[string]string
+	}{
+		{
+			name: "success",
+			prepare: func(subs *controller.MockImageStore) {
+				subs.EXPECT().GetImageAlbum(gomock.Any(), "test-album").Return(dbmodels.Album{AlbumName: "test-album"}, nil)
+				subs.EXPECT().ExportAlbumImages(gomock.Any(), "test-album", gomock.Any()).DoAndReturn(
+					func(_ context.Context, _ string, fn func(dbmodels.Image) error) error {
+						for _, image := range images {
+							if err := fn(image); err != nil {
+								return err
+							}
+						}
+
+						return nil
+					})
+			},
+			statusCode: 200,
+			entries: map[string]string{
+				"image-1": "first",
+				"image-2": "not-base64!",
+			},
+		},
+		{
+			name: "not_found",
+			prepare: func(subs *controller.MockImageStore) {
+				subs.EXPECT().GetImageAlbum(gomock.Any(), "test-album").Return(dbmodels.Album{}, dbhandler.ErrNoDataFound)
+			},
+			statusCode: 404,
+		},
+		{
+			name: "internal_server_error",
+			prepare: func(subs *controller.MockImageStore) {
+				subs.EXPECT().GetImageAlbum(gomock.Any(), "test-album").Return(dbmodels.Album{}, errFake)
+			},
+			statusCode: 500,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			router, controller, apiHandler := setupTestEnv(t)
+
+			if tt.prepare != nil {
+				tt.prepare(controller)
+			}
+
+			router.GET("/album/:albumName/export.zip", apiHandler.ExportAlbum)
+			req, _ := http.NewRequestWithContext(context.Background(), http.MethodGet, "/album/test-album/export.zip", nil)
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+			assert.Equal(t, tt.statusCode, w.Code)
+
+			if tt.entries == nil {
+				return
+			}
+
+			archive, err := zip.NewReader(bytes.NewReader(w.Body.Bytes()), int64(w.Body.Len()))
+			assert.Nil(t, err)
+			got := map[string]string{}
+			for _, entry := range archive.File {
+				reader, err := entry.Open()
+				assert.Nil(t, err)
+				content, _ := io.ReadAll(reader)
+				got[entry.Name] = string(content)
+			}
+			assert.Equal(t, tt.entries, got)
+		})
+	}
+}
+
+func Test_ImportAlbum(t *testing.T) {
+	t.Parallel()
+
+	var archive bytes.Buffer
+	zipWriter := zip.NewWriter(&archive)
+	for _, name := range []string{"photos/image-1", "image-2"} {
+		entry, _ := zipWriter.Create(name)
+		_, _ = entry.Write([]byte(name))
+	}
+	_ = zipWriter.Close()
+
+	tests := []struct {
+		name      string
+		albumName string
+		archive   []byte
+		prepare   func(
+			subs *controller.MockImageStore,
+		)
+		statusCode int
+		failed     int
+	}{
+		{
+			name:      "success",
+			albumName: "test-album",
+			archive:   archive.Bytes(),
+			prepare: func(subs *controller.MockImageStore) {
+				subs.EXPECT().CreateImageAlbum(gomock.Any(), dbmodels.Album{AlbumName: "test-album"}).Return(nil)
+				subs.EXPECT().CreateImage(gomock.Any(), dbmodels.Image{
+					ImageName: "image-1",
+					AlbumName: "test-album",
+					Image:     base64.StdEncoding.EncodeToString([]byte("photos/image-1")),
+				}).Return(nil)
+				subs.EXPECT().CreateImage(gomock.Any(), dbmodels.Image{
+					ImageName: "image-2",
+					AlbumName: "test-album",
+					Image:     base64.StdEncoding.EncodeToString([]byte("image-2")),
+				}).Return(nil)
+			},
+			statusCode: 201,
+		},
+		{
+			name:      "existing_album_with_entry_failure",
+			albumName: "test-album",
+			archive:   archive.Bytes(),
+			prepare: func(subs *controller.MockImageStore) {
+				subs.EXPECT().CreateImageAlbum(gomock.Any(), gomock.Any()).Return(dbhandler.ErrDuplicate)
+				subs.EXPECT().CreateImage(gomock.Any(), gomock.Any()).Return(dbhandler.ErrDuplicate)
+				subs.EXPECT().CreateImage(gomock.Any(), gomock.Any()).Return(nil)
+			},
+			statusCode: 207,
+			failed:     1,
+		},
+		{
+			name:       "not_a_zip",
+			albumName:  "test-album",
+			archive:    []byte("plain text"),
+			statusCode: 400,
+		},
+		{
+			name:       "missing_album_name",
+			archive:    archive.Bytes(),
+			statusCode: 400,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			router, controller, apiHandler := setupTestEnv(t)
+
+			if tt.prepare != nil {
+				tt.prepare(controller)
+			}
+
+			var body bytes.Buffer
+			form := multipart.NewWriter(&body)
+			_ = form.WriteField("albumName", tt.albumName)
+			file, _ := form.CreateFormFile("file", "album.zip")
+			_, _ = file.Write(tt.archive)
+			_ = form.Close()
+
+			router.POST("/album/import", apiHandler.ImportAlbum)
+			req, _ := http.NewRequestWithContext(context.Background(), http.MethodPost, "/album/import", &body)
+			req.Header.Set("Content-Type", form.FormDataContentType())
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+			assert.Equal(t, tt.statusCode, w.Code)
+
+			if tt.statusCode == 201 || tt.statusCode == 207 {
+				var response models.BatchResponse
+				assert.Nil(t, json.Unmarshal(w.Body.Bytes(), &response))
+				assert.Equal(t, tt.failed, response.Failed)
+			}
+		})
+	}
+}
@@ -0,0 +1,237 @@
+package apihandler
+
+import (
+	"errors"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"githum.com/anupam111/image-store/internal/controller"
+	"githum.com/anupam111/image-store/internal/db/dbhandler"
+	"githum.com/anupam111/image-store/internal/db/dbmodels"
+	"githum.com/anupam111/image-store/internal/models"
+	"githum.com/anupam111/image-store/internal/tenant"
+	"net/http"
+	"net/url"
+)
+
+// shareBasePath is the path of the public routes of the album shares.
+const shareBasePath = "/s"
+
+// CreateAlbumShare creates a public link to the album. The token of the
+// share is only ever shown in this response.
+func (a *APIHandler) CreateAlbumShare(ginCtx *gin.Context) {
+	var request models.AlbumShareRequest
+	if err := ginCtx.ShouldBindJSON(&request); err != nil {
+		ginCtx.JSON(http.StatusBadRequest, models.ResponseError{
+			HTTPStatusCode: http.StatusBadRequest,
+			ErrorCode:      "BAD-REQUEST",
+			MessageDetails: err.Error(),
+		})
+
+		return
+	}
+
+	token, share, err := a.imageStore.CreateAlbumShare(ginCtx.Request.Context(), dbmodels.AlbumShare{
+		AlbumName:     ginCtx.Param("albumName"),
+		ExpiresAt:     request.ExpiresAt,
+		AllowDownload: request.AllowDownload,
+	}, request.Password)
+	if err != nil {
+		writeAlbumShareError(ginCtx, err)
+
+		return
+	}
+
+	ginCtx.JSON(http.StatusCreated, models.CreatedAlbumShare{
+		AlbumShare: albumShareModel(share),
+		Token:      token,
+		URL:        sharePath(token),
+	})
+}
+
+// ListAlbumShares lists the shares of the album, revoked ones included.
+func (a *APIHandler) ListAlbumShares(ginCtx *gin.Context) {
+	shares, err := a.imageStore.ListAlbumShares(ginCtx.Request.Context(), ginCtx.Param("albumName"))
+	if err != nil {
+		writeAlbumShareError(ginCtx, err)
+
+		return
+	}
+
+	response := make([]models.AlbumShare, len(shares))
+	for idx, share := range shares {
+		response[idx] = albumShareModel(share)
+	}
+
+	ginCtx.JSON(http.StatusOK, response)
+}
+
+// RevokeAlbumShare revokes the share of the album with the id.
+func (a *APIHandler) RevokeAlbumShare(ginCtx *gin.Context) {
+	err := a.imageStore.RevokeAlbumShare(ginCtx.Request.Context(), ginCtx.Param("albumName"), ginCtx.Param("id"))
+	if err != nil {
+		writeAlbumShareError(ginCtx, err)
+
+		return
+	}
+
+	ginCtx.Status(http.StatusNoContent)
+}
+
+// GetSharedAlbum lists the images of the album of a share token. It is
+// public, the password of the share is read from the basic authorization.
+func (a *APIHandler) GetSharedAlbum(ginCtx *gin.Context) {
+	share, ok := a.openAlbumShare(ginCtx)
+	if !ok {
+		return
+	}
+
+	images, err := a.imageStore.GetAllImages(ginCtx.Request.Context(), share.AlbumName)
+	if err != nil {
+		writeAlbumShareError(ginCtx, err)
+
+		return
+	}
+
+	token := ginCtx.Param("token")
+	response := models.SharedAlbum{
+		AlbumName:     share.AlbumName,
+		AllowDownload: share.AllowDownload,
+		ExpiresAt:     share.ExpiresAt,
+		Images:        make([]models.SharedImage, len(images)),
+	}
+
+	for idx, image := range images {
+		response.Images[idx] = models.SharedImage{
+			ImageName: image.ImageName,
+			Metadata:  image.Metadata,
+			URL:       sharedImagePath(token, image.ImageName),
+		}
+	}
+
+	ginCtx.JSON(http.StatusOK, response)
+}
+
+// GetSharedImage serves the content of an image of the album of a share
+// token. It is served as an attachment with the download query parameter,
+// which the share must allow.
+func (a *APIHandler) GetSharedImage(ginCtx *gin.Context) {
+	share, ok := a.openAlbumShare(ginCtx)
+	if !ok {
+		return
+	}
+
+	download := ginCtx.Query("download") == "true"
+	if download && !share.AllowDownload {
+		writeShareDownloadForbidden(ginCtx)
+
+		return
+	}
+
+	image, err := a.imageStore.GetAlbumImage(ginCtx.Request.Context(), share.AlbumName, ginCtx.Param("imageName"))
+	if err != nil {
+		writeAlbumShareError(ginCtx, err)
+
+		return
+	}
+
+	disposition := "inline"
+	if download {
+		disposition = "attachment"
+	}
+
+	content := image.Content()
+	ginCtx.Header("Content-Disposition", fmt.Sprintf("%s; filename=%q", disposition, image.ImageName))
+	ginCtx.Header("Cache-Control", "private, no-cache")
+	ginCtx.Data(http.StatusOK, http.DetectContentType(content), content)
+}
+
+// ExportSharedAlbum streams the images of the album of a share token as a
+// ZIP archive, when the share allows downloads.
+func (a *APIHandler) ExportSharedAlbum(ginCtx *gin.Context) {
+	share, ok := a.openAlbumShare(ginCtx)
+	if !ok {
+		return
+	}
+
+	if !share.AllowDownload {
+		writeShareDownloadForbidden(ginCtx)
+
+		return
+	}
+
+	a.writeAlbumArchive(ginCtx, share.AlbumName)
+}
+
+// openAlbumShare returns the share of the token path parameter, and scopes
+// the rest of the request to the tenant of the share. It answers the error
+// and reports false when the share is not active or the password is wrong.
+func (a *APIHandler) openAlbumShare(ginCtx *gin.Context) (dbmodels.AlbumShare, bool) {
+	_, password, _ := ginCtx.Request.BasicAuth()
+
+	share, err := a.imageStore.OpenAlbumShare(ginCtx.Request.Context(), ginCtx.Param("token"), password)
+	if err != nil {
+		if errors.Is(err, controller.ErrSharePassword) {
+			ginCtx.Header("WWW-Authenticate", `Basic realm="album share", charset="UTF-8"`)
+		}
+
+		writeAlbumShareError(ginCtx, err)
+
+		return dbmodels.AlbumShare{}, false
+	}
+
+	ginCtx.Request = ginCtx.Request.WithContext(tenant.NewContext(ginCtx.Request.Context(), share.Tenant))
+
+	return share, true
+}
+
+func albumShareModel(share dbmodels.AlbumShare) models.AlbumShare {
+	return models.AlbumShare{
+		ID:            share.ID,
+		AlbumName:     share.AlbumName,
+		HasPassword:   share.PasswordHash != "",
+		AllowDownload: share.AllowDownload,
+		CreatedBy:     share.CreatedBy,
+		CreatedAt:     share.CreatedAt,
+		ExpiresAt:     share.ExpiresAt,
+		RevokedAt:     share.RevokedAt,
+	}
+}
+
+func sharePath(token string) string {
+	return fmt.Sprintf("%s/%s", shareBasePath, url.PathEscape(token))
+}
+
+func sharedImagePath(token, imageName string) string {
+	return fmt.Sprintf("%s/images/%s", sharePath(token), url.PathEscape(imageName))
+}
+
+func writeShareDownloadForbidden(ginCtx *gin.Context) {
+	ginCtx.JSON(http.StatusForbidden, models.ResponseError{
+		HTTPStatusCode: http.StatusForbidden,
+		ErrorCode:      "FORBIDDEN",
+		MessageDetails: "album share does not allow downloads",
+	})
+}
+
+func writeAlbumShareError(ginCtx *gin.Context, err error) {
+	status, errorCode := 0, ""
+
+	switch {
+	case errors.Is(err, controller.ErrInvalidShare):
+		status, errorCode = http.StatusBadRequest, "BAD-REQUEST"
+	case errors.Is(err, controller.ErrSharePassword):
+		status, errorCode = http.StatusUnauthorized, "UNAUTHORIZED"
+	case errors.Is(err, controller.ErrShareNotFound), errors.Is(err, dbhandler.ErrNoDataFound):
+		status, errorCode = http.StatusNotFound, "NOT-FOUND"
+	default:
+		writeInternalError(ginCtx, err)
+
+		return
+	}
+
+	ginCtx.JSON(status, models.ResponseError{
+		HTTPStatusCode: status,
+		ErrorCode:      errorCode,
+		MessageDetails: err.Error(),
+	})
+}
@@ -0,0 +1,290 @@
+package apihandler
+
+import (
+	"context"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"githum.com/anupam111/image-store/internal/controller"
+	"githum.com/anupam111/image-store/internal/db/dbhandler"
+	"githum.com/anupam111/image-store/internal/db/dbmodels"
+	"githum.com/anupam111/image-store/internal/tenant"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func Test_AlbumShares(t *testing.T) {
+	t.Parallel()
+
+	createdAt := time.Date(2022, 5, 1, 10, 0, 0, 0, time.UTC)
+	expiresAt := createdAt.Add(time.Hour)
+	share := dbmodels.AlbumShare{
+		ID:            "0011223344556677",
+		AlbumName:     "holiday",
+		PasswordHash:  "hash",
+		AllowDownload: true,
+		CreatedBy:     "alice",
+		CreatedAt:     createdAt,
+		ExpiresAt:     &expiresAt,
+	}
+
+	tests := []struct {
+		name         string
+		method       string
+		url          string
+		body         string
+		prepare      func(subs *controller.MockImageStore)
+		statusCode   int
+		expectedBody string
+	}{
+		{
+			name:   "create",
+			method: http.MethodPost,
+			url:    "/album/holiday/shares",
+			body:   `{"expiresAt":"2022-05-01T11:00:00Z","password":"secret","allowDownload":true}`,
+			prepare: func(subs *controller.MockImageStore) {
+				subs.EXPECT().CreateAlbumShare(gomock.Any(), dbmodels.AlbumShare{
+					AlbumName:     "holiday",
+					ExpiresAt:     &expiresAt,
+					AllowDownload: true,
+				}, "secret").Return("shr_token", share, nil)
+			},
+			statusCode: http.StatusCreated,
+			expectedBody: `{"id":"0011223344556677","albumName":"holiday","hasPassword":true,"allowDownload":true,
+				"createdBy":"alice","createdAt":"2022-05-01T10:00:00Z","expiresAt":"2022-05-01T11:00:00Z",
+				"token":"shr_token","url":"/s/shr_token"}`,
+		},
+		{
+			name:       "create_invalid_body",
+			method:     http.MethodPost,
+			url:        "/album/holiday/shares",
+			body:       `{"expiresAt":"tomorrow"}`,
+			statusCode: http.StatusBadRequest,
+		},
+		{
+			name:   "create_expired",
+			method: http.MethodPost,
+			url:    "/album/holiday/shares",
+			body:   `{}`,
+			prepare: func(subs *controller.MockImageStore) {
+				subs.EXPECT().CreateAlbumShare(gomock.Any(), gomock.Any(), "").Return("", dbmodels.AlbumShare{}, controller.ErrInvalidShare)
+			},
+			statusCode: http.StatusBadRequest,
+		},
+		{
+			name:   "create_forbidden",
+			method: http.MethodPost,
+			url:    "/album/holiday/shares",
+			body:   `{}`,
+			prepare: func(subs *controller.MockImageStore) {
+				subs.EXPECT().CreateAlbumShare(gomock.Any(), gomock.Any(), "").Return("", dbmodels.AlbumShare{}, controller.ErrAccessDenied)
+			},
+			statusCode: http.StatusForbidden,
+		},
+		{
+			name:   "list",
+			method: http.MethodGet,
+			url:    "/album/holiday/shares",
+			prepare: func(subs *controller.MockImageStore) {
+				subs.EXPECT().ListAlbumShares(gomock.Any(), "holiday").Return([]dbmodels.AlbumShare{share}, nil)
+			},
+			statusCode: http.StatusOK,
+			expectedBody: `[{"id":"0011223344556677","albumName":"holiday","hasPassword":true,"allowDownload":true,
+				"createdBy":"alice","createdAt":"2022-05-01T10:00:00Z","expiresAt":"2022-05-01T11:00:00Z"}]`,
+		},
+		{
+			name:   "revoke",
+			method: http.MethodDelete,
+			url:    "/album/holiday/shares/0011223344556677",
+			prepare: func(subs *controller.MockImageStore) {
+				subs.EXPECT().RevokeAlbumShare(gomock.Any(), "holiday", "0011223344556677").Return(nil)
+			},
+			statusCode: http.StatusNoContent,
+		},
+		{
+			name:   "revoke_unknown",
+			method: http.MethodDelete,
+			url:    "/album/holiday/shares/0011223344556677",
+			prepare: func(subs *controller.MockImageStore) {
+				subs.EXPECT().RevokeAlbumShare(gomock.Any(), "holiday", "0011223344556677").Return(dbhandler.ErrNoDataFound)
+			},
+			statusCode: http.StatusNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			router, controller, apiHandler := setupTestEnv(t)
+			if tt.prepare != nil {
+				tt.prepare(controller)
+			}
+
+			router.GET("/album/:albumName/shares", apiHandler.ListAlbumShares)
+			router.POST("/album/:albumName/shares", apiHandler.CreateAlbumShare)
+			router.DELETE("/album/:albumName/shares/:id", apiHandler.RevokeAlbumShare)
+
+			req := httptest.NewRequest(tt.method, tt.url, strings.NewReader(tt.body))
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+			assert.Equal(t, tt.statusCode, w.Code)
+
+			if tt.expectedBody != "" {
+				assert.JSONEq(t, tt.expectedBody, w.Body.String())
+			}
+		})
+	}
+}
+
+func Test_SharedAlbum(t *testing.T) {
+	t.Parallel()
+
+	viewOnly := dbmodels.AlbumShare{ID: "1", Tenant: "acme", AlbumName: "holiday"}
+	downloadable := dbmodels.AlbumShare{ID: "2", AlbumName: "holiday", AllowDownload: true}
+
+	image := dbmodels.Image{ImageName: "beach sunset.png", AlbumName: "holiday", Metadata: dbmodels.Metadata{"rating": 4.0}}
+	image.SetContent(pngContent)
+
+	tests := []struct {
+		name        string
+		url         string
+		password    string
+		prepare     func(subs *controller.MockImageStore)
+		statusCode  int
+		body        string
+		disposition string
+	}{
+		{
+			name:     "album",
+			url:      "/s/shr_token",
+			password: "secret",
+			prepare: func(subs *controller.MockImageStore) {
+				subs.EXPECT().OpenAlbumShare(gomock.Any(), "shr_token", "secret").Return(viewOnly, nil)
+				subs.EXPECT().GetAllImages(gomock.Any(), "holiday").
+					DoAndReturn(func(ctx context.Context, _ string) ([]dbmodels.Image, error) {
+						assert.Equal(t, "acme", tenant.FromContext(ctx), "the album is read in the tenant of the share")
+
+						return []dbmodels.Image{image}, nil
+					})
+			},
+			statusCode: http.StatusOK,
+			body: `{"albumName":"holiday","allowDownload":false,"images":[
+				{"imageName":"beach sunset.png","metadata":{"rating":4},"url":"/s/shr_token/images/beach%20sunset.png"}]}`,
+		},
+		{
+			name: "wrong_password",
+			url:  "/s/shr_token",
+			prepare: func(subs *controller.MockImageStore) {
+				subs.EXPECT().OpenAlbumShare(gomock.Any(), "shr_token", "").Return(dbmodels.AlbumShare{}, controller.ErrSharePassword)
+			},
+			statusCode: http.StatusUnauthorized,
+		},
+		{
+			name: "unknown_token",
+			url:  "/s/shr_token",
+			prepare: func(subs *controller.MockImageStore) {
+				subs.EXPECT().OpenAlbumShare(gomock.Any(), "shr_token", "").Return(dbmodels.AlbumShare{}, controller.ErrShareNotFound)
+			},
+			statusCode: http.StatusNotFound,
+		},
+		{
+			name: "image",
+			url:  "/s/shr_token/images/beach%20sunset.png",
+			prepare: func(subs *controller.MockImageStore) {
+				subs.EXPECT().OpenAlbumShare(gomock.Any(), "shr_token", "").Return(viewOnly, nil)
+				subs.EXPECT().GetAlbumImage(gomock.Any(), "holiday", "beach sunset.png").Return(image, nil)
+			},
+			statusCode:  http.StatusOK,
+			disposition: `inline; filename="beach sunset.png"`,
+		},
+		{
+			name: "image_download",
+			url:  "/s/shr_token/images/beach%20sunset.png?download=true",
+			prepare: func(subs *controller.MockImageStore) {
+				subs.EXPECT().OpenAlbumShare(gomock.Any(), "shr_token", "").Return(downloadable, nil)
+				subs.EXPECT().GetAlbumImage(gomock.Any(), "holiday", "beach sunset.png").Return(image, nil)
+			},
+			statusCode:  http.StatusOK,
+			disposition: `attachment; filename="beach sunset.png"`,
+		},
+		{
+			name: "image_download_forbidden",
+			url:  "/s/shr_token/images/beach%20sunset.png?download=true",
+			prepare: func(subs *controller.MockImageStore) {
+				subs.EXPECT().OpenAlbumShare(gomock.Any(), "shr_token", "").Return(viewOnly, nil)
+			},
+			statusCode: http.StatusForbidden,
+		},
+		{
+			name: "image_of_another_album",
+			url:  "/s/shr_token/images/city.png",
+			prepare: func(subs *controller.MockImageStore) {
+				subs.EXPECT().OpenAlbumShare(gomock.Any(), "shr_token", "").Return(viewOnly, nil)
+				subs.EXPECT().GetAlbumImage(gomock.Any(), "holiday", "city.png").Return(dbmodels.Image{}, dbhandler.ErrNoDataFound)
+			},
+			statusCode: http.StatusNotFound,
+		},
+		{
+			name: "archive",
+			url:  "/s/shr_token/download.zip",
+			prepare: func(subs *controller.MockImageStore) {
+				subs.EXPECT().OpenAlbumShare(gomock.Any(), "shr_token", "").Return(downloadable, nil)
+				subs.EXPECT().ExportAlbumImages(gomock.Any(), "holiday", gomock.Any()).
+					DoAndReturn(func(_ interface{}, _ string, fn func(dbmodels.Image) error) error {
+						return fn(image)
+					})
+			},
+			statusCode:  http.StatusOK,
+			disposition: `attachment; filename="holiday.zip"`,
+		},
+		{
+			name: "archive_forbidden",
+			url:  "/s/shr_token/download.zip",
+			prepare: func(subs *controller.MockImageStore) {
+				subs.EXPECT().OpenAlbumShare(gomock.Any(), "shr_token", "").Return(viewOnly, nil)
+			},
+			statusCode: http.StatusForbidden,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			router, controller, apiHandler := setupTestEnv(t)
+			if tt.prepare != nil {
+				tt.prepare(controller)
+			}
+
+			router.GET("/s/:token", apiHandler.GetSharedAlbum)
+			router.GET("/s/:token/images/:imageName", apiHandler.GetSharedImage)
+			router.GET("/s/:token/download.zip", apiHandler.ExportSharedAlbum)
+
+			req := httptest.NewRequest(http.MethodGet, tt.url, nil)
+			if tt.password != "" {
+				req.SetBasicAuth("", tt.password)
+			}
+
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+			assert.Equal(t, tt.statusCode, w.Code)
+
+			if tt.body != "" {
+				assert.JSONEq(t, tt.body, w.Body.String())
+			}
+
+			if tt.disposition != "" {
+				assert.Equal(t, tt.disposition, w.Header().Get("Content-Disposition"))
+			}
+
+			if tt.statusCode == http.StatusUnauthorized {
+				assert.NotEmpty(t, w.Header().Get("WWW-Authenticate"))
+			}
+		})
+	}
+}
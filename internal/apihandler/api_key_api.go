@@ -0,0 +1,123 @@
+package apihandler
+
+import (
+	"errors"
+	"github.com/gin-gonic/gin"
+	log "github.com/sirupsen/logrus"
+	"githum.com/anupam111/image-store/internal/controller"
+	"githum.com/anupam111/image-store/internal/db/dbhandler"
+	"githum.com/anupam111/image-store/internal/db/dbmodels"
+	"githum.com/anupam111/image-store/internal/models"
+	"githum.com/anupam111/image-store/internal/tenant"
+	"net/http"
+)
+
+// APIKeyHandler handles the api key management api.
+type APIKeyHandler struct {
+	log     *log.Logger
+	apiKeys controller.APIKeys
+}
+
+// NewAPIKeyHandler implements APIKeyHandler.
+func NewAPIKeyHandler(logger *log.Logger, apiKeys controller.APIKeys) *APIKeyHandler {
+	return &APIKeyHandler{
+		log:     logger,
+		apiKeys: apiKeys,
+	}
+}
+
+// CreateAPIKey creates an API key and answers it, the key is only ever shown
+// in this response.
+func (a *APIKeyHandler) CreateAPIKey(ginCtx *gin.Context) {
+	var request models.APIKeyRequest
+	if err := ginCtx.ShouldBindJSON(&request); err != nil || request.Name == "" {
+		details := "name is empty in request body"
+		if err != nil {
+			details = err.Error()
+		}
+
+		ginCtx.JSON(http.StatusBadRequest, models.ResponseError{
+			HTTPStatusCode: http.StatusBadRequest,
+			ErrorCode:      "BAD-REQUEST",
+			MessageDetails: details,
+		})
+
+		return
+	}
+
+	key, apiKey, err := a.apiKeys.CreateAPIKey(ginCtx.Request.Context(), request.Name, request.Tenant, request.Scopes)
+	if errors.Is(err, controller.ErrInvalidScope) || errors.Is(err, controller.ErrInvalidTenant) {
+		ginCtx.JSON(http.StatusBadRequest, models.ResponseError{
+			HTTPStatusCode: http.StatusBadRequest,
+			ErrorCode:      "BAD-REQUEST",
+			MessageDetails: err.Error(),
+		})
+
+		return
+	}
+
+	if err != nil {
+		writeInternalError(ginCtx, err)
+
+		return
+	}
+
+	ginCtx.JSON(http.StatusCreated, models.CreatedAPIKey{
+		APIKey: apiKeyModel(apiKey),
+		Key:    key,
+	})
+}
+
+func (a *APIKeyHandler) ListAPIKeys(ginCtx *gin.Context) {
+	apiKeys, err := a.apiKeys.ListAPIKeys(ginCtx.Request.Context())
+	if err != nil {
+		writeInternalError(ginCtx, err)
+
+		return
+	}
+
+	response := make([]models.APIKey, len(apiKeys))
+	for idx, apiKey := range apiKeys {
+		response[idx] = apiKeyModel(apiKey)
+	}
+
+	ginCtx.JSON(http.StatusOK, response)
+}
+
+func (a *APIKeyHandler) RevokeAPIKey(ginCtx *gin.Context) {
+	err := a.apiKeys.RevokeAPIKey(ginCtx.Request.Context(), ginCtx.Param("id"))
+	if errors.Is(err, dbhandler.ErrNoDataFound) {
+		ginCtx.JSON(http.StatusNotFound, models.ResponseError{
+			HTTPStatusCode: http.StatusNotFound,
+			ErrorCode:      "NOT-FOUND",
+			MessageDetails: "no active api key with id " + ginCtx.Param("id"),
+		})
+
+		return
+	}
+
+	if err != nil {
+		writeInternalError(ginCtx, err)
+
+		return
+	}
+
+	ginCtx.Status(http.StatusNoContent)
+}
+
+// apiKeyModel shows the keys bound to no tenant as bound to tenant.Any.
+func apiKeyModel(apiKey dbmodels.APIKey) models.APIKey {
+	tenantID := apiKey.Tenant
+	if tenantID == "" {
+		tenantID = tenant.Any
+	}
+
+	return models.APIKey{
+		ID:        apiKey.ID,
+		Name:      apiKey.Name,
+		Tenant:    tenantID,
+		Scopes:    apiKey.Scopes,
+		CreatedAt: apiKey.CreatedAt,
+		RevokedAt: apiKey.RevokedAt,
+	}
+}
@@ -0,0 +1,189 @@
+package apihandler
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	log "github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"githum.com/anupam111/image-store/internal/controller"
+	"githum.com/anupam111/image-store/internal/db/dbhandler"
+	"githum.com/anupam111/image-store/internal/db/dbmodels"
+	"githum.com/anupam111/image-store/internal/tenant"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func setupAPIKeyTestEnv(t *testing.T) (*gin.Engine, *controller.MockAPIKeys) {
+	t.Helper()
+	mockCtrl := gomock.NewController(t)
+	mockAPIKeys := controller.NewMockAPIKeys(mockCtrl)
+	handler := NewAPIKeyHandler(log.New(), mockAPIKeys)
+
+	router := gin.New()
+	router.POST("/keys", handler.CreateAPIKey)
+	router.GET("/keys", handler.ListAPIKeys)
+	router.DELETE("/keys/:id", handler.RevokeAPIKey)
+
+	return router, mockAPIKeys
+}
+
+func Test_APIKeyHandler(t *testing.T) {
+	t.Parallel()
+
+	createdAt := time.Date(2022, 5, 1, 10, 0, 0, 0, time.UTC)
+	apiKey := dbmodels.APIKey{
+		ID:        "0123456789abcdef",
+		Name:      "ci",
+		Hash:      "hash",
+		Tenant:    tenant.Default,
+		Scopes:    dbmodels.Scopes{dbmodels.ScopeImagesRead},
+		CreatedAt: createdAt,
+	}
+
+	tests := []struct {
+		name         string
+		method       string
+		url          string
+		body         string
+		prepare      func(apiKeys *controller.MockAPIKeys)
+		statusCode   int
+		expectedBody string
+	}{
+		{
+			name:   "create",
+			method: http.MethodPost,
+			url:    "/keys",
+			body:   `{"name":"ci","scopes":["images:read"]}`,
+			prepare: func(apiKeys *controller.MockAPIKeys) {
+				apiKeys.EXPECT().CreateAPIKey(gomock.Any(), "ci", "", dbmodels.Scopes{dbmodels.ScopeImagesRead}).
+					Return("isk_secret", apiKey, nil)
+			},
+			statusCode: http.StatusCreated,
+			expectedBody: `{"id":"0123456789abcdef","name":"ci","tenant":"default","scopes":["images:read"],` +
+				`"createdAt":"2022-05-01T10:00:00Z","key":"isk_secret"}`,
+		},
+		{
+			name:       "create_without_name",
+			method:     http.MethodPost,
+			url:        "/keys",
+			body:       `{"scopes":["images:read"]}`,
+			statusCode: http.StatusBadRequest,
+		},
+		{
+			name:   "create_with_invalid_scope",
+			method: http.MethodPost,
+			url:    "/keys",
+			body:   `{"name":"ci","scopes":["everything"]}`,
+			prepare: func(apiKeys *controller.MockAPIKeys) {
+				apiKeys.EXPECT().CreateAPIKey(gomock.Any(), "ci", "", dbmodels.Scopes{"everything"}).
+					Return("", dbmodels.APIKey{}, controller.ErrInvalidScope)
+			},
+			statusCode: http.StatusBadRequest,
+		},
+		{
+			name:   "create_for_tenant",
+			method: http.MethodPost,
+			url:    "/keys",
+			body:   `{"name":"ci","tenant":"acme","scopes":["images:read"]}`,
+			prepare: func(apiKeys *controller.MockAPIKeys) {
+				tenantKey := apiKey
+				tenantKey.Tenant = "acme"
+				apiKeys.EXPECT().CreateAPIKey(gomock.Any(), "ci", "acme", dbmodels.Scopes{dbmodels.ScopeImagesRead}).
+					Return("isk_secret", tenantKey, nil)
+			},
+			statusCode: http.StatusCreated,
+			expectedBody: `{"id":"0123456789abcdef","name":"ci","tenant":"acme","scopes":["images:read"],` +
+				`"createdAt":"2022-05-01T10:00:00Z","key":"isk_secret"}`,
+		},
+		{
+			name:   "create_unbound",
+			method: http.MethodPost,
+			url:    "/keys",
+			body:   `{"name":"ops","tenant":"*","scopes":["admin"]}`,
+			prepare: func(apiKeys *controller.MockAPIKeys) {
+				unboundKey := apiKey
+				unboundKey.Name = "ops"
+				unboundKey.Tenant = ""
+				unboundKey.Scopes = dbmodels.Scopes{dbmodels.ScopeAdmin}
+				apiKeys.EXPECT().CreateAPIKey(gomock.Any(), "ops", tenant.Any, dbmodels.Scopes{dbmodels.ScopeAdmin}).
+					Return("isk_secret", unboundKey, nil)
+			},
+			statusCode: http.StatusCreated,
+			expectedBody: `{"id":"0123456789abcdef","name":"ops","tenant":"*","scopes":["admin"],` +
+				`"createdAt":"2022-05-01T10:00:00Z","key":"isk_secret"}`,
+		},
+		{
+			name:   "create_with_invalid_tenant",
+			method: http.MethodPost,
+			url:    "/keys",
+			body:   `{"name":"ci","tenant":"../acme","scopes":["images:read"]}`,
+			prepare: func(apiKeys *controller.MockAPIKeys) {
+				apiKeys.EXPECT().CreateAPIKey(gomock.Any(), "ci", "../acme", dbmodels.Scopes{dbmodels.ScopeImagesRead}).
+					Return("", dbmodels.APIKey{}, controller.ErrInvalidTenant)
+			},
+			statusCode: http.StatusBadRequest,
+		},
+		{
+			name:   "list",
+			method: http.MethodGet,
+			url:    "/keys",
+			prepare: func(apiKeys *controller.MockAPIKeys) {
+				apiKeys.EXPECT().ListAPIKeys(gomock.Any()).Return([]dbmodels.APIKey{apiKey}, nil)
+			},
+			statusCode: http.StatusOK,
+			expectedBody: `[{"id":"0123456789abcdef","name":"ci","tenant":"default","scopes":["images:read"],` +
+				`"createdAt":"2022-05-01T10:00:00Z"}]`,
+		},
+		{
+			name:   "list_internal_server_error",
+			method: http.MethodGet,
+			url:    "/keys",
+			prepare: func(apiKeys *controller.MockAPIKeys) {
+				apiKeys.EXPECT().ListAPIKeys(gomock.Any()).Return(nil, errFake)
+			},
+			statusCode: http.StatusInternalServerError,
+		},
+		{
+			name:   "revoke",
+			method: http.MethodDelete,
+			url:    "/keys/0123456789abcdef",
+			prepare: func(apiKeys *controller.MockAPIKeys) {
+				apiKeys.EXPECT().RevokeAPIKey(gomock.Any(), "0123456789abcdef").Return(nil)
+			},
+			statusCode: http.StatusNoContent,
+		},
+		{
+			name:   "revoke_unknown",
+			method: http.MethodDelete,
+			url:    "/keys/unknown",
+			prepare: func(apiKeys *controller.MockAPIKeys) {
+				apiKeys.EXPECT().RevokeAPIKey(gomock.Any(), "unknown").Return(dbhandler.ErrNoDataFound)
+			},
+			statusCode: http.StatusNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			router, apiKeys := setupAPIKeyTestEnv(t)
+			if tt.prepare != nil {
+				tt.prepare(apiKeys)
+			}
+
+			req := httptest.NewRequest(tt.method, tt.url, strings.NewReader(tt.body))
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+			assert.Equal(t, tt.statusCode, w.Code)
+
+			if tt.expectedBody != "" {
+				assert.JSONEq(t, tt.expectedBody, w.Body.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,224 @@
+package apihandler
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	log "github.com/sirupsen/logrus"
+	"githum.com/anupam111/image-store/internal/constants"
+	"githum.com/anupam111/image-store/internal/controller"
+	"githum.com/anupam111/image-store/internal/db/dbmodels"
+	"githum.com/anupam111/image-store/internal/models"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// The formats of the audit log export.
+const (
+	auditFormatCSV   = "csv"
+	auditFormatJSONL = "jsonl"
+)
+
+// auditCSVHeader names the columns of the CSV export of the audit log.
+var auditCSVHeader = []string{
+	"id", "occurredAt", "actor", "action", "albumName", "target",
+	"details", "requestId", "clientIp", "outcome", "error",
+}
+
+// AuditHandler handles the audit log api.
+type AuditHandler struct {
+	log      *log.Logger
+	auditLog controller.AuditLog
+}
+
+// NewAuditHandler implements AuditHandler.
+func NewAuditHandler(logger *log.Logger, auditLog controller.AuditLog) *AuditHandler {
+	return &AuditHandler{
+		log:      logger,
+		auditLog: auditLog,
+	}
+}
+
+// ListAuditEvents answers a page of the audit log of the tenant of the
+// caller, latest event first, selected by the filter query parameters and
+// paginated by the limit and offset query parameters.
+func (a *AuditHandler) ListAuditEvents(ginCtx *gin.Context) {
+	filter, ok := auditFilter(ginCtx)
+	if !ok {
+		return
+	}
+
+	limit, ok := intQuery(ginCtx, "limit", constants.DefaultAuditLimit, 1, constants.MaxAuditLimit)
+	if !ok {
+		return
+	}
+
+	offset, ok := intQuery(ginCtx, "offset", 0, 0, -1)
+	if !ok {
+		return
+	}
+
+	events, total, err := a.auditLog.ListAuditEvents(ginCtx.Request.Context(), filter, limit, offset)
+	if err != nil {
+		writeInternalError(ginCtx, err)
+
+		return
+	}
+
+	response := models.AuditLog{
+		Total:  total,
+		Limit:  limit,
+		Offset: offset,
+		Events: make([]models.AuditEvent, len(events)),
+	}
+
+	for idx, event := range events {
+		response.Events[idx] = toAuditEvent(event)
+	}
+
+	ginCtx.JSON(http.StatusOK, response)
+}
+
+// ExportAuditEvents streams every event of the audit log of the tenant of the
+// caller selected by the filter query parameters, oldest first, as CSV or as
+// JSON lines after the format query parameter.
+func (a *AuditHandler) ExportAuditEvents(ginCtx *gin.Context) {
+	filter, ok := auditFilter(ginCtx)
+	if !ok {
+		return
+	}
+
+	format := ginCtx.DefaultQuery("format", auditFormatCSV)
+
+	var (
+		start = func() error { return nil }
+		write func(models.AuditEvent) error
+		flush = func() error { return nil }
+	)
+
+	switch format {
+	case auditFormatCSV:
+		ginCtx.Header("Content-Type", "text/csv; charset=utf-8")
+
+		writer := csv.NewWriter(ginCtx.Writer)
+		start = func() error {
+			return writer.Write(auditCSVHeader)
+		}
+		write = func(event models.AuditEvent) error {
+			return writer.Write([]string{
+				strconv.FormatInt(event.ID, 10), event.OccurredAt.Format(time.RFC3339Nano), event.Actor,
+				event.Action, event.AlbumName, event.Target, event.Details, event.RequestID,
+				event.ClientIP, event.Outcome, event.Error,
+			})
+		}
+		flush = func() error {
+			writer.Flush()
+
+			return writer.Error()
+		}
+	case auditFormatJSONL:
+		ginCtx.Header("Content-Type", "application/x-ndjson")
+
+		encoder := json.NewEncoder(ginCtx.Writer)
+		write = func(event models.AuditEvent) error {
+			return encoder.Encode(event)
+		}
+	default:
+		ginCtx.JSON(http.StatusBadRequest, models.ResponseError{
+			HTTPStatusCode: http.StatusBadRequest,
+			ErrorCode:      "BAD-REQUEST",
+			MessageDetails: fmt.Sprintf("format must be %s or %s", auditFormatCSV, auditFormatJSONL),
+		})
+
+		return
+	}
+
+	ginCtx.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", "audit."+format))
+	ginCtx.Status(http.StatusOK)
+
+	if err := start(); err != nil {
+		a.log.Errorf("error while exporting audit log: %v", err)
+		ginCtx.Abort()
+
+		return
+	}
+
+	err := a.auditLog.ExportAuditEvents(ginCtx.Request.Context(), filter, func(event dbmodels.AuditEvent) error {
+		if err := write(toAuditEvent(event)); err != nil {
+			return err
+		}
+
+		if err := flush(); err != nil {
+			return err
+		}
+
+		ginCtx.Writer.Flush()
+
+		return nil
+	})
+	if err != nil {
+		// The status line is already sent, the client sees the export cut
+		// short.
+		a.log.Errorf("error while exporting audit log: %v", err)
+		ginCtx.Abort()
+
+		return
+	}
+
+	if err = flush(); err != nil {
+		a.log.Errorf("error while finishing audit log export: %v", err)
+	}
+}
+
+// auditFilter parses the filter of the audit log from the query parameters.
+// It answers 400 and reports false when from or to is not an RFC 3339 time.
+func auditFilter(ginCtx *gin.Context) (dbmodels.AuditFilter, bool) {
+	filter := dbmodels.AuditFilter{
+		Actor:     ginCtx.Query("actor"),
+		Action:    ginCtx.Query("action"),
+		AlbumName: ginCtx.Query("albumName"),
+		Target:    ginCtx.Query("target"),
+		Outcome:   ginCtx.Query("outcome"),
+		RequestID: ginCtx.Query("requestId"),
+	}
+
+	for name, bound := range map[string]*time.Time{"from": &filter.From, "to": &filter.To} {
+		raw := ginCtx.Query(name)
+		if raw == "" {
+			continue
+		}
+
+		parsed, err := time.Parse(time.RFC3339, raw)
+		if err != nil {
+			ginCtx.JSON(http.StatusBadRequest, models.ResponseError{
+				HTTPStatusCode: http.StatusBadRequest,
+				ErrorCode:      "BAD-REQUEST",
+				MessageDetails: name + " must be an RFC 3339 time",
+			})
+
+			return dbmodels.AuditFilter{}, false
+		}
+
+		*bound = parsed
+	}
+
+	return filter, true
+}
+
+func toAuditEvent(event dbmodels.AuditEvent) models.AuditEvent {
+	return models.AuditEvent{
+		ID:         event.ID,
+		OccurredAt: event.OccurredAt,
+		Actor:      event.Actor,
+		Action:     event.Action,
+		AlbumName:  event.AlbumName,
+		Target:     event.Target,
+		Details:    event.Details,
+		RequestID:  event.RequestID,
+		ClientIP:   event.ClientIP,
+		Outcome:    event.Outcome,
+		Error:      event.Error,
+	}
+}
@@ -0,0 +1,163 @@
+package apihandler
+
+import (
+	"context"
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	log "github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"githum.com/anupam111/image-store/internal/controller"
+	"githum.com/anupam111/image-store/internal/db/dbmodels"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func setupAuditTestEnv(t *testing.T) (*gin.Engine, *controller.MockAuditLog) {
+	t.Helper()
+	mockCtrl := gomock.NewController(t)
+	mockAuditLog := controller.NewMockAuditLog(mockCtrl)
+	handler := NewAuditHandler(log.New(), mockAuditLog)
+
+	router := gin.New()
+	router.GET("/audit", handler.ListAuditEvents)
+	router.GET("/audit/export", handler.ExportAuditEvents)
+
+	return router, mockAuditLog
+}
+
+func Test_AuditHandler(t *testing.T) {
+	t.Parallel()
+
+	occurredAt := time.Date(2022, 5, 1, 10, 0, 0, 0, time.UTC)
+	event := dbmodels.AuditEvent{
+		ID:         7,
+		Tenant:     "default",
+		OccurredAt: occurredAt,
+		Actor:      "alice",
+		Action:     dbmodels.AuditImageMove,
+		AlbumName:  "holiday",
+		Target:     "beach.png",
+		Details:    "to=holiday/sea.png",
+		RequestID:  "req-1",
+		ClientIP:   "10.0.0.1",
+		Outcome:    dbmodels.OutcomeSuccess,
+	}
+	exportEvents := func(auditLog *controller.MockAuditLog, filter dbmodels.AuditFilter) {
+		auditLog.EXPECT().ExportAuditEvents(gomock.Any(), filter, gomock.Any()).DoAndReturn(
+			func(_ context.Context, _ dbmodels.AuditFilter, fn func(dbmodels.AuditEvent) error) error {
+				return fn(event)
+			})
+	}
+
+	tests := []struct {
+		name         string
+		url          string
+		prepare      func(auditLog *controller.MockAuditLog)
+		statusCode   int
+		contentType  string
+		expectedBody string
+	}{
+		{
+			name: "list",
+			url:  "/audit",
+			prepare: func(auditLog *controller.MockAuditLog) {
+				auditLog.EXPECT().ListAuditEvents(gomock.Any(), dbmodels.AuditFilter{}, 50, 0).
+					Return([]dbmodels.AuditEvent{event}, 1, nil)
+			},
+			statusCode: http.StatusOK,
+			expectedBody: `{"total":1,"limit":50,"offset":0,"events":[{"id":7,"occurredAt":"2022-05-01T10:00:00Z",` +
+				`"actor":"alice","action":"image.move","albumName":"holiday","target":"beach.png",` +
+				`"details":"to=holiday/sea.png","requestId":"req-1","clientIp":"10.0.0.1","outcome":"success"}]}`,
+		},
+		{
+			name: "list_with_filters",
+			url: "/audit?actor=bob&action=image.delete&albumName=holiday&outcome=denied" +
+				"&from=2022-05-01T00:00:00Z&to=2022-05-02T00:00:00Z&limit=10&offset=20",
+			prepare: func(auditLog *controller.MockAuditLog) {
+				auditLog.EXPECT().ListAuditEvents(gomock.Any(), dbmodels.AuditFilter{
+					Actor:     "bob",
+					Action:    dbmodels.AuditImageDelete,
+					AlbumName: "holiday",
+					Outcome:   dbmodels.OutcomeDenied,
+					From:      time.Date(2022, 5, 1, 0, 0, 0, 0, time.UTC),
+					To:        time.Date(2022, 5, 2, 0, 0, 0, 0, time.UTC),
+				}, 10, 20).Return(nil, 20, nil)
+			},
+			statusCode:   http.StatusOK,
+			expectedBody: `{"total":20,"limit":10,"offset":20,"events":[]}`,
+		},
+		{
+			name:       "list_with_invalid_from",
+			url:        "/audit?from=yesterday",
+			statusCode: http.StatusBadRequest,
+		},
+		{
+			name:       "list_with_invalid_limit",
+			url:        "/audit?limit=1000",
+			statusCode: http.StatusBadRequest,
+		},
+		{
+			name: "list_internal_server_error",
+			url:  "/audit",
+			prepare: func(auditLog *controller.MockAuditLog) {
+				auditLog.EXPECT().ListAuditEvents(gomock.Any(), dbmodels.AuditFilter{}, 50, 0).Return(nil, 0, errFake)
+			},
+			statusCode: http.StatusInternalServerError,
+		},
+		{
+			name: "export_csv",
+			url:  "/audit/export?actor=alice",
+			prepare: func(auditLog *controller.MockAuditLog) {
+				exportEvents(auditLog, dbmodels.AuditFilter{Actor: "alice"})
+			},
+			statusCode:  http.StatusOK,
+			contentType: "text/csv; charset=utf-8",
+			expectedBody: "id,occurredAt,actor,action,albumName,target,details,requestId,clientIp,outcome,error\n" +
+				"7,2022-05-01T10:00:00Z,alice,image.move,holiday,beach.png,to=holiday/sea.png,req-1,10.0.0.1,success,\n",
+		},
+		{
+			name: "export_jsonl",
+			url:  "/audit/export?format=jsonl",
+			prepare: func(auditLog *controller.MockAuditLog) {
+				exportEvents(auditLog, dbmodels.AuditFilter{})
+			},
+			statusCode:  http.StatusOK,
+			contentType: "application/x-ndjson",
+			expectedBody: `{"id":7,"occurredAt":"2022-05-01T10:00:00Z","actor":"alice","action":"image.move",` +
+				`"albumName":"holiday","target":"beach.png","details":"to=holiday/sea.png","requestId":"req-1",` +
+				`"clientIp":"10.0.0.1","outcome":"success"}` + "\n",
+		},
+		{
+			name:       "export_with_unknown_format",
+			url:        "/audit/export?format=xml",
+			statusCode: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			router, auditLog := setupAuditTestEnv(t)
+			if tt.prepare != nil {
+				tt.prepare(auditLog)
+			}
+
+			req := httptest.NewRequest(http.MethodGet, tt.url, nil)
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+			assert.Equal(t, tt.statusCode, w.Code)
+
+			switch {
+			case tt.contentType != "":
+				assert.Equal(t, tt.contentType, w.Header().Get("Content-Type"))
+				assert.Equal(t, tt.expectedBody, w.Body.String())
+			case tt.expectedBody != "":
+				assert.JSONEq(t, tt.expectedBody, w.Body.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,45 @@
+package apihandler
+
+import (
+	"github.com/gin-gonic/gin"
+	log "github.com/sirupsen/logrus"
+	"githum.com/anupam111/image-store/internal/models"
+	"net/http"
+)
+
+// DBStatus reports the database connectivity, nil when the database is reachable.
+type DBStatus interface {
+	Status() error
+}
+
+// HealthHandler handles the status api used by the readiness and liveness probes.
+type HealthHandler struct {
+	log      *log.Logger
+	dbStatus DBStatus
+}
+
+// NewHealthHandler implements HealthHandler.
+func NewHealthHandler(logger *log.Logger, dbStatus DBStatus) *HealthHandler {
+	return &HealthHandler{
+		log:      logger,
+		dbStatus: dbStatus,
+	}
+}
+
+// Status answers 200 while the database is reachable and 503 otherwise.
+func (h *HealthHandler) Status(ginCtx *gin.Context) {
+	if err := h.dbStatus.Status(); err != nil {
+		ginCtx.JSON(http.StatusServiceUnavailable, models.HealthStatus{
+			Status:   "DOWN",
+			Database: "DOWN",
+			Details:  err.Error(),
+		})
+
+		return
+	}
+
+	ginCtx.JSON(http.StatusOK, models.HealthStatus{
+		Status:   "UP",
+		Database: "UP",
+	})
+}
@@ -0,0 +1,62 @@
+package apihandler
+
+import (
+	"encoding/json"
+	"github.com/gin-gonic/gin"
+	log "github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"githum.com/anupam111/image-store/internal/models"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+type fakeDBStatus struct {
+	err error
+}
+
+func (f fakeDBStatus) Status() error {
+	return f.err
+}
+
+func Test_Status(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name           string
+		dbStatus       fakeDBStatus
+		expectedStatus int
+		expectedBody   models.HealthStatus
+	}{
+		{
+			name:           "database reachable",
+			expectedStatus: http.StatusOK,
+			expectedBody:   models.HealthStatus{Status: "UP", Database: "UP"},
+		},
+		{
+			name:           "database unreachable",
+			dbStatus:       fakeDBStatus{err: errFake},
+			expectedStatus: http.StatusServiceUnavailable,
+			expectedBody:   models.HealthStatus{Status: "DOWN", Database: "DOWN", Details: errFake.Error()},
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			router := gin.New()
+			handler := NewHealthHandler(log.New(), tt.dbStatus)
+			router.GET("/status", handler.Status)
+
+			req := httptest.NewRequest(http.MethodGet, "/status", nil)
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, req)
+
+			var body models.HealthStatus
+			assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &body))
+			assert.Equal(t, tt.expectedStatus, rec.Code)
+			assert.Equal(t, tt.expectedBody, body)
+		})
+	}
+}
@@ -1,9 +1,12 @@
 package apihandler
 
 import (
+	"context"
 	"errors"
+	"fmt"
 	"github.com/gin-gonic/gin"
 	log "github.com/sirupsen/logrus"
+	"githum.com/anupam111/image-store/internal/constants"
 	"githum.com/anupam111/image-store/internal/controller"
 	"githum.com/anupam111/image-store/internal/db/dbhandler"
 	"githum.com/anupam111/image-store/internal/db/dbmodels"
@@ -39,13 +42,9 @@ func (a *APIHandler) CreateImageAlbum(ginCtx *gin.Context) {
 
 	a.log.Debugf("album post request payload got: %+v", album)
 
-	err := a.imageStore.CreateImageAlbum(album)
+	err := a.imageStore.CreateImageAlbum(ginCtx.Request.Context(), album)
 	if err != nil {
-		ginCtx.JSON(http.StatusInternalServerError, models.ResponseError{
-			HTTPStatusCode: http.StatusInternalServerError,
-			ErrorCode:      "INTERNAL-SERVER-ERROR",
-			MessageDetails: err.Error(),
-		})
+		writeInternalError(ginCtx, err)
 
 		return
 	}
@@ -67,13 +66,9 @@ func (a *APIHandler) CreateImage(ginCtx *gin.Context) {
 
 	a.log.Debugf("image post request payload got: %+v", imageModel)
 
-	err := a.imageStore.CreateImage(imageModel)
+	err := a.imageStore.CreateImage(ginCtx.Request.Context(), imageModel)
 	if err != nil {
-		ginCtx.JSON(http.StatusInternalServerError, models.ResponseError{
-			HTTPStatusCode: http.StatusInternalServerError,
-			ErrorCode:      "INTERNAL-SERVER-ERROR",
-			MessageDetails: err.Error(),
-		})
+		writeInternalError(ginCtx, err)
 
 		return
 	}
@@ -93,13 +88,9 @@ func (a *APIHandler) DeleteImageAlbum(ginCtx *gin.Context) {
 		return
 	}
 
-	err := a.imageStore.DeleteImageAlbum(albumName)
+	err := a.imageStore.DeleteImageAlbum(ginCtx.Request.Context(), albumName)
 	if err != nil {
-		ginCtx.JSON(http.StatusInternalServerError, models.ResponseError{
-			HTTPStatusCode: http.StatusInternalServerError,
-			ErrorCode:      "INTERNAL-SERVER-ERROR",
-			MessageDetails: err.Error(),
-		})
+		writeInternalError(ginCtx, err)
 
 		return
 	}
@@ -130,13 +121,9 @@ func (a *APIHandler) DeleteImage(ginCtx *gin.Context) {
 		return
 	}
 
-	err := a.imageStore.DeleteImage(imageName, albumName)
+	err := a.imageStore.DeleteImage(ginCtx.Request.Context(), imageName, albumName)
 	if err != nil {
-		ginCtx.JSON(http.StatusInternalServerError, models.ResponseError{
-			HTTPStatusCode: http.StatusInternalServerError,
-			ErrorCode:      "INTERNAL-SERVER-ERROR",
-			MessageDetails: err.Error(),
-		})
+		writeInternalError(ginCtx, err)
 
 		return
 	}
@@ -156,7 +143,7 @@ func (a *APIHandler) GetImageByID(ginCtx *gin.Context) {
 		return
 	}
 
-	image, err := a.imageStore.GetImage(imageName)
+	image, err := a.imageStore.GetImage(ginCtx.Request.Context(), imageName)
 	if err != nil {
 		if errors.Is(err, dbhandler.ErrNoDataFound) {
 			ginCtx.JSON(http.StatusOK, image)
@@ -164,11 +151,7 @@ func (a *APIHandler) GetImageByID(ginCtx *gin.Context) {
 			return
 		}
 
-		ginCtx.JSON(http.StatusInternalServerError, models.ResponseError{
-			HTTPStatusCode: http.StatusInternalServerError,
-			ErrorCode:      "INTERNAL-SERVER-ERROR",
-			MessageDetails: err.Error(),
-		})
+		writeInternalError(ginCtx, err)
 
 		return
 	}
@@ -188,16 +171,234 @@ func (a *APIHandler) GetAlbumImages(ginCtx *gin.Context) {
 		return
 	}
 
-	images, err := a.imageStore.GetAllImages(albumName)
+	filters, err := metadataFilters(ginCtx.Request.URL.RawQuery)
 	if err != nil {
-		ginCtx.JSON(http.StatusInternalServerError, models.ResponseError{
-			HTTPStatusCode: http.StatusInternalServerError,
-			ErrorCode:      "INTERNAL-SERVER-ERROR",
+		ginCtx.JSON(http.StatusBadRequest, models.ResponseError{
+			HTTPStatusCode: http.StatusBadRequest,
+			ErrorCode:      "BAD-REQUEST",
 			MessageDetails: err.Error(),
 		})
 
 		return
 	}
 
+	images, err := listImages(ginCtx.Request.Context(), a.imageStore, albumName, filters)
+	if err != nil {
+		writeInternalError(ginCtx, err)
+
+		return
+	}
+
 	ginCtx.JSON(http.StatusOK, images)
 }
+
+// ImagesAction dispatches the custom methods of the images collection,
+// POST /album/images:batch and POST /album/images:batchDelete.
+func (a *APIHandler) ImagesAction(ginCtx *gin.Context) {
+	switch ginCtx.Param("action") {
+	case ":batch":
+		a.BatchCreateImages(ginCtx)
+	case ":batchDelete":
+		a.BatchDeleteImages(ginCtx)
+	default:
+		ginCtx.JSON(http.StatusNotFound, models.ResponseError{
+			HTTPStatusCode: http.StatusNotFound,
+			ErrorCode:      "NOT-FOUND",
+			MessageDetails: fmt.Sprintf("unknown images action %q", ginCtx.Param("action")),
+		})
+	}
+}
+
+func (a *APIHandler) BatchCreateImages(ginCtx *gin.Context) {
+	var request models.BatchCreateImagesRequest
+	if err := ginCtx.BindJSON(&request); err != nil {
+		ginCtx.JSON(http.StatusBadRequest, models.ResponseError{
+			HTTPStatusCode: http.StatusBadRequest,
+			ErrorCode:      "BAD-REQUEST",
+			MessageDetails: err.Error(),
+		})
+
+		return
+	}
+
+	if !validBatchSize(ginCtx, len(request.Images)) {
+		return
+	}
+
+	a.log.Debugf("batch image post request got %d images, allOrNothing=%t",
+		len(request.Images), request.AllOrNothing)
+
+	errs := a.imageStore.CreateImages(ginCtx.Request.Context(), request.Images, request.AllOrNothing)
+
+	results := make([]models.BatchItemResult, len(request.Images))
+	for idx, image := range request.Images {
+		results[idx] = batchItemResult(idx, image.ImageName, image.AlbumName, http.StatusCreated, errs[idx])
+	}
+
+	writeBatchResponse(ginCtx, http.StatusOK, results)
+}
+
+func (a *APIHandler) BatchDeleteImages(ginCtx *gin.Context) {
+	var request models.BatchDeleteImagesRequest
+	if err := ginCtx.BindJSON(&request); err != nil {
+		ginCtx.JSON(http.StatusBadRequest, models.ResponseError{
+			HTTPStatusCode: http.StatusBadRequest,
+			ErrorCode:      "BAD-REQUEST",
+			MessageDetails: err.Error(),
+		})
+
+		return
+	}
+
+	if !validBatchSize(ginCtx, len(request.Images)) {
+		return
+	}
+
+	a.log.Debugf("batch image delete request got %d images, allOrNothing=%t",
+		len(request.Images), request.AllOrNothing)
+
+	keys := make([]dbmodels.ImageKey, len(request.Images))
+	for idx, image := range request.Images {
+		keys[idx] = dbmodels.ImageKey{
+			ImageName: image.ImageName,
+			AlbumName: image.AlbumName,
+		}
+	}
+
+	errs := a.imageStore.DeleteImages(ginCtx.Request.Context(), keys, request.AllOrNothing)
+
+	results := make([]models.BatchItemResult, len(request.Images))
+	for idx, image := range request.Images {
+		results[idx] = batchItemResult(idx, image.ImageName, image.AlbumName, http.StatusNoContent, errs[idx])
+	}
+
+	writeBatchResponse(ginCtx, http.StatusOK, results)
+}
+
+func validBatchSize(ginCtx *gin.Context, size int) bool {
+	if size == 0 || size > constants.MaxBatchSize {
+		ginCtx.JSON(http.StatusBadRequest, models.ResponseError{
+			HTTPStatusCode: http.StatusBadRequest,
+			ErrorCode:      "BAD-REQUEST",
+			MessageDetails: fmt.Sprintf("batch must contain between 1 and %d images, got %d",
+				constants.MaxBatchSize, size),
+		})
+
+		return false
+	}
+
+	return true
+}
+
+func batchItemResult(index int, imageName, albumName string, successCode int, err error) models.BatchItemResult {
+	result := models.BatchItemResult{
+		Index:          index,
+		ImageName:      imageName,
+		AlbumName:      albumName,
+		HTTPStatusCode: successCode,
+	}
+
+	switch {
+	case err == nil:
+		return result
+	case errors.Is(err, controller.ErrBatchAborted):
+		result.HTTPStatusCode = http.StatusFailedDependency
+		result.ErrorCode = "ABORTED"
+	case errors.Is(err, controller.ErrAccessDenied):
+		result.HTTPStatusCode = http.StatusForbidden
+		result.ErrorCode = "FORBIDDEN"
+	case errors.Is(err, controller.ErrImageQuota):
+		result.HTTPStatusCode = http.StatusForbidden
+		result.ErrorCode = "QUOTA-EXCEEDED"
+	case errors.Is(err, controller.ErrStorageQuota):
+		result.HTTPStatusCode = http.StatusInsufficientStorage
+		result.ErrorCode = "INSUFFICIENT-STORAGE"
+	case errors.Is(err, dbhandler.ErrDuplicate):
+		result.HTTPStatusCode = http.StatusConflict
+		result.ErrorCode = "CONFLICT"
+	case errors.Is(err, context.DeadlineExceeded):
+		result.HTTPStatusCode = http.StatusGatewayTimeout
+		result.ErrorCode = "GATEWAY-TIMEOUT"
+	default:
+		result.HTTPStatusCode = http.StatusInternalServerError
+		result.ErrorCode = "INTERNAL-SERVER-ERROR"
+	}
+	result.MessageDetails = err.Error()
+
+	return result
+}
+
+// writeBatchResponse responds with successCode when every item succeeded and
+// with 207 otherwise.
+func writeBatchResponse(ginCtx *gin.Context, successCode int, results []models.BatchItemResult) {
+	response := models.BatchResponse{Results: results}
+	for _, result := range results {
+		if result.ErrorCode == "" {
+			response.Succeeded++
+		} else {
+			response.Failed++
+		}
+	}
+
+	if response.Failed > 0 {
+		ginCtx.JSON(http.StatusMultiStatus, response)
+
+		return
+	}
+
+	ginCtx.JSON(successCode, response)
+}
+
+// writeInternalError responds 403 when the caller lacks access to the album
+// or the album or tenant holds its quota of images, 507 when the image exceeds
+// a storage quota, 504 when the request ran out of time and 500 otherwise. The
+// driver does not always report a cancelled query as context.DeadlineExceeded,
+// so the request context is checked as well.
+func writeInternalError(ginCtx *gin.Context, err error) {
+	if errors.Is(err, controller.ErrAccessDenied) {
+		ginCtx.JSON(http.StatusForbidden, models.ResponseError{
+			HTTPStatusCode: http.StatusForbidden,
+			ErrorCode:      "FORBIDDEN",
+			MessageDetails: err.Error(),
+		})
+
+		return
+	}
+
+	if errors.Is(err, controller.ErrImageQuota) {
+		ginCtx.JSON(http.StatusForbidden, models.ResponseError{
+			HTTPStatusCode: http.StatusForbidden,
+			ErrorCode:      "QUOTA-EXCEEDED",
+			MessageDetails: err.Error(),
+		})
+
+		return
+	}
+
+	if errors.Is(err, controller.ErrStorageQuota) {
+		ginCtx.JSON(http.StatusInsufficientStorage, models.ResponseError{
+			HTTPStatusCode: http.StatusInsufficientStorage,
+			ErrorCode:      "INSUFFICIENT-STORAGE",
+			MessageDetails: err.Error(),
+		})
+
+		return
+	}
+
+	if errors.Is(err, context.DeadlineExceeded) ||
+		errors.Is(ginCtx.Request.Context().Err(), context.DeadlineExceeded) {
+		ginCtx.JSON(http.StatusGatewayTimeout, models.ResponseError{
+			HTTPStatusCode: http.StatusGatewayTimeout,
+			ErrorCode:      "GATEWAY-TIMEOUT",
+			MessageDetails: err.Error(),
+		})
+
+		return
+	}
+
+	ginCtx.JSON(http.StatusInternalServerError, models.ResponseError{
+		HTTPStatusCode: http.StatusInternalServerError,
+		ErrorCode:      "INTERNAL-SERVER-ERROR",
+		MessageDetails: err.Error(),
+	})
+}
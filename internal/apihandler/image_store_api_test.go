@@ -5,12 +5,14 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"github.com/gin-gonic/gin"
 	"github.com/golang/mock/gomock"
 	log "github.com/sirupsen/logrus"
 	"github.com/stretchr/testify/assert"
 	"githum.com/anupam111/image-store/internal/controller"
 	"githum.com/anupam111/image-store/internal/db/dbmodels"
+	"githum.com/anupam111/image-store/internal/models"
 	"io"
 	"net/http"
 	"net/http/httptest"
@@ -56,7 +58,7 @@ func Test_CreateImageAlbum(t *testing.T) {
 			url:     "/album",
 			payload: &inputPayload,
 			prepare: func(subs *controller.MockImageStore) {
-				subs.EXPECT().CreateImageAlbum(inputPayload).Return(nil)
+				subs.EXPECT().CreateImageAlbum(gomock.Any(), inputPayload).Return(nil)
 			},
 			statusCode: 201,
 		},
@@ -65,7 +67,7 @@ func Test_CreateImageAlbum(t *testing.T) {
 			url:     "/album",
 			payload: &inputPayload,
 			prepare: func(subs *controller.MockImageStore) {
-				subs.EXPECT().CreateImageAlbum(inputPayload).Return(errFake)
+				subs.EXPECT().CreateImageAlbum(gomock.Any(), inputPayload).Return(errFake)
 			},
 			statusCode: 500,
 		},
@@ -122,7 +124,7 @@ func Test_CreateImage(t *testing.T) {
 			url:     "/image",
 			payload: &inputPayload,
 			prepare: func(subs *controller.MockImageStore) {
-				subs.EXPECT().CreateImage(inputPayload).Return(nil)
+				subs.EXPECT().CreateImage(gomock.Any(), inputPayload).Return(nil)
 			},
 			statusCode: 201,
 		},
@@ -131,10 +133,30 @@ func Test_CreateImage(t *testing.T) {
 			url:     "/image",
 			payload: &inputPayload,
 			prepare: func(subs *controller.MockImageStore) {
-				subs.EXPECT().CreateImage(inputPayload).Return(errFake)
+				subs.EXPECT().CreateImage(gomock.Any(), inputPayload).Return(errFake)
 			},
 			statusCode: 500,
 		},
+		{
+			name:    "image_quota_exceeded",
+			url:     "/image",
+			payload: &inputPayload,
+			prepare: func(subs *controller.MockImageStore) {
+				subs.EXPECT().CreateImage(gomock.Any(), inputPayload).
+					Return(fmt.Errorf("error while creating image, %w", controller.ErrImageQuota))
+			},
+			statusCode: 403,
+		},
+		{
+			name:    "storage_quota_exceeded",
+			url:     "/image",
+			payload: &inputPayload,
+			prepare: func(subs *controller.MockImageStore) {
+				subs.EXPECT().CreateImage(gomock.Any(), inputPayload).
+					Return(fmt.Errorf("error while creating image, %w", controller.ErrStorageQuota))
+			},
+			statusCode: 507,
+		},
 		{
 			name:       "bad_request",
 			url:        "/image",
@@ -181,7 +203,7 @@ func Test_DeleteImagesAlbum(t *testing.T) {
 			name: "success",
 			url:  "/album/test-album",
 			prepare: func(subs *controller.MockImageStore) {
-				subs.EXPECT().DeleteImageAlbum("test-album").Return(nil)
+				subs.EXPECT().DeleteImageAlbum(gomock.Any(), "test-album").Return(nil)
 			},
 			statusCode: 204,
 		},
@@ -189,7 +211,7 @@ func Test_DeleteImagesAlbum(t *testing.T) {
 			name: "internal_server_error",
 			url:  "/album/test-album",
 			prepare: func(subs *controller.MockImageStore) {
-				subs.EXPECT().DeleteImageAlbum("test-album").Return(errFake)
+				subs.EXPECT().DeleteImageAlbum(gomock.Any(), "test-album").Return(errFake)
 			},
 			statusCode: 500,
 		},
@@ -237,7 +259,7 @@ func Test_DeleteImage(t *testing.T) {
 			name: "success",
 			url:  "/album/images/test-image?albumName=test-album",
 			prepare: func(subs *controller.MockImageStore) {
-				subs.EXPECT().DeleteImage("test-image", "test-album").Return(nil)
+				subs.EXPECT().DeleteImage(gomock.Any(), "test-image", "test-album").Return(nil)
 			},
 			statusCode: 204,
 		},
@@ -245,7 +267,7 @@ func Test_DeleteImage(t *testing.T) {
 			name: "internal_server_error",
 			url:  "/album/images/test-image?albumName=test-album",
 			prepare: func(subs *controller.MockImageStore) {
-				subs.EXPECT().DeleteImage("test-image", "test-album").Return(errFake)
+				subs.EXPECT().DeleteImage(gomock.Any(), "test-image", "test-album").Return(errFake)
 			},
 			statusCode: 500,
 		},
@@ -287,21 +309,29 @@ func Test_GetImageByID(t *testing.T) {
 		prepare func(
 			subs *controller.MockImageStore,
 		)
-		statusCode int
+		statusCode   int
+		expectedBody string
 	}{
 		{
 			name: "success",
 			url:  "/image/test-image",
 			prepare: func(subs *controller.MockImageStore) {
-				subs.EXPECT().GetImage("test-image").Return(dbmodels.Image{}, nil)
+				subs.EXPECT().GetImage(gomock.Any(), "test-image").Return(dbmodels.Image{
+					Tenant:    "acme",
+					ImageName: "test-image",
+					AlbumName: "test-album",
+					Image:     "aW1hZ2U=",
+					Metadata:  dbmodels.Metadata{"project": "apollo"},
+				}, nil)
 			},
-			statusCode: 200,
+			statusCode:   200,
+			expectedBody: `{"ImageName":"test-image","AlbumName":"test-album","Image":"aW1hZ2U="}`,
 		},
 		{
 			name: "internal_server_error",
 			url:  "/image/test-image",
 			prepare: func(subs *controller.MockImageStore) {
-				subs.EXPECT().GetImage("test-image").Return(dbmodels.Image{}, errFake)
+				subs.EXPECT().GetImage(gomock.Any(), "test-image").Return(dbmodels.Image{}, errFake)
 			},
 			statusCode: 500,
 		},
@@ -330,6 +360,10 @@ func Test_GetImageByID(t *testing.T) {
 			w := httptest.NewRecorder()
 			router.ServeHTTP(w, req)
 			assert.Equal(t, tt.statusCode, w.Code)
+
+			if tt.expectedBody != "" {
+				assert.JSONEq(t, tt.expectedBody, w.Body.String())
+			}
 		})
 	}
 }
@@ -349,7 +383,7 @@ func Test_GetImages(t *testing.T) {
 			name: "success",
 			url:  "/album/images?albumName=test-album",
 			prepare: func(subs *controller.MockImageStore) {
-				subs.EXPECT().GetAllImages("test-album").Return([]dbmodels.Image{}, nil)
+				subs.EXPECT().GetAllImages(gomock.Any(), "test-album").Return([]dbmodels.Image{}, nil)
 			},
 			statusCode: 200,
 		},
@@ -357,7 +391,7 @@ func Test_GetImages(t *testing.T) {
 			name: "internal_server_error",
 			url:  "/album/images?albumName=test-album",
 			prepare: func(subs *controller.MockImageStore) {
-				subs.EXPECT().GetAllImages("test-album").Return(nil, errFake)
+				subs.EXPECT().GetAllImages(gomock.Any(), "test-album").Return(nil, errFake)
 			},
 			statusCode: 500,
 		},
@@ -389,3 +423,105 @@ func Test_GetImages(t *testing.T) {
 		})
 	}
 }
+
+func Test_ImagesAction(t *testing.T) {
+	t.Parallel()
+
+	images := []dbmodels.Image{
+		{AlbumName: "test-album", ImageName: "image-1"},
+		{AlbumName: "test-album", ImageName: "image-2"},
+	}
+	keys := []dbmodels.ImageKey{
+		{AlbumName: "test-album", ImageName: "image-1"},
+		{AlbumName: "test-album", ImageName: "image-2"},
+	}
+
+	tests := []struct {
+		name    string
+		url     string
+		payload interface{}
+		prepare func(
+			subs *controller.MockImageStore,
+		)
+		statusCode int
+		failed     int
+	}{
+		{
+			name:    "batch_success",
+			url:     "/album/images:batch",
+			payload: models.BatchCreateImagesRequest{Images: images},
+			prepare: func(subs *controller.MockImageStore) {
+				subs.EXPECT().CreateImages(gomock.Any(), images, false).Return([]error{nil, nil})
+			},
+			statusCode: 200,
+		},
+		{
+			name:    "batch_partial_failure",
+			url:     "/album/images:batch",
+			payload: models.BatchCreateImagesRequest{AllOrNothing: true, Images: images},
+			prepare: func(subs *controller.MockImageStore) {
+				subs.EXPECT().CreateImages(gomock.Any(), images, true).Return([]error{controller.ErrBatchAborted, errFake})
+			},
+			statusCode: 207,
+			failed:     2,
+		},
+		{
+			name: "batch_delete_success",
+			url:  "/album/images:batchDelete",
+			payload: models.BatchDeleteImagesRequest{Images: []models.ImageRef{
+				{AlbumName: "test-album", ImageName: "image-1"},
+				{AlbumName: "test-album", ImageName: "image-2"},
+			}},
+			prepare: func(subs *controller.MockImageStore) {
+				subs.EXPECT().DeleteImages(gomock.Any(), keys, false).Return([]error{nil, nil})
+			},
+			statusCode: 200,
+		},
+		{
+			name:       "empty_batch",
+			url:        "/album/images:batch",
+			payload:    models.BatchCreateImagesRequest{},
+			statusCode: 400,
+		},
+		{
+			name:       "bad_request",
+			url:        "/album/images:batchDelete",
+			statusCode: 400,
+		},
+		{
+			name:       "unknown_action",
+			url:        "/album/images:purge",
+			payload:    models.BatchDeleteImagesRequest{},
+			statusCode: 404,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			router, controller, apiHandler := setupTestEnv(t)
+
+			if tt.prepare != nil {
+				tt.prepare(controller)
+			}
+			var payload io.Reader
+			if tt.payload != nil {
+				temp, _ := json.Marshal(tt.payload)
+				payload = bytes.NewReader(temp)
+			}
+			router.POST("/album/images:action", apiHandler.ImagesAction)
+			req, _ := http.NewRequestWithContext(context.Background(), http.MethodPost, tt.url, payload)
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+			assert.Equal(t, tt.statusCode, w.Code)
+
+			if tt.statusCode == 200 || tt.statusCode == 207 {
+				var response models.BatchResponse
+				assert.Nil(t, json.Unmarshal(w.Body.Bytes(), &response))
+				assert.Equal(t, tt.failed, response.Failed)
+			}
+		})
+	}
+}
@@ -0,0 +1,389 @@
+package apihandler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	log "github.com/sirupsen/logrus"
+	"githum.com/anupam111/image-store/internal/controller"
+	"githum.com/anupam111/image-store/internal/db/dbhandler"
+	"githum.com/anupam111/image-store/internal/db/dbmodels"
+	"githum.com/anupam111/image-store/internal/models"
+	"net/http"
+	"net/url"
+)
+
+const v2BasePath = "/v2"
+
+// APIHandlerV2 handles the resource oriented v2 api. Albums are addressed as
+// /albums/{album} and images as /albums/{album}/images/{image}, and every
+// response is wrapped in a models.Envelope.
+type APIHandlerV2 struct {
+	log        *log.Logger
+	imageStore controller.ImageStore
+}
+
+// NewAPIHandlerV2 implements APIHandlerV2.
+func NewAPIHandlerV2(logger *log.Logger, imageStore controller.ImageStore) *APIHandlerV2 {
+	return &APIHandlerV2{
+		log:        logger,
+		imageStore: imageStore,
+	}
+}
+
+func (a *APIHandlerV2) ListAlbums(ginCtx *gin.Context) {
+	albums, err := a.imageStore.ListImageAlbums(ginCtx.Request.Context())
+	if err != nil {
+		writeV2Error(ginCtx, err)
+
+		return
+	}
+
+	resources := make([]models.AlbumResource, len(albums))
+	for idx, album := range albums {
+		resources[idx] = albumResource(album)
+	}
+
+	ginCtx.JSON(http.StatusOK, models.Envelope{
+		Data:  resources,
+		Links: map[string]string{"self": albumsPath()},
+	})
+}
+
+func (a *APIHandlerV2) CreateAlbum(ginCtx *gin.Context) {
+	var request models.AlbumRequest
+	if err := ginCtx.ShouldBindJSON(&request); err != nil {
+		writeV2BadRequest(ginCtx, err.Error())
+
+		return
+	}
+
+	if request.Name == "" {
+		writeV2BadRequest(ginCtx, "name is empty in request body")
+
+		return
+	}
+
+	a.createAlbum(ginCtx, request.Name)
+}
+
+// PutAlbum creates the album named in the url. Creating an existing album is
+// not an error.
+func (a *APIHandlerV2) PutAlbum(ginCtx *gin.Context) {
+	albumName := ginCtx.Param("album")
+
+	_, err := a.imageStore.GetImageAlbum(ginCtx.Request.Context(), albumName)
+	if err == nil {
+		ginCtx.JSON(http.StatusOK, models.Envelope{
+			Data:  albumResource(dbmodels.Album{AlbumName: albumName}),
+			Links: map[string]string{"self": albumPath(albumName)},
+		})
+
+		return
+	}
+
+	if !errors.Is(err, dbhandler.ErrNoDataFound) {
+		writeV2Error(ginCtx, err)
+
+		return
+	}
+
+	a.createAlbum(ginCtx, albumName)
+}
+
+func (a *APIHandlerV2) createAlbum(ginCtx *gin.Context, albumName string) {
+	album := dbmodels.Album{AlbumName: albumName}
+	if err := a.imageStore.CreateImageAlbum(ginCtx.Request.Context(), album); err != nil {
+		writeV2Error(ginCtx, err)
+
+		return
+	}
+
+	ginCtx.Header("Location", albumPath(albumName))
+	ginCtx.JSON(http.StatusCreated, models.Envelope{
+		Data:  albumResource(album),
+		Links: map[string]string{"self": albumPath(albumName)},
+	})
+}
+
+func (a *APIHandlerV2) GetAlbum(ginCtx *gin.Context) {
+	album, err := a.imageStore.GetImageAlbum(ginCtx.Request.Context(), ginCtx.Param("album"))
+	if err != nil {
+		writeV2Error(ginCtx, err)
+
+		return
+	}
+
+	ginCtx.JSON(http.StatusOK, models.Envelope{
+		Data:  albumResource(album),
+		Links: map[string]string{"self": albumPath(album.AlbumName)},
+	})
+}
+
+func (a *APIHandlerV2) DeleteAlbum(ginCtx *gin.Context) {
+	albumName := ginCtx.Param("album")
+	if !a.albumExists(ginCtx, albumName) {
+		return
+	}
+
+	if err := a.imageStore.DeleteImageAlbum(ginCtx.Request.Context(), albumName); err != nil {
+		writeV2Error(ginCtx, err)
+
+		return
+	}
+
+	ginCtx.Status(http.StatusNoContent)
+}
+
+// ListImages lists the images of the album, filtered on their metadata by the
+// meta.* query parameters.
+func (a *APIHandlerV2) ListImages(ginCtx *gin.Context) {
+	filters, err := metadataFilters(ginCtx.Request.URL.RawQuery)
+	if err != nil {
+		writeV2BadRequest(ginCtx, err.Error())
+
+		return
+	}
+
+	albumName := ginCtx.Param("album")
+	if !a.albumExists(ginCtx, albumName) {
+		return
+	}
+
+	images, err := listImages(ginCtx.Request.Context(), a.imageStore, albumName, filters)
+	if err != nil {
+		writeV2Error(ginCtx, err)
+
+		return
+	}
+
+	resources := make([]models.ImageResource, len(images))
+	for idx, image := range images {
+		resources[idx] = imageResource(image)
+	}
+
+	ginCtx.JSON(http.StatusOK, models.Envelope{
+		Data: resources,
+		Links: map[string]string{
+			"self":  imagesPath(albumName),
+			"album": albumPath(albumName),
+		},
+	})
+}
+
+func (a *APIHandlerV2) GetImage(ginCtx *gin.Context) {
+	image, err := a.imageStore.GetAlbumImage(ginCtx.Request.Context(), ginCtx.Param("album"), ginCtx.Param("image"))
+	if err != nil {
+		writeV2Error(ginCtx, err)
+
+		return
+	}
+
+	writeV2Image(ginCtx, http.StatusOK, image)
+}
+
+// PutImage replaces the content of the image, creating it if needed.
+func (a *APIHandlerV2) PutImage(ginCtx *gin.Context) {
+	var request models.ImageRequest
+	if err := ginCtx.ShouldBindJSON(&request); err != nil {
+		writeV2BadRequest(ginCtx, err.Error())
+
+		return
+	}
+
+	albumName := ginCtx.Param("album")
+	if !a.albumExists(ginCtx, albumName) {
+		return
+	}
+
+	image := dbmodels.Image{
+		ImageName: ginCtx.Param("image"),
+		AlbumName: albumName,
+		Image:     request.Image,
+		Metadata:  request.Metadata,
+	}
+
+	created, err := a.imageStore.PutImage(ginCtx.Request.Context(), image)
+	if err != nil {
+		writeV2Error(ginCtx, err)
+
+		return
+	}
+
+	if created {
+		ginCtx.Header("Location", imagePath(image.AlbumName, image.ImageName))
+		writeV2Image(ginCtx, http.StatusCreated, image)
+
+		return
+	}
+
+	writeV2Image(ginCtx, http.StatusOK, image)
+}
+
+// PatchImage updates the fields present in the request, a changed name
+// renames the image within its album.
+func (a *APIHandlerV2) PatchImage(ginCtx *gin.Context) {
+	var request models.ImagePatchRequest
+	if err := ginCtx.ShouldBindJSON(&request); err != nil {
+		writeV2BadRequest(ginCtx, err.Error())
+
+		return
+	}
+
+	if request.Name != nil && *request.Name == "" {
+		writeV2BadRequest(ginCtx, "name can not be empty")
+
+		return
+	}
+
+	image, err := a.imageStore.GetAlbumImage(ginCtx.Request.Context(), ginCtx.Param("album"), ginCtx.Param("image"))
+	if err != nil {
+		writeV2Error(ginCtx, err)
+
+		return
+	}
+
+	key := dbmodels.ImageKey{
+		ImageName: image.ImageName,
+		AlbumName: image.AlbumName,
+	}
+
+	if request.Name != nil {
+		image.ImageName = *request.Name
+	}
+
+	if request.Image != nil {
+		image.Image = *request.Image
+	}
+
+	if request.Metadata != nil {
+		image.Metadata = mergeMetadata(image.Metadata, request.Metadata)
+	}
+
+	if err = a.imageStore.UpdateImage(ginCtx.Request.Context(), key, image); err != nil {
+		writeV2Error(ginCtx, err)
+
+		return
+	}
+
+	writeV2Image(ginCtx, http.StatusOK, image)
+}
+
+func (a *APIHandlerV2) DeleteImage(ginCtx *gin.Context) {
+	albumName, imageName := ginCtx.Param("album"), ginCtx.Param("image")
+	if _, err := a.imageStore.GetAlbumImage(ginCtx.Request.Context(), albumName, imageName); err != nil {
+		writeV2Error(ginCtx, err)
+
+		return
+	}
+
+	if err := a.imageStore.DeleteImage(ginCtx.Request.Context(), imageName, albumName); err != nil {
+		writeV2Error(ginCtx, err)
+
+		return
+	}
+
+	ginCtx.Status(http.StatusNoContent)
+}
+
+// albumExists writes a 404 envelope, or the lookup error, and returns false
+// when the album can not be found.
+func (a *APIHandlerV2) albumExists(ginCtx *gin.Context, albumName string) bool {
+	if _, err := a.imageStore.GetImageAlbum(ginCtx.Request.Context(), albumName); err != nil {
+		writeV2Error(ginCtx, err)
+
+		return false
+	}
+
+	return true
+}
+
+func writeV2Image(ginCtx *gin.Context, status int, image dbmodels.Image) {
+	resource := imageResource(image)
+	ginCtx.JSON(status, models.Envelope{
+		Data:  resource,
+		Links: resource.Links,
+	})
+}
+
+func writeV2BadRequest(ginCtx *gin.Context, message string) {
+	ginCtx.JSON(http.StatusBadRequest, models.Envelope{
+		Error: &models.ResponseError{
+			HTTPStatusCode: http.StatusBadRequest,
+			ErrorCode:      "BAD-REQUEST",
+			MessageDetails: message,
+		},
+	})
+}
+
+func writeV2Error(ginCtx *gin.Context, err error) {
+	responseError := models.ResponseError{
+		HTTPStatusCode: http.StatusInternalServerError,
+		ErrorCode:      "INTERNAL-SERVER-ERROR",
+		MessageDetails: err.Error(),
+	}
+
+	switch {
+	case errors.Is(err, dbhandler.ErrNoDataFound):
+		responseError.HTTPStatusCode = http.StatusNotFound
+		responseError.ErrorCode = "NOT-FOUND"
+	case errors.Is(err, dbhandler.ErrDuplicate):
+		responseError.HTTPStatusCode = http.StatusConflict
+		responseError.ErrorCode = "CONFLICT"
+	case errors.Is(err, controller.ErrAccessDenied):
+		responseError.HTTPStatusCode = http.StatusForbidden
+		responseError.ErrorCode = "FORBIDDEN"
+	case errors.Is(err, controller.ErrImageQuota):
+		responseError.HTTPStatusCode = http.StatusForbidden
+		responseError.ErrorCode = "QUOTA-EXCEEDED"
+	case errors.Is(err, controller.ErrStorageQuota):
+		responseError.HTTPStatusCode = http.StatusInsufficientStorage
+		responseError.ErrorCode = "INSUFFICIENT-STORAGE"
+	case errors.Is(err, context.DeadlineExceeded),
+		errors.Is(ginCtx.Request.Context().Err(), context.DeadlineExceeded):
+		responseError.HTTPStatusCode = http.StatusGatewayTimeout
+		responseError.ErrorCode = "GATEWAY-TIMEOUT"
+	}
+
+	ginCtx.JSON(responseError.HTTPStatusCode, models.Envelope{Error: &responseError})
+}
+
+func albumResource(album dbmodels.Album) models.AlbumResource {
+	return models.AlbumResource{
+		Name: album.AlbumName,
+		Links: map[string]string{
+			"self":   albumPath(album.AlbumName),
+			"images": imagesPath(album.AlbumName),
+		},
+	}
+}
+
+func imageResource(image dbmodels.Image) models.ImageResource {
+	return models.ImageResource{
+		Name:     image.ImageName,
+		Album:    image.AlbumName,
+		Image:    image.Image,
+		Metadata: image.Metadata,
+		Links: map[string]string{
+			"self":  imagePath(image.AlbumName, image.ImageName),
+			"album": albumPath(image.AlbumName),
+		},
+	}
+}
+
+func albumsPath() string {
+	return v2BasePath + "/albums"
+}
+
+func albumPath(albumName string) string {
+	return fmt.Sprintf("%s/%s", albumsPath(), url.PathEscape(albumName))
+}
+
+func imagesPath(albumName string) string {
+	return albumPath(albumName) + "/images"
+}
+
+func imagePath(albumName, imageName string) string {
+	return fmt.Sprintf("%s/%s", imagesPath(albumName), url.PathEscape(imageName))
+}
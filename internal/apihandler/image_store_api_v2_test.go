@@ -0,0 +1,288 @@
+package apihandler
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	log "github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"githum.com/anupam111/image-store/internal/controller"
+	"githum.com/anupam111/image-store/internal/db/dbhandler"
+	"githum.com/anupam111/image-store/internal/db/dbmodels"
+	"githum.com/anupam111/image-store/internal/models"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func setupV2TestEnv(t *testing.T) (*gin.Engine, *controller.MockImageStore) {
+	t.Helper()
+	mockCtrl := gomock.NewController(t)
+	mockController := controller.NewMockImageStore(mockCtrl)
+	handler := NewAPIHandlerV2(log.New(), mockController)
+
+	router := gin.New()
+	router.GET("/v2/albums", handler.ListAlbums)
+	router.POST("/v2/albums", handler.CreateAlbum)
+	router.GET("/v2/albums/:album", handler.GetAlbum)
+	router.PUT("/v2/albums/:album", handler.PutAlbum)
+	router.DELETE("/v2/albums/:album", handler.DeleteAlbum)
+	router.GET("/v2/albums/:album/images", handler.ListImages)
+	router.GET("/v2/albums/:album/images/:image", handler.GetImage)
+	router.PUT("/v2/albums/:album/images/:image", handler.PutImage)
+	router.PATCH("/v2/albums/:album/images/:image", handler.PatchImage)
+	router.DELETE("/v2/albums/:album/images/:image", handler.DeleteImage)
+
+	return router, mockController
+}
+
+func Test_V2API(t *testing.T) {
+	t.Parallel()
+
+	album := dbmodels.Album{AlbumName: "test-album"}
+	image := dbmodels.Image{AlbumName: "test-album", ImageName: "test-image", Image: "abc"}
+	key := dbmodels.ImageKey{AlbumName: "test-album", ImageName: "test-image"}
+
+	tests := []struct {
+		name    string
+		method  string
+		url     string
+		payload string
+		prepare func(
+			subs *controller.MockImageStore,
+		)
+		statusCode int
+		selfLink   string
+		errorCode  string
+	}{
+		{
+			name:   "list_albums",
+			method: http.MethodGet,
+			url:    "/v2/albums",
+			prepare: func(subs *controller.MockImageStore) {
+				subs.EXPECT().ListImageAlbums(gomock.Any()).Return([]dbmodels.Album{album}, nil)
+			},
+			statusCode: 200,
+			selfLink:   "/v2/albums",
+		},
+		{
+			name:    "create_album",
+			method:  http.MethodPost,
+			url:     "/v2/albums",
+			payload: `{"name":"test-album"}`,
+			prepare: func(subs *controller.MockImageStore) {
+				subs.EXPECT().CreateImageAlbum(gomock.Any(), album).Return(nil)
+			},
+			statusCode: 201,
+			selfLink:   "/v2/albums/test-album",
+		},
+		{
+			name:    "create_album_conflict",
+			method:  http.MethodPost,
+			url:     "/v2/albums",
+			payload: `{"name":"test-album"}`,
+			prepare: func(subs *controller.MockImageStore) {
+				subs.EXPECT().CreateImageAlbum(gomock.Any(), album).Return(dbhandler.ErrDuplicate)
+			},
+			statusCode: 409,
+			errorCode:  "CONFLICT",
+		},
+		{
+			name:       "create_album_bad_request",
+			method:     http.MethodPost,
+			url:        "/v2/albums",
+			payload:    `{}`,
+			statusCode: 400,
+			errorCode:  "BAD-REQUEST",
+		},
+		{
+			name:   "put_existing_album",
+			method: http.MethodPut,
+			url:    "/v2/albums/test-album",
+			prepare: func(subs *controller.MockImageStore) {
+				subs.EXPECT().GetImageAlbum(gomock.Any(), "test-album").Return(album, nil)
+			},
+			statusCode: 200,
+			selfLink:   "/v2/albums/test-album",
+		},
+		{
+			name:   "delete_missing_album",
+			method: http.MethodDelete,
+			url:    "/v2/albums/test-album",
+			prepare: func(subs *controller.MockImageStore) {
+				subs.EXPECT().GetImageAlbum(gomock.Any(), "test-album").Return(dbmodels.Album{}, dbhandler.ErrNoDataFound)
+			},
+			statusCode: 404,
+			errorCode:  "NOT-FOUND",
+		},
+		{
+			name:   "list_images",
+			method: http.MethodGet,
+			url:    "/v2/albums/test-album/images",
+			prepare: func(subs *controller.MockImageStore) {
+				subs.EXPECT().GetImageAlbum(gomock.Any(), "test-album").Return(album, nil)
+				subs.EXPECT().GetAllImages(gomock.Any(), "test-album").Return([]dbmodels.Image{image}, nil)
+			},
+			statusCode: 200,
+			selfLink:   "/v2/albums/test-album/images",
+		},
+		{
+			name:   "list_images_by_metadata",
+			method: http.MethodGet,
+			url:    "/v2/albums/test-album/images?meta.project=apollo&meta.rating>=4",
+			prepare: func(subs *controller.MockImageStore) {
+				subs.EXPECT().GetImageAlbum(gomock.Any(), "test-album").Return(album, nil)
+				subs.EXPECT().FilterImages(gomock.Any(), "test-album", []dbmodels.MetadataFilter{
+					{Key: "project", Op: "=", Value: "apollo"},
+					{Key: "rating", Op: ">=", Value: "4"},
+				}).Return([]dbmodels.Image{image}, nil)
+			},
+			statusCode: 200,
+			selfLink:   "/v2/albums/test-album/images",
+		},
+		{
+			name:       "list_images_bad_metadata_filter",
+			method:     http.MethodGet,
+			url:        "/v2/albums/test-album/images?meta.rating>high",
+			statusCode: 400,
+			errorCode:  "BAD-REQUEST",
+		},
+		{
+			name:   "get_image_of_other_album",
+			method: http.MethodGet,
+			url:    "/v2/albums/other-album/images/test-image",
+			prepare: func(subs *controller.MockImageStore) {
+				subs.EXPECT().GetAlbumImage(gomock.Any(), "other-album", "test-image").Return(dbmodels.Image{}, dbhandler.ErrNoDataFound)
+			},
+			statusCode: 404,
+			errorCode:  "NOT-FOUND",
+		},
+		{
+			name:    "put_new_image",
+			method:  http.MethodPut,
+			url:     "/v2/albums/test-album/images/test-image",
+			payload: `{"image":"abc"}`,
+			prepare: func(subs *controller.MockImageStore) {
+				subs.EXPECT().GetImageAlbum(gomock.Any(), "test-album").Return(album, nil)
+				subs.EXPECT().PutImage(gomock.Any(), image).Return(true, nil)
+			},
+			statusCode: 201,
+			selfLink:   "/v2/albums/test-album/images/test-image",
+		},
+		{
+			name:    "put_existing_image",
+			method:  http.MethodPut,
+			url:     "/v2/albums/test-album/images/test-image",
+			payload: `{"image":"abc"}`,
+			prepare: func(subs *controller.MockImageStore) {
+				subs.EXPECT().GetImageAlbum(gomock.Any(), "test-album").Return(album, nil)
+				subs.EXPECT().PutImage(gomock.Any(), image).Return(false, nil)
+			},
+			statusCode: 200,
+			selfLink:   "/v2/albums/test-album/images/test-image",
+		},
+		{
+			name:    "patch_image_rename",
+			method:  http.MethodPatch,
+			url:     "/v2/albums/test-album/images/test-image",
+			payload: `{"name":"renamed"}`,
+			prepare: func(subs *controller.MockImageStore) {
+				subs.EXPECT().GetAlbumImage(gomock.Any(), "test-album", "test-image").Return(image, nil)
+				subs.EXPECT().UpdateImage(gomock.Any(), key, dbmodels.Image{
+					AlbumName: "test-album",
+					ImageName: "renamed",
+					Image:     "abc",
+				}).Return(nil)
+			},
+			statusCode: 200,
+			selfLink:   "/v2/albums/test-album/images/renamed",
+		},
+		{
+			name:    "patch_image_metadata",
+			method:  http.MethodPatch,
+			url:     "/v2/albums/test-album/images/test-image",
+			payload: `{"metadata":{"rating":5,"draft":null}}`,
+			prepare: func(subs *controller.MockImageStore) {
+				stored := image
+				stored.Metadata = dbmodels.Metadata{"project": "apollo", "draft": true}
+				subs.EXPECT().GetAlbumImage(gomock.Any(), "test-album", "test-image").Return(stored, nil)
+				subs.EXPECT().UpdateImage(gomock.Any(), key, dbmodels.Image{
+					AlbumName: "test-album",
+					ImageName: "test-image",
+					Image:     "abc",
+					Metadata:  dbmodels.Metadata{"project": "apollo", "rating": 5.0},
+				}).Return(nil)
+			},
+			statusCode: 200,
+			selfLink:   "/v2/albums/test-album/images/test-image",
+		},
+		{
+			name:       "patch_image_empty_name",
+			method:     http.MethodPatch,
+			url:        "/v2/albums/test-album/images/test-image",
+			payload:    `{"name":""}`,
+			statusCode: 400,
+			errorCode:  "BAD-REQUEST",
+		},
+		{
+			name:   "delete_image",
+			method: http.MethodDelete,
+			url:    "/v2/albums/test-album/images/test-image",
+			prepare: func(subs *controller.MockImageStore) {
+				subs.EXPECT().GetAlbumImage(gomock.Any(), "test-album", "test-image").Return(image, nil)
+				subs.EXPECT().DeleteImage(gomock.Any(), "test-image", "test-album").Return(nil)
+			},
+			statusCode: 204,
+		},
+		{
+			name:   "internal_server_error",
+			method: http.MethodGet,
+			url:    "/v2/albums/test-album",
+			prepare: func(subs *controller.MockImageStore) {
+				subs.EXPECT().GetImageAlbum(gomock.Any(), "test-album").Return(dbmodels.Album{}, errFake)
+			},
+			statusCode: 500,
+			errorCode:  "INTERNAL-SERVER-ERROR",
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			router, controller := setupV2TestEnv(t)
+
+			if tt.prepare != nil {
+				tt.prepare(controller)
+			}
+
+			var payload io.Reader
+			if tt.payload != "" {
+				payload = bytes.NewReader([]byte(tt.payload))
+			}
+			req, _ := http.NewRequestWithContext(context.Background(), tt.method, tt.url, payload)
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+			assert.Equal(t, tt.statusCode, w.Code)
+
+			if tt.statusCode == http.StatusNoContent {
+				assert.Empty(t, w.Body.Bytes())
+
+				return
+			}
+
+			var envelope models.Envelope
+			assert.Nil(t, json.Unmarshal(w.Body.Bytes(), &envelope))
+			assert.Equal(t, tt.selfLink, envelope.Links["self"])
+			if tt.errorCode != "" {
+				assert.Equal(t, tt.errorCode, envelope.Error.ErrorCode)
+			} else {
+				assert.Nil(t, envelope.Error)
+			}
+		})
+	}
+}
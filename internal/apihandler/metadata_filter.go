@@ -0,0 +1,127 @@
+package apihandler
+
+import (
+	"context"
+	"fmt"
+	"githum.com/anupam111/image-store/internal/controller"
+	"githum.com/anupam111/image-store/internal/db/dbmodels"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// metadataFilterPrefix starts the query parameters which filter a listing on
+// the image metadata, e.g. ?meta.project=apollo&meta.rating>=4.
+const metadataFilterPrefix = "meta."
+
+var metadataKeyPattern = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+// metadataFilters parses the metadata filters of a raw query. The operator
+// is part of the parameter, so the query is split by hand: meta.rating>=4 is
+// the key "meta.rating>" to url.ParseQuery.
+func metadataFilters(rawQuery string) ([]dbmodels.MetadataFilter, error) {
+	var filters []dbmodels.MetadataFilter
+
+	for _, param := range strings.Split(rawQuery, "&") {
+		filter, ok, err := metadataFilter(param)
+		if err != nil {
+			return nil, err
+		}
+
+		if ok {
+			filters = append(filters, filter)
+		}
+	}
+
+	return filters, nil
+}
+
+// metadataFilter parses one query parameter, it reports false for the
+// parameters which are no metadata filter.
+func metadataFilter(param string) (dbmodels.MetadataFilter, bool, error) {
+	var filter dbmodels.MetadataFilter
+
+	rawKey, rawValue := param, ""
+
+	eq := strings.IndexByte(param, '=')
+	hasValue := eq >= 0
+
+	if hasValue {
+		rawKey, rawValue = param[:eq], param[eq+1:]
+	}
+
+	key, err := url.QueryUnescape(rawKey)
+	if err != nil {
+		return filter, false, fmt.Errorf("invalid query parameter %q: %w", param, err)
+	}
+
+	if !strings.HasPrefix(key, metadataFilterPrefix) {
+		return filter, false, nil
+	}
+
+	switch idx := strings.IndexAny(key, "<>"); {
+	case hasValue && idx == len(key)-1:
+		// meta.rating>=4
+		filter.Op = key[idx:] + dbmodels.MetadataEqual
+		key = key[:idx]
+	case hasValue && idx < 0:
+		filter.Op = dbmodels.MetadataEqual
+	case !hasValue && idx >= 0:
+		// meta.rating>4, the value is part of the key.
+		filter.Op = key[idx : idx+1]
+		key, filter.Value = key[:idx], key[idx+1:]
+	default:
+		return filter, false, fmt.Errorf("metadata filter %q has no valid operator", param)
+	}
+
+	if hasValue {
+		if filter.Value, err = url.QueryUnescape(rawValue); err != nil {
+			return filter, false, fmt.Errorf("invalid query parameter %q: %w", param, err)
+		}
+	}
+
+	filter.Key = strings.TrimPrefix(key, metadataFilterPrefix)
+	if !metadataKeyPattern.MatchString(filter.Key) {
+		return filter, false, fmt.Errorf("metadata filter key %q must only contain letters, digits, _ and -", filter.Key)
+	}
+
+	if err = filter.Validate(); err != nil {
+		return filter, false, err
+	}
+
+	return filter, true, nil
+}
+
+// listImages lists the images of the album, only those selected by the
+// metadata filters when there are any.
+func listImages(ctx context.Context, imageStore controller.ImageStore, albumName string,
+	filters []dbmodels.MetadataFilter) ([]dbmodels.Image, error) {
+	if len(filters) == 0 {
+		return imageStore.GetAllImages(ctx, albumName)
+	}
+
+	return imageStore.FilterImages(ctx, albumName, filters)
+}
+
+// mergeMetadata applies a JSON merge patch to the metadata: a null value
+// removes its key, other values replace the value of their key. The metadata
+// is copied, it may be shared with the store.
+func mergeMetadata(metadata dbmodels.Metadata, patch map[string]interface{}) dbmodels.Metadata {
+	merged := dbmodels.Metadata{}
+
+	for key, value := range metadata {
+		merged[key] = value
+	}
+
+	for key, value := range patch {
+		if value == nil {
+			delete(merged, key)
+
+			continue
+		}
+
+		merged[key] = value
+	}
+
+	return merged
+}
@@ -0,0 +1,81 @@
+package apihandler
+
+import (
+	"github.com/stretchr/testify/assert"
+	"githum.com/anupam111/image-store/internal/db/dbmodels"
+	"testing"
+)
+
+func Test_metadataFilters(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		rawQuery string
+		expected []dbmodels.MetadataFilter
+		wantErr  bool
+	}{
+		{
+			name:     "no_filter",
+			rawQuery: "albumName=test-album",
+		},
+		{
+			name:     "equal",
+			rawQuery: "albumName=test-album&meta.project=apollo+11",
+			expected: []dbmodels.MetadataFilter{{Key: "project", Op: "=", Value: "apollo 11"}},
+		},
+		{
+			name:     "compare",
+			rawQuery: "meta.a>=1&meta.b<=2&meta.c>3&meta.d<4",
+			expected: []dbmodels.MetadataFilter{
+				{Key: "a", Op: ">=", Value: "1"},
+				{Key: "b", Op: "<=", Value: "2"},
+				{Key: "c", Op: ">", Value: "3"},
+				{Key: "d", Op: "<", Value: "4"},
+			},
+		},
+		{
+			name:     "escaped_operator",
+			rawQuery: "meta.rating%3E=4&meta.size%3C10",
+			expected: []dbmodels.MetadataFilter{
+				{Key: "rating", Op: ">=", Value: "4"},
+				{Key: "size", Op: "<", Value: "10"},
+			},
+		},
+		{
+			name:     "no_operator",
+			rawQuery: "meta.rating",
+			wantErr:  true,
+		},
+		{
+			name:     "invalid_key",
+			rawQuery: "meta.a.b=1",
+			wantErr:  true,
+		},
+		{
+			name:     "compare_to_text",
+			rawQuery: "meta.rating>=high",
+			wantErr:  true,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			filters, err := metadataFilters(tt.rawQuery)
+			assert.Equal(t, tt.wantErr, err != nil)
+			assert.Equal(t, tt.expected, filters)
+		})
+	}
+}
+
+func Test_mergeMetadata(t *testing.T) {
+	t.Parallel()
+
+	metadata := dbmodels.Metadata{"project": "apollo", "draft": true}
+	merged := mergeMetadata(metadata, map[string]interface{}{"draft": nil, "rating": 4.0})
+
+	assert.Equal(t, dbmodels.Metadata{"project": "apollo", "rating": 4.0}, merged)
+	assert.Equal(t, dbmodels.Metadata{"project": "apollo", "draft": true}, metadata)
+}
@@ -0,0 +1,175 @@
+package apihandler
+
+import (
+	"github.com/gin-gonic/gin"
+	"githum.com/anupam111/image-store/internal/constants"
+	"githum.com/anupam111/image-store/internal/db/dbmodels"
+	"githum.com/anupam111/image-store/internal/models"
+	"html"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// SearchImages answers a page of the images whose image or album name, or a
+// string metadata value such as a description or tags, matches the q query
+// parameter, paginated by the limit and offset query parameters.
+func (a *APIHandler) SearchImages(ginCtx *gin.Context) {
+	text := strings.TrimSpace(ginCtx.Query("q"))
+	if text == "" {
+		ginCtx.JSON(http.StatusBadRequest, models.ResponseError{
+			HTTPStatusCode: http.StatusBadRequest,
+			ErrorCode:      "BAD-REQUEST",
+			MessageDetails: "q is empty in query parameter",
+		})
+
+		return
+	}
+
+	limit, ok := intQuery(ginCtx, "limit", constants.DefaultSearchLimit, 1, constants.MaxSearchLimit)
+	if !ok {
+		return
+	}
+
+	offset, ok := intQuery(ginCtx, "offset", 0, 0, -1)
+	if !ok {
+		return
+	}
+
+	matches, total, err := a.imageStore.SearchImages(ginCtx.Request.Context(), text, limit, offset)
+	if err != nil {
+		writeInternalError(ginCtx, err)
+
+		return
+	}
+
+	words := searchWords(text)
+	hits := make([]models.SearchHit, len(matches))
+
+	for idx, match := range matches {
+		hits[idx] = models.SearchHit{
+			ImageName: match.ImageName,
+			AlbumName: match.AlbumName,
+			Rank:      match.Rank,
+			Highlights: map[string]string{
+				"imageName": highlight(match.ImageName, words),
+				"albumName": highlight(match.AlbumName, words),
+			},
+		}
+
+		for key, value := range match.Metadata {
+			strs := dbmodels.Metadata{key: value}.Strings()
+			sort.Strings(strs)
+
+			if marked := highlight(strings.Join(strs, ", "), words); strings.Contains(marked, "<mark>") {
+				hits[idx].Highlights["metadata."+key] = marked
+			}
+		}
+	}
+
+	ginCtx.JSON(http.StatusOK, models.SearchResponse{
+		Query:  text,
+		Total:  total,
+		Limit:  limit,
+		Offset: offset,
+		Hits:   hits,
+	})
+}
+
+// intQuery parses the integer query parameter name, def when it is missing.
+// It answers 400 and reports false when the value is not an integer between
+// min and max, max being ignored when negative.
+func intQuery(ginCtx *gin.Context, name string, def, min, max int) (int, bool) {
+	raw, ok := ginCtx.GetQuery(name)
+	if !ok {
+		return def, true
+	}
+
+	value, err := strconv.Atoi(raw)
+	if err != nil || value < min || (max >= 0 && value > max) {
+		details := name + " must be an integer of at least " + strconv.Itoa(min)
+		if max >= 0 {
+			details += " and at most " + strconv.Itoa(max)
+		}
+
+		ginCtx.JSON(http.StatusBadRequest, models.ResponseError{
+			HTTPStatusCode: http.StatusBadRequest,
+			ErrorCode:      "BAD-REQUEST",
+			MessageDetails: details,
+		})
+
+		return 0, false
+	}
+
+	return value, true
+}
+
+// searchWords splits the search text into lower case words, the way the
+// search indexes split the names.
+func searchWords(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+// highlight HTML escapes name and wraps the parts of it matching one of the
+// words in <mark>, ignoring case.
+func highlight(name string, words []string) string {
+	lower := strings.ToLower(name)
+	if len(lower) != len(name) {
+		// The offsets of the lower case name do not match the name.
+		return html.EscapeString(name)
+	}
+
+	type span struct{ start, end int }
+
+	var spans []span
+
+	for _, word := range words {
+		for from := 0; from < len(lower); {
+			idx := strings.Index(lower[from:], word)
+			if idx < 0 {
+				break
+			}
+
+			spans = append(spans, span{start: from + idx, end: from + idx + len(word)})
+			from += idx + len(word)
+		}
+	}
+
+	sort.Slice(spans, func(i, j int) bool {
+		return spans[i].start < spans[j].start
+	})
+
+	// Overlapping matches are merged into one mark.
+	var merged []span
+
+	for _, s := range spans {
+		if n := len(merged); n > 0 && s.start <= merged[n-1].end {
+			if s.end > merged[n-1].end {
+				merged[n-1].end = s.end
+			}
+
+			continue
+		}
+
+		merged = append(merged, s)
+	}
+
+	var (
+		builder strings.Builder
+		last    int
+	)
+
+	for _, s := range merged {
+		builder.WriteString(html.EscapeString(name[last:s.start]))
+		builder.WriteString("<mark>" + html.EscapeString(name[s.start:s.end]) + "</mark>")
+		last = s.end
+	}
+
+	builder.WriteString(html.EscapeString(name[last:]))
+
+	return builder.String()
+}
@@ -0,0 +1,171 @@
+package apihandler
+
+import (
+	"encoding/json"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"githum.com/anupam111/image-store/internal/controller"
+	"githum.com/anupam111/image-store/internal/db/dbmodels"
+	"githum.com/anupam111/image-store/internal/models"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func Test_SearchImages(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		url     string
+		prepare func(
+			subs *controller.MockImageStore,
+		)
+		statusCode   int
+		expectedBody *models.SearchResponse
+	}{
+		{
+			name: "success",
+			url:  "/search?q=Sunset+beach&limit=5&offset=10",
+			prepare: func(subs *controller.MockImageStore) {
+				subs.EXPECT().SearchImages(gomock.Any(), "Sunset beach", 5, 10).Return([]dbmodels.ImageMatch{
+					{ImageName: "beach-sunset<1>.png", AlbumName: "holiday", Rank: 0.5},
+				}, 11, nil)
+			},
+			statusCode: http.StatusOK,
+			expectedBody: &models.SearchResponse{
+				Query:  "Sunset beach",
+				Total:  11,
+				Limit:  5,
+				Offset: 10,
+				Hits: []models.SearchHit{{
+					ImageName: "beach-sunset<1>.png",
+					AlbumName: "holiday",
+					Rank:      0.5,
+					Highlights: map[string]string{
+						"imageName": "<mark>beach</mark>-<mark>sunset</mark>&lt;1&gt;.png",
+						"albumName": "holiday",
+					},
+				}},
+			},
+		},
+		{
+			name: "metadata_match",
+			url:  "/search?q=lighthouse",
+			prepare: func(subs *controller.MockImageStore) {
+				subs.EXPECT().SearchImages(gomock.Any(), "lighthouse", 20, 0).Return([]dbmodels.ImageMatch{{
+					ImageName: "coast.png",
+					AlbumName: "holiday",
+					Metadata: dbmodels.Metadata{
+						"description": "The lighthouse at dawn",
+						"tags":        []interface{}{"sea", "Lighthouse"},
+						"rating":      4.0,
+					},
+					Rank: 1,
+				}}, 1, nil)
+			},
+			statusCode: http.StatusOK,
+			expectedBody: &models.SearchResponse{
+				Query: "lighthouse",
+				Total: 1,
+				Limit: 20,
+				Hits: []models.SearchHit{{
+					ImageName: "coast.png",
+					AlbumName: "holiday",
+					Rank:      1,
+					Highlights: map[string]string{
+						"imageName":            "coast.png",
+						"albumName":            "holiday",
+						"metadata.description": "The <mark>lighthouse</mark> at dawn",
+						"metadata.tags":        "<mark>Lighthouse</mark>, sea",
+					},
+				}},
+			},
+		},
+		{
+			name: "default_page",
+			url:  "/search?q=sunset",
+			prepare: func(subs *controller.MockImageStore) {
+				subs.EXPECT().SearchImages(gomock.Any(), "sunset", 20, 0).Return([]dbmodels.ImageMatch{}, 0, nil)
+			},
+			statusCode: http.StatusOK,
+			expectedBody: &models.SearchResponse{
+				Query: "sunset",
+				Limit: 20,
+				Hits:  []models.SearchHit{},
+			},
+		},
+		{
+			name:       "missing_query",
+			url:        "/search?q=+",
+			statusCode: http.StatusBadRequest,
+		},
+		{
+			name:       "limit_too_large",
+			url:        "/search?q=sunset&limit=101",
+			statusCode: http.StatusBadRequest,
+		},
+		{
+			name:       "negative_offset",
+			url:        "/search?q=sunset&offset=-1",
+			statusCode: http.StatusBadRequest,
+		},
+		{
+			name: "internal_server_error",
+			url:  "/search?q=sunset",
+			prepare: func(subs *controller.MockImageStore) {
+				subs.EXPECT().SearchImages(gomock.Any(), "sunset", 20, 0).Return(nil, 0, errFake)
+			},
+			statusCode: http.StatusInternalServerError,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			router, controller, apiHandler := setupTestEnv(t)
+
+			if tt.prepare != nil {
+				tt.prepare(controller)
+			}
+			router.GET("/search", apiHandler.SearchImages)
+			req := httptest.NewRequest(http.MethodGet, tt.url, nil)
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+			assert.Equal(t, tt.statusCode, w.Code)
+
+			if tt.expectedBody != nil {
+				var body models.SearchResponse
+				assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+				assert.Equal(t, *tt.expectedBody, body)
+			}
+		})
+	}
+}
+
+func Test_highlight(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		text     string
+		words    []string
+		expected string
+	}{
+		{name: "no_match", text: "city.png", words: []string{"sunset"}, expected: "city.png"},
+		{name: "ignores_case", text: "Sunset.png", words: []string{"sunset"}, expected: "<mark>Sunset</mark>.png"},
+		{name: "every_occurrence", text: "aXa", words: []string{"a"}, expected: "<mark>a</mark>X<mark>a</mark>"},
+		{name: "merges_overlaps", text: "sunset", words: []string{"suns", "nset"}, expected: "<mark>sunset</mark>"},
+		{name: "escapes_html", text: "<b>&sun", words: []string{"sun"}, expected: "&lt;b&gt;&amp;<mark>sun</mark>"},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			assert.Equal(t, tt.expected, highlight(tt.text, tt.words))
+		})
+	}
+}
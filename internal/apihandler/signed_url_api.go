@@ -0,0 +1,214 @@
+package apihandler
+
+import (
+	"errors"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	log "github.com/sirupsen/logrus"
+	"githum.com/anupam111/image-store/internal/auth"
+	"githum.com/anupam111/image-store/internal/constants"
+	"githum.com/anupam111/image-store/internal/controller"
+	"githum.com/anupam111/image-store/internal/db/dbhandler"
+	"githum.com/anupam111/image-store/internal/models"
+	"githum.com/anupam111/image-store/internal/tenant"
+	"githum.com/anupam111/image-store/internal/transform"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// contentBasePath is the path of the image content route, which is
+// authorized by the signature of its url instead of credentials.
+const contentBasePath = "/v1/content"
+
+// SignedURLHandler issues and serves the signed urls of image content.
+type SignedURLHandler struct {
+	log        *log.Logger
+	imageStore controller.ImageStore
+	signer     *auth.URLSigner
+	baseURL    string
+	maxTTL     time.Duration
+	now        func() time.Time
+}
+
+// NewSignedURLHandler implements SignedURLHandler. Issued urls live up to
+// maxTTL and are prefixed with baseURL, the public base URL of the service.
+func NewSignedURLHandler(logger *log.Logger, imageStore controller.ImageStore, signer *auth.URLSigner,
+	baseURL string, maxTTL time.Duration) *SignedURLHandler {
+	return &SignedURLHandler{
+		log:        logger,
+		imageStore: imageStore,
+		signer:     signer,
+		baseURL:    strings.TrimSuffix(baseURL, "/"),
+		maxTTL:     maxTTL,
+		now:        time.Now,
+	}
+}
+
+// CreateSignedURL issues an url of the content of the image of the request
+// body, which needs no credentials until it expires. The caller must be
+// allowed to read the image.
+func (s *SignedURLHandler) CreateSignedURL(ginCtx *gin.Context) {
+	var request models.SignedURLRequest
+	if err := ginCtx.ShouldBindJSON(&request); err != nil || request.AlbumName == "" || request.ImageName == "" {
+		details := "albumName or imageName is empty in request body"
+		if err != nil {
+			details = err.Error()
+		}
+
+		ginCtx.JSON(http.StatusBadRequest, models.ResponseError{
+			HTTPStatusCode: http.StatusBadRequest,
+			ErrorCode:      "BAD-REQUEST",
+			MessageDetails: details,
+		})
+
+		return
+	}
+
+	if request.ExpiresIn == 0 {
+		request.ExpiresIn = constants.DefaultSignedURLTTL
+	}
+
+	ttl := time.Duration(request.ExpiresIn) * time.Second
+	if request.ExpiresIn < 0 || ttl > s.maxTTL {
+		ginCtx.JSON(http.StatusBadRequest, models.ResponseError{
+			HTTPStatusCode: http.StatusBadRequest,
+			ErrorCode:      "BAD-REQUEST",
+			MessageDetails: fmt.Sprintf("expiresIn must be positive and at most %d seconds", int64(s.maxTTL/time.Second)),
+		})
+
+		return
+	}
+
+	if request.Transform != "" {
+		if _, err := transform.Parse(request.Transform); err != nil {
+			ginCtx.JSON(http.StatusBadRequest, models.ResponseError{
+				HTTPStatusCode: http.StatusBadRequest,
+				ErrorCode:      "BAD-REQUEST",
+				MessageDetails: err.Error(),
+			})
+
+			return
+		}
+	}
+
+	if _, err := s.imageStore.GetAlbumImage(ginCtx.Request.Context(), request.AlbumName, request.ImageName); err != nil {
+		writeSignedURLError(ginCtx, err)
+
+		return
+	}
+
+	target := auth.SignedTarget{
+		Tenant:    tenant.FromContext(ginCtx.Request.Context()),
+		AlbumName: request.AlbumName,
+		ImageName: request.ImageName,
+		Transform: request.Transform,
+	}
+	expires := s.now().Add(ttl).Truncate(time.Second)
+
+	ginCtx.JSON(http.StatusCreated, models.SignedURL{
+		URL:       s.baseURL + signedURLPath(target, expires.Unix(), s.signer.Sign(target, expires)),
+		ExpiresAt: expires.UTC(),
+	})
+}
+
+// GetContent answers the raw content of the image of a signed url, or its
+// derivative for the transform of the url, after checking its signature and
+// expiry. The url names the tenant of the image.
+func (s *SignedURLHandler) GetContent(ginCtx *gin.Context) {
+	target := auth.SignedTarget{
+		Tenant:    ginCtx.Query("tenant"),
+		AlbumName: ginCtx.Param("albumName"),
+		ImageName: ginCtx.Param("imageName"),
+		Transform: ginCtx.Query("transform"),
+	}
+
+	expires, err := strconv.ParseInt(ginCtx.Query("expires"), 10, 64)
+	if err != nil {
+		err = auth.ErrInvalidSignature
+	} else {
+		err = s.signer.Verify(target, expires, ginCtx.Query("signature"))
+	}
+
+	if err != nil {
+		ginCtx.JSON(http.StatusForbidden, models.ResponseError{
+			HTTPStatusCode: http.StatusForbidden,
+			ErrorCode:      "FORBIDDEN",
+			MessageDetails: err.Error(),
+		})
+
+		return
+	}
+
+	ctx := tenant.NewContext(ginCtx.Request.Context(), target.Tenant)
+
+	image, err := s.imageStore.GetAlbumImage(ctx, target.AlbumName, target.ImageName)
+	if err != nil {
+		writeSignedURLError(ginCtx, err)
+
+		return
+	}
+
+	// Caches must not keep the content past the expiry of the url.
+	maxAge := time.Unix(expires, 0).Sub(s.now()) / time.Second
+	ginCtx.Header("Cache-Control", fmt.Sprintf("private, max-age=%d", maxAge))
+
+	content := image.Content()
+	if target.Transform == "" {
+		ginCtx.Data(http.StatusOK, http.DetectContentType(content), content)
+
+		return
+	}
+
+	// The spec was checked when the url was issued.
+	contentType := ""
+
+	spec, err := transform.Parse(target.Transform)
+	if err == nil {
+		content, contentType, err = transform.Apply(content, spec)
+	}
+
+	if err == nil {
+		ginCtx.Data(http.StatusOK, contentType, content)
+
+		return
+	}
+
+	ginCtx.Header("Cache-Control", "no-store")
+	ginCtx.JSON(http.StatusUnprocessableEntity, models.ResponseError{
+		HTTPStatusCode: http.StatusUnprocessableEntity,
+		ErrorCode:      "UNPROCESSABLE-ENTITY",
+		MessageDetails: err.Error(),
+	})
+}
+
+// signedURLPath returns the path and query of the signed url of target.
+func signedURLPath(target auth.SignedTarget, expires int64, signature string) string {
+	query := url.Values{}
+	query.Set("tenant", target.Tenant)
+	query.Set("expires", strconv.FormatInt(expires, 10))
+	query.Set("signature", signature)
+
+	if target.Transform != "" {
+		query.Set("transform", target.Transform)
+	}
+
+	return fmt.Sprintf("%s/%s/%s?%s", contentBasePath,
+		url.PathEscape(target.AlbumName), url.PathEscape(target.ImageName), query.Encode())
+}
+
+func writeSignedURLError(ginCtx *gin.Context, err error) {
+	if errors.Is(err, dbhandler.ErrNoDataFound) {
+		ginCtx.JSON(http.StatusNotFound, models.ResponseError{
+			HTTPStatusCode: http.StatusNotFound,
+			ErrorCode:      "NOT-FOUND",
+			MessageDetails: err.Error(),
+		})
+
+		return
+	}
+
+	writeInternalError(ginCtx, err)
+}
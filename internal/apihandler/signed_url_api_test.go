@@ -0,0 +1,299 @@
+package apihandler
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	log "github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"githum.com/anupam111/image-store/internal/auth"
+	"githum.com/anupam111/image-store/internal/controller"
+	"githum.com/anupam111/image-store/internal/db/dbhandler"
+	"githum.com/anupam111/image-store/internal/db/dbmodels"
+	"githum.com/anupam111/image-store/internal/models"
+	"githum.com/anupam111/image-store/internal/tenant"
+	goimage "image"
+	"image/png"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+var pngContent = []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR")
+
+func setupSignedURLTestEnv(t *testing.T) (*gin.Engine, *controller.MockImageStore, *auth.URLSigner) {
+	t.Helper()
+
+	mockController := controller.NewMockImageStore(gomock.NewController(t))
+	signer := auth.NewURLSigner([]byte("secret"))
+	handler := NewSignedURLHandler(log.New(), mockController, signer, "https://images.example.com/", time.Hour)
+
+	router := gin.New()
+	router.POST("/v1/signed-urls", handler.CreateSignedURL)
+	router.GET("/v1/content/:albumName/:imageName", handler.GetContent)
+
+	return router, mockController, signer
+}
+
+func Test_CreateSignedURL(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name       string
+		body       string
+		prepare    func(subs *controller.MockImageStore)
+		statusCode int
+		ttl        time.Duration
+	}{
+		{
+			name: "default_ttl",
+			body: `{"albumName":"holiday","imageName":"beach.png"}`,
+			prepare: func(subs *controller.MockImageStore) {
+				subs.EXPECT().GetAlbumImage(gomock.Any(), "holiday", "beach.png").Return(dbmodels.Image{}, nil)
+			},
+			statusCode: http.StatusCreated,
+			ttl:        15 * time.Minute,
+		},
+		{
+			name: "expires_in",
+			body: `{"albumName":"holiday","imageName":"beach.png","expiresIn":3600}`,
+			prepare: func(subs *controller.MockImageStore) {
+				subs.EXPECT().GetAlbumImage(gomock.Any(), "holiday", "beach.png").Return(dbmodels.Image{}, nil)
+			},
+			statusCode: http.StatusCreated,
+			ttl:        time.Hour,
+		},
+		{
+			name:       "expires_in_too_long",
+			body:       `{"albumName":"holiday","imageName":"beach.png","expiresIn":3601}`,
+			statusCode: http.StatusBadRequest,
+		},
+		{
+			name:       "negative_expires_in",
+			body:       `{"albumName":"holiday","imageName":"beach.png","expiresIn":-1}`,
+			statusCode: http.StatusBadRequest,
+		},
+		{
+			name:       "missing_image",
+			body:       `{"albumName":"holiday"}`,
+			statusCode: http.StatusBadRequest,
+		},
+		{
+			name: "transform",
+			body: `{"albumName":"holiday","imageName":"beach.png","transform":"w=100"}`,
+			prepare: func(subs *controller.MockImageStore) {
+				subs.EXPECT().GetAlbumImage(gomock.Any(), "holiday", "beach.png").Return(dbmodels.Image{}, nil)
+			},
+			statusCode: http.StatusCreated,
+			ttl:        15 * time.Minute,
+		},
+		{
+			name:       "invalid_transform",
+			body:       `{"albumName":"holiday","imageName":"beach.png","transform":"w=0"}`,
+			statusCode: http.StatusBadRequest,
+		},
+		{
+			name: "not_found",
+			body: `{"albumName":"holiday","imageName":"beach.png"}`,
+			prepare: func(subs *controller.MockImageStore) {
+				subs.EXPECT().GetAlbumImage(gomock.Any(), "holiday", "beach.png").Return(dbmodels.Image{}, dbhandler.ErrNoDataFound)
+			},
+			statusCode: http.StatusNotFound,
+		},
+		{
+			name: "forbidden",
+			body: `{"albumName":"holiday","imageName":"beach.png"}`,
+			prepare: func(subs *controller.MockImageStore) {
+				subs.EXPECT().GetAlbumImage(gomock.Any(), "holiday", "beach.png").
+					Return(dbmodels.Image{}, fmt.Errorf("error while getting image, %w", controller.ErrAccessDenied))
+			},
+			statusCode: http.StatusForbidden,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			router, subs, _ := setupSignedURLTestEnv(t)
+			if tt.prepare != nil {
+				tt.prepare(subs)
+			}
+
+			req := httptest.NewRequest(http.MethodPost, "/v1/signed-urls", strings.NewReader(tt.body))
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+			assert.Equal(t, tt.statusCode, w.Code)
+
+			if tt.statusCode != http.StatusCreated {
+				return
+			}
+
+			var signedURL models.SignedURL
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &signedURL))
+			assert.True(t, strings.HasPrefix(signedURL.URL, "https://images.example.com/v1/content/holiday/beach.png?"))
+			assert.Contains(t, signedURL.URL, "tenant=default")
+			assert.WithinDuration(t, time.Now().Add(tt.ttl), signedURL.ExpiresAt, 2*time.Second)
+		})
+	}
+}
+
+func Test_GetContent(t *testing.T) {
+	t.Parallel()
+
+	target := auth.SignedTarget{Tenant: "acme", AlbumName: "holiday", ImageName: "beach sunset.png"}
+	image := dbmodels.Image{ImageName: target.ImageName, AlbumName: target.AlbumName}
+	image.SetContent(pngContent)
+
+	var decodable bytes.Buffer
+	require.NoError(t, png.Encode(&decodable, goimage.NewGray(goimage.Rect(0, 0, 200, 100))))
+
+	photo := dbmodels.Image{ImageName: target.ImageName, AlbumName: target.AlbumName}
+	photo.SetContent(decodable.Bytes())
+
+	transformed := target
+	transformed.Transform = "w=100"
+
+	tests := []struct {
+		name        string
+		url         func(signer *auth.URLSigner) string
+		prepare     func(subs *controller.MockImageStore)
+		statusCode  int
+		contentType string
+		bounds      goimage.Rectangle
+	}{
+		{
+			name: "success",
+			url: func(signer *auth.URLSigner) string {
+				expires := time.Now().Add(time.Minute)
+				return signedURLPath(target, expires.Unix(), signer.Sign(target, expires))
+			},
+			prepare: func(subs *controller.MockImageStore) {
+				subs.EXPECT().GetAlbumImage(gomock.Any(), target.AlbumName, target.ImageName).
+					DoAndReturn(func(ctx context.Context, _, _ string) (dbmodels.Image, error) {
+						assert.Equal(t, "acme", tenant.FromContext(ctx), "the image is read in the tenant of the url")
+
+						return image, nil
+					})
+			},
+			statusCode:  http.StatusOK,
+			contentType: "image/png",
+		},
+		{
+			name: "other_tenant",
+			url: func(signer *auth.URLSigner) string {
+				expires := time.Now().Add(time.Minute)
+				return strings.Replace(signedURLPath(target, expires.Unix(), signer.Sign(target, expires)),
+					"tenant=acme", "tenant=globex", 1)
+			},
+			statusCode: http.StatusForbidden,
+		},
+		{
+			name: "other_image",
+			url: func(signer *auth.URLSigner) string {
+				expires := time.Now().Add(time.Minute)
+				return strings.Replace(signedURLPath(target, expires.Unix(), signer.Sign(target, expires)),
+					"sunset", "city", 1)
+			},
+			statusCode: http.StatusForbidden,
+		},
+		{
+			name: "added_transform",
+			url: func(signer *auth.URLSigner) string {
+				expires := time.Now().Add(time.Minute)
+				return signedURLPath(target, expires.Unix(), signer.Sign(target, expires)) + "&transform=w%3D100"
+			},
+			statusCode: http.StatusForbidden,
+		},
+		{
+			name: "expired",
+			url: func(signer *auth.URLSigner) string {
+				expires := time.Now().Add(-time.Minute)
+				return signedURLPath(target, expires.Unix(), signer.Sign(target, expires))
+			},
+			statusCode: http.StatusForbidden,
+		},
+		{
+			name: "missing_signature",
+			url: func(signer *auth.URLSigner) string {
+				return "/v1/content/holiday/beach.png"
+			},
+			statusCode: http.StatusForbidden,
+		},
+		{
+			name: "signed_transform",
+			url: func(signer *auth.URLSigner) string {
+				expires := time.Now().Add(time.Minute)
+				return signedURLPath(transformed, expires.Unix(), signer.Sign(transformed, expires))
+			},
+			prepare: func(subs *controller.MockImageStore) {
+				subs.EXPECT().GetAlbumImage(gomock.Any(), target.AlbumName, target.ImageName).Return(photo, nil)
+			},
+			statusCode:  http.StatusOK,
+			contentType: "image/png",
+			bounds:      goimage.Rect(0, 0, 100, 50),
+		},
+		{
+			name: "untransformable_image",
+			url: func(signer *auth.URLSigner) string {
+				expires := time.Now().Add(time.Minute)
+				return signedURLPath(transformed, expires.Unix(), signer.Sign(transformed, expires))
+			},
+			prepare: func(subs *controller.MockImageStore) {
+				subs.EXPECT().GetAlbumImage(gomock.Any(), target.AlbumName, target.ImageName).Return(image, nil)
+			},
+			statusCode: http.StatusUnprocessableEntity,
+		},
+		{
+			name: "deleted_image",
+			url: func(signer *auth.URLSigner) string {
+				expires := time.Now().Add(time.Minute)
+				return signedURLPath(target, expires.Unix(), signer.Sign(target, expires))
+			},
+			prepare: func(subs *controller.MockImageStore) {
+				subs.EXPECT().GetAlbumImage(gomock.Any(), target.AlbumName, target.ImageName).
+					Return(dbmodels.Image{}, dbhandler.ErrNoDataFound)
+			},
+			statusCode: http.StatusNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			router, subs, signer := setupSignedURLTestEnv(t)
+			if tt.prepare != nil {
+				tt.prepare(subs)
+			}
+
+			req := httptest.NewRequest(http.MethodGet, tt.url(signer), nil)
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+			assert.Equal(t, tt.statusCode, w.Code)
+
+			if tt.statusCode == http.StatusOK {
+				assert.Equal(t, tt.contentType, w.Header().Get("Content-Type"))
+				assert.Regexp(t, `^private, max-age=(59|60)$`, w.Header().Get("Cache-Control"))
+
+				if tt.bounds.Empty() {
+					assert.Equal(t, pngContent, w.Body.Bytes())
+
+					return
+				}
+
+				derivative, err := png.Decode(w.Body)
+				require.NoError(t, err)
+				assert.Equal(t, tt.bounds, derivative.Bounds())
+			}
+		})
+	}
+}
@@ -0,0 +1,35 @@
+package apihandler
+
+import (
+	"github.com/gin-gonic/gin"
+	"githum.com/anupam111/image-store/internal/models"
+	"net/http"
+)
+
+// GetUsage reports the images and bytes stored by the tenant of the caller
+// and by the albums the caller can view, against their quotas.
+func (a *APIHandler) GetUsage(ginCtx *gin.Context) {
+	report, err := a.imageStore.GetUsage(ginCtx.Request.Context())
+	if err != nil {
+		writeInternalError(ginCtx, err)
+
+		return
+	}
+
+	response := models.Usage{
+		Tenant: report.Tenant,
+		Images: models.QuotaUsage{Used: report.Usage.Images, Limit: report.Quotas.TenantImages},
+		Bytes:  models.QuotaUsage{Used: report.Usage.Bytes, Limit: report.Quotas.TenantBytes},
+		Albums: make([]models.AlbumUsage, len(report.Albums)),
+	}
+
+	for idx, album := range report.Albums {
+		response.Albums[idx] = models.AlbumUsage{
+			AlbumName: album.AlbumName,
+			Images:    models.QuotaUsage{Used: album.Images, Limit: report.Quotas.AlbumImages},
+			Bytes:     models.QuotaUsage{Used: album.Bytes, Limit: report.Quotas.AlbumBytes},
+		}
+	}
+
+	ginCtx.JSON(http.StatusOK, response)
+}
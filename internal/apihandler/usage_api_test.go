@@ -0,0 +1,69 @@
+package apihandler
+
+import (
+	"fmt"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"githum.com/anupam111/image-store/internal/config"
+	"githum.com/anupam111/image-store/internal/controller"
+	"githum.com/anupam111/image-store/internal/db/dbmodels"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func Test_GetUsage(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name         string
+		prepare      func(subs *controller.MockImageStore)
+		statusCode   int
+		expectedBody string
+	}{
+		{
+			name: "usage",
+			prepare: func(subs *controller.MockImageStore) {
+				subs.EXPECT().GetUsage(gomock.Any()).Return(controller.UsageReport{
+					Tenant: "acme",
+					Usage:  dbmodels.Usage{Images: 3, Bytes: 54},
+					Albums: []dbmodels.AlbumUsage{
+						{Tenant: "acme", AlbumName: "holiday", Usage: dbmodels.Usage{Images: 3, Bytes: 54}},
+					},
+					Quotas: config.Quotas{TenantBytes: 1000, AlbumImages: 10},
+				}, nil)
+			},
+			statusCode: http.StatusOK,
+			expectedBody: `{"tenant":"acme","images":{"used":3},"bytes":{"used":54,"limit":1000},` +
+				`"albums":[{"albumName":"holiday","images":{"used":3,"limit":10},"bytes":{"used":54}}]}`,
+		},
+		{
+			name: "error",
+			prepare: func(subs *controller.MockImageStore) {
+				subs.EXPECT().GetUsage(gomock.Any()).Return(controller.UsageReport{}, fmt.Errorf("some error"))
+			},
+			statusCode: http.StatusInternalServerError,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			router, controller, apiHandler := setupTestEnv(t)
+			tt.prepare(controller)
+
+			router.GET("/usage", apiHandler.GetUsage)
+
+			req := httptest.NewRequest(http.MethodGet, "/usage", nil)
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+			assert.Equal(t, tt.statusCode, w.Code)
+
+			if tt.expectedBody != "" {
+				assert.JSONEq(t, tt.expectedBody, w.Body.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,272 @@
+package auth
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// jwksRefreshInterval is the minimum time between two loads of the key
+	// set, so that tokens with unknown key ids can not flood the provider.
+	jwksRefreshInterval = time.Minute
+	jwksFetchTimeout    = 10 * time.Second
+	// maxJWKSSize bounds the size of the key set document.
+	maxJWKSSize = 1 << 20
+)
+
+var errUnknownKey = errors.New("unknown signing key")
+
+// KeySet holds the public keys of a JSON Web Key Set (RFC 7517), loaded from a
+// local file or an http(s) URL. It is reloaded when a token is signed with an
+// unknown key, which happens when the provider rotates its keys.
+type KeySet struct {
+	source string
+	client *http.Client
+	now    func() time.Time
+
+	mu       sync.RWMutex
+	keys     map[string]crypto.PublicKey
+	loadedAt time.Time
+}
+
+// NewKeySet implements KeySet, source being a file path or an http(s) URL.
+func NewKeySet(source string) *KeySet {
+	return &KeySet{
+		source: source,
+		client: &http.Client{Timeout: jwksFetchTimeout},
+		now:    time.Now,
+	}
+}
+
+// Load loads the keys of the source, replacing the current ones.
+func (k *KeySet) Load(ctx context.Context) error {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	return k.load(ctx)
+}
+
+func (k *KeySet) load(ctx context.Context) error {
+	// Failed loads count too, an unreachable provider is not retried on
+	// every request.
+	k.loadedAt = k.now()
+
+	data, err := k.read(ctx)
+	if err != nil {
+		return fmt.Errorf("error while reading jwks %s, %w", k.source, err)
+	}
+
+	keys, err := parseJWKS(data)
+	if err != nil {
+		return fmt.Errorf("error while parsing jwks %s, %w", k.source, err)
+	}
+
+	k.keys = keys
+
+	return nil
+}
+
+func (k *KeySet) read(ctx context.Context) ([]byte, error) {
+	if !strings.HasPrefix(k.source, "http://") && !strings.HasPrefix(k.source, "https://") {
+		return os.ReadFile(k.source)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, k.source, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := k.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status %s", resp.Status)
+	}
+
+	return io.ReadAll(io.LimitReader(resp.Body, maxJWKSSize))
+}
+
+// key returns the public key of kid. An empty kid selects the only key of a
+// set with one key.
+func (k *KeySet) key(ctx context.Context, kid string) (crypto.PublicKey, error) {
+	k.mu.RLock()
+	key, ok := lookupKey(k.keys, kid)
+	k.mu.RUnlock()
+
+	if ok {
+		return key, nil
+	}
+
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	// Another request may have reloaded the keys meanwhile.
+	if key, ok = lookupKey(k.keys, kid); ok {
+		return key, nil
+	}
+
+	if k.now().Sub(k.loadedAt) < jwksRefreshInterval {
+		return nil, errUnknownKey
+	}
+
+	if err := k.load(ctx); err != nil {
+		return nil, err
+	}
+
+	if key, ok = lookupKey(k.keys, kid); ok {
+		return key, nil
+	}
+
+	return nil, errUnknownKey
+}
+
+func lookupKey(keys map[string]crypto.PublicKey, kid string) (crypto.PublicKey, bool) {
+	if kid == "" && len(keys) == 1 {
+		for _, key := range keys {
+			return key, true
+		}
+	}
+
+	key, ok := keys[kid]
+
+	return key, ok
+}
+
+type jwk struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	// RSA keys.
+	N string `json:"n"`
+	E string `json:"e"`
+	// EC keys.
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+// parseJWKS returns the signature keys of a key set by key id. Keys of other
+// types than RSA and EC, and encryption keys, are skipped.
+func parseJWKS(data []byte) (map[string]crypto.PublicKey, error) {
+	var set struct {
+		Keys []jwk `json:"keys"`
+	}
+
+	if err := json.Unmarshal(data, &set); err != nil {
+		return nil, err
+	}
+
+	keys := make(map[string]crypto.PublicKey, len(set.Keys))
+
+	for _, jwk := range set.Keys {
+		if jwk.Use != "" && jwk.Use != "sig" {
+			continue
+		}
+
+		var (
+			key crypto.PublicKey
+			err error
+		)
+
+		switch jwk.Kty {
+		case "RSA":
+			key, err = jwk.rsaKey()
+		case "EC":
+			key, err = jwk.ecKey()
+		default:
+			continue
+		}
+
+		if err != nil {
+			return nil, fmt.Errorf("invalid key %q: %w", jwk.Kid, err)
+		}
+
+		keys[jwk.Kid] = key
+	}
+
+	if len(keys) == 0 {
+		return nil, errors.New("no RSA or EC signature key")
+	}
+
+	return keys, nil
+}
+
+func (j jwk) rsaKey() (*rsa.PublicKey, error) {
+	n, err := base64Int(j.N)
+	if err != nil {
+		return nil, fmt.Errorf("invalid modulus: %w", err)
+	}
+
+	e, err := base64Int(j.E)
+	if err != nil {
+		return nil, fmt.Errorf("invalid exponent: %w", err)
+	}
+
+	if !e.IsInt64() || e.Int64() < 3 || e.Int64() > 1<<31-1 {
+		return nil, errors.New("invalid exponent")
+	}
+
+	return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+}
+
+func (j jwk) ecKey() (*ecdsa.PublicKey, error) {
+	var curve elliptic.Curve
+
+	switch j.Crv {
+	case "P-256":
+		curve = elliptic.P256()
+	case "P-384":
+		curve = elliptic.P384()
+	case "P-521":
+		curve = elliptic.P521()
+	default:
+		return nil, fmt.Errorf("unsupported curve %q", j.Crv)
+	}
+
+	x, err := base64Int(j.X)
+	if err != nil {
+		return nil, fmt.Errorf("invalid x: %w", err)
+	}
+
+	y, err := base64Int(j.Y)
+	if err != nil {
+		return nil, fmt.Errorf("invalid y: %w", err)
+	}
+
+	if !curve.IsOnCurve(x, y) {
+		return nil, errors.New("point is not on the curve")
+	}
+
+	return &ecdsa.PublicKey{Curve: curve, X: x, Y: y}, nil
+}
+
+func base64Int(value string) (*big.Int, error) {
+	data, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(data) == 0 {
+		return nil, errors.New("empty value")
+	}
+
+	return new(big.Int).SetBytes(data), nil
+}
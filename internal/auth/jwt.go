@@ -0,0 +1,304 @@
+package auth
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"githum.com/anupam111/image-store/internal/tenant"
+	"math"
+	"math/big"
+	"strings"
+	"time"
+
+	// Register the hash functions of the signature algorithms.
+	_ "crypto/sha256"
+	_ "crypto/sha512"
+)
+
+// jwtLeeway tolerates the clock skew between the provider and the service.
+const jwtLeeway = time.Minute
+
+// ErrInvalidToken is returned for a malformed, badly signed, expired or
+// foreign bearer token.
+var ErrInvalidToken = errors.New("invalid bearer token")
+
+// JWTVerifier authenticates the JWT bearer tokens (RFC 7519) of an OIDC
+// provider and maps their claims to a principal.
+type JWTVerifier struct {
+	issuer      string
+	audience    string
+	groupsClaim string
+	tenantClaim string
+	keys        *KeySet
+	now         func() time.Time
+}
+
+// NewJWTVerifier implements JWTVerifier. Tokens must be issued by issuer for
+// audience and signed by one of keys. The groups of the principal are read
+// from the groupsClaim claim, its scopes from the scope or scp claim and the
+// tenant it is bound to from the tenantClaim claim, tenant.Default when the
+// token has none.
+func NewJWTVerifier(issuer, audience, groupsClaim, tenantClaim string, keys *KeySet) *JWTVerifier {
+	return &JWTVerifier{
+		issuer:      issuer,
+		audience:    audience,
+		groupsClaim: groupsClaim,
+		tenantClaim: tenantClaim,
+		keys:        keys,
+		now:         time.Now,
+	}
+}
+
+// IsJWT reports whether the bearer token looks like a JWT rather than an API
+// key.
+func IsJWT(token string) bool {
+	return strings.Count(token, ".") == 2
+}
+
+type jwtHeader struct {
+	Alg string `json:"alg"`
+	Kid string `json:"kid"`
+}
+
+// Verify checks the signature and the claims of token and returns its
+// principal, ErrInvalidToken when it is not acceptable.
+func (v *JWTVerifier) Verify(ctx context.Context, token string) (Principal, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return Principal{}, fmt.Errorf("%w: malformed", ErrInvalidToken)
+	}
+
+	var header jwtHeader
+	if err := decodeSegment(parts[0], &header); err != nil {
+		return Principal{}, fmt.Errorf("%w: invalid header: %v", ErrInvalidToken, err)
+	}
+
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return Principal{}, fmt.Errorf("%w: invalid signature encoding", ErrInvalidToken)
+	}
+
+	key, err := v.keys.key(ctx, header.Kid)
+	if errors.Is(err, errUnknownKey) {
+		return Principal{}, fmt.Errorf("%w: %v %q", ErrInvalidToken, err, header.Kid)
+	}
+
+	if err != nil {
+		return Principal{}, err
+	}
+
+	if err = verifySignature(header.Alg, key, parts[0]+"."+parts[1], signature); err != nil {
+		return Principal{}, fmt.Errorf("%w: %v", ErrInvalidToken, err)
+	}
+
+	var claims map[string]json.RawMessage
+	if err = decodeSegment(parts[1], &claims); err != nil {
+		return Principal{}, fmt.Errorf("%w: invalid claims: %v", ErrInvalidToken, err)
+	}
+
+	if err = v.validate(claims); err != nil {
+		return Principal{}, fmt.Errorf("%w: %v", ErrInvalidToken, err)
+	}
+
+	return v.principal(claims)
+}
+
+// validate checks the registered claims of a token.
+func (v *JWTVerifier) validate(claims map[string]json.RawMessage) error {
+	var issuer string
+	if err := json.Unmarshal(claims["iss"], &issuer); err != nil || issuer != v.issuer {
+		return fmt.Errorf("issuer is not %s", v.issuer)
+	}
+
+	audiences, err := stringsClaim(claims["aud"])
+	if err != nil || !contains(audiences, v.audience) {
+		return fmt.Errorf("audience is not %s", v.audience)
+	}
+
+	now := v.now()
+
+	expiresAt, ok := timeClaim(claims["exp"])
+	if !ok {
+		return errors.New("missing or invalid exp")
+	}
+
+	if !now.Before(expiresAt.Add(jwtLeeway)) {
+		return errors.New("token expired")
+	}
+
+	if raw, present := claims["nbf"]; present {
+		notBefore, ok := timeClaim(raw)
+		if !ok {
+			return errors.New("invalid nbf")
+		}
+
+		if now.Add(jwtLeeway).Before(notBefore) {
+			return errors.New("token not valid yet")
+		}
+	}
+
+	return nil
+}
+
+// principal maps the claims of a valid token to its principal.
+func (v *JWTVerifier) principal(claims map[string]json.RawMessage) (Principal, error) {
+	var principal Principal
+	if err := json.Unmarshal(claims["sub"], &principal.Subject); err != nil || principal.Subject == "" {
+		return Principal{}, fmt.Errorf("%w: missing sub", ErrInvalidToken)
+	}
+
+	var err error
+	if raw, ok := claims[v.groupsClaim]; ok {
+		if principal.Groups, err = stringsClaim(raw); err != nil {
+			return Principal{}, fmt.Errorf("%w: invalid %s claim", ErrInvalidToken, v.groupsClaim)
+		}
+	}
+
+	principal.Tenant = tenant.Default
+	if raw, ok := claims[v.tenantClaim]; ok {
+		if err = json.Unmarshal(raw, &principal.Tenant); err != nil || !tenant.Valid(principal.Tenant) {
+			return Principal{}, fmt.Errorf("%w: invalid %s claim", ErrInvalidToken, v.tenantClaim)
+		}
+	}
+
+	// scope is a space separated string (RFC 8693), some providers send scp
+	// as an array instead.
+	raw, ok := claims["scope"]
+	if !ok {
+		raw, ok = claims["scp"]
+	}
+
+	if ok {
+		scopes, err := stringsClaim(raw)
+		if err != nil {
+			return Principal{}, fmt.Errorf("%w: invalid scope claim", ErrInvalidToken)
+		}
+
+		for _, scope := range scopes {
+			principal.Scopes = append(principal.Scopes, strings.Fields(scope)...)
+		}
+	}
+
+	return principal, nil
+}
+
+func decodeSegment(segment string, value interface{}) error {
+	data, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return err
+	}
+
+	return json.Unmarshal(data, value)
+}
+
+// stringsClaim decodes a claim holding either a string or an array of
+// strings.
+func stringsClaim(raw json.RawMessage) ([]string, error) {
+	var value string
+	if err := json.Unmarshal(raw, &value); err == nil {
+		return []string{value}, nil
+	}
+
+	var values []string
+	if err := json.Unmarshal(raw, &values); err != nil {
+		return nil, err
+	}
+
+	return values, nil
+}
+
+// timeClaim decodes a NumericDate claim, seconds since the epoch.
+func timeClaim(raw json.RawMessage) (time.Time, bool) {
+	var seconds float64
+	if err := json.Unmarshal(raw, &seconds); err != nil || math.IsNaN(seconds) || math.IsInf(seconds, 0) {
+		return time.Time{}, false
+	}
+
+	whole, fraction := math.Modf(seconds)
+
+	return time.Unix(int64(whole), int64(fraction*float64(time.Second))), true
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+
+	return false
+}
+
+// signatureHashes are the hash functions of the supported JWS algorithms.
+var signatureHashes = map[string]crypto.Hash{
+	"RS256": crypto.SHA256,
+	"RS384": crypto.SHA384,
+	"RS512": crypto.SHA512,
+	"PS256": crypto.SHA256,
+	"PS384": crypto.SHA384,
+	"PS512": crypto.SHA512,
+	"ES256": crypto.SHA256,
+	"ES384": crypto.SHA384,
+	"ES512": crypto.SHA512,
+}
+
+// verifySignature checks the signature of the signing input with the key.
+// The algorithm must match the type of the key: "none" and the HMAC
+// algorithms are never accepted.
+func verifySignature(alg string, key crypto.PublicKey, signingInput string, signature []byte) error {
+	hash, ok := signatureHashes[alg]
+	if !ok {
+		return fmt.Errorf("unsupported algorithm %q", alg)
+	}
+
+	hasher := hash.New()
+	hasher.Write([]byte(signingInput))
+	digest := hasher.Sum(nil)
+
+	switch pub := key.(type) {
+	case *rsa.PublicKey:
+		switch alg[:2] {
+		case "RS":
+			return rsa.VerifyPKCS1v15(pub, hash, digest, signature)
+		case "PS":
+			return rsa.VerifyPSS(pub, hash, digest, signature, &rsa.PSSOptions{SaltLength: rsa.PSSSaltLengthEqualsHash})
+		}
+	case *ecdsa.PublicKey:
+		if ecdsaAlgorithm(pub) != alg {
+			break
+		}
+
+		size := (pub.Curve.Params().BitSize + 7) / 8
+		if len(signature) != 2*size {
+			return errors.New("invalid signature length")
+		}
+
+		r := new(big.Int).SetBytes(signature[:size])
+		s := new(big.Int).SetBytes(signature[size:])
+
+		if !ecdsa.Verify(pub, digest, r, s) {
+			return errors.New("invalid signature")
+		}
+
+		return nil
+	}
+
+	return fmt.Errorf("algorithm %q does not match the key", alg)
+}
+
+// ecdsaAlgorithm returns the only algorithm allowed for the curve of key.
+func ecdsaAlgorithm(key *ecdsa.PublicKey) string {
+	switch key.Curve.Params().BitSize {
+	case 256:
+		return "ES256"
+	case 384:
+		return "ES384"
+	default:
+		return "ES512"
+	}
+}
@@ -0,0 +1,432 @@
+package auth
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"githum.com/anupam111/image-store/internal/db/dbmodels"
+	"githum.com/anupam111/image-store/internal/tenant"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+const (
+	testIssuer   = "https://login.example.com"
+	testAudience = "image-store"
+)
+
+var testNow = time.Date(2022, 5, 1, 10, 0, 0, 0, time.UTC)
+
+func base64Encode(data []byte) string {
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func rsaJWK(kid string, key *rsa.PrivateKey) map[string]string {
+	return map[string]string{
+		"kty": "RSA",
+		"kid": kid,
+		"use": "sig",
+		"n":   base64Encode(key.N.Bytes()),
+		"e":   base64Encode(big.NewInt(int64(key.E)).Bytes()),
+	}
+}
+
+func ecJWK(kid string, key *ecdsa.PrivateKey) map[string]string {
+	return map[string]string{
+		"kty": "EC",
+		"kid": kid,
+		"crv": "P-256",
+		"x":   base64Encode(key.X.FillBytes(make([]byte, 32))),
+		"y":   base64Encode(key.Y.FillBytes(make([]byte, 32))),
+	}
+}
+
+func marshalJWKS(t *testing.T, keys ...map[string]string) []byte {
+	t.Helper()
+	data, err := json.Marshal(map[string]interface{}{"keys": keys})
+	require.NoError(t, err)
+
+	return data
+}
+
+// signToken returns a JWT of the claims signed with key.
+func signToken(t *testing.T, alg, kid string, key crypto.Signer, claims map[string]interface{}) string {
+	t.Helper()
+	header, err := json.Marshal(map[string]string{"alg": alg, "kid": kid, "typ": "JWT"})
+	require.NoError(t, err)
+
+	payload, err := json.Marshal(claims)
+	require.NoError(t, err)
+
+	signingInput := base64Encode(header) + "." + base64Encode(payload)
+	digest := signatureHashes[alg].New()
+	digest.Write([]byte(signingInput))
+
+	var signature []byte
+
+	switch key := key.(type) {
+	case *rsa.PrivateKey:
+		if strings.HasPrefix(alg, "PS") {
+			signature, err = rsa.SignPSS(rand.Reader, key, signatureHashes[alg], digest.Sum(nil),
+				&rsa.PSSOptions{SaltLength: rsa.PSSSaltLengthEqualsHash})
+		} else {
+			signature, err = rsa.SignPKCS1v15(rand.Reader, key, signatureHashes[alg], digest.Sum(nil))
+		}
+	case *ecdsa.PrivateKey:
+		var r, s *big.Int
+		r, s, err = ecdsa.Sign(rand.Reader, key, digest.Sum(nil))
+		signature = append(r.FillBytes(make([]byte, 32)), s.FillBytes(make([]byte, 32))...)
+	}
+
+	require.NoError(t, err)
+
+	return signingInput + "." + base64Encode(signature)
+}
+
+func validClaims() map[string]interface{} {
+	return map[string]interface{}{
+		"iss":    testIssuer,
+		"aud":    []string{"other", testAudience},
+		"sub":    "user-1",
+		"exp":    testNow.Add(time.Hour).Unix(),
+		"nbf":    testNow.Add(-time.Minute).Unix(),
+		"groups": []string{"editors", "viewers"},
+		"scope":  "openid images:read albums:read",
+	}
+}
+
+func Test_JWTVerifier_Verify(t *testing.T) {
+	t.Parallel()
+
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	otherKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	path := filepath.Join(t.TempDir(), "jwks.json")
+	require.NoError(t, os.WriteFile(path, marshalJWKS(t, rsaJWK("rsa", rsaKey), ecJWK("ec", ecKey)), 0o600))
+
+	keys := NewKeySet(path)
+	keys.now = func() time.Time { return testNow }
+	require.NoError(t, keys.Load(context.Background()))
+
+	verifier := NewJWTVerifier(testIssuer, testAudience, "groups", "tenant", keys)
+	verifier.now = func() time.Time { return testNow }
+
+	withClaim := func(name string, value interface{}) map[string]interface{} {
+		claims := validClaims()
+		if value == nil {
+			delete(claims, name)
+		} else {
+			claims[name] = value
+		}
+
+		return claims
+	}
+
+	tests := []struct {
+		name     string
+		token    string
+		expected Principal
+		wantErr  bool
+	}{
+		{
+			name:  "rs256",
+			token: signToken(t, "RS256", "rsa", rsaKey, validClaims()),
+			expected: Principal{
+				Subject: "user-1",
+				Groups:  []string{"editors", "viewers"},
+				Scopes:  dbmodels.Scopes{"openid", dbmodels.ScopeImagesRead, dbmodels.ScopeAlbumsRead},
+				Tenant:  tenant.Default,
+			},
+		},
+		{
+			name:  "ps256",
+			token: signToken(t, "PS256", "rsa", rsaKey, validClaims()),
+			expected: Principal{
+				Subject: "user-1",
+				Groups:  []string{"editors", "viewers"},
+				Scopes:  dbmodels.Scopes{"openid", dbmodels.ScopeImagesRead, dbmodels.ScopeAlbumsRead},
+				Tenant:  tenant.Default,
+			},
+		},
+		{
+			name:  "es256_without_scope",
+			token: signToken(t, "ES256", "ec", ecKey, withClaim("scope", nil)),
+			expected: Principal{
+				Subject: "user-1",
+				Groups:  []string{"editors", "viewers"},
+				Tenant:  tenant.Default,
+			},
+		},
+		{
+			name: "scp_array_and_single_group",
+			token: signToken(t, "ES256", "ec", ecKey, func() map[string]interface{} {
+				claims := withClaim("scope", nil)
+				claims["scp"] = []string{dbmodels.ScopeImagesWrite}
+				claims["groups"] = "admins"
+				claims["aud"] = testAudience
+
+				return claims
+			}()),
+			expected: Principal{
+				Subject: "user-1",
+				Groups:  []string{"admins"},
+				Scopes:  dbmodels.Scopes{dbmodels.ScopeImagesWrite},
+				Tenant:  tenant.Default,
+			},
+		},
+		{
+			name:  "tenant",
+			token: signToken(t, "RS256", "rsa", rsaKey, withClaim("tenant", "acme")),
+			expected: Principal{
+				Subject: "user-1",
+				Groups:  []string{"editors", "viewers"},
+				Scopes:  dbmodels.Scopes{"openid", dbmodels.ScopeImagesRead, dbmodels.ScopeAlbumsRead},
+				Tenant:  "acme",
+			},
+		},
+		{
+			name:    "invalid_tenant",
+			token:   signToken(t, "RS256", "rsa", rsaKey, withClaim("tenant", "../acme")),
+			wantErr: true,
+		},
+		{
+			name:    "expired",
+			token:   signToken(t, "RS256", "rsa", rsaKey, withClaim("exp", testNow.Add(-2*time.Minute).Unix())),
+			wantErr: true,
+		},
+		{
+			name:  "expired_within_leeway",
+			token: signToken(t, "RS256", "rsa", rsaKey, withClaim("exp", testNow.Add(-30*time.Second).Unix())),
+			expected: Principal{
+				Subject: "user-1",
+				Groups:  []string{"editors", "viewers"},
+				Scopes:  dbmodels.Scopes{"openid", dbmodels.ScopeImagesRead, dbmodels.ScopeAlbumsRead},
+				Tenant:  tenant.Default,
+			},
+		},
+		{
+			name:    "missing_exp",
+			token:   signToken(t, "RS256", "rsa", rsaKey, withClaim("exp", nil)),
+			wantErr: true,
+		},
+		{
+			name:    "not_valid_yet",
+			token:   signToken(t, "RS256", "rsa", rsaKey, withClaim("nbf", testNow.Add(time.Hour).Unix())),
+			wantErr: true,
+		},
+		{
+			name:    "foreign_issuer",
+			token:   signToken(t, "RS256", "rsa", rsaKey, withClaim("iss", "https://evil.example.com")),
+			wantErr: true,
+		},
+		{
+			name:    "foreign_audience",
+			token:   signToken(t, "RS256", "rsa", rsaKey, withClaim("aud", "other")),
+			wantErr: true,
+		},
+		{
+			name:    "missing_subject",
+			token:   signToken(t, "RS256", "rsa", rsaKey, withClaim("sub", nil)),
+			wantErr: true,
+		},
+		{
+			name:    "unknown_key",
+			token:   signToken(t, "RS256", "rotated", rsaKey, validClaims()),
+			wantErr: true,
+		},
+		{
+			name:    "wrong_key",
+			token:   signToken(t, "ES256", "ec", otherKey, validClaims()),
+			wantErr: true,
+		},
+		{
+			name:    "algorithm_of_other_key_type",
+			token:   signToken(t, "RS256", "ec", rsaKey, validClaims()),
+			wantErr: true,
+		},
+		{
+			name:    "tampered_claims",
+			token:   tamper(signToken(t, "RS256", "rsa", rsaKey, validClaims())),
+			wantErr: true,
+		},
+		{
+			name:    "none_algorithm",
+			token:   unsigned(t, validClaims()),
+			wantErr: true,
+		},
+		{
+			name:    "malformed",
+			token:   "a.b",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			principal, err := verifier.Verify(context.Background(), tt.token)
+			if tt.wantErr {
+				assert.ErrorIs(t, err, ErrInvalidToken)
+
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, principal)
+		})
+	}
+}
+
+// tamper replaces the claims of a token, keeping its signature.
+func tamper(token string) string {
+	parts := strings.Split(token, ".")
+	claims := validClaims()
+	claims["sub"] = "admin"
+	payload, _ := json.Marshal(claims)
+
+	return parts[0] + "." + base64Encode(payload) + "." + parts[2]
+}
+
+func unsigned(t *testing.T, claims map[string]interface{}) string {
+	t.Helper()
+	header, err := json.Marshal(map[string]string{"alg": "none", "kid": "rsa"})
+	require.NoError(t, err)
+
+	payload, err := json.Marshal(claims)
+	require.NoError(t, err)
+
+	return base64Encode(header) + "." + base64Encode(payload) + "."
+}
+
+func Test_KeySet_Rotation(t *testing.T) {
+	t.Parallel()
+
+	oldKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	newKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	var (
+		rotated int32
+		fetches int32
+	)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&fetches, 1)
+
+		if atomic.LoadInt32(&rotated) == 1 {
+			_, _ = w.Write(marshalJWKS(t, ecJWK("new", newKey)))
+
+			return
+		}
+
+		_, _ = w.Write(marshalJWKS(t, ecJWK("old", oldKey)))
+	}))
+	defer server.Close()
+
+	now := testNow
+	keys := NewKeySet(server.URL)
+	keys.now = func() time.Time { return now }
+	require.NoError(t, keys.Load(context.Background()))
+
+	verifier := NewJWTVerifier(testIssuer, testAudience, "groups", "tenant", keys)
+	verifier.now = func() time.Time { return testNow }
+
+	_, err = verifier.Verify(context.Background(), signToken(t, "ES256", "old", oldKey, validClaims()))
+	require.NoError(t, err)
+
+	atomic.StoreInt32(&rotated, 1)
+	newToken := signToken(t, "ES256", "new", newKey, validClaims())
+
+	// The key set was just loaded, it is not reloaded yet.
+	_, err = verifier.Verify(context.Background(), newToken)
+	assert.ErrorIs(t, err, ErrInvalidToken)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&fetches))
+
+	now = now.Add(jwksRefreshInterval)
+
+	_, err = verifier.Verify(context.Background(), newToken)
+	require.NoError(t, err)
+	assert.Equal(t, int32(2), atomic.LoadInt32(&fetches))
+}
+
+func Test_parseJWKS(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		jwks    string
+		kids    []string
+		wantErr bool
+	}{
+		{
+			name: "skips_encryption_and_symmetric_keys",
+			jwks: `{"keys":[{"kty":"EC","kid":"sig","crv":"P-256",` +
+				`"x":"MKBCTNIcKUSDii11ySs3526iDZ8AiTo7Tu6KPAqv7D4","y":"4Etl6SRW2YiLUrN5vfvVHuhp7x8PxltmWWlbbM4IFyM"},` +
+				`{"kty":"RSA","kid":"enc","use":"enc","n":"AQAB","e":"AQAB"},{"kty":"oct","kid":"hmac","k":"c2VjcmV0"}]}`,
+			kids: []string{"sig"},
+		},
+		{
+			name: "point_not_on_curve",
+			jwks: `{"keys":[{"kty":"EC","kid":"sig","crv":"P-256",` +
+				`"x":"MKBCTNIcKUSDii11ySs3526iDZ8AiTo7Tu6KPAqv7D4","y":"MKBCTNIcKUSDii11ySs3526iDZ8AiTo7Tu6KPAqv7D4"}]}`,
+			wantErr: true,
+		},
+		{
+			name:    "no_key",
+			jwks:    `{"keys":[]}`,
+			wantErr: true,
+		},
+		{
+			name:    "invalid_json",
+			jwks:    `{"keys":`,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			keys, err := parseJWKS([]byte(tt.jwks))
+			if tt.wantErr {
+				assert.Error(t, err)
+
+				return
+			}
+
+			require.NoError(t, err)
+
+			kids := make([]string, 0, len(keys))
+			for kid := range keys {
+				kids = append(kids, kid)
+			}
+
+			assert.ElementsMatch(t, tt.kids, kids)
+		})
+	}
+}
@@ -0,0 +1,47 @@
+package auth
+
+import (
+	"context"
+	"githum.com/anupam111/image-store/internal/db/dbmodels"
+)
+
+// Principal is the authenticated caller of a request, either an API key or
+// the user of a JWT bearer token.
+type Principal struct {
+	// Subject identifies the caller: the sub claim of a token, "apikey:<id>"
+	// for an API key.
+	Subject string
+	Groups  []string
+	Scopes  dbmodels.Scopes
+	// APIKeyID is the id of the API key which authenticated the request,
+	// empty for tokens.
+	APIKeyID string
+	// Tenant is the only tenant the principal may act on, empty when the
+	// principal has the admin scope and may name any tenant.
+	Tenant string
+}
+
+// APIKeyPrincipal returns the principal of an API key.
+func APIKeyPrincipal(apiKey dbmodels.APIKey) Principal {
+	return Principal{
+		Subject:  "apikey:" + apiKey.ID,
+		Scopes:   apiKey.Scopes,
+		APIKeyID: apiKey.ID,
+		Tenant:   apiKey.Tenant,
+	}
+}
+
+type principalKey struct{}
+
+// NewContext returns a context carrying the principal.
+func NewContext(ctx context.Context, principal Principal) context.Context {
+	return context.WithValue(ctx, principalKey{}, principal)
+}
+
+// FromContext returns the principal of the request of ctx, false when the
+// request is not authenticated, e.g. when authentication is disabled.
+func FromContext(ctx context.Context) (Principal, bool) {
+	principal, ok := ctx.Value(principalKey{}).(Principal)
+
+	return principal, ok
+}
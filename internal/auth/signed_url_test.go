@@ -0,0 +1,107 @@
+package auth
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func Test_URLSigner_Verify(t *testing.T) {
+	t.Parallel()
+
+	signer := NewURLSigner([]byte("secret"))
+	signer.now = func() time.Time { return testNow }
+
+	target := SignedTarget{Tenant: "acme", AlbumName: "holiday", ImageName: "beach.png"}
+	expires := testNow.Add(time.Hour)
+	signature := signer.Sign(target, expires)
+
+	tests := []struct {
+		name      string
+		signer    *URLSigner
+		target    SignedTarget
+		expires   int64
+		signature string
+		err       error
+	}{
+		{
+			name:      "valid",
+			signer:    signer,
+			target:    target,
+			expires:   expires.Unix(),
+			signature: signature,
+		},
+		{
+			name:      "other_image",
+			signer:    signer,
+			target:    SignedTarget{Tenant: "acme", AlbumName: "holiday", ImageName: "city.png"},
+			expires:   expires.Unix(),
+			signature: signature,
+			err:       ErrInvalidSignature,
+		},
+		{
+			name:      "ambiguous_names",
+			signer:    signer,
+			target:    SignedTarget{Tenant: "acme", AlbumName: "holidaybeach", ImageName: ".png"},
+			expires:   expires.Unix(),
+			signature: signature,
+			err:       ErrInvalidSignature,
+		},
+		{
+			name:      "added_transform",
+			signer:    signer,
+			target:    SignedTarget{Tenant: "acme", AlbumName: "holiday", ImageName: "beach.png", Transform: "w=100"},
+			expires:   expires.Unix(),
+			signature: signature,
+			err:       ErrInvalidSignature,
+		},
+		{
+			name:      "other_tenant",
+			signer:    signer,
+			target:    SignedTarget{Tenant: "other", AlbumName: "holiday", ImageName: "beach.png"},
+			expires:   expires.Unix(),
+			signature: signature,
+			err:       ErrInvalidSignature,
+		},
+		{
+			name:      "extended_expiry",
+			signer:    signer,
+			target:    target,
+			expires:   expires.Add(time.Hour).Unix(),
+			signature: signature,
+			err:       ErrInvalidSignature,
+		},
+		{
+			name:      "other_key",
+			signer:    NewURLSigner([]byte("other")),
+			target:    target,
+			expires:   expires.Unix(),
+			signature: signature,
+			err:       ErrInvalidSignature,
+		},
+		{
+			name:      "malformed_signature",
+			signer:    signer,
+			target:    target,
+			expires:   expires.Unix(),
+			signature: "not base64!",
+			err:       ErrInvalidSignature,
+		},
+		{
+			name:      "expired",
+			signer:    signer,
+			target:    target,
+			expires:   testNow.Unix(),
+			signature: signer.Sign(target, testNow),
+			err:       ErrURLExpired,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			assert.ErrorIs(t, tt.signer.Verify(tt.target, tt.expires, tt.signature), tt.err)
+		})
+	}
+}
@@ -4,11 +4,14 @@ import (
 	"fmt"
 	"github.com/jmoiron/sqlx"
 	"github.com/kelseyhightower/envconfig"
+	"strconv"
+	"strings"
+	"time"
 )
 
 // DBConnector ...
 type DBConnector interface {
-	Connect(driverName, dbConnection string) *sqlx.DB
+	Connect(driverName, dbConnection string) (*sqlx.DB, error)
 }
 
 // ImageStoreServiceConfig Configuration specific to Image store Service.
@@ -17,11 +20,188 @@ type ImageStoreServiceConfig struct {
 	ServiceConfig ServiceConfig
 }
 
-//ServiceConfig ...
+// ServiceConfig ...
 type ServiceConfig struct {
 	LogLevel     string `envconfig:"LOG_LEVEL"`
 	Port         int    `envconfig:"PORT" default:"27006"`
+	GRPCPort     int    `envconfig:"GRPC_PORT" default:"27007"`
 	GinAccessLog bool   `envconfig:"GIN_ACCESS_LOG" default:"true"`
+	// RequestTimeout is the deadline of every request without a RouteTimeouts entry, 0 disables it.
+	RequestTimeout time.Duration `envconfig:"REQUEST_TIMEOUT" default:"30s"`
+	RouteTimeouts  RouteTimeouts `envconfig:"ROUTE_TIMEOUTS"`
+	// AuthEnabled requires an API key, or a JWT of OIDCIssuer, with the scopes
+	// of the route on every request but /status.
+	AuthEnabled bool `envconfig:"AUTH_ENABLED" default:"true"`
+	// OIDCIssuer enables the JWT bearer tokens issued by this OIDC provider for
+	// OIDCAudience. They are verified with the keys of OIDCJWKS, the path or
+	// URL of a JWKS document. The groups of the caller are read from the
+	// OIDCGroupsClaim claim, the tenant it is bound to from the
+	// OIDCTenantClaim claim.
+	OIDCIssuer      string `envconfig:"OIDC_ISSUER"`
+	OIDCAudience    string `envconfig:"OIDC_AUDIENCE"`
+	OIDCJWKS        string `envconfig:"OIDC_JWKS"`
+	OIDCGroupsClaim string `envconfig:"OIDC_GROUPS_CLAIM" default:"groups"`
+	OIDCTenantClaim string `envconfig:"OIDC_TENANT_CLAIM" default:"tenant"`
+	// SignedURLKey is the HMAC key of the signed image content urls, empty
+	// disables them. They live up to SignedURLMaxTTL and are prefixed with
+	// SignedURLBase, the public base URL of the service, when it is set.
+	SignedURLKey    string        `envconfig:"SIGNED_URL_KEY"`
+	SignedURLMaxTTL time.Duration `envconfig:"SIGNED_URL_MAX_TTL" default:"168h"`
+	SignedURLBase   string        `envconfig:"SIGNED_URL_BASE"`
+	// RateLimit is the token bucket of each client, identified by its API key
+	// or principal, or else by its IP. The routes without a RouteRateLimits
+	// entry share it, the others have a bucket of their own. IPRateLimit is
+	// the bucket of each client IP, taken from before authentication so that
+	// failed attempts are limited too. RateLimitStore keeps the buckets in
+	// "memory", per replica, or in "postgres", shared by the replicas.
+	RateLimit       RateLimit       `envconfig:"RATE_LIMIT"`
+	RouteRateLimits RouteRateLimits `envconfig:"ROUTE_RATE_LIMITS"`
+	IPRateLimit     RateLimit       `envconfig:"IP_RATE_LIMIT"`
+	RateLimitStore  string          `envconfig:"RATE_LIMIT_STORE" default:"memory"`
+	// TrustedProxies are the addresses or CIDRs of the proxies whose
+	// X-Forwarded-For and X-Real-IP headers give the client IP. Without them
+	// the client IP is the peer address of the connection.
+	TrustedProxies []string `envconfig:"TRUSTED_PROXIES"`
+	// EncryptionKeyProvider encrypts the image content at rest with data keys
+	// wrapped by its master keys, read by "keyfile" from EncryptionKeyFile.
+	// Without provider the content is stored in plain. Every
+	// EncryptionRewrapInterval, 0 disabling it, the content stored in plain
+	// or under a retired master key is rewrapped with the current one.
+	EncryptionKeyProvider    string        `envconfig:"ENCRYPTION_KEY_PROVIDER"`
+	EncryptionKeyFile        string        `envconfig:"ENCRYPTION_KEY_FILE"`
+	EncryptionRewrapInterval time.Duration `envconfig:"ENCRYPTION_REWRAP_INTERVAL" default:"1h"`
+	Quotas
+}
+
+// Quotas limits the images of each tenant and of each album, and the bytes
+// of their base64 content, whether or not it is encrypted at rest. A zero
+// quota is unlimited.
+type Quotas struct {
+	TenantImages int64 `envconfig:"QUOTA_TENANT_IMAGES"`
+	TenantBytes  int64 `envconfig:"QUOTA_TENANT_BYTES"`
+	AlbumImages  int64 `envconfig:"QUOTA_ALBUM_IMAGES"`
+	AlbumBytes   int64 `envconfig:"QUOTA_ALBUM_BYTES"`
+}
+
+// RouteTimeouts maps a route, as "METHOD /full/path" with gin path parameters,
+// to its request deadline. It is decoded from a comma separated list such as
+// "GET /v1/album/:albumName/export.zip=10m,POST /v1/album/import=5m".
+type RouteTimeouts map[string]time.Duration
+
+// Decode implements envconfig.Decoder.
+func (r *RouteTimeouts) Decode(value string) error {
+	timeouts := RouteTimeouts{}
+	for _, entry := range strings.Split(value, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		idx := strings.LastIndex(entry, "=")
+		if idx < 0 {
+			return fmt.Errorf("route timeout %q is not of the form \"METHOD /path=duration\"", entry)
+		}
+
+		timeout, err := time.ParseDuration(entry[idx+1:])
+		if err != nil {
+			return fmt.Errorf("error while parsing timeout of route %q, %w", entry[:idx], err)
+		}
+
+		timeouts[strings.TrimSpace(entry[:idx])] = timeout
+	}
+
+	*r = timeouts
+
+	return nil
+}
+
+// RateLimit allows Requests requests per Period, in bursts of up to Requests.
+// It is decoded from "requests/period" such as "600/1m". The zero RateLimit
+// is unlimited.
+type RateLimit struct {
+	Requests int
+	Period   time.Duration
+}
+
+// Decode implements envconfig.Decoder.
+func (r *RateLimit) Decode(value string) error {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		*r = RateLimit{}
+
+		return nil
+	}
+
+	idx := strings.Index(value, "/")
+	if idx < 0 {
+		return fmt.Errorf("rate limit %q is not of the form \"requests/period\"", value)
+	}
+
+	requests, err := strconv.Atoi(value[:idx])
+	if err != nil || requests < 0 {
+		return fmt.Errorf("rate limit %q has an invalid number of requests", value)
+	}
+
+	period, err := time.ParseDuration(value[idx+1:])
+	if err != nil {
+		return fmt.Errorf("error while parsing period of rate limit %q, %w", value, err)
+	}
+
+	if period <= 0 {
+		return fmt.Errorf("rate limit %q has a period which is not positive", value)
+	}
+
+	*r = RateLimit{Requests: requests, Period: period}
+
+	return nil
+}
+
+// Unlimited is true for the zero RateLimit.
+func (r RateLimit) Unlimited() bool {
+	return r.Requests <= 0
+}
+
+// PerSecond is the rate at which the bucket refills.
+func (r RateLimit) PerSecond() float64 {
+	return float64(r.Requests) / r.Period.Seconds()
+}
+
+// String returns the RateLimit as it is decoded.
+func (r RateLimit) String() string {
+	return strconv.Itoa(r.Requests) + "/" + r.Period.String()
+}
+
+// RouteRateLimits maps a route to its rate limit. HTTP routes are written as
+// "METHOD /full/path" with gin path parameters, gRPC methods by their full
+// name. It is decoded from a comma separated list such as
+// "POST /v1/album/images=60/1m,/imagestore.v1.ImageStore/UploadImages=60/1m".
+type RouteRateLimits map[string]RateLimit
+
+// Decode implements envconfig.Decoder.
+func (r *RouteRateLimits) Decode(value string) error {
+	limits := RouteRateLimits{}
+	for _, entry := range strings.Split(value, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		idx := strings.LastIndex(entry, "=")
+		if idx < 0 {
+			return fmt.Errorf("route rate limit %q is not of the form \"ROUTE=requests/period\"", entry)
+		}
+
+		var limit RateLimit
+		if err := limit.Decode(entry[idx+1:]); err != nil {
+			return fmt.Errorf("error while parsing rate limit of route %q, %w", entry[:idx], err)
+		}
+
+		limits[strings.TrimSpace(entry[:idx])] = limit
+	}
+
+	*r = limits
+
+	return nil
 }
 
 // DBConfig represents database configurations.
@@ -33,6 +213,18 @@ type DBConfig struct {
 	Username   string `envconfig:"DB_USERNAME"`
 	DriverName string `envconfig:"DB_DRIVER" default:"postgres"`
 	Port       int    `envconfig:"DB_PORT" default:"5432"`
+	// ConnectRetries is the number of connection attempts at startup, waiting
+	// ConnectBackoff after the first failure and doubling up to ConnectMaxBackoff.
+	ConnectRetries    int           `envconfig:"DB_CONNECT_RETRIES" default:"5"`
+	ConnectBackoff    time.Duration `envconfig:"DB_CONNECT_BACKOFF" default:"1s"`
+	ConnectMaxBackoff time.Duration `envconfig:"DB_CONNECT_MAX_BACKOFF" default:"30s"`
+	// HealthCheckInterval is the period of the connectivity monitor, 0 disables it.
+	HealthCheckInterval time.Duration `envconfig:"DB_HEALTH_CHECK_INTERVAL" default:"10s"`
+	// ReplicaHosts are the read replicas of the database, as host or host:port.
+	// They share the credentials and name of the primary.
+	ReplicaHosts []string `envconfig:"DB_REPLICA_HOSTS"`
+	// AutoMigrate applies pending schema migrations on start.
+	AutoMigrate bool `envconfig:"DB_AUTO_MIGRATE" default:"false"`
 }
 
 // GeImageStoreConfig Provides image-store service related all configurations.
@@ -1,9 +1,276 @@
 package constants
 
+// The queries quote identifiers with double quotes and use ? bind vars, the
+// dialect of the database rewrites them before execution.
 const (
-	GetImagesQuery                        = `SELECT * FROM Image WHERE "albumName"=$1`
-	GetImageByIDQuery                     = `SELECT * FROM Image WHERE "imageName"=$1`
-	DeleteImagesOfAlbumQuery              = `DELETE FROM Image WHERE "albumName"=$1`
-	DeleteImageWithImageNameAndAlbumQuery = `DELETE FROM Image WHERE "imageName"=$1 AND "albumName"=$2`
-	DeleteAlbum                           = `DELETE FROM Album WHERE "albumName"=$1`
+	ListAlbumsQuery                       = `SELECT * FROM Album WHERE "tenant"=? ORDER BY "albumName"`
+	GetAlbumImageQuery                    = `SELECT * FROM Image WHERE "tenant"=? AND "imageName"=? AND "albumName"=?`
+	UpdateImageQuery                      = `UPDATE Image SET "imageName"=?, "image"=?, "metadata"=?, "keyId"=? WHERE "tenant"=? AND "imageName"=? AND "albumName"=?`
+	GetAlbumQuery                         = `SELECT * FROM Album WHERE "tenant"=? AND "albumName"=?`
+	GetImagesQuery                        = `SELECT * FROM Image WHERE "tenant"=? AND "albumName"=?`
+	GetImagesOfAlbumsQuery                = `SELECT * FROM Image WHERE "tenant"=? AND "albumName" IN (?) ORDER BY "albumName", "imageName"`
+	GetImageByIDQuery                     = `SELECT * FROM Image WHERE "tenant"=? AND "imageName"=?`
+	DeleteImagesOfAlbumQuery              = `DELETE FROM Image WHERE "tenant"=? AND "albumName"=?`
+	DeleteImageWithImageNameAndAlbumQuery = `DELETE FROM Image WHERE "tenant"=? AND "imageName"=? AND "albumName"=?`
+	DeleteAlbum                           = `DELETE FROM Album WHERE "tenant"=? AND "albumName"=?`
+	InsertImageQuery                      = `INSERT INTO Image(
+			"tenant",
+			"imageName",
+			"albumName",
+			"image",
+			"metadata",
+			"keyId"
+		) VALUES(
+			:tenant,
+			:imageName,
+			:albumName,
+			:image,
+			:metadata,
+			:keyId
+		)`
+	InsertAlbumQuery = `INSERT INTO Album(
+			"tenant",
+			"albumName"
+		) VALUES(
+			:tenant,
+			:albumName
+		)`
+)
+
+// The API key queries.
+const (
+	ListAPIKeysQuery     = `SELECT * FROM ApiKey ORDER BY "createdAt", "id"`
+	GetAPIKeyByHashQuery = `SELECT * FROM ApiKey WHERE "hash"=?`
+	RevokeAPIKeyQuery    = `UPDATE ApiKey SET "revokedAt"=? WHERE "id"=? AND "revokedAt" IS NULL`
+	InsertAPIKeyQuery    = `INSERT INTO ApiKey(
+			"id",
+			"name",
+			"tenant",
+			"hash",
+			"scopes",
+			"createdAt"
+		) VALUES(
+			:id,
+			:name,
+			:tenant,
+			:hash,
+			:scopes,
+			:createdAt
+		)`
+)
+
+// The album access queries.
+const (
+	ListAlbumAccessQuery = `SELECT * FROM AlbumAccess WHERE "tenant"=? AND "albumName"=? ORDER BY "principalType", "principal"`
+	// ListPrincipalAccessQuery selects the accesses of a user, the
+	// PrincipalGroupsCondition of the groups of the user is appended with fmt.
+	ListPrincipalAccessQuery = `SELECT * FROM AlbumAccess
+		WHERE "tenant"=? AND (("principalType"='user' AND "principal"=?)%s)
+		ORDER BY "albumName", "principalType", "principal"`
+	PrincipalGroupsCondition = ` OR ("principalType"='group' AND "principal" IN (?))`
+	DeleteAlbumAccessQuery   = `DELETE FROM AlbumAccess WHERE "tenant"=? AND "albumName"=?`
+	RevokeAlbumAccessQuery   = `DELETE FROM AlbumAccess WHERE "tenant"=? AND "albumName"=? AND "principalType"=? AND "principal"=?`
+	InsertAlbumAccessQuery   = `INSERT INTO AlbumAccess(
+			"tenant",
+			"albumName",
+			"principalType",
+			"principal",
+			"role"
+		) VALUES(
+			:tenant,
+			:albumName,
+			:principalType,
+			:principal,
+			:role
+		)`
+)
+
+// The album share queries.
+const (
+	ListAlbumSharesQuery     = `SELECT * FROM AlbumShare WHERE "tenant"=? AND "albumName"=? ORDER BY "createdAt", "id"`
+	GetAlbumShareByHashQuery = `SELECT * FROM AlbumShare WHERE "hash"=?`
+	RevokeAlbumShareQuery    = `UPDATE AlbumShare SET "revokedAt"=? WHERE "tenant"=? AND "albumName"=? AND "id"=? AND "revokedAt" IS NULL`
+	DeleteAlbumSharesQuery   = `DELETE FROM AlbumShare WHERE "tenant"=? AND "albumName"=?`
+	InsertAlbumShareQuery    = `INSERT INTO AlbumShare(
+			"id",
+			"tenant",
+			"albumName",
+			"hash",
+			"passwordHash",
+			"allowDownload",
+			"createdBy",
+			"createdAt",
+			"expiresAt"
+		) VALUES(
+			:id,
+			:tenant,
+			:albumName,
+			:hash,
+			:passwordHash,
+			:allowDownload,
+			:createdBy,
+			:createdAt,
+			:expiresAt
+		)`
+)
+
+// The album usage queries. Every album has a usage row, which the image writes
+// update in their transaction.
+const (
+	InsertAlbumUsageQuery = `INSERT INTO AlbumUsage("tenant", "albumName", "imageCount", "byteCount") VALUES(?, ?, 0, 0)`
+	AddAlbumUsageQuery    = `UPDATE AlbumUsage SET "imageCount"="imageCount"+?, "byteCount"="byteCount"+?
+		WHERE "tenant"=? AND "albumName"=?`
+	ResetAlbumUsageQuery  = `UPDATE AlbumUsage SET "imageCount"=0, "byteCount"=0 WHERE "tenant"=? AND "albumName"=?`
+	DeleteAlbumUsageQuery = `DELETE FROM AlbumUsage WHERE "tenant"=? AND "albumName"=?`
+	GetAlbumUsageQuery    = `SELECT * FROM AlbumUsage WHERE "tenant"=? AND "albumName"=?`
+	ListAlbumUsageQuery   = `SELECT * FROM AlbumUsage WHERE "tenant"=? ORDER BY "albumName"`
+	GetTenantUsageQuery   = `SELECT COALESCE(SUM("imageCount"), 0) AS "imageCount", COALESCE(SUM("byteCount"), 0) AS "byteCount"
+		FROM AlbumUsage WHERE "tenant"=?`
+	// GetImageSizeQuery selects the length of the stored content of an image,
+	// which is its size as dbmodels.Image.Size counts it unless it is sealed,
+	// and the key id of the content.
+	GetImageSizeQuery = `SELECT COALESCE(LENGTH("image"), 0) AS "size", "keyId"
+		FROM Image WHERE "tenant"=? AND "imageName"=? AND "albumName"=?`
+)
+
+// The encryption queries, which act on the images of every tenant.
+const (
+	// ListImagesToRewrapQuery selects a page of the images whose content is
+	// not sealed with the current master key, after the tenant and image name
+	// given. The key id is compared as two ranges, which the index of the
+	// key id serves. Image names are unique per tenant.
+	ListImagesToRewrapQuery = `SELECT "tenant", "imageName", "albumName" FROM Image
+		WHERE ("keyId" < ? OR "keyId" > ?) AND ("tenant" > ? OR ("tenant" = ? AND "imageName" > ?))
+		ORDER BY "tenant", "imageName"
+		LIMIT ?`
+	// RewrapImageQuery replaces the content of an image and its key id, unless
+	// the content changed since it was read.
+	RewrapImageQuery = `UPDATE Image SET "image"=?, "keyId"=? WHERE "tenant"=? AND "imageName"=? AND "albumName"=? AND "image"=?`
+)
+
+// The audit queries. The conditions of an audit filter are appended with fmt.
+// The audit log is append-only, there is no query updating or deleting its
+// events.
+const (
+	ListAuditEventsQuery = `SELECT AuditEvent.*, COUNT(*) OVER() AS "total" FROM AuditEvent
+		WHERE "tenant"=?%s ORDER BY "id" DESC LIMIT ? OFFSET ?`
+	StreamAuditEventsQuery = `SELECT * FROM AuditEvent WHERE "tenant"=?%s ORDER BY "id"`
+	InsertAuditEventQuery  = `INSERT INTO AuditEvent(
+			"tenant",
+			"occurredAt",
+			"actor",
+			"action",
+			"albumName",
+			"target",
+			"details",
+			"requestId",
+			"clientIp",
+			"outcome",
+			"error"
+		) VALUES(
+			:tenant,
+			:occurredAt,
+			:actor,
+			:action,
+			:albumName,
+			:target,
+			:details,
+			:requestId,
+			:clientIp,
+			:outcome,
+			:error
+		)`
+)
+
+// SearchAlbumsCondition restricts a search to some albums.
+const SearchAlbumsCondition = ` AND "albumName" IN (?)`
+
+// FilterImagesQuery selects the images of an album, the metadata conditions
+// of the filters are appended with fmt.
+const FilterImagesQuery = `SELECT * FROM Image WHERE "tenant"=? AND "albumName"=?%s ORDER BY "imageName"`
+
+// The search queries take the search text for the bind vars of the rank, the
+// tenant, the search text for each of their other text bind vars, then the
+// limit and the offset. The album condition of a restricted search is
+// appended with fmt, after the metadata condition of SearchImagesLikeQuery.
+const (
+	// SearchImagesQuery matches the words of the image and album names, and of
+	// the string values of the metadata, on Postgres. The document expression
+	// is the one of the image_search_idx index, so that the index is used.
+	SearchImagesQuery = `SELECT "imageName", "albumName", "metadata",
+			ts_rank(to_tsvector('simple', regexp_replace("imageName" || ' ' || "albumName", '[^[:alnum:]]+', ' ', 'g'))
+				|| jsonb_to_tsvector('simple', COALESCE("metadata", '{}'::jsonb), '["string"]'),
+				plainto_tsquery('simple', ?)) AS "rank",
+			COUNT(*) OVER() AS "total"
+		FROM Image
+		WHERE "tenant"=? AND (to_tsvector('simple', regexp_replace("imageName" || ' ' || "albumName", '[^[:alnum:]]+', ' ', 'g'))
+				|| jsonb_to_tsvector('simple', COALESCE("metadata", '{}'::jsonb), '["string"]'))
+			@@ plainto_tsquery('simple', ?)%s
+		ORDER BY "rank" DESC, "albumName", "imageName"
+		LIMIT ? OFFSET ?`
+	// SearchImagesLikeQuery matches the search text anywhere in the image and
+	// album names, and in the metadata with the condition appended with fmt, on
+	// databases without full-text search. Image name matches rank above album
+	// name matches, which rank above metadata matches. The search text is lower
+	// case with the LIKE wildcards escaped by !.
+	SearchImagesLikeQuery = `SELECT "imageName", "albumName", "metadata",
+			CASE WHEN LOWER("imageName") LIKE ? ESCAPE '!' THEN 3
+				WHEN LOWER("albumName") LIKE ? ESCAPE '!' THEN 2 ELSE 1 END AS "rank",
+			COUNT(*) OVER() AS "total"
+		FROM Image
+		WHERE "tenant"=? AND (LOWER("imageName") LIKE ? ESCAPE '!' OR LOWER("albumName") LIKE ? ESCAPE '!' OR %s)%s
+		ORDER BY "rank" DESC, "albumName", "imageName"
+		LIMIT ? OFFSET ?`
+	// SearchMetadataJSONCondition matches the search text in the string values
+	// of the metadata on MySQL and MariaDB.
+	SearchMetadataJSONCondition = `JSON_SEARCH(LOWER("metadata"), 'one', ?, '!') IS NOT NULL`
+	// SearchMetadataTextCondition matches the search text in the JSON text of
+	// the metadata, keys included, on databases which can not query JSON. The
+	// matches are checked against the string values afterwards.
+	SearchMetadataTextCondition = `LOWER("metadata") LIKE ? ESCAPE '!'`
+)
+
+// The rate limit queries only run on Postgres.
+const (
+	// TakeRateLimitTokenQuery refills the bucket of $1, up to $2 tokens at $3
+	// tokens per second, and takes a token from it when it holds one. A new
+	// bucket starts full. It returns the tokens left and whether one was
+	// taken.
+	TakeRateLimitTokenQuery = `INSERT INTO RateLimitBucket AS b ("bucketKey", "tokens", "allowed", "updatedAt")
+		VALUES ($1, CAST($2 AS DOUBLE PRECISION) - 1, TRUE, now())
+		ON CONFLICT ("bucketKey") DO UPDATE SET
+			"tokens" = LEAST($2, b."tokens" + EXTRACT(EPOCH FROM now() - b."updatedAt") * CAST($3 AS DOUBLE PRECISION))
+				- CASE WHEN LEAST($2, b."tokens" + EXTRACT(EPOCH FROM now() - b."updatedAt") * $3) >= 1 THEN 1 ELSE 0 END,
+			"allowed" = LEAST($2, b."tokens" + EXTRACT(EPOCH FROM now() - b."updatedAt") * $3) >= 1,
+			"updatedAt" = now()
+		RETURNING "tokens", "allowed"`
+	// SweepRateLimitBucketsQuery deletes the buckets left alone for longer
+	// than $1 seconds.
+	SweepRateLimitBucketsQuery = `DELETE FROM RateLimitBucket WHERE "updatedAt" < now() - make_interval(secs => $1)`
+)
+
+const (
+	// MaxBatchSize is the maximum number of items accepted by a single batch request.
+	MaxBatchSize = 500
+	// MaxImageSize is the maximum raw size of a single image received through the
+	// bulk upload paths, ZIP import and gRPC upload.
+	MaxImageSize = 32 << 20
+	// DefaultSearchLimit is the page size of a search without limit.
+	DefaultSearchLimit = 20
+	// MaxSearchLimit is the largest page size of a search.
+	MaxSearchLimit = 100
+	// DefaultAuditLimit is the page size of the audit log without limit.
+	DefaultAuditLimit = 50
+	// MaxAuditLimit is the largest page size of the audit log, larger reads
+	// go through its export.
+	MaxAuditLimit = 500
+	// RewrapBatchSize is the number of images read at a time by the rewrap
+	// of the image content after a master key rotation.
+	RewrapBatchSize = 100
+	// MaxTxRetries is the number of times a transaction is retried after a
+	// serialization failure or deadlock.
+	MaxTxRetries = 3
+	// DefaultSignedURLTTL is the lifetime, in seconds, of a signed url issued
+	// without expiresIn.
+	DefaultSignedURLTTL = 15 * 60
 )
@@ -0,0 +1,248 @@
+package controller
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"githum.com/anupam111/image-store/internal/auth"
+	"githum.com/anupam111/image-store/internal/db/dbhandler"
+	"githum.com/anupam111/image-store/internal/db/dbmodels"
+)
+
+var (
+	// ErrAccessDenied is returned when the caller lacks the role an operation
+	// requires on an album.
+	ErrAccessDenied = errors.New("album access denied")
+	// ErrInvalidAccess is returned when granting an unknown role, or a role to
+	// an unknown type of principal.
+	ErrInvalidAccess = errors.New("invalid album access")
+	// ErrLastOwner is returned when revoking or downgrading the last owner of
+	// an album.
+	ErrLastOwner = errors.New("album must keep an owner")
+)
+
+// albumAccess checks the roles of the caller of a request on albums, caching
+// the role on each album for the duration of the operation. Inside WithTx it
+// is created in the transaction function, so that a retried transaction reads
+// the roles again instead of trusting the ones of the aborted attempt. Callers
+// with the admin scope, and requests without principal because authentication
+// is disabled, have every role on every album.
+type albumAccess struct {
+	principal    auth.Principal
+	unrestricted bool
+	ranks        map[string]int
+}
+
+func newAlbumAccess(ctx context.Context) *albumAccess {
+	principal, ok := auth.FromContext(ctx)
+
+	return &albumAccess{
+		principal:    principal,
+		unrestricted: !ok || principal.Scopes.Allows(dbmodels.ScopeAdmin),
+		ranks:        map[string]int{},
+	}
+}
+
+// require returns ErrAccessDenied unless the caller has at least role on the
+// album.
+func (a *albumAccess) require(ctx context.Context, store dbhandler.ImageStore, albumName, role string) error {
+	if a.unrestricted {
+		return nil
+	}
+
+	rank, ok := a.ranks[albumName]
+	if !ok {
+		accesses, err := store.ListAlbumAccess(ctx, albumName)
+		if err != nil {
+			return err
+		}
+
+		for _, access := range accesses {
+			if a.grants(access) && dbmodels.RoleRank(access.Role) > rank {
+				rank = dbmodels.RoleRank(access.Role)
+			}
+		}
+
+		a.ranks[albumName] = rank
+	}
+
+	if rank < dbmodels.RoleRank(role) {
+		return fmt.Errorf("%w: %s role required on album %s", ErrAccessDenied, role, albumName)
+	}
+
+	return nil
+}
+
+// grants reports whether the access is granted to the caller.
+func (a *albumAccess) grants(access dbmodels.AlbumAccess) bool {
+	switch access.PrincipalType {
+	case dbmodels.PrincipalUser:
+		return access.Principal == a.principal.Subject
+	case dbmodels.PrincipalGroup:
+		for _, group := range a.principal.Groups {
+			if group == access.Principal {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// visibleAlbums returns the set of the albums the caller can view, nil when
+// the caller can view every album.
+func (a *albumAccess) visibleAlbums(ctx context.Context, store dbhandler.ImageStore) (map[string]bool, error) {
+	if a.unrestricted {
+		return nil, nil
+	}
+
+	accesses, err := store.ListPrincipalAccess(ctx, a.principal.Subject, a.principal.Groups)
+	if err != nil {
+		return nil, err
+	}
+
+	visible := make(map[string]bool, len(accesses))
+	for _, access := range accesses {
+		if dbmodels.RoleRank(access.Role) > 0 {
+			visible[access.AlbumName] = true
+		}
+	}
+
+	return visible, nil
+}
+
+// ListAlbumAccess returns the accesses to the album, which only its owners
+// can see.
+func (i *ImageController) ListAlbumAccess(ctx context.Context, albumName string) ([]dbmodels.AlbumAccess, error) {
+	if err := newAlbumAccess(ctx).require(ctx, i.imageStore, albumName, dbmodels.RoleOwner); err != nil {
+		return nil, fmt.Errorf("error while listing album access, %w", err)
+	}
+
+	if _, err := i.imageStore.GetAlbum(ctx, albumName); err != nil {
+		return nil, fmt.Errorf("error while listing album access, %w", err)
+	}
+
+	accesses, err := i.imageStore.ListAlbumAccess(ctx, albumName)
+	if err != nil {
+		return nil, fmt.Errorf("error while listing album access, %w", err)
+	}
+
+	return accesses, nil
+}
+
+// GrantAlbumAccess grants the role to the principal of the access, replacing
+// the role the principal had. Only the owners of the album can grant access,
+// and the album keeps at least one owner.
+func (i *ImageController) GrantAlbumAccess(ctx context.Context, access dbmodels.AlbumAccess) error {
+	if err := validAlbumAccess(access); err != nil {
+		return err
+	}
+
+	event := accessEvent(dbmodels.AuditAccessGrant, access)
+	event.Details = "role=" + access.Role
+
+	err := i.withAudit(ctx, event, func(tx dbhandler.ImageStore) error {
+		accesses, err := ownedAlbumAccess(ctx, tx, newAlbumAccess(ctx), access.AlbumName)
+		if err != nil {
+			return err
+		}
+
+		if access.Role != dbmodels.RoleOwner && removesLastOwner(accesses, access) {
+			return ErrLastOwner
+		}
+
+		return tx.GrantAlbumAccess(ctx, access)
+	})
+	if err != nil {
+		return fmt.Errorf("error while granting album access, %w", err)
+	}
+
+	return nil
+}
+
+// RevokeAlbumAccess removes the role of the principal on the album. Only the
+// owners of the album can revoke access, and the album keeps at least one
+// owner.
+func (i *ImageController) RevokeAlbumAccess(ctx context.Context, albumName, principalType, principal string) error {
+	revoked := dbmodels.AlbumAccess{AlbumName: albumName, PrincipalType: principalType, Principal: principal}
+
+	err := i.withAudit(ctx, accessEvent(dbmodels.AuditAccessRevoke, revoked), func(tx dbhandler.ImageStore) error {
+		accesses, err := ownedAlbumAccess(ctx, tx, newAlbumAccess(ctx), albumName)
+		if err != nil {
+			return err
+		}
+
+		if removesLastOwner(accesses, revoked) {
+			return ErrLastOwner
+		}
+
+		return tx.RevokeAlbumAccess(ctx, albumName, principalType, principal)
+	})
+	if err != nil {
+		return fmt.Errorf("error while revoking album access, %w", err)
+	}
+
+	return nil
+}
+
+// accessEvent is the event of a change of the access of its principal.
+func accessEvent(action string, access dbmodels.AlbumAccess) dbmodels.AuditEvent {
+	return dbmodels.AuditEvent{
+		Action:    action,
+		AlbumName: access.AlbumName,
+		Target:    access.PrincipalType + ":" + access.Principal,
+	}
+}
+
+// ownedAlbumAccess returns the accesses of the album after checking that the
+// caller owns it.
+func ownedAlbumAccess(ctx context.Context, tx dbhandler.ImageStore, albumAccess *albumAccess,
+	albumName string) ([]dbmodels.AlbumAccess, error) {
+	if err := albumAccess.require(ctx, tx, albumName, dbmodels.RoleOwner); err != nil {
+		return nil, err
+	}
+
+	if _, err := tx.GetAlbum(ctx, albumName); err != nil {
+		return nil, err
+	}
+
+	return tx.ListAlbumAccess(ctx, albumName)
+}
+
+// removesLastOwner reports whether the principal of access is the only owner
+// in accesses.
+func removesLastOwner(accesses []dbmodels.AlbumAccess, access dbmodels.AlbumAccess) bool {
+	owners, isOwner := 0, false
+
+	for _, existing := range accesses {
+		if existing.Role != dbmodels.RoleOwner {
+			continue
+		}
+
+		owners++
+
+		if existing.PrincipalType == access.PrincipalType && existing.Principal == access.Principal {
+			isOwner = true
+		}
+	}
+
+	return isOwner && owners == 1
+}
+
+func validAlbumAccess(access dbmodels.AlbumAccess) error {
+	if access.PrincipalType != dbmodels.PrincipalUser && access.PrincipalType != dbmodels.PrincipalGroup {
+		return fmt.Errorf("%w: principal type must be %s or %s", ErrInvalidAccess,
+			dbmodels.PrincipalUser, dbmodels.PrincipalGroup)
+	}
+
+	if access.Principal == "" {
+		return fmt.Errorf("%w: principal is empty", ErrInvalidAccess)
+	}
+
+	if dbmodels.RoleRank(access.Role) == 0 {
+		return fmt.Errorf("%w: role must be %s, %s or %s", ErrInvalidAccess,
+			dbmodels.RoleViewer, dbmodels.RoleEditor, dbmodels.RoleOwner)
+	}
+
+	return nil
+}
@@ -0,0 +1,162 @@
+package controller
+
+import (
+	"context"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"githum.com/anupam111/image-store/internal/auth"
+	"githum.com/anupam111/image-store/internal/config"
+	"githum.com/anupam111/image-store/internal/db/dbhandler"
+	"githum.com/anupam111/image-store/internal/db/dbmodels"
+	"githum.com/anupam111/image-store/internal/db/memstore"
+	"githum.com/anupam111/image-store/internal/tenant"
+	"testing"
+)
+
+func principalContext(subject string, groups []string, scopes ...string) context.Context {
+	return auth.NewContext(context.Background(), auth.Principal{
+		Subject: subject,
+		Groups:  groups,
+		Scopes:  scopes,
+	})
+}
+
+func albumNames(albums []dbmodels.Album) []string {
+	names := make([]string, len(albums))
+	for idx, album := range albums {
+		names[idx] = album.AlbumName
+	}
+
+	return names
+}
+
+func TestAlbumAccess(t *testing.T) {
+	t.Parallel()
+
+	store := memstore.NewMemStore()
+	controller := NewImageController(logrus.New(), store, store, config.Quotas{})
+
+	var (
+		alice  = principalContext("alice", nil)
+		bob    = principalContext("bob", []string{"editors"})
+		carol  = principalContext("carol", []string{"viewers"})
+		admin  = principalContext("apikey:1", nil, dbmodels.ScopeAdmin)
+		anyone = context.Background()
+	)
+
+	require.NoError(t, controller.CreateImageAlbum(alice, dbmodels.Album{AlbumName: "holiday"}))
+	require.NoError(t, controller.CreateImageAlbum(bob, dbmodels.Album{AlbumName: "work"}))
+	require.NoError(t, controller.CreateImage(alice, dbmodels.Image{ImageName: "beach.png", AlbumName: "holiday"}))
+
+	accesses, err := controller.ListAlbumAccess(alice, "holiday")
+	require.NoError(t, err)
+	assert.Equal(t, []dbmodels.AlbumAccess{
+		{Tenant: tenant.Default, AlbumName: "holiday", PrincipalType: dbmodels.PrincipalUser, Principal: "alice", Role: dbmodels.RoleOwner},
+	}, accesses, "the creator owns the album")
+
+	albums, err := controller.ListImageAlbums(bob)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"work"}, albumNames(albums))
+
+	_, err = controller.GetAlbumImage(bob, "holiday", "beach.png")
+	assert.ErrorIs(t, err, ErrAccessDenied)
+	_, err = controller.GetImage(bob, "beach.png")
+	assert.ErrorIs(t, err, ErrAccessDenied)
+	assert.ErrorIs(t, controller.DeleteImageAlbum(bob, "holiday"), ErrAccessDenied)
+
+	require.NoError(t, controller.GrantAlbumAccess(alice, dbmodels.AlbumAccess{
+		AlbumName: "holiday", PrincipalType: dbmodels.PrincipalGroup, Principal: "editors", Role: dbmodels.RoleEditor,
+	}))
+	require.NoError(t, controller.GrantAlbumAccess(alice, dbmodels.AlbumAccess{
+		AlbumName: "holiday", PrincipalType: dbmodels.PrincipalGroup, Principal: "viewers", Role: dbmodels.RoleViewer,
+	}))
+
+	// Editors change the images, but not the album nor its access.
+	assert.NoError(t, controller.CreateImage(bob, dbmodels.Image{ImageName: "sunset.png", AlbumName: "holiday"}))
+	assert.ErrorIs(t, controller.DeleteImageAlbum(bob, "holiday"), ErrAccessDenied)
+	assert.ErrorIs(t, controller.GrantAlbumAccess(bob, dbmodels.AlbumAccess{
+		AlbumName: "holiday", PrincipalType: dbmodels.PrincipalUser, Principal: "bob", Role: dbmodels.RoleOwner,
+	}), ErrAccessDenied)
+
+	// Viewers read.
+	images, err := controller.GetAllImages(carol, "holiday")
+	assert.NoError(t, err)
+	assert.Len(t, images, 2)
+	assert.ErrorIs(t, controller.DeleteImage(carol, "beach.png", "holiday"), ErrAccessDenied)
+
+	matches, total, err := controller.SearchImages(carol, "png", 10, 0)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, total)
+	assert.Len(t, matches, 2)
+
+	byAlbum, err := controller.GetImagesOfAlbums(carol, []string{"holiday", "work"})
+	assert.NoError(t, err)
+	assert.Len(t, byAlbum["holiday"], 2)
+	assert.NotContains(t, byAlbum, "work")
+
+	// An all-or-nothing batch fails on the first album without access.
+	errs := controller.CreateImages(bob, []dbmodels.Image{
+		{ImageName: "a.png", AlbumName: "work"},
+		{ImageName: "b.png", AlbumName: "holiday"},
+		{ImageName: "c.png", AlbumName: "private"},
+	}, true)
+	assert.ErrorIs(t, errs[0], ErrBatchAborted)
+	assert.ErrorIs(t, errs[1], ErrBatchAborted)
+	assert.ErrorIs(t, errs[2], ErrAccessDenied)
+
+	// Admins and unauthenticated requests see everything.
+	for _, ctx := range []context.Context{admin, anyone} {
+		albums, err = controller.ListImageAlbums(ctx)
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"holiday", "work"}, albumNames(albums))
+	}
+
+	require.NoError(t, controller.DeleteImageAlbum(alice, "holiday"))
+
+	albums, err = controller.ListImageAlbums(carol)
+	assert.NoError(t, err)
+	assert.Empty(t, albums)
+
+	matches, total, err = controller.SearchImages(carol, "png", 10, 0)
+	assert.NoError(t, err)
+	assert.Zero(t, total)
+	assert.Empty(t, matches)
+}
+
+func TestGrantAlbumAccess(t *testing.T) {
+	t.Parallel()
+
+	store := memstore.NewMemStore()
+	controller := NewImageController(logrus.New(), store, store, config.Quotas{})
+	alice := principalContext("alice", nil)
+	admin := principalContext("apikey:1", nil, dbmodels.ScopeAdmin)
+
+	require.NoError(t, controller.CreateImageAlbum(alice, dbmodels.Album{AlbumName: "holiday"}))
+
+	grant := func(ctx context.Context, principalType, principal, role string) error {
+		return controller.GrantAlbumAccess(ctx, dbmodels.AlbumAccess{
+			AlbumName: "holiday", PrincipalType: principalType, Principal: principal, Role: role,
+		})
+	}
+
+	assert.ErrorIs(t, grant(alice, "team", "bob", dbmodels.RoleViewer), ErrInvalidAccess)
+	assert.ErrorIs(t, grant(alice, dbmodels.PrincipalUser, "", dbmodels.RoleViewer), ErrInvalidAccess)
+	assert.ErrorIs(t, grant(alice, dbmodels.PrincipalUser, "bob", "admin"), ErrInvalidAccess)
+
+	assert.ErrorIs(t, grant(alice, dbmodels.PrincipalUser, "alice", dbmodels.RoleEditor), ErrLastOwner)
+	assert.ErrorIs(t, controller.RevokeAlbumAccess(alice, "holiday", dbmodels.PrincipalUser, "alice"), ErrLastOwner)
+
+	require.NoError(t, grant(alice, dbmodels.PrincipalGroup, "admins", dbmodels.RoleOwner))
+	assert.NoError(t, controller.RevokeAlbumAccess(alice, "holiday", dbmodels.PrincipalUser, "alice"))
+	assert.ErrorIs(t, controller.RevokeAlbumAccess(admin, "holiday", dbmodels.PrincipalUser, "alice"),
+		dbhandler.ErrNoDataFound)
+	assert.ErrorIs(t, grant(alice, dbmodels.PrincipalUser, "alice", dbmodels.RoleOwner), ErrAccessDenied,
+		"alice is no owner anymore")
+
+	_, err := controller.ListAlbumAccess(admin, "missing")
+	assert.ErrorIs(t, err, dbhandler.ErrNoDataFound)
+	assert.ErrorIs(t, controller.GrantAlbumAccess(admin, dbmodels.AlbumAccess{
+		AlbumName: "missing", PrincipalType: dbmodels.PrincipalUser, Principal: "bob", Role: dbmodels.RoleViewer,
+	}), dbhandler.ErrNoDataFound)
+}
@@ -0,0 +1,151 @@
+package controller
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"githum.com/anupam111/image-store/internal/auth"
+	"githum.com/anupam111/image-store/internal/db/dbhandler"
+	"githum.com/anupam111/image-store/internal/db/dbmodels"
+	"golang.org/x/crypto/bcrypt"
+	"time"
+)
+
+// shareTokenPrefix starts every share token, so that leaked links are easy to
+// spot.
+const shareTokenPrefix = "shr_"
+
+var (
+	// ErrInvalidShare is returned when creating a share which has already
+	// expired.
+	ErrInvalidShare = errors.New("invalid album share")
+	// ErrShareNotFound is returned for an unknown, revoked or expired share
+	// token.
+	ErrShareNotFound = errors.New("album share not found")
+	// ErrSharePassword is returned when opening a share without its password.
+	ErrSharePassword = errors.New("album share password required")
+)
+
+// CreateAlbumShare creates a share of the album with the expiry and download
+// permission of share, protected by password when it is not empty. It
+// returns the token of the share, which can not be recovered afterwards,
+// together with its stored form. Only the owners of the album can share it.
+func (i *ImageController) CreateAlbumShare(ctx context.Context, share dbmodels.AlbumShare, password string) (string, dbmodels.AlbumShare, error) {
+	now := i.now().UTC().Truncate(time.Microsecond)
+	if share.ExpiresAt != nil && !share.ExpiresAt.After(now) {
+		return "", dbmodels.AlbumShare{}, fmt.Errorf("%w: expiry is in the past", ErrInvalidShare)
+	}
+
+	id, err := randomToken(8, hex.EncodeToString)
+	if err != nil {
+		return "", dbmodels.AlbumShare{}, err
+	}
+
+	secret, err := randomToken(32, base64.RawURLEncoding.EncodeToString)
+	if err != nil {
+		return "", dbmodels.AlbumShare{}, err
+	}
+
+	token := shareTokenPrefix + secret
+	share.ID = id
+	share.Hash = hashToken(token)
+	share.CreatedAt = now
+	share.RevokedAt = nil
+
+	if share.ExpiresAt != nil {
+		expiresAt := share.ExpiresAt.UTC().Truncate(time.Microsecond)
+		share.ExpiresAt = &expiresAt
+	}
+
+	if principal, ok := auth.FromContext(ctx); ok {
+		share.CreatedBy = principal.Subject
+	}
+
+	if password != "" {
+		hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+		if err != nil {
+			return "", dbmodels.AlbumShare{}, fmt.Errorf("error while hashing album share password, %w", err)
+		}
+
+		share.PasswordHash = string(hash)
+	}
+
+	event := dbmodels.AuditEvent{Action: dbmodels.AuditShareCreate, AlbumName: share.AlbumName, Target: share.ID}
+	event.Details = fmt.Sprintf("allowDownload=%t password=%t", share.AllowDownload, share.PasswordHash != "")
+	if share.ExpiresAt != nil {
+		event.Details += " expiresAt=" + share.ExpiresAt.Format(time.RFC3339)
+	}
+
+	err = i.withAudit(ctx, event, func(tx dbhandler.ImageStore) error {
+		if _, err := ownedAlbumAccess(ctx, tx, newAlbumAccess(ctx), share.AlbumName); err != nil {
+			return err
+		}
+
+		return tx.CreateAlbumShare(ctx, share)
+	})
+	if err != nil {
+		return "", dbmodels.AlbumShare{}, fmt.Errorf("error while creating album share, %w", err)
+	}
+
+	i.log.Infof("album %s shared as %s by %q", share.AlbumName, share.ID, share.CreatedBy)
+
+	return token, share, nil
+}
+
+// ListAlbumShares returns the shares of the album, which only its owners can
+// see.
+func (i *ImageController) ListAlbumShares(ctx context.Context, albumName string) ([]dbmodels.AlbumShare, error) {
+	if _, err := ownedAlbumAccess(ctx, i.imageStore, newAlbumAccess(ctx), albumName); err != nil {
+		return nil, fmt.Errorf("error while listing album shares, %w", err)
+	}
+
+	shares, err := i.imageStore.ListAlbumShares(ctx, albumName)
+	if err != nil {
+		return nil, fmt.Errorf("error while listing album shares, %w", err)
+	}
+
+	return shares, nil
+}
+
+// RevokeAlbumShare revokes the share of the album, its link stops working at
+// once. Only the owners of the album can revoke its shares.
+func (i *ImageController) RevokeAlbumShare(ctx context.Context, albumName, id string) error {
+	event := dbmodels.AuditEvent{Action: dbmodels.AuditShareRevoke, AlbumName: albumName, Target: id}
+
+	err := i.withAudit(ctx, event, func(tx dbhandler.ImageStore) error {
+		if err := newAlbumAccess(ctx).require(ctx, tx, albumName, dbmodels.RoleOwner); err != nil {
+			return err
+		}
+
+		return tx.RevokeAlbumShare(ctx, albumName, id, i.now().UTC().Truncate(time.Microsecond))
+	})
+	if err != nil {
+		return fmt.Errorf("error while revoking album share, %w", err)
+	}
+
+	i.log.Infof("album %s share %s revoked", albumName, id)
+
+	return nil
+}
+
+// OpenAlbumShare returns the active share of the token, after checking the
+// password of the share when it has one.
+func (i *ImageController) OpenAlbumShare(ctx context.Context, token, password string) (dbmodels.AlbumShare, error) {
+	share, err := i.imageStore.GetAlbumShareByHash(ctx, hashToken(token))
+	if errors.Is(err, dbhandler.ErrNoDataFound) || (err == nil && !share.Active(i.now())) {
+		return dbmodels.AlbumShare{}, ErrShareNotFound
+	}
+
+	if err != nil {
+		return dbmodels.AlbumShare{}, fmt.Errorf("error while opening album share, %w", err)
+	}
+
+	if share.PasswordHash != "" &&
+		bcrypt.CompareHashAndPassword([]byte(share.PasswordHash), []byte(password)) != nil {
+		return dbmodels.AlbumShare{}, ErrSharePassword
+	}
+
+	return share, nil
+}
@@ -0,0 +1,94 @@
+package controller
+
+import (
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"githum.com/anupam111/image-store/internal/config"
+	"githum.com/anupam111/image-store/internal/db/dbhandler"
+	"githum.com/anupam111/image-store/internal/db/dbmodels"
+	"githum.com/anupam111/image-store/internal/db/memstore"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAlbumShares(t *testing.T) {
+	t.Parallel()
+
+	now := time.Date(2022, 5, 1, 10, 0, 0, 0, time.UTC)
+	store := memstore.NewMemStore()
+	controller := NewImageController(logrus.New(), store, store, config.Quotas{})
+	controller.now = func() time.Time { return now }
+
+	var (
+		alice = principalContext("alice", nil)
+		bob   = principalContext("bob", []string{"editors"})
+	)
+
+	require.NoError(t, controller.CreateImageAlbum(alice, dbmodels.Album{AlbumName: "holiday"}))
+	require.NoError(t, controller.GrantAlbumAccess(alice, dbmodels.AlbumAccess{
+		AlbumName: "holiday", PrincipalType: dbmodels.PrincipalGroup, Principal: "editors", Role: dbmodels.RoleEditor,
+	}))
+
+	// Only owners share.
+	_, _, err := controller.CreateAlbumShare(bob, dbmodels.AlbumShare{AlbumName: "holiday"}, "")
+	assert.ErrorIs(t, err, ErrAccessDenied)
+	_, _, err = controller.CreateAlbumShare(alice, dbmodels.AlbumShare{AlbumName: "missing"}, "")
+	assert.ErrorIs(t, err, ErrAccessDenied)
+
+	past := now.Add(-time.Minute)
+	_, _, err = controller.CreateAlbumShare(alice, dbmodels.AlbumShare{AlbumName: "holiday", ExpiresAt: &past}, "")
+	assert.ErrorIs(t, err, ErrInvalidShare)
+
+	expiresAt := now.Add(time.Hour)
+	token, share, err := controller.CreateAlbumShare(alice,
+		dbmodels.AlbumShare{AlbumName: "holiday", ExpiresAt: &expiresAt, AllowDownload: true}, "secret")
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(token, shareTokenPrefix))
+	assert.Equal(t, hashToken(token), share.Hash)
+	assert.Equal(t, "alice", share.CreatedBy)
+	assert.NotContains(t, share.PasswordHash, "secret")
+
+	publicToken, _, err := controller.CreateAlbumShare(alice, dbmodels.AlbumShare{AlbumName: "holiday"}, "")
+	require.NoError(t, err)
+
+	_, err = controller.ListAlbumShares(bob, "holiday")
+	assert.ErrorIs(t, err, ErrAccessDenied)
+
+	shares, err := controller.ListAlbumShares(alice, "holiday")
+	require.NoError(t, err)
+	assert.Len(t, shares, 2)
+
+	// Opening checks the password.
+	_, err = controller.OpenAlbumShare(bob, token, "")
+	assert.ErrorIs(t, err, ErrSharePassword)
+	_, err = controller.OpenAlbumShare(bob, token, "wrong")
+	assert.ErrorIs(t, err, ErrSharePassword)
+
+	opened, err := controller.OpenAlbumShare(bob, token, "secret")
+	assert.NoError(t, err)
+	assert.Equal(t, share.ID, opened.ID)
+
+	opened, err = controller.OpenAlbumShare(bob, publicToken, "")
+	assert.NoError(t, err)
+	assert.False(t, opened.AllowDownload)
+
+	_, err = controller.OpenAlbumShare(bob, "shr_unknown", "")
+	assert.ErrorIs(t, err, ErrShareNotFound)
+
+	// Expired and revoked shares are gone.
+	now = expiresAt
+	_, err = controller.OpenAlbumShare(bob, token, "secret")
+	assert.ErrorIs(t, err, ErrShareNotFound)
+
+	assert.ErrorIs(t, controller.RevokeAlbumShare(bob, "holiday", opened.ID), ErrAccessDenied)
+	require.NoError(t, controller.RevokeAlbumShare(alice, "holiday", opened.ID))
+	assert.ErrorIs(t, controller.RevokeAlbumShare(alice, "holiday", opened.ID), dbhandler.ErrNoDataFound)
+
+	_, err = controller.OpenAlbumShare(bob, publicToken, "")
+	assert.ErrorIs(t, err, ErrShareNotFound)
+
+	// Deleting the album deletes its shares.
+	require.NoError(t, controller.DeleteImageAlbum(alice, "holiday"))
+}
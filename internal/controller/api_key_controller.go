@@ -0,0 +1,267 @@
+package controller
+
+//go:generate mockgen -source ./api_key_controller.go -package controller -destination api_key_controller_mock.go
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	log "github.com/sirupsen/logrus"
+	"githum.com/anupam111/image-store/internal/auth"
+	"githum.com/anupam111/image-store/internal/db/dbhandler"
+	"githum.com/anupam111/image-store/internal/db/dbmodels"
+	"githum.com/anupam111/image-store/internal/tenant"
+	"strings"
+	"time"
+)
+
+// apiKeyPrefix starts every API key, so that leaked keys are easy to spot.
+const apiKeyPrefix = "isk_"
+
+var (
+	// ErrInvalidAPIKey is returned for an unknown or revoked API key.
+	ErrInvalidAPIKey = errors.New("invalid api key")
+	// ErrInvalidScope is returned when creating an API key with an unknown
+	// scope or without scope.
+	ErrInvalidScope = errors.New("invalid api key scope")
+	// ErrInvalidTenant is returned when creating an API key bound to an
+	// invalid tenant, or to another tenant than the one of the caller.
+	ErrInvalidTenant = errors.New("invalid api key tenant")
+)
+
+type APIKeys interface {
+	CreateAPIKey(ctx context.Context, name, tenant string, scopes dbmodels.Scopes) (string, dbmodels.APIKey, error)
+	ListAPIKeys(ctx context.Context) ([]dbmodels.APIKey, error)
+	RevokeAPIKey(ctx context.Context, id string) error
+	Authenticate(ctx context.Context, key string) (dbmodels.APIKey, error)
+}
+
+// APIKeyController manages the API keys and authenticates the requests with
+// them.
+type APIKeyController struct {
+	log         *log.Logger
+	apiKeyStore dbhandler.APIKeyStore
+	auditStore  dbhandler.AuditStore
+	now         func() time.Time
+}
+
+// NewAPIKeyController implements APIKeyController. The creations and
+// revocations of keys are recorded in the audit log in their transaction,
+// and in the audit log of auditStore when they fail.
+func NewAPIKeyController(log *log.Logger, apiKeyStore dbhandler.APIKeyStore, auditStore dbhandler.AuditStore) *APIKeyController {
+	return &APIKeyController{
+		log:         log,
+		apiKeyStore: apiKeyStore,
+		auditStore:  auditStore,
+		now:         time.Now,
+	}
+}
+
+// CreateAPIKey creates an API key with the scopes and returns the key, which
+// can not be recovered afterwards, together with its stored form. The key is
+// bound to tenantID, tenant.Default when it is empty. An admin key is bound
+// to no tenant when tenantID is tenant.Any. A caller bound to a tenant only
+// ever creates keys bound to its own.
+func (a *APIKeyController) CreateAPIKey(ctx context.Context, name, tenantID string, scopes dbmodels.Scopes) (string, dbmodels.APIKey, error) {
+	if bound := boundTenant(ctx); bound != "" {
+		if tenantID != "" && tenantID != bound {
+			return "", dbmodels.APIKey{}, fmt.Errorf("%w: caller is bound to tenant %s", ErrInvalidTenant, bound)
+		}
+
+		tenantID = bound
+	}
+
+	if len(scopes) == 0 {
+		return "", dbmodels.APIKey{}, fmt.Errorf("%w: no scope", ErrInvalidScope)
+	}
+
+	switch {
+	case tenantID == "":
+		tenantID = tenant.Default
+	case tenantID == tenant.Any && scopes.Allows(dbmodels.ScopeAdmin):
+		tenantID = ""
+	case tenantID == tenant.Any:
+		return "", dbmodels.APIKey{}, fmt.Errorf("%w: only admin keys can be bound to no tenant", ErrInvalidTenant)
+	case !tenant.Valid(tenantID):
+		return "", dbmodels.APIKey{}, fmt.Errorf("%w: %q", ErrInvalidTenant, tenantID)
+	}
+
+	for _, scope := range scopes {
+		if !dbmodels.ValidScope(scope) {
+			return "", dbmodels.APIKey{}, fmt.Errorf("%w: %q", ErrInvalidScope, scope)
+		}
+	}
+
+	id, err := randomToken(8, hex.EncodeToString)
+	if err != nil {
+		return "", dbmodels.APIKey{}, err
+	}
+
+	secret, err := randomToken(32, base64.RawURLEncoding.EncodeToString)
+	if err != nil {
+		return "", dbmodels.APIKey{}, err
+	}
+
+	key := apiKeyPrefix + secret
+	apiKey := dbmodels.APIKey{
+		ID:        id,
+		Name:      name,
+		Tenant:    tenantID,
+		Hash:      hashToken(key),
+		Scopes:    scopes,
+		CreatedAt: a.now().UTC().Truncate(time.Microsecond),
+	}
+
+	err = a.withAudit(ctx, dbmodels.AuditEvent{
+		Action:  dbmodels.AuditAPIKeyCreate,
+		Target:  apiKey.ID,
+		Details: fmt.Sprintf("name=%s tenant=%s scopes=%s", apiKey.Name, apiKey.Tenant, strings.Join(apiKey.Scopes, ",")),
+	}, func(tx dbhandler.APIKeyStore) error {
+		return tx.CreateAPIKey(ctx, apiKey)
+	})
+	if err != nil {
+		return "", dbmodels.APIKey{}, fmt.Errorf("error while creating api key, %w", err)
+	}
+
+	a.log.Infof("api key %s (%s) created with scopes %v for tenant %q", apiKey.ID, apiKey.Name, apiKey.Scopes, apiKey.Tenant)
+
+	return key, apiKey, nil
+}
+
+// ListAPIKeys lists the API keys, only the ones of its tenant to a caller
+// bound to a tenant.
+func (a *APIKeyController) ListAPIKeys(ctx context.Context) ([]dbmodels.APIKey, error) {
+	keys, err := listAPIKeys(ctx, a.apiKeyStore)
+	if err != nil {
+		return nil, fmt.Errorf("error while listing api keys, %w", err)
+	}
+
+	return keys, nil
+}
+
+func listAPIKeys(ctx context.Context, apiKeyStore dbhandler.APIKeyStore) ([]dbmodels.APIKey, error) {
+	keys, err := apiKeyStore.ListAPIKeys(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	bound := boundTenant(ctx)
+	if bound == "" {
+		return keys, nil
+	}
+
+	tenantKeys := make([]dbmodels.APIKey, 0, len(keys))
+	for _, key := range keys {
+		if key.Tenant == bound {
+			tenantKeys = append(tenantKeys, key)
+		}
+	}
+
+	return tenantKeys, nil
+}
+
+// RevokeAPIKey revokes the API key, the keys of other tenants are not found
+// by a caller bound to a tenant.
+func (a *APIKeyController) RevokeAPIKey(ctx context.Context, id string) error {
+	err := a.withAudit(ctx, dbmodels.AuditEvent{Action: dbmodels.AuditAPIKeyRevoke, Target: id},
+		func(tx dbhandler.APIKeyStore) error {
+			if boundTenant(ctx) != "" {
+				keys, err := listAPIKeys(ctx, tx)
+				if err != nil {
+					return err
+				}
+
+				if !hasAPIKey(keys, id) {
+					return dbhandler.ErrNoDataFound
+				}
+			}
+
+			return tx.RevokeAPIKey(ctx, id, a.now().UTC().Truncate(time.Microsecond))
+		})
+	if err != nil {
+		return fmt.Errorf("error while revoking api key, %w", err)
+	}
+
+	a.log.Infof("api key %s revoked", id)
+
+	return nil
+}
+
+// withAudit is ImageController.withAudit for the API keys.
+func (a *APIKeyController) withAudit(ctx context.Context, event dbmodels.AuditEvent,
+	fn func(tx dbhandler.APIKeyStore) error) error {
+	err := a.apiKeyStore.WithAPIKeyTx(ctx, func(tx dbhandler.APIKeyStore) error {
+		if err := fn(tx); err != nil {
+			return err
+		}
+
+		return recordSuccess(ctx, tx, a.now(), event)
+	})
+	if err != nil {
+		recordFailure(ctx, a.log, a.auditStore, a.now(), event, err)
+	}
+
+	return err
+}
+
+// Authenticate returns the API key of key, ErrInvalidAPIKey when it is
+// unknown or revoked.
+func (a *APIKeyController) Authenticate(ctx context.Context, key string) (dbmodels.APIKey, error) {
+	apiKey, err := a.apiKeyStore.GetAPIKeyByHash(ctx, hashToken(key))
+	if errors.Is(err, dbhandler.ErrNoDataFound) {
+		return dbmodels.APIKey{}, ErrInvalidAPIKey
+	}
+
+	if err != nil {
+		return dbmodels.APIKey{}, fmt.Errorf("error while authenticating api key, %w", err)
+	}
+
+	if apiKey.RevokedAt != nil {
+		return dbmodels.APIKey{}, ErrInvalidAPIKey
+	}
+
+	return apiKey, nil
+}
+
+// boundTenant returns the tenant the caller is bound to, empty when it may
+// act on any tenant.
+func boundTenant(ctx context.Context) string {
+	principal, ok := auth.FromContext(ctx)
+	if !ok {
+		return ""
+	}
+
+	return principal.Tenant
+}
+
+func hasAPIKey(keys []dbmodels.APIKey, id string) bool {
+	for _, key := range keys {
+		if key.ID == id {
+			return true
+		}
+	}
+
+	return false
+}
+
+// hashToken is the stored form of an API key or a share token. They are
+// random, so a plain SHA-256 is enough to make them unrecoverable from the
+// database.
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+
+	return hex.EncodeToString(sum[:])
+}
+
+func randomToken(size int, encode func([]byte) string) (string, error) {
+	token := make([]byte, size)
+	if _, err := rand.Read(token); err != nil {
+		return "", fmt.Errorf("error while generating random token, %w", err)
+	}
+
+	return encode(token), nil
+}
@@ -0,0 +1,243 @@
+package controller
+
+import (
+	"context"
+	"github.com/golang/mock/gomock"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"githum.com/anupam111/image-store/internal/auth"
+	"githum.com/anupam111/image-store/internal/db/dbhandler"
+	"githum.com/anupam111/image-store/internal/db/dbmodels"
+	"githum.com/anupam111/image-store/internal/db/memstore"
+	"githum.com/anupam111/image-store/internal/tenant"
+	"strings"
+	"testing"
+	"time"
+)
+
+var testNow = time.Date(2022, 5, 1, 10, 0, 0, 0, time.UTC)
+
+func apiKeySetUp(t *testing.T) (*dbhandler.MockAPIKeyStore, *APIKeyController) {
+	t.Helper()
+	mockStore := dbhandler.NewMockAPIKeyStore(gomock.NewController(t))
+	mockStore.EXPECT().WithAPIKeyTx(gomock.Any(), gomock.Any()).DoAndReturn(
+		func(ctx context.Context, fn func(tx dbhandler.APIKeyStore) error) error {
+			return fn(mockStore)
+		},
+	).AnyTimes()
+	mockStore.EXPECT().CreateAuditEvent(gomock.Any(), gomock.Any()).Return(nil).AnyTimes()
+	controller := NewAPIKeyController(logrus.New(), mockStore, memstore.NewMemStore())
+	controller.now = func() time.Time {
+		return testNow
+	}
+
+	return mockStore, controller
+}
+
+func TestCreateAPIKey(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name           string
+		ctx            context.Context
+		tenant         string
+		scopes         dbmodels.Scopes
+		prepare        func(subs *dbhandler.MockAPIKeyStore)
+		expectedTenant string
+		expectedError  error
+	}{
+		{
+			name:   "success",
+			scopes: dbmodels.Scopes{dbmodels.ScopeAlbumsRead},
+			prepare: func(subs *dbhandler.MockAPIKeyStore) {
+				subs.EXPECT().CreateAPIKey(gomock.Any(), gomock.Any()).Return(nil)
+			},
+			expectedTenant: tenant.Default,
+		},
+		{
+			name:   "unbound_admin",
+			tenant: tenant.Any,
+			scopes: dbmodels.Scopes{dbmodels.ScopeAdmin},
+			prepare: func(subs *dbhandler.MockAPIKeyStore) {
+				subs.EXPECT().CreateAPIKey(gomock.Any(), gomock.Any()).Return(nil)
+			},
+		},
+		{
+			name:          "unbound_without_admin",
+			tenant:        tenant.Any,
+			scopes:        dbmodels.Scopes{dbmodels.ScopeAlbumsRead},
+			expectedError: ErrInvalidTenant,
+		},
+		{
+			name:   "tenant",
+			tenant: "acme",
+			scopes: dbmodels.Scopes{dbmodels.ScopeAlbumsRead},
+			prepare: func(subs *dbhandler.MockAPIKeyStore) {
+				subs.EXPECT().CreateAPIKey(gomock.Any(), gomock.Any()).Return(nil)
+			},
+			expectedTenant: "acme",
+		},
+		{
+			name:   "caller_tenant",
+			ctx:    auth.NewContext(context.Background(), auth.Principal{Subject: "admin", Tenant: "acme"}),
+			scopes: dbmodels.Scopes{dbmodels.ScopeAlbumsRead},
+			prepare: func(subs *dbhandler.MockAPIKeyStore) {
+				subs.EXPECT().CreateAPIKey(gomock.Any(), gomock.Any()).Return(nil)
+			},
+			expectedTenant: "acme",
+		},
+		{
+			name:          "other_tenant_than_caller",
+			ctx:           auth.NewContext(context.Background(), auth.Principal{Subject: "admin", Tenant: "acme"}),
+			tenant:        "globex",
+			scopes:        dbmodels.Scopes{dbmodels.ScopeAlbumsRead},
+			expectedError: ErrInvalidTenant,
+		},
+		{
+			name:          "invalid_tenant",
+			tenant:        "../acme",
+			scopes:        dbmodels.Scopes{dbmodels.ScopeAlbumsRead},
+			expectedError: ErrInvalidTenant,
+		},
+		{
+			name:          "no_scope",
+			expectedError: ErrInvalidScope,
+		},
+		{
+			name:          "unknown_scope",
+			scopes:        dbmodels.Scopes{"albums:delete"},
+			expectedError: ErrInvalidScope,
+		},
+		{
+			name:   "store_error",
+			scopes: dbmodels.Scopes{dbmodels.ScopeAdmin},
+			prepare: func(subs *dbhandler.MockAPIKeyStore) {
+				subs.EXPECT().CreateAPIKey(gomock.Any(), gomock.Any()).Return(errFake)
+			},
+			expectedError: errFake,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			mockStore, controller := apiKeySetUp(t)
+			if tt.prepare != nil {
+				tt.prepare(mockStore)
+			}
+
+			ctx := tt.ctx
+			if ctx == nil {
+				ctx = context.Background()
+			}
+
+			key, apiKey, err := controller.CreateAPIKey(ctx, "ci", tt.tenant, tt.scopes)
+			if tt.expectedError != nil {
+				assert.ErrorIs(t, err, tt.expectedError)
+
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.True(t, strings.HasPrefix(key, apiKeyPrefix))
+			assert.Equal(t, hashToken(key), apiKey.Hash)
+			assert.Equal(t, "ci", apiKey.Name)
+			assert.Equal(t, tt.expectedTenant, apiKey.Tenant)
+			assert.Equal(t, tt.scopes, apiKey.Scopes)
+			assert.Equal(t, testNow, apiKey.CreatedAt)
+			assert.Len(t, apiKey.ID, 16)
+		})
+	}
+}
+
+func TestAuthenticate(t *testing.T) {
+	t.Parallel()
+
+	apiKey := dbmodels.APIKey{ID: "id", Hash: hashToken("isk_key"), Scopes: dbmodels.Scopes{dbmodels.ScopeAdmin}}
+	revoked := apiKey
+	revoked.RevokedAt = &testNow
+
+	tests := []struct {
+		name           string
+		prepare        func(subs *dbhandler.MockAPIKeyStore)
+		expectedError  error
+		expectedAPIKey dbmodels.APIKey
+	}{
+		{
+			name: "success",
+			prepare: func(subs *dbhandler.MockAPIKeyStore) {
+				subs.EXPECT().GetAPIKeyByHash(gomock.Any(), apiKey.Hash).Return(apiKey, nil)
+			},
+			expectedAPIKey: apiKey,
+		},
+		{
+			name: "unknown",
+			prepare: func(subs *dbhandler.MockAPIKeyStore) {
+				subs.EXPECT().GetAPIKeyByHash(gomock.Any(), apiKey.Hash).Return(dbmodels.APIKey{}, dbhandler.ErrNoDataFound)
+			},
+			expectedError: ErrInvalidAPIKey,
+		},
+		{
+			name: "revoked",
+			prepare: func(subs *dbhandler.MockAPIKeyStore) {
+				subs.EXPECT().GetAPIKeyByHash(gomock.Any(), apiKey.Hash).Return(revoked, nil)
+			},
+			expectedError: ErrInvalidAPIKey,
+		},
+		{
+			name: "store_error",
+			prepare: func(subs *dbhandler.MockAPIKeyStore) {
+				subs.EXPECT().GetAPIKeyByHash(gomock.Any(), apiKey.Hash).Return(dbmodels.APIKey{}, errFake)
+			},
+			expectedError: errFake,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			mockStore, controller := apiKeySetUp(t)
+			tt.prepare(mockStore)
+
+			got, err := controller.Authenticate(context.Background(), "isk_key")
+			if tt.expectedError != nil {
+				assert.ErrorIs(t, err, tt.expectedError)
+			} else {
+				assert.NoError(t, err)
+			}
+
+			assert.Equal(t, tt.expectedAPIKey, got)
+		})
+	}
+}
+
+func TestRevokeAPIKey(t *testing.T) {
+	t.Parallel()
+
+	mockStore, controller := apiKeySetUp(t)
+	mockStore.EXPECT().RevokeAPIKey(gomock.Any(), "id", testNow).Return(nil)
+	mockStore.EXPECT().RevokeAPIKey(gomock.Any(), "other", testNow).Return(dbhandler.ErrNoDataFound)
+
+	assert.NoError(t, controller.RevokeAPIKey(context.Background(), "id"))
+	assert.ErrorIs(t, controller.RevokeAPIKey(context.Background(), "other"), dbhandler.ErrNoDataFound)
+}
+
+func TestAPIKeysOfBoundTenant(t *testing.T) {
+	t.Parallel()
+
+	keys := []dbmodels.APIKey{{ID: "global"}, {ID: "acme", Tenant: "acme"}, {ID: "globex", Tenant: "globex"}}
+	ctx := auth.NewContext(context.Background(), auth.Principal{Subject: "admin", Tenant: "acme"})
+
+	mockStore, controller := apiKeySetUp(t)
+	mockStore.EXPECT().ListAPIKeys(gomock.Any()).Return(keys, nil).Times(3)
+	mockStore.EXPECT().RevokeAPIKey(gomock.Any(), "acme", testNow).Return(nil)
+
+	got, err := controller.ListAPIKeys(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, []dbmodels.APIKey{{ID: "acme", Tenant: "acme"}}, got)
+
+	assert.NoError(t, controller.RevokeAPIKey(ctx, "acme"))
+	assert.ErrorIs(t, controller.RevokeAPIKey(ctx, "globex"), dbhandler.ErrNoDataFound)
+}
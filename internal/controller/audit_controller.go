@@ -0,0 +1,147 @@
+package controller
+
+//go:generate mockgen -source ./audit_controller.go -package controller -destination audit_controller_mock.go
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	log "github.com/sirupsen/logrus"
+	"githum.com/anupam111/image-store/internal/auth"
+	"githum.com/anupam111/image-store/internal/db/dbhandler"
+	"githum.com/anupam111/image-store/internal/db/dbmodels"
+	"githum.com/anupam111/image-store/internal/requestinfo"
+	"time"
+)
+
+// anonymousActor is the actor of the operations of unauthenticated callers,
+// when authentication is disabled or from the command line.
+const anonymousActor = "anonymous"
+
+// auditFailureTimeout bounds the recording of the event of a failed
+// operation, which outlives the deadline of its request.
+const auditFailureTimeout = 5 * time.Second
+
+type AuditLog interface {
+	ListAuditEvents(ctx context.Context, filter dbmodels.AuditFilter, limit, offset int) ([]dbmodels.AuditEvent, int, error)
+	ExportAuditEvents(ctx context.Context, filter dbmodels.AuditFilter, fn func(dbmodels.AuditEvent) error) error
+}
+
+// AuditController reads the audit log of the tenant of the caller.
+type AuditController struct {
+	log        *log.Logger
+	auditStore dbhandler.AuditStore
+}
+
+// NewAuditController implements AuditController.
+func NewAuditController(log *log.Logger, auditStore dbhandler.AuditStore) *AuditController {
+	return &AuditController{
+		log:        log,
+		auditStore: auditStore,
+	}
+}
+
+// ListAuditEvents returns a page of the events selected by the filter, latest
+// first, and the number of events of all pages.
+func (a *AuditController) ListAuditEvents(ctx context.Context, filter dbmodels.AuditFilter,
+	limit, offset int) ([]dbmodels.AuditEvent, int, error) {
+	events, total, err := a.auditStore.ListAuditEvents(ctx, filter, limit, offset)
+	if err != nil {
+		return nil, 0, fmt.Errorf("error while listing audit events, %w", err)
+	}
+
+	return events, total, nil
+}
+
+// ExportAuditEvents calls fn with every event selected by the filter, oldest
+// first.
+func (a *AuditController) ExportAuditEvents(ctx context.Context, filter dbmodels.AuditFilter,
+	fn func(dbmodels.AuditEvent) error) error {
+	if err := a.auditStore.StreamAuditEvents(ctx, filter, fn); err != nil {
+		return fmt.Errorf("error while exporting audit events, %w", err)
+	}
+
+	return nil
+}
+
+// auditRecorder appends events to the audit log, the ImageStore and
+// APIKeyStore passed to the WithTx callbacks in their transaction.
+type auditRecorder interface {
+	CreateAuditEvent(ctx context.Context, event dbmodels.AuditEvent) error
+}
+
+// auditEvent fills in the time of the event of an operation, its caller, its
+// request and its outcome err.
+func auditEvent(ctx context.Context, now time.Time, event dbmodels.AuditEvent, err error) dbmodels.AuditEvent {
+	info := requestinfo.FromContext(ctx)
+
+	event.OccurredAt = now.UTC().Truncate(time.Microsecond)
+	event.RequestID = info.ID
+	event.ClientIP = info.ClientIP
+	event.Actor = anonymousActor
+
+	if principal, ok := auth.FromContext(ctx); ok {
+		event.Actor = principal.Subject
+	}
+
+	switch {
+	case err == nil:
+		event.Outcome = dbmodels.OutcomeSuccess
+	case errors.Is(err, ErrAccessDenied):
+		event.Outcome = dbmodels.OutcomeDenied
+		event.Error = err.Error()
+	default:
+		event.Outcome = dbmodels.OutcomeFailure
+		event.Error = err.Error()
+	}
+
+	return event
+}
+
+// recordSuccess appends the event of an operation to the audit log through
+// tx, the store of the transaction of its writes, so that they are never
+// committed without their event.
+func recordSuccess(ctx context.Context, tx auditRecorder, now time.Time, event dbmodels.AuditEvent) error {
+	if err := tx.CreateAuditEvent(ctx, auditEvent(ctx, now, event, nil)); err != nil {
+		return fmt.Errorf("error while recording %s of %q, %w", event.Action, event.Target, err)
+	}
+
+	return nil
+}
+
+// recordFailure appends the event of an operation which failed with err to
+// the audit log, once its writes are rolled back. The event is recorded on a
+// context detached from the one of the request, whose deadline may be what
+// failed the operation. The operations rejected for invalid input before
+// reaching the store are not recorded. A failure to record is logged, the
+// operation failed already.
+func recordFailure(ctx context.Context, logger *log.Logger, auditStore dbhandler.AuditStore,
+	now time.Time, event dbmodels.AuditEvent, err error) {
+	event = auditEvent(ctx, now, event, err)
+
+	ctx, cancel := context.WithTimeout(detachedContext{ctx}, auditFailureTimeout)
+	defer cancel()
+
+	if err := auditStore.CreateAuditEvent(ctx, event); err != nil {
+		logger.Errorf("error while recording %s of %q in album %q by %q: %v",
+			event.Action, event.Target, event.AlbumName, event.Actor, err)
+	}
+}
+
+// detachedContext has the values of its context, but neither its deadline
+// nor its cancellation.
+type detachedContext struct {
+	context.Context
+}
+
+func (detachedContext) Deadline() (time.Time, bool) {
+	return time.Time{}, false
+}
+
+func (detachedContext) Done() <-chan struct{} {
+	return nil
+}
+
+func (detachedContext) Err() error {
+	return nil
+}
@@ -0,0 +1,169 @@
+package controller
+
+import (
+	"context"
+	"github.com/golang/mock/gomock"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"githum.com/anupam111/image-store/internal/config"
+	"githum.com/anupam111/image-store/internal/db/dbhandler"
+	"githum.com/anupam111/image-store/internal/db/dbmodels"
+	"githum.com/anupam111/image-store/internal/db/memstore"
+	"githum.com/anupam111/image-store/internal/requestinfo"
+	"testing"
+	"time"
+)
+
+func TestAudit(t *testing.T) {
+	t.Parallel()
+
+	now := time.Date(2022, 5, 1, 10, 0, 0, 0, time.UTC)
+	store := memstore.NewMemStore()
+	controller := NewImageController(logrus.New(), store, store, config.Quotas{})
+	controller.now = func() time.Time { return now }
+	auditLog := NewAuditController(logrus.New(), store)
+
+	var (
+		alice = requestinfo.NewContext(principalContext("alice", nil),
+			requestinfo.Info{ID: "req-1", ClientIP: "10.0.0.1"})
+		bob    = principalContext("bob", nil)
+		anyone = context.Background()
+	)
+
+	require.NoError(t, controller.CreateImageAlbum(alice, dbmodels.Album{AlbumName: "holiday"}))
+	require.NoError(t, controller.CreateImage(alice, dbmodels.Image{ImageName: "beach.png", AlbumName: "holiday"}))
+	require.NoError(t, controller.UpdateImage(alice, dbmodels.ImageKey{AlbumName: "holiday", ImageName: "beach.png"},
+		dbmodels.Image{ImageName: "sea.png", AlbumName: "holiday"}))
+	require.NoError(t, controller.GrantAlbumAccess(alice, dbmodels.AlbumAccess{
+		AlbumName: "holiday", PrincipalType: dbmodels.PrincipalGroup, Principal: "viewers", Role: dbmodels.RoleViewer,
+	}))
+	assert.ErrorIs(t, controller.DeleteImage(bob, "sea.png", "holiday"), ErrAccessDenied)
+	assert.Error(t, controller.CreateImage(anyone, dbmodels.Image{ImageName: "beach.png", AlbumName: "missing"}))
+	require.NoError(t, controller.DeleteImageAlbum(alice, "holiday"))
+
+	var events []dbmodels.AuditEvent
+	require.NoError(t, auditLog.ExportAuditEvents(anyone, dbmodels.AuditFilter{}, func(event dbmodels.AuditEvent) error {
+		event.ID, event.Error = 0, ""
+		events = append(events, event)
+
+		return nil
+	}))
+
+	aliceEvent := func(action, target, details string) dbmodels.AuditEvent {
+		return dbmodels.AuditEvent{
+			Tenant: "default", OccurredAt: now, Actor: "alice", Action: action, AlbumName: "holiday",
+			Target: target, Details: details, RequestID: "req-1", ClientIP: "10.0.0.1", Outcome: dbmodels.OutcomeSuccess,
+		}
+	}
+
+	assert.Equal(t, []dbmodels.AuditEvent{
+		aliceEvent(dbmodels.AuditAlbumCreate, "holiday", ""),
+		aliceEvent(dbmodels.AuditImageCreate, "beach.png", ""),
+		aliceEvent(dbmodels.AuditImageMove, "beach.png", "to=holiday/sea.png"),
+		aliceEvent(dbmodels.AuditAccessGrant, "group:viewers", "role=viewer"),
+		{
+			Tenant: "default", OccurredAt: now, Actor: "bob", Action: dbmodels.AuditImageDelete, AlbumName: "holiday",
+			Target: "sea.png", Outcome: dbmodels.OutcomeDenied,
+		},
+		{
+			Tenant: "default", OccurredAt: now, Actor: anonymousActor, Action: dbmodels.AuditImageCreate, AlbumName: "missing",
+			Target: "beach.png", Outcome: dbmodels.OutcomeFailure,
+		},
+		aliceEvent(dbmodels.AuditAlbumDelete, "holiday", ""),
+	}, events)
+
+	page, total, err := auditLog.ListAuditEvents(anyone, dbmodels.AuditFilter{Actor: "bob"}, 10, 0)
+	require.NoError(t, err)
+	assert.Equal(t, 1, total)
+	require.Len(t, page, 1)
+	assert.Contains(t, page[0].Error, ErrAccessDenied.Error())
+}
+
+func TestAuditOfCanceledRequest(t *testing.T) {
+	t.Parallel()
+
+	store := memstore.NewMemStore()
+	controller := NewImageController(logrus.New(), store, store, config.Quotas{})
+	auditLog := NewAuditController(logrus.New(), store)
+
+	ctx, cancel := context.WithCancel(principalContext("alice", nil))
+	cancel()
+
+	assert.ErrorIs(t, controller.CreateImageAlbum(ctx, dbmodels.Album{AlbumName: "holiday"}), context.Canceled)
+
+	page, total, err := auditLog.ListAuditEvents(context.Background(), dbmodels.AuditFilter{}, 10, 0)
+	require.NoError(t, err)
+	assert.Equal(t, 1, total)
+	require.Len(t, page, 1)
+	assert.Equal(t, "alice", page[0].Actor)
+	assert.Equal(t, dbmodels.OutcomeFailure, page[0].Outcome)
+}
+
+func TestAuditInTransaction(t *testing.T) {
+	t.Parallel()
+
+	mockCtrl := gomock.NewController(t)
+	mockStore := dbhandler.NewMockImageStore(mockCtrl)
+	auditStore := memstore.NewMemStore()
+	controller := NewImageController(logrus.New(), mockStore, auditStore, config.Quotas{})
+	auditLog := NewAuditController(logrus.New(), auditStore)
+
+	mockStore.EXPECT().WithTx(gomock.Any(), gomock.Any()).DoAndReturn(
+		func(ctx context.Context, fn func(tx dbhandler.ImageStore) error) error {
+			return fn(mockStore)
+		},
+	)
+	mockStore.EXPECT().CreateAlbum(gomock.Any(), dbmodels.Album{AlbumName: "holiday"}).Return(nil)
+	mockStore.EXPECT().CreateAuditEvent(gomock.Any(), gomock.Any()).Return(errFake)
+
+	assert.ErrorIs(t, controller.CreateImageAlbum(context.Background(), dbmodels.Album{AlbumName: "holiday"}), errFake)
+
+	page, _, err := auditLog.ListAuditEvents(context.Background(), dbmodels.AuditFilter{}, 10, 0)
+	require.NoError(t, err)
+	require.Len(t, page, 1)
+	assert.Equal(t, dbmodels.AuditAlbumCreate, page[0].Action)
+	assert.Equal(t, dbmodels.OutcomeFailure, page[0].Outcome)
+}
+
+func TestUpdateEvent(t *testing.T) {
+	t.Parallel()
+
+	key := dbmodels.ImageKey{AlbumName: "holiday", ImageName: "beach.png"}
+
+	tests := []struct {
+		name          string
+		image         dbmodels.Image
+		expectedEvent dbmodels.AuditEvent
+	}{
+		{
+			name:  "update",
+			image: dbmodels.Image{AlbumName: "holiday", ImageName: "beach.png"},
+			expectedEvent: dbmodels.AuditEvent{
+				Action: dbmodels.AuditImageUpdate, AlbumName: "holiday", Target: "beach.png",
+			},
+		},
+		{
+			name:  "other_album",
+			image: dbmodels.Image{AlbumName: "work", ImageName: "beach.png"},
+			expectedEvent: dbmodels.AuditEvent{
+				Action: dbmodels.AuditImageUpdate, AlbumName: "holiday", Target: "beach.png",
+			},
+		},
+		{
+			name:  "rename",
+			image: dbmodels.Image{AlbumName: "work", ImageName: "sea.png"},
+			expectedEvent: dbmodels.AuditEvent{
+				Action: dbmodels.AuditImageMove, AlbumName: "holiday", Target: "beach.png", Details: "to=holiday/sea.png",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			assert.Equal(t, tt.expectedEvent, updateEvent(key, tt.image))
+		})
+	}
+}
@@ -3,33 +3,136 @@ package controller
 //go:generate mockgen -source ./image_store_controller.go -package controller -destination image_store_controller_mock.go
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	log "github.com/sirupsen/logrus"
+	"githum.com/anupam111/image-store/internal/auth"
+	"githum.com/anupam111/image-store/internal/config"
 	"githum.com/anupam111/image-store/internal/db/dbhandler"
 	"githum.com/anupam111/image-store/internal/db/dbmodels"
+	"sort"
+	"time"
 )
 
 type ImageStore interface {
-	CreateImageAlbum(album dbmodels.Album) error
-	DeleteImageAlbum(albumName string) error
-	CreateImage(image dbmodels.Image) error
-	DeleteImage(imageName, albumName string) error
-	GetImage(id string) (dbmodels.Image, error)
-	GetAllImages(albumName string) ([]dbmodels.Image, error)
+	CreateImageAlbum(ctx context.Context, album dbmodels.Album) error
+	DeleteImageAlbum(ctx context.Context, albumName string) error
+	CreateImage(ctx context.Context, image dbmodels.Image) error
+	DeleteImage(ctx context.Context, imageName, albumName string) error
+	GetImage(ctx context.Context, id string) (dbmodels.Image, error)
+	GetAllImages(ctx context.Context, albumName string) ([]dbmodels.Image, error)
+	CreateImages(ctx context.Context, images []dbmodels.Image, allOrNothing bool) []error
+	DeleteImages(ctx context.Context, keys []dbmodels.ImageKey, allOrNothing bool) []error
+	GetImageAlbum(ctx context.Context, albumName string) (dbmodels.Album, error)
+	ExportAlbumImages(ctx context.Context, albumName string, fn func(dbmodels.Image) error) error
+	ListImageAlbums(ctx context.Context) ([]dbmodels.Album, error)
+	GetAlbumImage(ctx context.Context, albumName, imageName string) (dbmodels.Image, error)
+	UpdateImage(ctx context.Context, key dbmodels.ImageKey, image dbmodels.Image) error
+	PutImage(ctx context.Context, image dbmodels.Image) (bool, error)
+	GetImagesOfAlbums(ctx context.Context, albumNames []string) (map[string][]dbmodels.Image, error)
+	SearchImages(ctx context.Context, text string, limit, offset int) ([]dbmodels.ImageMatch, int, error)
+	FilterImages(ctx context.Context, albumName string, filters []dbmodels.MetadataFilter) ([]dbmodels.Image, error)
+	ListAlbumAccess(ctx context.Context, albumName string) ([]dbmodels.AlbumAccess, error)
+	GrantAlbumAccess(ctx context.Context, access dbmodels.AlbumAccess) error
+	RevokeAlbumAccess(ctx context.Context, albumName, principalType, principal string) error
+	CreateAlbumShare(ctx context.Context, share dbmodels.AlbumShare, password string) (string, dbmodels.AlbumShare, error)
+	ListAlbumShares(ctx context.Context, albumName string) ([]dbmodels.AlbumShare, error)
+	RevokeAlbumShare(ctx context.Context, albumName, id string) error
+	OpenAlbumShare(ctx context.Context, token, password string) (dbmodels.AlbumShare, error)
+	GetUsage(ctx context.Context) (UsageReport, error)
 }
 
+// ErrBatchAborted is reported for the items of an all-or-nothing batch which
+// were rolled back because another item of the batch failed.
+var ErrBatchAborted = errors.New("batch aborted because another item failed")
+
 type ImageController struct {
 	log        *log.Logger
 	imageStore dbhandler.ImageStore
+	auditStore dbhandler.AuditStore
+	quotas     config.Quotas
+	now        func() time.Time
 }
 
-func NewImageController(log *log.Logger, imageStore dbhandler.ImageStore) *ImageController {
+// NewImageController implements ImageController. The writes are recorded in
+// the audit log in their transaction, and in the audit log of auditStore
+// when they fail. The image writes are held to quotas.
+func NewImageController(log *log.Logger, imageStore dbhandler.ImageStore, auditStore dbhandler.AuditStore,
+	quotas config.Quotas) *ImageController {
 	return &ImageController{log: log,
-		imageStore: imageStore}
+		imageStore: imageStore,
+		auditStore: auditStore,
+		quotas:     quotas,
+		now:        time.Now}
 }
 
-func (i *ImageController) CreateImageAlbum(album dbmodels.Album) error {
-	err := i.imageStore.CreateAlbum(album)
+// withAudit runs fn in a transaction which records event in the audit log
+// when fn succeeds. When the transaction fails, the failure is recorded once
+// it is rolled back.
+func (i *ImageController) withAudit(ctx context.Context, event dbmodels.AuditEvent,
+	fn func(tx dbhandler.ImageStore) error) error {
+	err := i.imageStore.WithTx(ctx, func(tx dbhandler.ImageStore) error {
+		if err := fn(tx); err != nil {
+			return err
+		}
+
+		return recordSuccess(ctx, tx, i.now(), event)
+	})
+	if err != nil {
+		i.auditFailure(ctx, event, err)
+	}
+
+	return err
+}
+
+// auditFailure records the operation of event, which failed with err, in the
+// audit log.
+func (i *ImageController) auditFailure(ctx context.Context, event dbmodels.AuditEvent, err error) {
+	recordFailure(ctx, i.log, i.auditStore, i.now(), event, err)
+}
+
+func albumEvent(action, albumName string) dbmodels.AuditEvent {
+	return dbmodels.AuditEvent{Action: action, AlbumName: albumName, Target: albumName}
+}
+
+func imageEvent(action, albumName, imageName string) dbmodels.AuditEvent {
+	return dbmodels.AuditEvent{Action: action, AlbumName: albumName, Target: imageName}
+}
+
+// updateEvent is the event of the update of the image of key, a move when
+// the update renames the image. The image stays in the album of key.
+func updateEvent(key dbmodels.ImageKey, image dbmodels.Image) dbmodels.AuditEvent {
+	if image.ImageName == key.ImageName {
+		return imageEvent(dbmodels.AuditImageUpdate, key.AlbumName, key.ImageName)
+	}
+
+	event := imageEvent(dbmodels.AuditImageMove, key.AlbumName, key.ImageName)
+	event.Details = "to=" + key.AlbumName + "/" + image.ImageName
+
+	return event
+}
+
+// CreateImageAlbum creates the album, owned by the caller.
+func (i *ImageController) CreateImageAlbum(ctx context.Context, album dbmodels.Album) error {
+	principal, authenticated := auth.FromContext(ctx)
+
+	err := i.withAudit(ctx, albumEvent(dbmodels.AuditAlbumCreate, album.AlbumName), func(tx dbhandler.ImageStore) error {
+		if err := tx.CreateAlbum(ctx, album); err != nil {
+			return err
+		}
+
+		if !authenticated {
+			return nil
+		}
+
+		return tx.GrantAlbumAccess(ctx, dbmodels.AlbumAccess{
+			AlbumName:     album.AlbumName,
+			PrincipalType: dbmodels.PrincipalUser,
+			Principal:     principal.Subject,
+			Role:          dbmodels.RoleOwner,
+		})
+	})
 	if err != nil {
 		return fmt.Errorf("error while creating image album, %w", err)
 	}
@@ -37,8 +140,28 @@ func (i *ImageController) CreateImageAlbum(album dbmodels.Album) error {
 	return nil
 }
 
-func (i *ImageController) DeleteImageAlbum(albumName string) error {
-	err := i.imageStore.DeleteAlbum(albumName)
+// DeleteImageAlbum deletes the album together with its images and accesses in
+// a single transaction. Only the owners of the album can delete it.
+func (i *ImageController) DeleteImageAlbum(ctx context.Context, albumName string) error {
+	err := i.withAudit(ctx, albumEvent(dbmodels.AuditAlbumDelete, albumName), func(tx dbhandler.ImageStore) error {
+		if err := newAlbumAccess(ctx).require(ctx, tx, albumName, dbmodels.RoleOwner); err != nil {
+			return err
+		}
+
+		if err := tx.DeleteAllImagesOfAlbum(ctx, albumName); err != nil {
+			return err
+		}
+
+		if err := tx.DeleteAlbumAccess(ctx, albumName); err != nil {
+			return err
+		}
+
+		if err := tx.DeleteAlbumShares(ctx, albumName); err != nil {
+			return err
+		}
+
+		return tx.DeleteAlbum(ctx, albumName)
+	})
 	if err != nil {
 		return fmt.Errorf("error while deleting image album, %w", err)
 	}
@@ -46,8 +169,13 @@ func (i *ImageController) DeleteImageAlbum(albumName string) error {
 	return nil
 }
 
-func (i *ImageController) CreateImage(image dbmodels.Image) error {
-	err := i.imageStore.CreateImage(image)
+// CreateImage creates the image, unless it exceeds a quota of its album or
+// tenant.
+func (i *ImageController) CreateImage(ctx context.Context, image dbmodels.Image) error {
+	err := i.withAudit(ctx, imageEvent(dbmodels.AuditImageCreate, image.AlbumName, image.ImageName),
+		func(tx dbhandler.ImageStore) error {
+			return i.createImage(ctx, tx, newAlbumAccess(ctx), image)
+		})
 	if err != nil {
 		return fmt.Errorf("error while creating image, %w", err)
 	}
@@ -55,8 +183,23 @@ func (i *ImageController) CreateImage(image dbmodels.Image) error {
 	return nil
 }
 
-func (i *ImageController) DeleteImage(imageName, albumName string) error {
-	err := i.imageStore.DeleteImageWithImageName(imageName, albumName)
+func (i *ImageController) createImage(ctx context.Context, tx dbhandler.ImageStore, albumAccess *albumAccess,
+	image dbmodels.Image) error {
+	if err := albumAccess.require(ctx, tx, image.AlbumName, dbmodels.RoleEditor); err != nil {
+		return err
+	}
+
+	if err := i.checkQuotas(ctx, tx, image.AlbumName, 1, image.Size()); err != nil {
+		return err
+	}
+
+	return tx.CreateImage(ctx, image)
+}
+
+func (i *ImageController) DeleteImage(ctx context.Context, imageName, albumName string) error {
+	err := i.withAudit(ctx, imageEvent(dbmodels.AuditImageDelete, albumName, imageName), func(tx dbhandler.ImageStore) error {
+		return i.deleteImage(ctx, tx, newAlbumAccess(ctx), imageName, albumName)
+	})
 	if err != nil {
 		return fmt.Errorf("error while deleting image, %w", err)
 	}
@@ -64,8 +207,21 @@ func (i *ImageController) DeleteImage(imageName, albumName string) error {
 	return nil
 }
 
-func (i *ImageController) GetImage(id string) (dbmodels.Image, error) {
-	image, err := i.imageStore.GetImageByID(id)
+func (i *ImageController) deleteImage(ctx context.Context, tx dbhandler.ImageStore, albumAccess *albumAccess,
+	imageName, albumName string) error {
+	if err := albumAccess.require(ctx, tx, albumName, dbmodels.RoleEditor); err != nil {
+		return err
+	}
+
+	return tx.DeleteImageWithImageName(ctx, imageName, albumName)
+}
+
+func (i *ImageController) GetImage(ctx context.Context, id string) (dbmodels.Image, error) {
+	image, err := i.imageStore.GetImageByID(ctx, id)
+	if err == nil {
+		err = newAlbumAccess(ctx).require(ctx, i.imageStore, image.AlbumName, dbmodels.RoleViewer)
+	}
+
 	if err != nil {
 		return dbmodels.Image{}, fmt.Errorf("error while getting image, %w", err)
 	}
@@ -73,8 +229,12 @@ func (i *ImageController) GetImage(id string) (dbmodels.Image, error) {
 	return image, nil
 }
 
-func (i *ImageController) GetAllImages(albumName string) ([]dbmodels.Image, error) {
-	images, err := i.imageStore.GetAllImages(albumName)
+func (i *ImageController) GetAllImages(ctx context.Context, albumName string) ([]dbmodels.Image, error) {
+	if err := newAlbumAccess(ctx).require(ctx, i.imageStore, albumName, dbmodels.RoleViewer); err != nil {
+		return nil, fmt.Errorf("error while getting images of album %s, %w", albumName, err)
+	}
+
+	images, err := i.imageStore.GetAllImages(ctx, albumName)
 	if err != nil {
 		return nil, fmt.Errorf("error while getting images with "+
 			"album name = %s,error :  %w", albumName, err)
@@ -82,3 +242,352 @@ func (i *ImageController) GetAllImages(albumName string) ([]dbmodels.Image, erro
 
 	return images, nil
 }
+
+func (i *ImageController) GetImageAlbum(ctx context.Context, albumName string) (dbmodels.Album, error) {
+	err := newAlbumAccess(ctx).require(ctx, i.imageStore, albumName, dbmodels.RoleViewer)
+	if err != nil {
+		return dbmodels.Album{}, fmt.Errorf("error while getting image album, %w", err)
+	}
+
+	album, err := i.imageStore.GetAlbum(ctx, albumName)
+	if err != nil {
+		return dbmodels.Album{}, fmt.Errorf("error while getting image album, %w", err)
+	}
+
+	return album, nil
+}
+
+// ExportAlbumImages calls fn for each image of the album while they are read
+// from the database.
+func (i *ImageController) ExportAlbumImages(ctx context.Context, albumName string, fn func(dbmodels.Image) error) error {
+	err := newAlbumAccess(ctx).require(ctx, i.imageStore, albumName, dbmodels.RoleViewer)
+	if err == nil {
+		err = i.imageStore.StreamImages(ctx, albumName, fn)
+	}
+
+	if err != nil {
+		return fmt.Errorf("error while exporting images of album %s, %w", albumName, err)
+	}
+
+	return nil
+}
+
+// ListImageAlbums lists the albums the caller can view.
+func (i *ImageController) ListImageAlbums(ctx context.Context) ([]dbmodels.Album, error) {
+	visible, err := newAlbumAccess(ctx).visibleAlbums(ctx, i.imageStore)
+	if err != nil {
+		return nil, fmt.Errorf("error while listing image albums, %w", err)
+	}
+
+	albums, err := i.imageStore.ListAlbums(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("error while listing image albums, %w", err)
+	}
+
+	if visible == nil {
+		return albums, nil
+	}
+
+	filtered := make([]dbmodels.Album, 0, len(visible))
+	for _, album := range albums {
+		if visible[album.AlbumName] {
+			filtered = append(filtered, album)
+		}
+	}
+
+	return filtered, nil
+}
+
+func (i *ImageController) GetAlbumImage(ctx context.Context, albumName, imageName string) (dbmodels.Image, error) {
+	err := newAlbumAccess(ctx).require(ctx, i.imageStore, albumName, dbmodels.RoleViewer)
+	if err != nil {
+		return dbmodels.Image{}, fmt.Errorf("error while getting image, %w", err)
+	}
+
+	image, err := i.imageStore.GetAlbumImage(ctx, albumName, imageName)
+	if err != nil {
+		return dbmodels.Image{}, fmt.Errorf("error while getting image, %w", err)
+	}
+
+	return image, nil
+}
+
+// UpdateImage replaces the image, unless its new content exceeds a storage
+// quota. It renames the image when image has another name than key, the
+// image stays in the album of key.
+func (i *ImageController) UpdateImage(ctx context.Context, key dbmodels.ImageKey, image dbmodels.Image) error {
+	err := i.withAudit(ctx, updateEvent(key, image), func(tx dbhandler.ImageStore) error {
+		return i.updateImage(ctx, tx, key, image)
+	})
+	if err != nil {
+		return fmt.Errorf("error while updating image, %w", err)
+	}
+
+	return nil
+}
+
+func (i *ImageController) updateImage(ctx context.Context, tx dbhandler.ImageStore, key dbmodels.ImageKey,
+	image dbmodels.Image) error {
+	if err := newAlbumAccess(ctx).require(ctx, tx, key.AlbumName, dbmodels.RoleEditor); err != nil {
+		return err
+	}
+
+	if i.limitsBytes() {
+		current, err := tx.GetAlbumImage(ctx, key.AlbumName, key.ImageName)
+		if err != nil {
+			return err
+		}
+
+		if err = i.checkQuotas(ctx, tx, key.AlbumName, 0, image.Size()-current.Size()); err != nil {
+			return err
+		}
+	}
+
+	return tx.UpdateImage(ctx, key, image)
+}
+
+// PutImage replaces the content of the image, or creates it when it does not
+// exist yet, in a single transaction. It reports whether the image was
+// created.
+func (i *ImageController) PutImage(ctx context.Context, image dbmodels.Image) (bool, error) {
+	key := dbmodels.ImageKey{
+		ImageName: image.ImageName,
+		AlbumName: image.AlbumName,
+	}
+
+	var (
+		created bool
+		event   dbmodels.AuditEvent
+	)
+
+	err := i.imageStore.WithTx(ctx, func(tx dbhandler.ImageStore) error {
+		created, event = false, updateEvent(key, image)
+
+		err := i.updateImage(ctx, tx, key, image)
+		if errors.Is(err, dbhandler.ErrNoDataFound) {
+			created, event = true, imageEvent(dbmodels.AuditImageCreate, image.AlbumName, image.ImageName)
+			err = i.createImage(ctx, tx, newAlbumAccess(ctx), image)
+		}
+
+		if err != nil {
+			return err
+		}
+
+		return recordSuccess(ctx, tx, i.now(), event)
+	})
+	if err != nil {
+		i.auditFailure(ctx, event, err)
+
+		if created {
+			return false, fmt.Errorf("error while creating image, %w", err)
+		}
+
+		return false, fmt.Errorf("error while updating image, %w", err)
+	}
+
+	return created, nil
+}
+
+// GetImagesOfAlbums returns the images of the albums, keyed by album name,
+// using a single lookup for all of them. The albums the caller can not view
+// have no images.
+func (i *ImageController) GetImagesOfAlbums(ctx context.Context, albumNames []string) (map[string][]dbmodels.Image, error) {
+	visible, err := newAlbumAccess(ctx).visibleAlbums(ctx, i.imageStore)
+	if err != nil {
+		return nil, fmt.Errorf("error while getting images of albums, %w", err)
+	}
+
+	if visible != nil {
+		filtered := make([]string, 0, len(albumNames))
+		for _, name := range albumNames {
+			if visible[name] {
+				filtered = append(filtered, name)
+			}
+		}
+
+		albumNames = filtered
+	}
+
+	images, err := i.imageStore.GetImagesOfAlbums(ctx, albumNames)
+	if err != nil {
+		return nil, fmt.Errorf("error while getting images of albums, %w", err)
+	}
+
+	byAlbum := make(map[string][]dbmodels.Image, len(albumNames))
+	for _, image := range images {
+		byAlbum[image.AlbumName] = append(byAlbum[image.AlbumName], image)
+	}
+
+	return byAlbum, nil
+}
+
+// FilterImages returns the images of the album whose metadata is selected by
+// all the filters.
+func (i *ImageController) FilterImages(ctx context.Context, albumName string,
+	filters []dbmodels.MetadataFilter) ([]dbmodels.Image, error) {
+	if err := newAlbumAccess(ctx).require(ctx, i.imageStore, albumName, dbmodels.RoleViewer); err != nil {
+		return nil, fmt.Errorf("error while filtering images of album %s, %w", albumName, err)
+	}
+
+	images, err := i.imageStore.FilterImages(ctx, albumName, filters)
+	if err != nil {
+		return nil, fmt.Errorf("error while filtering images of album %s, %w", albumName, err)
+	}
+
+	return images, nil
+}
+
+// SearchImages returns a page of the images matching text, best match first,
+// and the number of matches of all pages. Only the albums the caller can view
+// are searched.
+func (i *ImageController) SearchImages(ctx context.Context, text string, limit, offset int) ([]dbmodels.ImageMatch, int, error) {
+	visible, err := newAlbumAccess(ctx).visibleAlbums(ctx, i.imageStore)
+	if err != nil {
+		return nil, 0, fmt.Errorf("error while searching images, %w", err)
+	}
+
+	var albumNames []string
+
+	if visible != nil {
+		if len(visible) == 0 {
+			return []dbmodels.ImageMatch{}, 0, nil
+		}
+
+		for name := range visible {
+			albumNames = append(albumNames, name)
+		}
+
+		sort.Strings(albumNames)
+	}
+
+	matches, total, err := i.imageStore.SearchImages(ctx, text, albumNames, limit, offset)
+	if err != nil {
+		return nil, 0, fmt.Errorf("error while searching images, %w", err)
+	}
+
+	return matches, total, nil
+}
+
+// CreateImages creates the images and returns one error per image, nil for
+// the images which were created. When allOrNothing is set the images are
+// created in a single transaction, otherwise each image is created on its own.
+func (i *ImageController) CreateImages(ctx context.Context, images []dbmodels.Image, allOrNothing bool) []error {
+	if !allOrNothing {
+		errs := make([]error, len(images))
+		for idx, image := range images {
+			errs[idx] = i.CreateImage(ctx, image)
+		}
+
+		return errs
+	}
+
+	events := make([]dbmodels.AuditEvent, len(images))
+	for idx, image := range images {
+		events[idx] = imageEvent(dbmodels.AuditImageCreate, image.AlbumName, image.ImageName)
+	}
+
+	err := i.imageStore.WithTx(ctx, func(tx dbhandler.ImageStore) error {
+		// The roles are read again when the transaction is retried.
+		albumAccess := newAlbumAccess(ctx)
+
+		for idx, image := range images {
+			if err := i.createImage(ctx, tx, albumAccess, image); err != nil {
+				return &dbhandler.BatchError{Index: idx, Err: err}
+			}
+		}
+
+		return i.recordBatchSuccess(ctx, tx, events)
+	})
+
+	return i.batchResult(ctx, events, err, "error while creating image")
+}
+
+// DeleteImages deletes the images and returns one error per image, nil for
+// the images which were deleted. When allOrNothing is set the images are
+// deleted in a single transaction, otherwise each image is deleted on its own.
+func (i *ImageController) DeleteImages(ctx context.Context, keys []dbmodels.ImageKey, allOrNothing bool) []error {
+	if !allOrNothing {
+		errs := make([]error, len(keys))
+		for idx, key := range keys {
+			errs[idx] = i.DeleteImage(ctx, key.ImageName, key.AlbumName)
+		}
+
+		return errs
+	}
+
+	events := make([]dbmodels.AuditEvent, len(keys))
+	for idx, key := range keys {
+		events[idx] = imageEvent(dbmodels.AuditImageDelete, key.AlbumName, key.ImageName)
+	}
+
+	err := i.imageStore.WithTx(ctx, func(tx dbhandler.ImageStore) error {
+		albumAccess := newAlbumAccess(ctx)
+
+		for idx, key := range keys {
+			if err := i.deleteImage(ctx, tx, albumAccess, key.ImageName, key.AlbumName); err != nil {
+				return &dbhandler.BatchError{Index: idx, Err: err}
+			}
+		}
+
+		return i.recordBatchSuccess(ctx, tx, events)
+	})
+
+	return i.batchResult(ctx, events, err, "error while deleting image")
+}
+
+// recordBatchSuccess records the events of the items of a transactional
+// batch in its transaction tx.
+func (i *ImageController) recordBatchSuccess(ctx context.Context, tx dbhandler.ImageStore,
+	events []dbmodels.AuditEvent) error {
+	for _, event := range events {
+		if err := recordSuccess(ctx, tx, i.now(), event); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// batchResult returns the errors of the items of a transactional batch which
+// failed with err, prefixed with message, and records their failure in the
+// audit log.
+func (i *ImageController) batchResult(ctx context.Context, events []dbmodels.AuditEvent, err error,
+	message string) []error {
+	if err != nil {
+		err = fmt.Errorf("%s, %w", message, err)
+	}
+
+	errs := batchErrors(len(events), err)
+	if err != nil {
+		for idx, event := range events {
+			i.auditFailure(ctx, event, errs[idx])
+		}
+	}
+
+	return errs
+}
+
+// batchErrors spreads the error of a transactional batch over its items. The
+// failing item gets the error, the others get ErrBatchAborted.
+func batchErrors(size int, err error) []error {
+	errs := make([]error, size)
+	if err == nil {
+		return errs
+	}
+
+	var batchErr *dbhandler.BatchError
+	if !errors.As(err, &batchErr) {
+		for idx := range errs {
+			errs[idx] = err
+		}
+
+		return errs
+	}
+
+	for idx := range errs {
+		errs[idx] = ErrBatchAborted
+	}
+	errs[batchErr.Index] = err
+
+	return errs
+}
@@ -5,6 +5,7 @@
 package controller
 
 import (
+	context "context"
 	reflect "reflect"
 
 	gomock "github.com/golang/mock/gomock"
@@ -34,88 +35,368 @@ func (m *MockImageStore) EXPECT() *MockImageStoreMockRecorder {
 	return m.recorder
 }
 
+// CreateAlbumShare mocks base method.
+func (m *MockImageStore) CreateAlbumShare(ctx context.Context, share dbmodels.AlbumShare, password string) (string, dbmodels.AlbumShare, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateAlbumShare", ctx, share, password)
+	ret0, _ := ret[0].(string)
+	ret1, _ := ret[1].(dbmodels.AlbumShare)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// CreateAlbumShare indicates an expected call of CreateAlbumShare.
+func (mr *MockImageStoreMockRecorder) CreateAlbumShare(ctx, share, password interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateAlbumShare", reflect.TypeOf((*MockImageStore)(nil).CreateAlbumShare), ctx, share, password)
+}
+
 // CreateImage mocks base method.
-func (m *MockImageStore) CreateImage(image dbmodels.Image) error {
+func (m *MockImageStore) CreateImage(ctx context.Context, image dbmodels.Image) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "CreateImage", image)
+	ret := m.ctrl.Call(m, "CreateImage", ctx, image)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
 // CreateImage indicates an expected call of CreateImage.
-func (mr *MockImageStoreMockRecorder) CreateImage(image interface{}) *gomock.Call {
+func (mr *MockImageStoreMockRecorder) CreateImage(ctx, image interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateImage", reflect.TypeOf((*MockImageStore)(nil).CreateImage), image)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateImage", reflect.TypeOf((*MockImageStore)(nil).CreateImage), ctx, image)
 }
 
 // CreateImageAlbum mocks base method.
-func (m *MockImageStore) CreateImageAlbum(album dbmodels.Album) error {
+func (m *MockImageStore) CreateImageAlbum(ctx context.Context, album dbmodels.Album) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "CreateImageAlbum", album)
+	ret := m.ctrl.Call(m, "CreateImageAlbum", ctx, album)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
 // CreateImageAlbum indicates an expected call of CreateImageAlbum.
-func (mr *MockImageStoreMockRecorder) CreateImageAlbum(album interface{}) *gomock.Call {
+func (mr *MockImageStoreMockRecorder) CreateImageAlbum(ctx, album interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateImageAlbum", reflect.TypeOf((*MockImageStore)(nil).CreateImageAlbum), album)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateImageAlbum", reflect.TypeOf((*MockImageStore)(nil).CreateImageAlbum), ctx, album)
+}
+
+// CreateImages mocks base method.
+func (m *MockImageStore) CreateImages(ctx context.Context, images []dbmodels.Image, allOrNothing bool) []error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateImages", ctx, images, allOrNothing)
+	ret0, _ := ret[0].([]error)
+	return ret0
+}
+
+// CreateImages indicates an expected call of CreateImages.
+func (mr *MockImageStoreMockRecorder) CreateImages(ctx, images, allOrNothing interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateImages", reflect.TypeOf((*MockImageStore)(nil).CreateImages), ctx, images, allOrNothing)
 }
 
 // DeleteImage mocks base method.
-func (m *MockImageStore) DeleteImage(imageName, albumName string) error {
+func (m *MockImageStore) DeleteImage(ctx context.Context, imageName, albumName string) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "DeleteImage", imageName, albumName)
+	ret := m.ctrl.Call(m, "DeleteImage", ctx, imageName, albumName)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
 // DeleteImage indicates an expected call of DeleteImage.
-func (mr *MockImageStoreMockRecorder) DeleteImage(imageName, albumName interface{}) *gomock.Call {
+func (mr *MockImageStoreMockRecorder) DeleteImage(ctx, imageName, albumName interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteImage", reflect.TypeOf((*MockImageStore)(nil).DeleteImage), imageName, albumName)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteImage", reflect.TypeOf((*MockImageStore)(nil).DeleteImage), ctx, imageName, albumName)
 }
 
 // DeleteImageAlbum mocks base method.
-func (m *MockImageStore) DeleteImageAlbum(albumName string) error {
+func (m *MockImageStore) DeleteImageAlbum(ctx context.Context, albumName string) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "DeleteImageAlbum", albumName)
+	ret := m.ctrl.Call(m, "DeleteImageAlbum", ctx, albumName)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
 // DeleteImageAlbum indicates an expected call of DeleteImageAlbum.
-func (mr *MockImageStoreMockRecorder) DeleteImageAlbum(albumName interface{}) *gomock.Call {
+func (mr *MockImageStoreMockRecorder) DeleteImageAlbum(ctx, albumName interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteImageAlbum", reflect.TypeOf((*MockImageStore)(nil).DeleteImageAlbum), ctx, albumName)
+}
+
+// DeleteImages mocks base method.
+func (m *MockImageStore) DeleteImages(ctx context.Context, keys []dbmodels.ImageKey, allOrNothing bool) []error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteImages", ctx, keys, allOrNothing)
+	ret0, _ := ret[0].([]error)
+	return ret0
+}
+
+// DeleteImages indicates an expected call of DeleteImages.
+func (mr *MockImageStoreMockRecorder) DeleteImages(ctx, keys, allOrNothing interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteImages", reflect.TypeOf((*MockImageStore)(nil).DeleteImages), ctx, keys, allOrNothing)
+}
+
+// ExportAlbumImages mocks base method.
+func (m *MockImageStore) ExportAlbumImages(ctx context.Context, albumName string, fn func(dbmodels.Image) error) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ExportAlbumImages", ctx, albumName, fn)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ExportAlbumImages indicates an expected call of ExportAlbumImages.
+func (mr *MockImageStoreMockRecorder) ExportAlbumImages(ctx, albumName, fn interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ExportAlbumImages", reflect.TypeOf((*MockImageStore)(nil).ExportAlbumImages), ctx, albumName, fn)
+}
+
+// FilterImages mocks base method.
+func (m *MockImageStore) FilterImages(ctx context.Context, albumName string, filters []dbmodels.MetadataFilter) ([]dbmodels.Image, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FilterImages", ctx, albumName, filters)
+	ret0, _ := ret[0].([]dbmodels.Image)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// FilterImages indicates an expected call of FilterImages.
+func (mr *MockImageStoreMockRecorder) FilterImages(ctx, albumName, filters interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteImageAlbum", reflect.TypeOf((*MockImageStore)(nil).DeleteImageAlbum), albumName)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FilterImages", reflect.TypeOf((*MockImageStore)(nil).FilterImages), ctx, albumName, filters)
+}
+
+// GetAlbumImage mocks base method.
+func (m *MockImageStore) GetAlbumImage(ctx context.Context, albumName, imageName string) (dbmodels.Image, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetAlbumImage", ctx, albumName, imageName)
+	ret0, _ := ret[0].(dbmodels.Image)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetAlbumImage indicates an expected call of GetAlbumImage.
+func (mr *MockImageStoreMockRecorder) GetAlbumImage(ctx, albumName, imageName interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetAlbumImage", reflect.TypeOf((*MockImageStore)(nil).GetAlbumImage), ctx, albumName, imageName)
 }
 
 // GetAllImages mocks base method.
-func (m *MockImageStore) GetAllImages(albumName string) ([]dbmodels.Image, error) {
+func (m *MockImageStore) GetAllImages(ctx context.Context, albumName string) ([]dbmodels.Image, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "GetAllImages", albumName)
+	ret := m.ctrl.Call(m, "GetAllImages", ctx, albumName)
 	ret0, _ := ret[0].([]dbmodels.Image)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // GetAllImages indicates an expected call of GetAllImages.
-func (mr *MockImageStoreMockRecorder) GetAllImages(albumName interface{}) *gomock.Call {
+func (mr *MockImageStoreMockRecorder) GetAllImages(ctx, albumName interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetAllImages", reflect.TypeOf((*MockImageStore)(nil).GetAllImages), albumName)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetAllImages", reflect.TypeOf((*MockImageStore)(nil).GetAllImages), ctx, albumName)
 }
 
 // GetImage mocks base method.
-func (m *MockImageStore) GetImage(id string) (dbmodels.Image, error) {
+func (m *MockImageStore) GetImage(ctx context.Context, id string) (dbmodels.Image, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "GetImage", id)
+	ret := m.ctrl.Call(m, "GetImage", ctx, id)
 	ret0, _ := ret[0].(dbmodels.Image)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // GetImage indicates an expected call of GetImage.
-func (mr *MockImageStoreMockRecorder) GetImage(id interface{}) *gomock.Call {
+func (mr *MockImageStoreMockRecorder) GetImage(ctx, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetImage", reflect.TypeOf((*MockImageStore)(nil).GetImage), ctx, id)
+}
+
+// GetImageAlbum mocks base method.
+func (m *MockImageStore) GetImageAlbum(ctx context.Context, albumName string) (dbmodels.Album, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetImageAlbum", ctx, albumName)
+	ret0, _ := ret[0].(dbmodels.Album)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetImageAlbum indicates an expected call of GetImageAlbum.
+func (mr *MockImageStoreMockRecorder) GetImageAlbum(ctx, albumName interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetImageAlbum", reflect.TypeOf((*MockImageStore)(nil).GetImageAlbum), ctx, albumName)
+}
+
+// GetImagesOfAlbums mocks base method.
+func (m *MockImageStore) GetImagesOfAlbums(ctx context.Context, albumNames []string) (map[string][]dbmodels.Image, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetImagesOfAlbums", ctx, albumNames)
+	ret0, _ := ret[0].(map[string][]dbmodels.Image)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetImagesOfAlbums indicates an expected call of GetImagesOfAlbums.
+func (mr *MockImageStoreMockRecorder) GetImagesOfAlbums(ctx, albumNames interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetImagesOfAlbums", reflect.TypeOf((*MockImageStore)(nil).GetImagesOfAlbums), ctx, albumNames)
+}
+
+// GetUsage mocks base method.
+func (m *MockImageStore) GetUsage(ctx context.Context) (UsageReport, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetUsage", ctx)
+	ret0, _ := ret[0].(UsageReport)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetUsage indicates an expected call of GetUsage.
+func (mr *MockImageStoreMockRecorder) GetUsage(ctx interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetUsage", reflect.TypeOf((*MockImageStore)(nil).GetUsage), ctx)
+}
+
+// GrantAlbumAccess mocks base method.
+func (m *MockImageStore) GrantAlbumAccess(ctx context.Context, access dbmodels.AlbumAccess) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GrantAlbumAccess", ctx, access)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// GrantAlbumAccess indicates an expected call of GrantAlbumAccess.
+func (mr *MockImageStoreMockRecorder) GrantAlbumAccess(ctx, access interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GrantAlbumAccess", reflect.TypeOf((*MockImageStore)(nil).GrantAlbumAccess), ctx, access)
+}
+
+// ListAlbumAccess mocks base method.
+func (m *MockImageStore) ListAlbumAccess(ctx context.Context, albumName string) ([]dbmodels.AlbumAccess, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListAlbumAccess", ctx, albumName)
+	ret0, _ := ret[0].([]dbmodels.AlbumAccess)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListAlbumAccess indicates an expected call of ListAlbumAccess.
+func (mr *MockImageStoreMockRecorder) ListAlbumAccess(ctx, albumName interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListAlbumAccess", reflect.TypeOf((*MockImageStore)(nil).ListAlbumAccess), ctx, albumName)
+}
+
+// ListAlbumShares mocks base method.
+func (m *MockImageStore) ListAlbumShares(ctx context.Context, albumName string) ([]dbmodels.AlbumShare, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListAlbumShares", ctx, albumName)
+	ret0, _ := ret[0].([]dbmodels.AlbumShare)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListAlbumShares indicates an expected call of ListAlbumShares.
+func (mr *MockImageStoreMockRecorder) ListAlbumShares(ctx, albumName interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListAlbumShares", reflect.TypeOf((*MockImageStore)(nil).ListAlbumShares), ctx, albumName)
+}
+
+// ListImageAlbums mocks base method.
+func (m *MockImageStore) ListImageAlbums(ctx context.Context) ([]dbmodels.Album, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListImageAlbums", ctx)
+	ret0, _ := ret[0].([]dbmodels.Album)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListImageAlbums indicates an expected call of ListImageAlbums.
+func (mr *MockImageStoreMockRecorder) ListImageAlbums(ctx interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListImageAlbums", reflect.TypeOf((*MockImageStore)(nil).ListImageAlbums), ctx)
+}
+
+// OpenAlbumShare mocks base method.
+func (m *MockImageStore) OpenAlbumShare(ctx context.Context, token, password string) (dbmodels.AlbumShare, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "OpenAlbumShare", ctx, token, password)
+	ret0, _ := ret[0].(dbmodels.AlbumShare)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// OpenAlbumShare indicates an expected call of OpenAlbumShare.
+func (mr *MockImageStoreMockRecorder) OpenAlbumShare(ctx, token, password interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "OpenAlbumShare", reflect.TypeOf((*MockImageStore)(nil).OpenAlbumShare), ctx, token, password)
+}
+
+// PutImage mocks base method.
+func (m *MockImageStore) PutImage(ctx context.Context, image dbmodels.Image) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "PutImage", ctx, image)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// PutImage indicates an expected call of PutImage.
+func (mr *MockImageStoreMockRecorder) PutImage(ctx, image interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PutImage", reflect.TypeOf((*MockImageStore)(nil).PutImage), ctx, image)
+}
+
+// RevokeAlbumAccess mocks base method.
+func (m *MockImageStore) RevokeAlbumAccess(ctx context.Context, albumName, principalType, principal string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RevokeAlbumAccess", ctx, albumName, principalType, principal)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RevokeAlbumAccess indicates an expected call of RevokeAlbumAccess.
+func (mr *MockImageStoreMockRecorder) RevokeAlbumAccess(ctx, albumName, principalType, principal interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RevokeAlbumAccess", reflect.TypeOf((*MockImageStore)(nil).RevokeAlbumAccess), ctx, albumName, principalType, principal)
+}
+
+// RevokeAlbumShare mocks base method.
+func (m *MockImageStore) RevokeAlbumShare(ctx context.Context, albumName, id string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RevokeAlbumShare", ctx, albumName, id)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RevokeAlbumShare indicates an expected call of RevokeAlbumShare.
+func (mr *MockImageStoreMockRecorder) RevokeAlbumShare(ctx, albumName, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RevokeAlbumShare", reflect.TypeOf((*MockImageStore)(nil).RevokeAlbumShare), ctx, albumName, id)
+}
+
+// SearchImages mocks base method.
+func (m *MockImageStore) SearchImages(ctx context.Context, text string, limit, offset int) ([]dbmodels.ImageMatch, int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SearchImages", ctx, text, limit, offset)
+	ret0, _ := ret[0].([]dbmodels.ImageMatch)
+	ret1, _ := ret[1].(int)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// SearchImages indicates an expected call of SearchImages.
+func (mr *MockImageStoreMockRecorder) SearchImages(ctx, text, limit, offset interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SearchImages", reflect.TypeOf((*MockImageStore)(nil).SearchImages), ctx, text, limit, offset)
+}
+
+// UpdateImage mocks base method.
+func (m *MockImageStore) UpdateImage(ctx context.Context, key dbmodels.ImageKey, image dbmodels.Image) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateImage", ctx, key, image)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpdateImage indicates an expected call of UpdateImage.
+func (mr *MockImageStoreMockRecorder) UpdateImage(ctx, key, image interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetImage", reflect.TypeOf((*MockImageStore)(nil).GetImage), id)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateImage", reflect.TypeOf((*MockImageStore)(nil).UpdateImage), ctx, key, image)
 }
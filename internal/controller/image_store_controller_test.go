@@ -1,13 +1,16 @@
 package controller
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"github.com/golang/mock/gomock"
 	"github.com/sirupsen/logrus"
 	"github.com/stretchr/testify/assert"
+	"githum.com/anupam111/image-store/internal/config"
 	"githum.com/anupam111/image-store/internal/db/dbhandler"
 	"githum.com/anupam111/image-store/internal/db/dbmodels"
+	"githum.com/anupam111/image-store/internal/db/memstore"
 	"testing"
 )
 
@@ -28,9 +31,22 @@ func testSetUp(t *testing.T) (
 	return mockCtrl, mockHandler, NewImageController(
 		log,
 		mockHandler,
+		memstore.NewMemStore(),
+		config.Quotas{},
 	)
 }
 
+// expectTx makes WithTx run its callback on the mock itself, which records
+// the audit events of the callback.
+func expectTx(subs *dbhandler.MockImageStore) {
+	subs.EXPECT().WithTx(gomock.Any(), gomock.Any()).DoAndReturn(
+		func(ctx context.Context, fn func(tx dbhandler.ImageStore) error) error {
+			return fn(subs)
+		},
+	).AnyTimes()
+	subs.EXPECT().CreateAuditEvent(gomock.Any(), gomock.Any()).Return(nil).AnyTimes()
+}
+
 func TestCreateAlbum(t *testing.T) {
 	t.Parallel()
 
@@ -52,7 +68,8 @@ func TestCreateAlbum(t *testing.T) {
 			prepare: func(
 				subs *dbhandler.MockImageStore,
 			) {
-				subs.EXPECT().CreateAlbum(album).Return(nil)
+				expectTx(subs)
+				subs.EXPECT().CreateAlbum(gomock.Any(), album).Return(nil)
 			},
 			expectedError: nil,
 		},
@@ -62,7 +79,8 @@ func TestCreateAlbum(t *testing.T) {
 			prepare: func(
 				subs *dbhandler.MockImageStore,
 			) {
-				subs.EXPECT().CreateAlbum(album).Return(errFake)
+				expectTx(subs)
+				subs.EXPECT().CreateAlbum(gomock.Any(), album).Return(errFake)
 			},
 			expectedError: fmt.Errorf("error while creating image album, %w", errFake),
 		},
@@ -77,7 +95,7 @@ func TestCreateAlbum(t *testing.T) {
 				tt.prepare(mockDbHandler)
 			}
 
-			err := controller.CreateImageAlbum(tt.input)
+			err := controller.CreateImageAlbum(context.Background(), tt.input)
 			assert.Equal(t, tt.expectedError, err)
 		})
 	}
@@ -105,7 +123,8 @@ func TestCreateImage(t *testing.T) {
 			prepare: func(
 				subs *dbhandler.MockImageStore,
 			) {
-				subs.EXPECT().CreateImage(image).Return(nil)
+				expectTx(subs)
+				subs.EXPECT().CreateImage(gomock.Any(), image).Return(nil)
 			},
 			expectedError: nil,
 		},
@@ -115,7 +134,8 @@ func TestCreateImage(t *testing.T) {
 			prepare: func(
 				subs *dbhandler.MockImageStore,
 			) {
-				subs.EXPECT().CreateImage(image).Return(errFake)
+				expectTx(subs)
+				subs.EXPECT().CreateImage(gomock.Any(), image).Return(errFake)
 			},
 			expectedError: fmt.Errorf("error while creating image, %w", errFake),
 		},
@@ -130,7 +150,7 @@ func TestCreateImage(t *testing.T) {
 				tt.prepare(mockDbHandler)
 			}
 
-			err := controller.CreateImage(tt.input)
+			err := controller.CreateImage(context.Background(), tt.input)
 			assert.Equal(t, tt.expectedError, err)
 		})
 	}
@@ -151,7 +171,11 @@ func TestDeleteAlbum(t *testing.T) {
 			prepare: func(
 				subs *dbhandler.MockImageStore,
 			) {
-				subs.EXPECT().DeleteAlbum("test-album").Return(nil)
+				expectTx(subs)
+				subs.EXPECT().DeleteAllImagesOfAlbum(gomock.Any(), "test-album").Return(nil)
+				subs.EXPECT().DeleteAlbumAccess(gomock.Any(), "test-album").Return(nil)
+				subs.EXPECT().DeleteAlbumShares(gomock.Any(), "test-album").Return(nil)
+				subs.EXPECT().DeleteAlbum(gomock.Any(), "test-album").Return(nil)
 			},
 			expectedError: nil,
 		},
@@ -160,7 +184,21 @@ func TestDeleteAlbum(t *testing.T) {
 			prepare: func(
 				subs *dbhandler.MockImageStore,
 			) {
-				subs.EXPECT().DeleteAlbum("test-album").Return(errFake)
+				expectTx(subs)
+				subs.EXPECT().DeleteAllImagesOfAlbum(gomock.Any(), "test-album").Return(nil)
+				subs.EXPECT().DeleteAlbumAccess(gomock.Any(), "test-album").Return(nil)
+				subs.EXPECT().DeleteAlbumShares(gomock.Any(), "test-album").Return(nil)
+				subs.EXPECT().DeleteAlbum(gomock.Any(), "test-album").Return(errFake)
+			},
+			expectedError: fmt.Errorf("error while deleting image album, %w", errFake),
+		},
+		{
+			name: "images_error",
+			prepare: func(
+				subs *dbhandler.MockImageStore,
+			) {
+				expectTx(subs)
+				subs.EXPECT().DeleteAllImagesOfAlbum(gomock.Any(), "test-album").Return(errFake)
 			},
 			expectedError: fmt.Errorf("error while deleting image album, %w", errFake),
 		},
@@ -175,7 +213,7 @@ func TestDeleteAlbum(t *testing.T) {
 				tt.prepare(mockDbHandler)
 			}
 
-			err := controller.DeleteImageAlbum("test-album")
+			err := controller.DeleteImageAlbum(context.Background(), "test-album")
 			assert.Equal(t, tt.expectedError, err)
 		})
 	}
@@ -196,7 +234,8 @@ func TestDeleteImage(t *testing.T) {
 			prepare: func(
 				subs *dbhandler.MockImageStore,
 			) {
-				subs.EXPECT().DeleteImageWithImageName("test-image", "test-album").Return(nil)
+				expectTx(subs)
+				subs.EXPECT().DeleteImageWithImageName(gomock.Any(), "test-image", "test-album").Return(nil)
 			},
 			expectedError: nil,
 		},
@@ -205,7 +244,8 @@ func TestDeleteImage(t *testing.T) {
 			prepare: func(
 				subs *dbhandler.MockImageStore,
 			) {
-				subs.EXPECT().DeleteImageWithImageName("test-image", "test-album").Return(errFake)
+				expectTx(subs)
+				subs.EXPECT().DeleteImageWithImageName(gomock.Any(), "test-image", "test-album").Return(errFake)
 			},
 			expectedError: fmt.Errorf("error while deleting image, %w", errFake),
 		},
@@ -220,7 +260,7 @@ func TestDeleteImage(t *testing.T) {
 				tt.prepare(mockDbHandler)
 			}
 
-			err := controller.DeleteImage("test-image", "test-album")
+			err := controller.DeleteImage(context.Background(), "test-image", "test-album")
 			assert.Equal(t, tt.expectedError, err)
 		})
 	}
@@ -242,7 +282,7 @@ func TestGetImageByID(t *testing.T) {
 			prepare: func(
 				subs *dbhandler.MockImageStore,
 			) {
-				subs.EXPECT().GetImageByID("test-image").Return(dbmodels.Image{}, nil)
+				subs.EXPECT().GetImageByID(gomock.Any(), "test-image").Return(dbmodels.Image{}, nil)
 			},
 			expectedError: nil,
 			expectedImage: dbmodels.Image{},
@@ -252,7 +292,7 @@ func TestGetImageByID(t *testing.T) {
 			prepare: func(
 				subs *dbhandler.MockImageStore,
 			) {
-				subs.EXPECT().GetImageByID("test-image").Return(dbmodels.Image{}, errFake)
+				subs.EXPECT().GetImageByID(gomock.Any(), "test-image").Return(dbmodels.Image{}, errFake)
 			},
 			expectedError: fmt.Errorf("error while getting image, %w", errFake),
 			expectedImage: dbmodels.Image{},
@@ -268,7 +308,7 @@ func TestGetImageByID(t *testing.T) {
 				tt.prepare(mockDbHandler)
 			}
 
-			image, err := controller.GetImage("test-image")
+			image, err := controller.GetImage(context.Background(), "test-image")
 			assert.Equal(t, tt.expectedError, err)
 			assert.Equal(t, tt.expectedImage, image)
 		})
@@ -291,7 +331,7 @@ func TestGetAllImages(t *testing.T) {
 			prepare: func(
 				subs *dbhandler.MockImageStore,
 			) {
-				subs.EXPECT().GetAllImages("test-album").Return(nil, nil)
+				subs.EXPECT().GetAllImages(gomock.Any(), "test-album").Return(nil, nil)
 			},
 			expectedError: nil,
 			expectedImage: nil,
@@ -301,7 +341,7 @@ func TestGetAllImages(t *testing.T) {
 			prepare: func(
 				subs *dbhandler.MockImageStore,
 			) {
-				subs.EXPECT().GetAllImages("test-album").Return(nil, errFake)
+				subs.EXPECT().GetAllImages(gomock.Any(), "test-album").Return(nil, errFake)
 			},
 			expectedError: fmt.Errorf("error while getting images with album name = test-album,error :  %w", errFake),
 			expectedImage: nil,
@@ -317,9 +357,422 @@ func TestGetAllImages(t *testing.T) {
 				tt.prepare(mockDbHandler)
 			}
 
-			image, err := controller.GetAllImages("test-album")
+			image, err := controller.GetAllImages(context.Background(), "test-album")
 			assert.Equal(t, tt.expectedError, err)
 			assert.Equal(t, tt.expectedImage, image)
 		})
 	}
 }
+
+func TestCreateImages(t *testing.T) {
+	t.Parallel()
+
+	images := []dbmodels.Image{
+		{AlbumName: "test-album", ImageName: "image-1"},
+		{AlbumName: "test-album", ImageName: "image-2"},
+	}
+
+	tests := []struct {
+		name         string
+		allOrNothing bool
+		prepare      func(
+			subs *dbhandler.MockImageStore,
+		)
+		expectedErrors []error
+	}{
+		{
+			name: "best_effort",
+			prepare: func(
+				subs *dbhandler.MockImageStore,
+			) {
+				expectTx(subs)
+				subs.EXPECT().CreateImage(gomock.Any(), images[0]).Return(nil)
+				subs.EXPECT().CreateImage(gomock.Any(), images[1]).Return(errFake)
+			},
+			expectedErrors: []error{nil, fmt.Errorf("error while creating image, %w", errFake)},
+		},
+		{
+			name:         "all_or_nothing_success",
+			allOrNothing: true,
+			prepare: func(
+				subs *dbhandler.MockImageStore,
+			) {
+				expectTx(subs)
+				subs.EXPECT().CreateImage(gomock.Any(), images[0]).Return(nil)
+				subs.EXPECT().CreateImage(gomock.Any(), images[1]).Return(nil)
+			},
+			expectedErrors: []error{nil, nil},
+		},
+		{
+			name:         "all_or_nothing_item_error",
+			allOrNothing: true,
+			prepare: func(
+				subs *dbhandler.MockImageStore,
+			) {
+				expectTx(subs)
+				subs.EXPECT().CreateImage(gomock.Any(), images[0]).Return(nil)
+				subs.EXPECT().CreateImage(gomock.Any(), images[1]).Return(errFake)
+			},
+			expectedErrors: []error{
+				ErrBatchAborted,
+				fmt.Errorf("error while creating image, %w", &dbhandler.BatchError{Index: 1, Err: errFake}),
+			},
+		},
+		{
+			name:         "all_or_nothing_commit_error",
+			allOrNothing: true,
+			prepare: func(
+				subs *dbhandler.MockImageStore,
+			) {
+				subs.EXPECT().WithTx(gomock.Any(), gomock.Any()).Return(errFake)
+			},
+			expectedErrors: []error{
+				fmt.Errorf("error while creating image, %w", errFake),
+				fmt.Errorf("error while creating image, %w", errFake),
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			_, mockDbHandler, controller := testSetUp(t)
+			if tt.prepare != nil {
+				tt.prepare(mockDbHandler)
+			}
+
+			errs := controller.CreateImages(context.Background(), images, tt.allOrNothing)
+			assert.Equal(t, tt.expectedErrors, errs)
+		})
+	}
+}
+
+func TestDeleteImages(t *testing.T) {
+	t.Parallel()
+
+	keys := []dbmodels.ImageKey{
+		{AlbumName: "test-album", ImageName: "image-1"},
+		{AlbumName: "test-album", ImageName: "image-2"},
+	}
+
+	tests := []struct {
+		name         string
+		allOrNothing bool
+		prepare      func(
+			subs *dbhandler.MockImageStore,
+		)
+		expectedErrors []error
+	}{
+		{
+			name: "best_effort",
+			prepare: func(
+				subs *dbhandler.MockImageStore,
+			) {
+				expectTx(subs)
+				subs.EXPECT().DeleteImageWithImageName(gomock.Any(), "image-1", "test-album").Return(errFake)
+				subs.EXPECT().DeleteImageWithImageName(gomock.Any(), "image-2", "test-album").Return(nil)
+			},
+			expectedErrors: []error{fmt.Errorf("error while deleting image, %w", errFake), nil},
+		},
+		{
+			name:         "all_or_nothing_item_error",
+			allOrNothing: true,
+			prepare: func(
+				subs *dbhandler.MockImageStore,
+			) {
+				expectTx(subs)
+				subs.EXPECT().DeleteImageWithImageName(gomock.Any(), "image-1", "test-album").Return(errFake)
+			},
+			expectedErrors: []error{
+				fmt.Errorf("error while deleting image, %w", &dbhandler.BatchError{Index: 0, Err: errFake}),
+				ErrBatchAborted,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			_, mockDbHandler, controller := testSetUp(t)
+			if tt.prepare != nil {
+				tt.prepare(mockDbHandler)
+			}
+
+			errs := controller.DeleteImages(context.Background(), keys, tt.allOrNothing)
+			assert.Equal(t, tt.expectedErrors, errs)
+		})
+	}
+}
+
+func TestExportAlbumImages(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		prepare func(
+			subs *dbhandler.MockImageStore,
+		)
+		expectedError error
+	}{
+		{
+			name: "success",
+			prepare: func(
+				subs *dbhandler.MockImageStore,
+			) {
+				subs.EXPECT().StreamImages(gomock.Any(), "test-album", gomock.Any()).Return(nil)
+			},
+			expectedError: nil,
+		},
+		{
+			name: "error",
+			prepare: func(
+				subs *dbhandler.MockImageStore,
+			) {
+				subs.EXPECT().StreamImages(gomock.Any(), "test-album", gomock.Any()).Return(errFake)
+			},
+			expectedError: fmt.Errorf("error while exporting images of album test-album, %w", errFake),
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			_, mockDbHandler, controller := testSetUp(t)
+			if tt.prepare != nil {
+				tt.prepare(mockDbHandler)
+			}
+
+			err := controller.ExportAlbumImages(context.Background(), "test-album", func(dbmodels.Image) error { return nil })
+			assert.Equal(t, tt.expectedError, err)
+		})
+	}
+}
+
+func TestPutImage(t *testing.T) {
+	t.Parallel()
+
+	image := dbmodels.Image{
+		AlbumName: "test-album",
+		ImageName: "test-image",
+		Image:     "abc",
+	}
+	key := dbmodels.ImageKey{
+		AlbumName: "test-album",
+		ImageName: "test-image",
+	}
+
+	tests := []struct {
+		name    string
+		prepare func(
+			subs *dbhandler.MockImageStore,
+		)
+		expectedCreated bool
+		expectedError   error
+	}{
+		{
+			name: "replaced",
+			prepare: func(
+				subs *dbhandler.MockImageStore,
+			) {
+				expectTx(subs)
+				subs.EXPECT().UpdateImage(gomock.Any(), key, image).Return(nil)
+			},
+			expectedCreated: false,
+		},
+		{
+			name: "created",
+			prepare: func(
+				subs *dbhandler.MockImageStore,
+			) {
+				expectTx(subs)
+				subs.EXPECT().UpdateImage(gomock.Any(), key, image).Return(dbhandler.ErrNoDataFound)
+				subs.EXPECT().CreateImage(gomock.Any(), image).Return(nil)
+			},
+			expectedCreated: true,
+		},
+		{
+			name: "update_error",
+			prepare: func(
+				subs *dbhandler.MockImageStore,
+			) {
+				expectTx(subs)
+				subs.EXPECT().UpdateImage(gomock.Any(), key, image).Return(errFake)
+			},
+			expectedError: fmt.Errorf("error while updating image, %w", errFake),
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			_, mockDbHandler, controller := testSetUp(t)
+			if tt.prepare != nil {
+				tt.prepare(mockDbHandler)
+			}
+
+			created, err := controller.PutImage(context.Background(), image)
+			assert.Equal(t, tt.expectedError, err)
+			assert.Equal(t, tt.expectedCreated, created)
+		})
+	}
+}
+
+func TestGetImagesOfAlbums(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		prepare func(
+			subs *dbhandler.MockImageStore,
+		)
+		expectedError  error
+		expectedImages map[string][]dbmodels.Image
+	}{
+		{
+			name: "success",
+			prepare: func(
+				subs *dbhandler.MockImageStore,
+			) {
+				subs.EXPECT().GetImagesOfAlbums(gomock.Any(), []string{"album-1", "album-2"}).Return([]dbmodels.Image{
+					{AlbumName: "album-1", ImageName: "image-1"},
+					{AlbumName: "album-1", ImageName: "image-2"},
+					{AlbumName: "album-2", ImageName: "image-3"},
+				}, nil)
+			},
+			expectedImages: map[string][]dbmodels.Image{
+				"album-1": {{AlbumName: "album-1", ImageName: "image-1"}, {AlbumName: "album-1", ImageName: "image-2"}},
+				"album-2": {{AlbumName: "album-2", ImageName: "image-3"}},
+			},
+		},
+		{
+			name: "error",
+			prepare: func(
+				subs *dbhandler.MockImageStore,
+			) {
+				subs.EXPECT().GetImagesOfAlbums(gomock.Any(), []string{"album-1", "album-2"}).Return(nil, errFake)
+			},
+			expectedError: fmt.Errorf("error while getting images of albums, %w", errFake),
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			_, mockDbHandler, controller := testSetUp(t)
+			if tt.prepare != nil {
+				tt.prepare(mockDbHandler)
+			}
+
+			images, err := controller.GetImagesOfAlbums(context.Background(), []string{"album-1", "album-2"})
+			assert.Equal(t, tt.expectedError, err)
+			assert.Equal(t, tt.expectedImages, images)
+		})
+	}
+}
+
+func TestSearchImages(t *testing.T) {
+	t.Parallel()
+
+	matches := []dbmodels.ImageMatch{{AlbumName: "album-1", ImageName: "image-1", Rank: 0.5}}
+
+	tests := []struct {
+		name    string
+		prepare func(
+			subs *dbhandler.MockImageStore,
+		)
+		expectedError   error
+		expectedMatches []dbmodels.ImageMatch
+		expectedTotal   int
+	}{
+		{
+			name: "success",
+			prepare: func(
+				subs *dbhandler.MockImageStore,
+			) {
+				subs.EXPECT().SearchImages(gomock.Any(), "image", gomock.Nil(), 10, 20).Return(matches, 21, nil)
+			},
+			expectedMatches: matches,
+			expectedTotal:   21,
+		},
+		{
+			name: "error",
+			prepare: func(
+				subs *dbhandler.MockImageStore,
+			) {
+				subs.EXPECT().SearchImages(gomock.Any(), "image", gomock.Nil(), 10, 20).Return(nil, 0, errFake)
+			},
+			expectedError: fmt.Errorf("error while searching images, %w", errFake),
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			_, mockDbHandler, controller := testSetUp(t)
+			if tt.prepare != nil {
+				tt.prepare(mockDbHandler)
+			}
+
+			matches, total, err := controller.SearchImages(context.Background(), "image", 10, 20)
+			assert.Equal(t, tt.expectedError, err)
+			assert.Equal(t, tt.expectedMatches, matches)
+			assert.Equal(t, tt.expectedTotal, total)
+		})
+	}
+}
+
+func TestFilterImages(t *testing.T) {
+	t.Parallel()
+
+	filters := []dbmodels.MetadataFilter{{Key: "project", Op: "=", Value: "apollo"}}
+	images := []dbmodels.Image{{AlbumName: "album-1", ImageName: "image-1"}}
+
+	tests := []struct {
+		name    string
+		prepare func(
+			subs *dbhandler.MockImageStore,
+		)
+		expectedError  error
+		expectedImages []dbmodels.Image
+	}{
+		{
+			name: "success",
+			prepare: func(
+				subs *dbhandler.MockImageStore,
+			) {
+				subs.EXPECT().FilterImages(gomock.Any(), "album-1", filters).Return(images, nil)
+			},
+			expectedImages: images,
+		},
+		{
+			name: "error",
+			prepare: func(
+				subs *dbhandler.MockImageStore,
+			) {
+				subs.EXPECT().FilterImages(gomock.Any(), "album-1", filters).Return(nil, errFake)
+			},
+			expectedError: fmt.Errorf("error while filtering images of album album-1, %w", errFake),
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			_, mockDbHandler, controller := testSetUp(t)
+			if tt.prepare != nil {
+				tt.prepare(mockDbHandler)
+			}
+
+			images, err := controller.FilterImages(context.Background(), "album-1", filters)
+			assert.Equal(t, tt.expectedError, err)
+			assert.Equal(t, tt.expectedImages, images)
+		})
+	}
+}
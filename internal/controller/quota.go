@@ -0,0 +1,118 @@
+package controller
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"githum.com/anupam111/image-store/internal/config"
+	"githum.com/anupam111/image-store/internal/db/dbhandler"
+	"githum.com/anupam111/image-store/internal/db/dbmodels"
+	"githum.com/anupam111/image-store/internal/tenant"
+)
+
+var (
+	// ErrStorageQuota is returned when the content of an image would take more
+	// bytes than the quota of its album or tenant allows.
+	ErrStorageQuota = errors.New("storage quota exceeded")
+	// ErrImageQuota is returned when an album or tenant already holds as many
+	// images as its quota allows.
+	ErrImageQuota = errors.New("image quota exceeded")
+)
+
+// UsageReport is the usage of the tenant of the caller and of the albums the
+// caller can view, with the quotas they are held to.
+type UsageReport struct {
+	Tenant string
+	Usage  dbmodels.Usage
+	Albums []dbmodels.AlbumUsage
+	Quotas config.Quotas
+}
+
+// GetUsage reports the usage of the tenant of the caller against the quotas.
+func (i *ImageController) GetUsage(ctx context.Context) (UsageReport, error) {
+	visible, err := newAlbumAccess(ctx).visibleAlbums(ctx, i.imageStore)
+	if err != nil {
+		return UsageReport{}, fmt.Errorf("error while getting usage, %w", err)
+	}
+
+	usage, err := i.imageStore.GetTenantUsage(ctx)
+	if err != nil {
+		return UsageReport{}, fmt.Errorf("error while getting usage, %w", err)
+	}
+
+	albums, err := i.imageStore.ListAlbumUsage(ctx)
+	if err != nil {
+		return UsageReport{}, fmt.Errorf("error while getting usage, %w", err)
+	}
+
+	if visible != nil {
+		filtered := make([]dbmodels.AlbumUsage, 0, len(visible))
+		for _, album := range albums {
+			if visible[album.AlbumName] {
+				filtered = append(filtered, album)
+			}
+		}
+
+		albums = filtered
+	}
+
+	return UsageReport{
+		Tenant: tenant.FromContext(ctx),
+		Usage:  usage,
+		Albums: albums,
+		Quotas: i.quotas,
+	}, nil
+}
+
+// checkQuotas returns ErrImageQuota or ErrStorageQuota when adding images
+// images taking bytes bytes to the album would exceed a quota. The usage is
+// read before the write, concurrent writes may overshoot a quota by the images
+// in flight.
+func (i *ImageController) checkQuotas(ctx context.Context, store dbhandler.ImageStore,
+	albumName string, images, bytes int64) error {
+	if i.quotas.AlbumImages > 0 || i.quotas.AlbumBytes > 0 {
+		usage, err := store.GetAlbumUsage(ctx, albumName)
+		if errors.Is(err, dbhandler.ErrNoDataFound) {
+			// The write itself fails on the missing album.
+			err = nil
+		}
+
+		if err != nil {
+			return err
+		}
+
+		err = exceedsQuota("album "+albumName, usage.Usage, i.quotas.AlbumImages, i.quotas.AlbumBytes, images, bytes)
+		if err != nil {
+			return err
+		}
+	}
+
+	if i.quotas.TenantImages > 0 || i.quotas.TenantBytes > 0 {
+		usage, err := store.GetTenantUsage(ctx)
+		if err != nil {
+			return err
+		}
+
+		return exceedsQuota("tenant "+tenant.FromContext(ctx), usage, i.quotas.TenantImages, i.quotas.TenantBytes, images, bytes)
+	}
+
+	return nil
+}
+
+// limitsBytes reports whether a quota limits the bytes of image content.
+func (i *ImageController) limitsBytes() bool {
+	return i.quotas.AlbumBytes > 0 || i.quotas.TenantBytes > 0
+}
+
+func exceedsQuota(owner string, usage dbmodels.Usage, maxImages, maxBytes, images, bytes int64) error {
+	if maxImages > 0 && images > 0 && usage.Images+images > maxImages {
+		return fmt.Errorf("%w: %s holds %d images, its quota is %d images", ErrImageQuota, owner, usage.Images, maxImages)
+	}
+
+	if maxBytes > 0 && bytes > 0 && usage.Bytes+bytes > maxBytes {
+		return fmt.Errorf("%w: %s stores %d bytes and the image needs %d more, its quota is %d bytes",
+			ErrStorageQuota, owner, usage.Bytes, bytes, maxBytes)
+	}
+
+	return nil
+}
@@ -0,0 +1,74 @@
+package controller
+
+import (
+	"context"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"githum.com/anupam111/image-store/internal/config"
+	"githum.com/anupam111/image-store/internal/db/dbmodels"
+	"githum.com/anupam111/image-store/internal/db/memstore"
+	"githum.com/anupam111/image-store/internal/tenant"
+	"testing"
+)
+
+func TestQuotas(t *testing.T) {
+	t.Parallel()
+
+	store := memstore.NewMemStore()
+	controller := NewImageController(logrus.New(), store, store, config.Quotas{
+		AlbumImages: 2,
+		TenantBytes: 10,
+	})
+	ctx := context.Background()
+
+	newImage := func(albumName, imageName, content string) dbmodels.Image {
+		return dbmodels.Image{ImageName: imageName, AlbumName: albumName, Image: content}
+	}
+
+	require.NoError(t, controller.CreateImageAlbum(ctx, dbmodels.Album{AlbumName: "holiday"}))
+	require.NoError(t, controller.CreateImageAlbum(ctx, dbmodels.Album{AlbumName: "work"}))
+	require.NoError(t, controller.CreateImage(ctx, newImage("holiday", "beach.png", "1234")))
+	require.NoError(t, controller.CreateImage(ctx, newImage("holiday", "city.png", "12")))
+
+	err := controller.CreateImage(ctx, newImage("holiday", "sunset.png", "1"))
+	assert.ErrorIs(t, err, ErrImageQuota)
+	assert.Contains(t, err.Error(), "album holiday holds 2 images, its quota is 2 images")
+
+	err = controller.CreateImage(ctx, newImage("work", "report.png", "12345"))
+	assert.ErrorIs(t, err, ErrStorageQuota)
+	assert.Contains(t, err.Error(), "tenant default stores 6 bytes and the image needs 5 more, its quota is 10 bytes")
+
+	errs := controller.CreateImages(ctx, []dbmodels.Image{
+		newImage("work", "a.png", "12"),
+		newImage("work", "b.png", "123"),
+	}, true)
+	assert.ErrorIs(t, errs[1], ErrStorageQuota, "the images of the batch count")
+
+	_, err = controller.PutImage(ctx, newImage("work", "a.png", "12345"))
+	assert.ErrorIs(t, err, ErrStorageQuota)
+
+	key := dbmodels.ImageKey{ImageName: "beach.png", AlbumName: "holiday"}
+	assert.ErrorIs(t, controller.UpdateImage(ctx, key, newImage("holiday", "beach.png", "123456789")), ErrStorageQuota)
+	assert.NoError(t, controller.UpdateImage(ctx, key, newImage("holiday", "beach.png", "1")), "shrinking is allowed")
+
+	created, err := controller.PutImage(ctx, newImage("work", "a.png", "12345"))
+	assert.NoError(t, err)
+	assert.True(t, created)
+
+	report, err := controller.GetUsage(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, UsageReport{
+		Tenant: tenant.Default,
+		Usage:  dbmodels.Usage{Images: 3, Bytes: 8},
+		Albums: []dbmodels.AlbumUsage{
+			{Tenant: tenant.Default, AlbumName: "holiday", Usage: dbmodels.Usage{Images: 2, Bytes: 3}},
+			{Tenant: tenant.Default, AlbumName: "work", Usage: dbmodels.Usage{Images: 1, Bytes: 5}},
+		},
+		Quotas: config.Quotas{AlbumImages: 2, TenantBytes: 10},
+	}, report)
+
+	report, err = controller.GetUsage(principalContext("mallory", nil))
+	assert.NoError(t, err)
+	assert.Empty(t, report.Albums, "only the albums the caller can view are reported")
+}
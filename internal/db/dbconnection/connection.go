@@ -3,15 +3,32 @@
 package dbconnection
 
 import (
+	"context"
 	"fmt"
+	_ "github.com/go-sql-driver/mysql" // mysql driver
 	"github.com/jmoiron/sqlx"
+	_ "github.com/lib/pq"           // postgres driver
+	_ "github.com/mattn/go-sqlite3" // sqlite3 driver
+	log "github.com/sirupsen/logrus"
 	"githum.com/anupam111/image-store/internal/config"
+	"githum.com/anupam111/image-store/internal/db/dialect"
+	"sync"
+	"time"
 )
 
 //go:generate mockgen -source ../../config/config.go -package dbconnection -destination connection_mock.go
 
+// Pool holds the connections to the primary database, DB, and to its read
+// replicas. Reads are spread over the replicas by Reader.
 type Pool struct {
-	DB *sqlx.DB
+	DB      *sqlx.DB
+	Dialect dialect.Dialect
+
+	mu     sync.RWMutex
+	status error
+
+	replicas []*replica
+	next     uint32
 }
 
 type Connector struct{}
@@ -22,24 +39,130 @@ func setConnector(dbConfig *config.DBConfig) {
 	}
 }
 
-func New(dbConfig *config.DBConfig) *Pool {
+// New connects to the database. A failed attempt is retried up to
+// dbConfig.ConnectRetries times with exponential backoff, so the service
+// survives a database that starts after it. The read replicas are connected
+// once; a replica which is down at start is left out with a warning.
+func New(dbConfig *config.DBConfig) (*Pool, error) {
 	setConnector(dbConfig)
-	dbConnection := fmt.Sprintf(
-		"host=%s port=%d user=%s password='%s' dbname=%s sslmode=disable",
-		dbConfig.Host,
-		dbConfig.Port,
-		dbConfig.Username,
-		dbConfig.Password,
-		dbConfig.Name,
-	)
+	sqlDialect, err := dialect.New(dbConfig.DriverName)
+	if err != nil {
+		return nil, err
+	}
+
+	connection, err := connect(dbConfig, sqlDialect.DataSourceName(dbConfig), dbConfig.ConnectRetries)
+	if err != nil {
+		return nil, err
+	}
+
+	if dbConfig.DriverName == dialect.SQLiteDriver {
+		// SQLite allows a single writer, serializing in the pool
+		// avoids busy errors and keeps in-memory databases shared.
+		connection.SetMaxOpenConns(1)
+	}
+
+	pool := &Pool{
+		DB:      connection,
+		Dialect: sqlDialect,
+	}
+
+	for _, host := range dbConfig.ReplicaHosts {
+		replicaConfig, err := replicaDBConfig(dbConfig, host)
+		if err != nil {
+			return nil, err
+		}
+
+		replicaConnection, err := connect(replicaConfig, sqlDialect.DataSourceName(replicaConfig), 0)
+		if err != nil {
+			log.Warnf("read replica %s left out, %v", host, err)
+
+			continue
+		}
+
+		pool.replicas = append(pool.replicas, newReplica(host, replicaConnection))
+	}
+
+	return pool, nil
+}
+
+// connect opens a connection to the database, retrying up to retries times.
+func connect(dbConfig *config.DBConfig, dbConnection string, retries int) (*sqlx.DB, error) {
+	backoff := dbConfig.ConnectBackoff
+	for attempt := 0; ; attempt++ {
+		connection, err := dbConfig.Connector.Connect(dbConfig.DriverName, dbConnection)
+		if err == nil {
+			return connection, nil
+		}
+
+		if attempt >= retries {
+			return nil, fmt.Errorf("error while connecting to database after %d attempts, %w", attempt+1, err)
+		}
 
-	connection := dbConfig.Connector.Connect(dbConfig.DriverName, dbConnection)
+		log.Warnf("error while connecting to database, retrying in %s, %v", backoff, err)
+		time.Sleep(backoff)
 
-	return &Pool{
-		DB: connection,
+		backoff *= 2
+		if dbConfig.ConnectMaxBackoff > 0 && backoff > dbConfig.ConnectMaxBackoff {
+			backoff = dbConfig.ConnectMaxBackoff
+		}
 	}
 }
 
-func (d *Connector) Connect(driverName, dbConnection string) *sqlx.DB {
-	return sqlx.MustConnect(driverName, dbConnection)
+func (d *Connector) Connect(driverName, dbConnection string) (*sqlx.DB, error) {
+	return sqlx.Connect(driverName, dbConnection)
+}
+
+// Monitor pings the database and its read replicas every interval until ctx
+// is done. The outcome for the primary is reported by Status, replicas which
+// fail their check are skipped by Reader until they pass again. It blocks, so
+// callers run it in a goroutine.
+func (p *Pool) Monitor(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		p.check(ctx, interval)
+
+		for _, r := range p.replicas {
+			r.check(ctx, interval)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (p *Pool) check(ctx context.Context, timeout time.Duration) {
+	pingCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	err := p.DB.PingContext(pingCtx)
+	if err != nil && ctx.Err() != nil {
+		// The monitor is shutting down, the ping says nothing about the database.
+		return
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if err != nil && p.status == nil {
+		log.Errorf("database connectivity lost, %v", err)
+	} else if err == nil && p.status != nil {
+		log.Info("database connectivity restored")
+	}
+
+	p.status = err
+}
+
+// Status returns the outcome of the last connectivity check of Monitor, nil
+// when the database was reachable. Connect already pinged the database, so
+// before the first check it reports the database as reachable.
+func (p *Pool) Status() error {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	return p.status
 }
@@ -35,11 +35,12 @@ func (m *MockDBConnector) EXPECT() *MockDBConnectorMockRecorder {
 }
 
 // Connect mocks base method.
-func (m *MockDBConnector) Connect(driverName, dbConnection string) *sqlx.DB {
+func (m *MockDBConnector) Connect(driverName, dbConnection string) (*sqlx.DB, error) {
 	m.ctrl.T.Helper()
 	ret := m.ctrl.Call(m, "Connect", driverName, dbConnection)
 	ret0, _ := ret[0].(*sqlx.DB)
-	return ret0
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
 }
 
 // Connect indicates an expected call of Connect.
@@ -1,12 +1,17 @@
 package dbconnection
 
 import (
+	"context"
+	"errors"
 	"githum.com/anupam111/image-store/internal/config"
+	"githum.com/anupam111/image-store/internal/db/dialect"
 	"testing"
+	"time"
 
 	gomock "github.com/golang/mock/gomock"
 	"github.com/jmoiron/sqlx"
 	"github.com/stretchr/testify/assert"
+	sqlxmock "github.com/zhashkevych/go-sqlxmock"
 )
 
 func getMockController(t *testing.T) (*gomock.Controller, *MockDBConnector) {
@@ -48,19 +53,19 @@ func Test_setConnector(t *testing.T) {
 }
 
 func TestNew(t *testing.T) {
+	const dsn = "host=host port=5432 user=dummy password='dummy' dbname=dummy sslmode=disable"
+
 	tests := []struct {
 		name     string
 		dbConfig *config.DBConfig
 		prepare  func(connector *MockDBConnector)
 		want     *Pool
+		wantErr  bool
 	}{
 		{
 			name: "OK",
 			prepare: func(connector *MockDBConnector) {
-				connector.EXPECT().Connect(
-					"postgres",
-					"host=host port=5432 user=dummy password='dummy' dbname=dummy sslmode=disable",
-				).Return(&sqlx.DB{})
+				connector.EXPECT().Connect("postgres", dsn).Return(&sqlx.DB{}, nil)
 			},
 			dbConfig: &config.DBConfig{
 				DriverName: "postgres",
@@ -71,16 +76,76 @@ func TestNew(t *testing.T) {
 				Password:   "dummy",
 			},
 			want: &Pool{
-				DB: &sqlx.DB{},
+				DB:      &sqlx.DB{},
+				Dialect: dialect.Postgres{},
+			},
+		},
+		{
+			name: "OK after retry",
+			prepare: func(connector *MockDBConnector) {
+				gomock.InOrder(
+					connector.EXPECT().Connect("postgres", dsn).Return(nil, errors.New("connection refused")).Times(2),
+					connector.EXPECT().Connect("postgres", dsn).Return(&sqlx.DB{}, nil),
+				)
+			},
+			dbConfig: &config.DBConfig{
+				DriverName:     "postgres",
+				Host:           "host",
+				Port:           5432,
+				Name:           "dummy",
+				Username:       "dummy",
+				Password:       "dummy",
+				ConnectRetries: 3,
+				ConnectBackoff: time.Millisecond,
+			},
+			want: &Pool{
+				DB:      &sqlx.DB{},
+				Dialect: dialect.Postgres{},
+			},
+		},
+		{
+			name: "Retries exhausted",
+			prepare: func(connector *MockDBConnector) {
+				connector.EXPECT().Connect("postgres", dsn).Return(nil, errors.New("connection refused")).Times(3)
+			},
+			dbConfig: &config.DBConfig{
+				DriverName:     "postgres",
+				Host:           "host",
+				Port:           5432,
+				Name:           "dummy",
+				Username:       "dummy",
+				Password:       "dummy",
+				ConnectRetries: 2,
+				ConnectBackoff: time.Millisecond,
 			},
+			wantErr: true,
 		},
 	}
 
 	for _, tt := range tests {
-		_, mockConnector := getMockController(t)
-		tt.prepare(mockConnector)
-		tt.dbConfig.Connector = mockConnector
-		got := New(tt.dbConfig)
-		assert.Equal(t, got, tt.want)
+		t.Run(tt.name, func(t *testing.T) {
+			_, mockConnector := getMockController(t)
+			tt.prepare(mockConnector)
+			tt.dbConfig.Connector = mockConnector
+			got, err := New(tt.dbConfig)
+			assert.Equal(t, tt.wantErr, err != nil)
+			assert.Equal(t, tt.want, got)
+		})
 	}
 }
+
+func TestPool_Monitor(t *testing.T) {
+	db, mock, err := sqlxmock.Newx(sqlxmock.MonitorPingsOption(true))
+	assert.NoError(t, err)
+
+	mock.ExpectPing().WillReturnError(errors.New("connection refused"))
+
+	pool := &Pool{DB: db}
+	pool.check(context.Background(), time.Second)
+	assert.Error(t, pool.Status())
+
+	mock.ExpectPing()
+	pool.check(context.Background(), time.Second)
+	assert.NoError(t, pool.Status())
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
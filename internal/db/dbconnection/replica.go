@@ -0,0 +1,219 @@
+package dbconnection
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"github.com/jmoiron/sqlx"
+	log "github.com/sirupsen/logrus"
+	"githum.com/anupam111/image-store/internal/config"
+	"githum.com/anupam111/image-store/internal/db/dialect"
+	"net"
+	"strconv"
+	"sync/atomic"
+	"time"
+)
+
+// replica is a read replica of the primary database.
+type replica struct {
+	host string
+	db   *sqlx.DB
+	// healthy is 1 while the last check of the replica succeeded.
+	healthy int32
+}
+
+func newReplica(host string, db *sqlx.DB) *replica {
+	return &replica{
+		host:    host,
+		db:      db,
+		healthy: 1,
+	}
+}
+
+func (r *replica) isHealthy() bool {
+	return atomic.LoadInt32(&r.healthy) == 1
+}
+
+func (r *replica) check(ctx context.Context, timeout time.Duration) {
+	pingCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	err := r.db.PingContext(pingCtx)
+	if err != nil && ctx.Err() != nil {
+		return
+	}
+
+	if err != nil {
+		if atomic.SwapInt32(&r.healthy, 0) == 1 {
+			log.Errorf("read replica %s unreachable, reading from the others, %v", r.host, err)
+		}
+
+		return
+	}
+
+	if atomic.SwapInt32(&r.healthy, 1) == 0 {
+		log.Infof("read replica %s reachable again", r.host)
+	}
+}
+
+// replicaDBConfig returns the configuration of the replica at host, given as
+// "host" or "host:port". The other settings are those of the primary.
+func replicaDBConfig(dbConfig *config.DBConfig, host string) (*config.DBConfig, error) {
+	replicaConfig := *dbConfig
+
+	replicaHost, port, err := net.SplitHostPort(host)
+	if err != nil {
+		replicaConfig.Host = host
+
+		return &replicaConfig, nil
+	}
+
+	replicaConfig.Host = replicaHost
+	if replicaConfig.Port, err = strconv.Atoi(port); err != nil {
+		return nil, fmt.Errorf("invalid port of read replica %s, %w", host, err)
+	}
+
+	return &replicaConfig, nil
+}
+
+// Queryer is implemented by *sqlx.DB, *sqlx.Tx and the readers of a Pool.
+type Queryer interface {
+	sqlx.ExtContext
+	GetContext(ctx context.Context, dest interface{}, query string, args ...interface{}) error
+	SelectContext(ctx context.Context, dest interface{}, query string, args ...interface{}) error
+}
+
+// Reader returns the database to read from: a healthy replica, taken in
+// turns, or the primary when there is none or when ctx already wrote to the
+// primary, so that a request reads its own writes. A read which fails on the
+// replica because of its connection is retried on the primary.
+func (p *Pool) Reader(ctx context.Context) Queryer {
+	if len(p.replicas) == 0 || hasWritten(ctx) {
+		return p.DB
+	}
+
+	start := atomic.AddUint32(&p.next, 1)
+	for i := range p.replicas {
+		r := p.replicas[(int(start)+i)%len(p.replicas)]
+		if r.isHealthy() {
+			return &replicaReader{replica: r, primary: p.DB, dialect: p.Dialect}
+		}
+	}
+
+	return p.DB
+}
+
+// replicaReader reads from a replica, and from the primary when the replica
+// can not be reached. The replica is then marked down until its next
+// successful check, rather than failing every read until then.
+type replicaReader struct {
+	replica *replica
+	primary *sqlx.DB
+	dialect dialect.Dialect
+}
+
+// failover reports whether the read has to be retried on the primary.
+func (r *replicaReader) failover(err error) bool {
+	if err == nil || !r.dialect.IsConnectionFailure(err) {
+		return false
+	}
+
+	if atomic.SwapInt32(&r.replica.healthy, 0) == 1 {
+		log.Errorf("read replica %s unreachable, reading from the others, %v", r.replica.host, err)
+	}
+
+	return true
+}
+
+func (r *replicaReader) GetContext(ctx context.Context, dest interface{}, query string, args ...interface{}) error {
+	err := r.replica.db.GetContext(ctx, dest, query, args...)
+	if r.failover(err) {
+		return r.primary.GetContext(ctx, dest, query, args...)
+	}
+
+	return err
+}
+
+func (r *replicaReader) SelectContext(ctx context.Context, dest interface{}, query string, args ...interface{}) error {
+	err := r.replica.db.SelectContext(ctx, dest, query, args...)
+	if r.failover(err) {
+		return r.primary.SelectContext(ctx, dest, query, args...)
+	}
+
+	return err
+}
+
+// QueryContext only fails over when the query fails, not when reading its
+// rows does.
+func (r *replicaReader) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	rows, err := r.replica.db.QueryContext(ctx, query, args...)
+	if r.failover(err) {
+		return r.primary.QueryContext(ctx, query, args...)
+	}
+
+	return rows, err
+}
+
+// QueryxContext only fails over when the query fails, not when reading its
+// rows does.
+func (r *replicaReader) QueryxContext(ctx context.Context, query string, args ...interface{}) (*sqlx.Rows, error) {
+	rows, err := r.replica.db.QueryxContext(ctx, query, args...)
+	if r.failover(err) {
+		return r.primary.QueryxContext(ctx, query, args...)
+	}
+
+	return rows, err
+}
+
+func (r *replicaReader) QueryRowxContext(ctx context.Context, query string, args ...interface{}) *sqlx.Row {
+	row := r.replica.db.QueryRowxContext(ctx, query, args...)
+	if r.failover(row.Err()) {
+		return r.primary.QueryRowxContext(ctx, query, args...)
+	}
+
+	return row
+}
+
+// ExecContext runs on the primary, the replicas are read only.
+func (r *replicaReader) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	return r.primary.ExecContext(ctx, query, args...)
+}
+
+func (r *replicaReader) DriverName() string {
+	return r.primary.DriverName()
+}
+
+func (r *replicaReader) Rebind(query string) string {
+	return r.primary.Rebind(query)
+}
+
+func (r *replicaReader) BindNamed(query string, arg interface{}) (string, []interface{}, error) {
+	return r.primary.BindNamed(query, arg)
+}
+
+type sessionKey struct{}
+
+// session records whether a request wrote to the primary.
+type session struct {
+	written int32
+}
+
+// WithSession returns a context which remembers the writes made with it.
+// Once it wrote, Reader sends its reads to the primary instead of a replica,
+// which may lag behind.
+func WithSession(ctx context.Context) context.Context {
+	return context.WithValue(ctx, sessionKey{}, &session{})
+}
+
+// MarkWritten records a write to the primary in the session of ctx, if any.
+func MarkWritten(ctx context.Context) {
+	if s, ok := ctx.Value(sessionKey{}).(*session); ok {
+		atomic.StoreInt32(&s.written, 1)
+	}
+}
+
+func hasWritten(ctx context.Context) bool {
+	s, ok := ctx.Value(sessionKey{}).(*session)
+
+	return ok && atomic.LoadInt32(&s.written) == 1
+}
@@ -0,0 +1,138 @@
+package dbconnection
+
+import (
+	"context"
+	"errors"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	sqlxmock "github.com/zhashkevych/go-sqlxmock"
+	"githum.com/anupam111/image-store/internal/config"
+	"githum.com/anupam111/image-store/internal/db/dialect"
+	"net"
+	"testing"
+)
+
+func TestNew_Replicas(t *testing.T) {
+	_, mockConnector := getMockController(t)
+	primary, replicaDB := &sqlx.DB{}, &sqlx.DB{}
+
+	mockConnector.EXPECT().Connect(
+		"postgres",
+		"host=host port=5432 user=dummy password='dummy' dbname=dummy sslmode=disable",
+	).Return(primary, nil)
+	mockConnector.EXPECT().Connect(
+		"postgres",
+		"host=replica-1 port=5432 user=dummy password='dummy' dbname=dummy sslmode=disable",
+	).Return(replicaDB, nil)
+	mockConnector.EXPECT().Connect(
+		"postgres",
+		"host=replica-2 port=5433 user=dummy password='dummy' dbname=dummy sslmode=disable",
+	).Return(nil, errors.New("connection refused"))
+
+	got, err := New(&config.DBConfig{
+		Connector:      mockConnector,
+		DriverName:     "postgres",
+		Host:           "host",
+		Port:           5432,
+		Name:           "dummy",
+		Username:       "dummy",
+		Password:       "dummy",
+		ReplicaHosts:   []string{"replica-1", "replica-2:5433"},
+		ConnectRetries: 3,
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, &Pool{
+		DB:       primary,
+		Dialect:  dialect.Postgres{},
+		replicas: []*replica{newReplica("replica-1", replicaDB)},
+	}, got)
+}
+
+func TestPool_Reader(t *testing.T) {
+	primary, replica1, replica2 := &sqlx.DB{}, &sqlx.DB{}, &sqlx.DB{}
+
+	tests := []struct {
+		name     string
+		replicas []*replica
+		written  bool
+		want     []*sqlx.DB
+	}{
+		{
+			name: "no replicas",
+			want: []*sqlx.DB{primary, primary},
+		},
+		{
+			name:     "round robin",
+			replicas: []*replica{newReplica("replica-1", replica1), newReplica("replica-2", replica2)},
+			want:     []*sqlx.DB{replica2, replica1, replica2},
+		},
+		{
+			name:     "unhealthy replica skipped",
+			replicas: []*replica{newReplica("replica-1", replica1), {host: "replica-2", db: replica2}},
+			want:     []*sqlx.DB{replica1, replica1},
+		},
+		{
+			name:     "all replicas unhealthy",
+			replicas: []*replica{{host: "replica-1", db: replica1}},
+			want:     []*sqlx.DB{primary},
+		},
+		{
+			name:     "read your writes",
+			replicas: []*replica{newReplica("replica-1", replica1)},
+			written:  true,
+			want:     []*sqlx.DB{primary, primary},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pool := &Pool{DB: primary, replicas: tt.replicas}
+			ctx := WithSession(context.Background())
+			if tt.written {
+				MarkWritten(ctx)
+			}
+
+			for _, want := range tt.want {
+				got := pool.Reader(ctx)
+				if reader, ok := got.(*replicaReader); ok {
+					got = reader.replica.db
+				}
+
+				assert.Same(t, want, got)
+			}
+		})
+	}
+}
+
+func TestPool_Reader_Failover(t *testing.T) {
+	primary, primaryMock, err := sqlxmock.Newx()
+	require.NoError(t, err)
+
+	replicaDB, replicaMock, err := sqlxmock.Newx()
+	require.NoError(t, err)
+
+	pool := &Pool{DB: primary, Dialect: dialect.Postgres{}, replicas: []*replica{newReplica("replica-1", replicaDB)}}
+	ctx := context.Background()
+
+	// A statement error is returned as it is.
+	replicaMock.ExpectQuery("SELECT 1").WillReturnError(&pq.Error{Code: "42P01"})
+
+	var one int
+	assert.Error(t, pool.Reader(ctx).GetContext(ctx, &one, "SELECT 1"))
+	assert.True(t, pool.replicas[0].isHealthy())
+
+	// A connection error is retried on the primary, and the replica skipped
+	// until its next check.
+	replicaMock.ExpectQuery("SELECT 1").WillReturnError(&net.OpError{Op: "read", Err: errors.New("connection reset")})
+	primaryMock.ExpectQuery("SELECT 1").WillReturnRows(sqlxmock.NewRows([]string{"one"}).AddRow(1))
+
+	assert.NoError(t, pool.Reader(ctx).GetContext(ctx, &one, "SELECT 1"))
+	assert.Equal(t, 1, one)
+	assert.False(t, pool.replicas[0].isHealthy())
+	assert.Same(t, primary, pool.Reader(ctx))
+
+	assert.NoError(t, replicaMock.ExpectationsWereMet())
+	assert.NoError(t, primaryMock.ExpectationsWereMet())
+}
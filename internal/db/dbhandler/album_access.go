@@ -0,0 +1,97 @@
+package dbhandler
+
+import (
+	"context"
+	"fmt"
+	"github.com/jmoiron/sqlx"
+	"githum.com/anupam111/image-store/internal/constants"
+	"githum.com/anupam111/image-store/internal/db/dbmodels"
+	"githum.com/anupam111/image-store/internal/tenant"
+)
+
+// GrantAlbumAccess grants the role to the principal, replacing the role the
+// principal already had on the album.
+func (db *DBHandler) GrantAlbumAccess(ctx context.Context, access dbmodels.AlbumAccess) error {
+	access.Tenant = tenant.FromContext(ctx)
+
+	return db.runTx(ctx, func(txn *sqlx.Tx) error {
+		if _, err := txn.ExecContext(ctx, db.query(constants.RevokeAlbumAccessQuery),
+			access.Tenant, access.AlbumName, access.PrincipalType, access.Principal); err != nil {
+			return db.translateError(err)
+		}
+
+		if _, err := txn.NamedExecContext(ctx, db.query(constants.InsertAlbumAccessQuery), access); err != nil {
+			return db.translateError(err)
+		}
+
+		return nil
+	})
+}
+
+// RevokeAlbumAccess removes the role of the principal on the album, it
+// returns ErrNoDataFound when the principal has none.
+func (db *DBHandler) RevokeAlbumAccess(ctx context.Context, albumName, principalType, principal string) error {
+	return db.runTx(ctx, func(txn *sqlx.Tx) error {
+		result, err := txn.ExecContext(ctx, db.query(constants.RevokeAlbumAccessQuery),
+			tenant.FromContext(ctx), albumName, principalType, principal)
+		if err != nil {
+			return db.translateError(err)
+		}
+
+		rows, err := result.RowsAffected()
+		if err == nil && rows == 0 {
+			err = ErrNoDataFound
+		}
+
+		return err
+	})
+}
+
+// DeleteAlbumAccess removes every access to the album, which has to be done
+// before deleting the album.
+func (db *DBHandler) DeleteAlbumAccess(ctx context.Context, albumName string) error {
+	return db.runTx(ctx, func(txn *sqlx.Tx) error {
+		_, err := txn.ExecContext(ctx, db.query(constants.DeleteAlbumAccessQuery), tenant.FromContext(ctx), albumName)
+
+		return err
+	})
+}
+
+func (db *DBHandler) ListAlbumAccess(ctx context.Context, albumName string) ([]dbmodels.AlbumAccess, error) {
+	accesses := []dbmodels.AlbumAccess{}
+
+	if err := db.reader(ctx).SelectContext(ctx, &accesses, db.query(constants.ListAlbumAccessQuery),
+		tenant.FromContext(ctx), albumName); err != nil {
+		db.log.Errorf("error while listing access of album %s: %v", albumName, err)
+
+		return nil, fmt.Errorf("%w", err)
+	}
+
+	return accesses, nil
+}
+
+// ListPrincipalAccess returns the accesses granted to the user or to one of
+// the groups, across all albums of the tenant.
+func (db *DBHandler) ListPrincipalAccess(ctx context.Context, user string, groups []string) ([]dbmodels.AlbumAccess, error) {
+	accesses := []dbmodels.AlbumAccess{}
+	args := []interface{}{tenant.FromContext(ctx), user}
+
+	condition := ""
+	if len(groups) > 0 {
+		condition = constants.PrincipalGroupsCondition
+		args = append(args, groups)
+	}
+
+	query, args, err := sqlx.In(fmt.Sprintf(constants.ListPrincipalAccessQuery, condition), args...)
+	if err != nil {
+		return nil, fmt.Errorf("%w", err)
+	}
+
+	if err = db.reader(ctx).SelectContext(ctx, &accesses, db.query(query), args...); err != nil {
+		db.log.Errorf("error while listing access of %s: %v", user, err)
+
+		return nil, fmt.Errorf("%w", err)
+	}
+
+	return accesses, nil
+}
@@ -0,0 +1,89 @@
+package dbhandler
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"github.com/jmoiron/sqlx"
+	"githum.com/anupam111/image-store/internal/constants"
+	"githum.com/anupam111/image-store/internal/db/dbmodels"
+	"githum.com/anupam111/image-store/internal/tenant"
+	"time"
+)
+
+func (db *DBHandler) CreateAlbumShare(ctx context.Context, share dbmodels.AlbumShare) error {
+	share.Tenant = tenant.FromContext(ctx)
+
+	return db.runTx(ctx, func(txn *sqlx.Tx) error {
+		if _, err := txn.NamedExecContext(ctx, db.query(constants.InsertAlbumShareQuery), share); err != nil {
+			return db.translateError(err)
+		}
+
+		return nil
+	})
+}
+
+// ListAlbumShares returns the shares of the album, revoked or not, ordered
+// by creation time and id.
+func (db *DBHandler) ListAlbumShares(ctx context.Context, albumName string) ([]dbmodels.AlbumShare, error) {
+	shares := []dbmodels.AlbumShare{}
+
+	if err := db.reader(ctx).SelectContext(ctx, &shares, db.query(constants.ListAlbumSharesQuery),
+		tenant.FromContext(ctx), albumName); err != nil {
+		db.log.Errorf("error while listing shares of album %s: %v", albumName, err)
+
+		return nil, fmt.Errorf("%w", err)
+	}
+
+	return shares, nil
+}
+
+// GetAlbumShareByHash returns the share, revoked or not, with the hash of
+// its token. Token hashes are unique across tenants, the share is looked up
+// in all of them and tells its tenant.
+func (db *DBHandler) GetAlbumShareByHash(ctx context.Context, hash string) (dbmodels.AlbumShare, error) {
+	res := dbmodels.AlbumShare{}
+
+	if err := db.reader(ctx).GetContext(ctx, &res, db.query(constants.GetAlbumShareByHashQuery), hash); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return dbmodels.AlbumShare{}, ErrNoDataFound
+		}
+
+		db.log.Errorf("error while getting album share: %v", err)
+
+		return dbmodels.AlbumShare{}, fmt.Errorf("%w", err)
+	}
+
+	return res, nil
+}
+
+// RevokeAlbumShare revokes the share of the album, it returns
+// ErrNoDataFound when the album has no share with the id which is not
+// revoked yet.
+func (db *DBHandler) RevokeAlbumShare(ctx context.Context, albumName, id string, revokedAt time.Time) error {
+	return db.runTx(ctx, func(txn *sqlx.Tx) error {
+		result, err := txn.ExecContext(ctx, db.query(constants.RevokeAlbumShareQuery),
+			revokedAt, tenant.FromContext(ctx), albumName, id)
+		if err != nil {
+			return db.translateError(err)
+		}
+
+		rows, err := result.RowsAffected()
+		if err == nil && rows == 0 {
+			err = ErrNoDataFound
+		}
+
+		return err
+	})
+}
+
+// DeleteAlbumShares removes every share of the album, which has to be done
+// before deleting the album.
+func (db *DBHandler) DeleteAlbumShares(ctx context.Context, albumName string) error {
+	return db.runTx(ctx, func(txn *sqlx.Tx) error {
+		_, err := txn.ExecContext(ctx, db.query(constants.DeleteAlbumSharesQuery), tenant.FromContext(ctx), albumName)
+
+		return err
+	})
+}
@@ -0,0 +1,100 @@
+package dbhandler
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"github.com/jmoiron/sqlx"
+	"githum.com/anupam111/image-store/internal/constants"
+	"githum.com/anupam111/image-store/internal/db/dbmodels"
+	"githum.com/anupam111/image-store/internal/tenant"
+)
+
+func (db *DBHandler) GetAlbumUsage(ctx context.Context, albumName string) (dbmodels.AlbumUsage, error) {
+	res := dbmodels.AlbumUsage{}
+
+	if err := db.reader(ctx).GetContext(ctx, &res, db.query(constants.GetAlbumUsageQuery),
+		tenant.FromContext(ctx), albumName); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return dbmodels.AlbumUsage{}, ErrNoDataFound
+		}
+
+		db.log.Errorf("error while getting usage of album %s: %v", albumName, err)
+
+		return dbmodels.AlbumUsage{}, fmt.Errorf("%w", err)
+	}
+
+	return res, nil
+}
+
+// ListAlbumUsage returns the usage of every album of the tenant, ordered by
+// album name.
+func (db *DBHandler) ListAlbumUsage(ctx context.Context) ([]dbmodels.AlbumUsage, error) {
+	usages := []dbmodels.AlbumUsage{}
+
+	if err := db.reader(ctx).SelectContext(ctx, &usages, db.query(constants.ListAlbumUsageQuery),
+		tenant.FromContext(ctx)); err != nil {
+		db.log.Errorf("error while listing album usage: %v", err)
+
+		return nil, fmt.Errorf("%w", err)
+	}
+
+	return usages, nil
+}
+
+// GetTenantUsage returns the usage of all the albums of the tenant.
+func (db *DBHandler) GetTenantUsage(ctx context.Context) (dbmodels.Usage, error) {
+	res := dbmodels.Usage{}
+
+	if err := db.reader(ctx).GetContext(ctx, &res, db.query(constants.GetTenantUsageQuery),
+		tenant.FromContext(ctx)); err != nil {
+		db.log.Errorf("error while getting tenant usage: %v", err)
+
+		return dbmodels.Usage{}, fmt.Errorf("%w", err)
+	}
+
+	return res, nil
+}
+
+// addAlbumUsage adds images and bytes, negative to remove them, to the usage
+// of the album.
+func (db *DBHandler) addAlbumUsage(ctx context.Context, txn *sqlx.Tx, albumName string, images, bytes int64) error {
+	_, err := txn.ExecContext(ctx, db.query(constants.AddAlbumUsageQuery), images, bytes, tenant.FromContext(ctx), albumName)
+	if err != nil {
+		return fmt.Errorf("error while updating usage of album %s, %w", albumName, err)
+	}
+
+	return nil
+}
+
+// imageSize returns the size of the image as dbmodels.Image.Size counts it,
+// ErrNoDataFound when the album has no image of that name. Sealed content is
+// opened to measure it, the length of plain content is enough.
+func (db *DBHandler) imageSize(ctx context.Context, txn *sqlx.Tx, imageName, albumName string) (int64, error) {
+	var stored struct {
+		Size  int64  `db:"size"`
+		KeyID string `db:"keyId"`
+	}
+
+	err := txn.GetContext(ctx, &stored, db.query(constants.GetImageSizeQuery), tenant.FromContext(ctx), imageName, albumName)
+	if err != nil {
+		return 0, db.translateError(err)
+	}
+
+	if stored.KeyID == "" {
+		return stored.Size, nil
+	}
+
+	var image dbmodels.Image
+	if err = txn.GetContext(ctx, &image, db.query(constants.GetAlbumImageQuery),
+		tenant.FromContext(ctx), imageName, albumName); err != nil {
+		return 0, db.translateError(err)
+	}
+
+	if err = db.openImage(ctx, &image); err != nil {
+		return 0, err
+	}
+
+	return image.Size(), nil
+}
@@ -0,0 +1,71 @@
+package dbhandler
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"github.com/jmoiron/sqlx"
+	"githum.com/anupam111/image-store/internal/constants"
+	"githum.com/anupam111/image-store/internal/db/dbmodels"
+	"time"
+)
+
+var _ APIKeyStore = (*DBHandler)(nil)
+
+func (db *DBHandler) CreateAPIKey(ctx context.Context, key dbmodels.APIKey) error {
+	return db.runTx(ctx, func(txn *sqlx.Tx) error {
+		if _, err := txn.NamedExecContext(ctx, db.query(constants.InsertAPIKeyQuery), key); err != nil {
+			return db.translateError(err)
+		}
+
+		return nil
+	})
+}
+
+func (db *DBHandler) ListAPIKeys(ctx context.Context) ([]dbmodels.APIKey, error) {
+	keys := []dbmodels.APIKey{}
+
+	if err := db.reader(ctx).SelectContext(ctx, &keys, db.query(constants.ListAPIKeysQuery)); err != nil {
+		db.log.Errorf("error while listing api keys: %v", err)
+
+		return nil, fmt.Errorf("%w", err)
+	}
+
+	return keys, nil
+}
+
+// GetAPIKeyByHash returns the API key, revoked or not, with the hash.
+func (db *DBHandler) GetAPIKeyByHash(ctx context.Context, hash string) (dbmodels.APIKey, error) {
+	res := dbmodels.APIKey{}
+
+	if err := db.reader(ctx).GetContext(ctx, &res, db.query(constants.GetAPIKeyByHashQuery), hash); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return dbmodels.APIKey{}, ErrNoDataFound
+		}
+
+		db.log.Errorf("error while getting api key: %v", err)
+
+		return dbmodels.APIKey{}, fmt.Errorf("%w", err)
+	}
+
+	return res, nil
+}
+
+// RevokeAPIKey revokes the API key, it returns ErrNoDataFound when there is
+// no API key with the id which is not revoked yet.
+func (db *DBHandler) RevokeAPIKey(ctx context.Context, id string, revokedAt time.Time) error {
+	return db.runTx(ctx, func(txn *sqlx.Tx) error {
+		result, err := txn.ExecContext(ctx, db.query(constants.RevokeAPIKeyQuery), revokedAt, id)
+		if err != nil {
+			return db.translateError(err)
+		}
+
+		rows, err := result.RowsAffected()
+		if err == nil && rows == 0 {
+			err = ErrNoDataFound
+		}
+
+		return err
+	})
+}
@@ -0,0 +1,124 @@
+package dbhandler
+
+import (
+	"context"
+	"fmt"
+	"github.com/jmoiron/sqlx"
+	"githum.com/anupam111/image-store/internal/constants"
+	"githum.com/anupam111/image-store/internal/db/dbmodels"
+	"githum.com/anupam111/image-store/internal/tenant"
+)
+
+var _ AuditStore = (*DBHandler)(nil)
+
+// CreateAuditEvent appends the event to the audit log of the tenant, the
+// database numbers it.
+func (db *DBHandler) CreateAuditEvent(ctx context.Context, event dbmodels.AuditEvent) error {
+	event.Tenant = tenant.FromContext(ctx)
+
+	return db.runTx(ctx, func(txn *sqlx.Tx) error {
+		if _, err := txn.NamedExecContext(ctx, db.query(constants.InsertAuditEventQuery), event); err != nil {
+			return db.translateError(err)
+		}
+
+		return nil
+	})
+}
+
+// ListAuditEvents returns a page of the events selected by the filter, latest
+// first, and the number of events of all pages. The number of events is 0
+// when offset is past the last event.
+func (db *DBHandler) ListAuditEvents(ctx context.Context, filter dbmodels.AuditFilter,
+	limit, offset int) ([]dbmodels.AuditEvent, int, error) {
+	conditions, args := auditConditions(ctx, filter)
+
+	rows := []struct {
+		dbmodels.AuditEvent
+		Total int `db:"total"`
+	}{}
+
+	query := db.query(fmt.Sprintf(constants.ListAuditEventsQuery, conditions))
+	if err := db.reader(ctx).SelectContext(ctx, &rows, query, append(args, limit, offset)...); err != nil {
+		db.log.Errorf("error while listing audit events: %v", err)
+
+		return nil, 0, fmt.Errorf("%w", err)
+	}
+
+	events := make([]dbmodels.AuditEvent, len(rows))
+	total := 0
+	for idx, row := range rows {
+		events[idx] = row.AuditEvent
+		total = row.Total
+	}
+
+	return events, total, nil
+}
+
+// StreamAuditEvents calls fn with every event selected by the filter, oldest
+// first, without holding them all in memory.
+func (db *DBHandler) StreamAuditEvents(ctx context.Context, filter dbmodels.AuditFilter,
+	fn func(dbmodels.AuditEvent) error) error {
+	conditions, args := auditConditions(ctx, filter)
+
+	rows, err := db.reader(ctx).QueryxContext(ctx, db.query(fmt.Sprintf(constants.StreamAuditEventsQuery, conditions)), args...)
+	if err != nil {
+		return fmt.Errorf("%w", err)
+	}
+
+	defer rows.Close()
+
+	for rows.Next() {
+		event := dbmodels.AuditEvent{}
+
+		if err = rows.StructScan(&event); err != nil {
+			return fmt.Errorf("error while scanning db data to audit event: %w", err)
+		}
+
+		if err = fn(event); err != nil {
+			return err
+		}
+	}
+
+	if err = rows.Err(); err != nil {
+		return fmt.Errorf("%w", err)
+	}
+
+	return nil
+}
+
+// auditConditions returns the conditions of the filter, to append to the
+// audit queries, and the args of the queries up to the conditions.
+func auditConditions(ctx context.Context, filter dbmodels.AuditFilter) (string, []interface{}) {
+	var (
+		conditions string
+		args       = []interface{}{tenant.FromContext(ctx)}
+	)
+
+	for _, field := range []struct {
+		column, value string
+	}{
+		{column: "actor", value: filter.Actor},
+		{column: "action", value: filter.Action},
+		{column: "albumName", value: filter.AlbumName},
+		{column: "target", value: filter.Target},
+		{column: "outcome", value: filter.Outcome},
+		{column: "requestId", value: filter.RequestID},
+	} {
+		if field.value != "" {
+			conditions += ` AND "` + field.column + `"=?`
+			args = append(args, field.value)
+		}
+	}
+
+	if !filter.From.IsZero() {
+		conditions += ` AND "occurredAt">=?`
+		args = append(args, filter.From)
+	}
+
+	if !filter.To.IsZero() {
+		conditions += ` AND "occurredAt"<?`
+		args = append(args, filter.To)
+	}
+
+	return conditions, args
+}
@@ -0,0 +1,105 @@
+package dbhandler_test
+
+import (
+	"github.com/kelseyhightower/envconfig"
+	"github.com/sirupsen/logrus"
+	"githum.com/anupam111/image-store/internal/config"
+	"githum.com/anupam111/image-store/internal/db/dbconnection"
+	"githum.com/anupam111/image-store/internal/db/dbhandler"
+	"githum.com/anupam111/image-store/internal/db/dbhandler/storetest"
+	"githum.com/anupam111/image-store/internal/db/dialect"
+	"githum.com/anupam111/image-store/internal/db/migration"
+	"os"
+	"testing"
+)
+
+// newSQLStore returns a store of the database of dbConfig, prepared by
+// newSQLPool, which keeps the image content in plain.
+func newSQLStore(t *testing.T, dbConfig config.DBConfig) *dbhandler.DBHandler {
+	t.Helper()
+
+	return dbhandler.NewDBHandler(logrus.New(), newSQLPool(t, dbConfig), nil)
+}
+
+// newSQLPool connects to the database of dbConfig, migrates it and empties
+// its tables.
+func newSQLPool(t *testing.T, dbConfig config.DBConfig) *dbconnection.Pool {
+	t.Helper()
+	pool, err := dbconnection.New(&dbConfig)
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening the database", err)
+	}
+
+	t.Cleanup(func() {
+		pool.DB.Close()
+	})
+
+	log := logrus.New()
+	migrator, err := migration.NewMigrator(log, pool.DB)
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when setting up migrations", err)
+	}
+
+	defer migrator.Close()
+
+	if err = migrator.Up(0); err != nil {
+		t.Fatalf("an error '%s' was not expected when migrating", err)
+	}
+
+	for _, table := range []string{"Image", "AlbumAccess", "AlbumShare", "AlbumUsage", "Album", "ApiKey"} {
+		if _, err = pool.DB.Exec("DELETE FROM " + table); err != nil {
+			t.Fatalf("an error '%s' was not expected when emptying %s", err, table)
+		}
+	}
+
+	// The audit log is append-only, it can only be truncated. SQLite has no
+	// TRUNCATE, its databases are in memory.
+	if pool.DB.DriverName() != dialect.SQLiteDriver {
+		if _, err = pool.DB.Exec("TRUNCATE TABLE AuditEvent"); err != nil {
+			t.Fatalf("an error '%s' was not expected when emptying AuditEvent", err)
+		}
+	}
+
+	return pool
+}
+
+func TestConformance_SQLite(t *testing.T) {
+	sqliteConfig := config.DBConfig{
+		DriverName: dialect.SQLiteDriver,
+		Name:       ":memory:",
+	}
+
+	storetest.Run(t, func(t *testing.T) dbhandler.ImageStore {
+		return newSQLStore(t, sqliteConfig)
+	})
+	storetest.RunAPIKeys(t, func(t *testing.T) dbhandler.APIKeyStore {
+		return newSQLStore(t, sqliteConfig)
+	})
+	storetest.RunAudit(t, func(t *testing.T) dbhandler.AuditStore {
+		return newSQLStore(t, sqliteConfig)
+	})
+}
+
+// TestConformance_External runs the suite against the database configured by
+// the DB_ variables prefixed with TEST_, e.g. TEST_DB_DRIVER=postgres. Its
+// tables are emptied.
+func TestConformance_External(t *testing.T) {
+	if os.Getenv("TEST_DB_DRIVER") == "" {
+		t.Skip("TEST_DB_DRIVER not set")
+	}
+
+	var dbConfig config.DBConfig
+	if err := envconfig.Process("TEST", &dbConfig); err != nil {
+		t.Fatalf("an error '%s' was not expected when reading the database config", err)
+	}
+
+	storetest.Run(t, func(t *testing.T) dbhandler.ImageStore {
+		return newSQLStore(t, dbConfig)
+	})
+	storetest.RunAPIKeys(t, func(t *testing.T) dbhandler.APIKeyStore {
+		return newSQLStore(t, dbConfig)
+	})
+	storetest.RunAudit(t, func(t *testing.T) dbhandler.AuditStore {
+		return newSQLStore(t, dbConfig)
+	})
+}
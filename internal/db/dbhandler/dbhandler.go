@@ -3,14 +3,20 @@ package dbhandler
 //go:generate mockgen -source ./dbhandler.go -package dbhandler -destination dbhandler_mock.go
 
 import (
+	"context"
 	"database/sql"
 	"errors"
 	"fmt"
-	"github.com/lib/pq"
+	"github.com/jmoiron/sqlx"
 	log "github.com/sirupsen/logrus"
 	"githum.com/anupam111/image-store/internal/constants"
 	"githum.com/anupam111/image-store/internal/db/dbconnection"
 	"githum.com/anupam111/image-store/internal/db/dbmodels"
+	"githum.com/anupam111/image-store/internal/db/dialect"
+	"githum.com/anupam111/image-store/internal/encryption"
+	"githum.com/anupam111/image-store/internal/tenant"
+	"strings"
+	"time"
 )
 
 var (
@@ -18,127 +24,215 @@ var (
 	ErrNoDataFound = errors.New("no record found")
 )
 
+// BatchError reports which item of a transactional batch operation failed.
+type BatchError struct {
+	Index int
+	Err   error
+}
+
+func (e *BatchError) Error() string {
+	return fmt.Sprintf("batch item %d: %v", e.Index, e.Err)
+}
+
+func (e *BatchError) Unwrap() error {
+	return e.Err
+}
+
+// ImageStore stores the albums and images. Every call acts on the tenant of
+// its context, albums and images of other tenants are invisible to it.
+// CreateAuditEvent records the writes of a WithTx callback in the same
+// transaction.
 type ImageStore interface {
-	CreateAlbum(album dbmodels.Album) error
-	CreateImage(image dbmodels.Image) error
-	DeleteAlbum(albumName string) error
-	DeleteAllImagesOfAlbum(albumName string) error
-	DeleteImageWithImageName(imageName, albumName string) error
-	GetImageByID(imageID string) (dbmodels.Image, error)
-	GetAllImages(albumName string) ([]dbmodels.Image, error)
+	CreateAlbum(ctx context.Context, album dbmodels.Album) error
+	CreateImage(ctx context.Context, image dbmodels.Image) error
+	DeleteAlbum(ctx context.Context, albumName string) error
+	DeleteAllImagesOfAlbum(ctx context.Context, albumName string) error
+	DeleteImageWithImageName(ctx context.Context, imageName, albumName string) error
+	GetImageByID(ctx context.Context, imageID string) (dbmodels.Image, error)
+	GetAllImages(ctx context.Context, albumName string) ([]dbmodels.Image, error)
+	GetAlbum(ctx context.Context, albumName string) (dbmodels.Album, error)
+	StreamImages(ctx context.Context, albumName string, fn func(dbmodels.Image) error) error
+	ListAlbums(ctx context.Context) ([]dbmodels.Album, error)
+	GetAlbumImage(ctx context.Context, albumName, imageName string) (dbmodels.Image, error)
+	UpdateImage(ctx context.Context, key dbmodels.ImageKey, image dbmodels.Image) error
+	GetImagesOfAlbums(ctx context.Context, albumNames []string) ([]dbmodels.Image, error)
+	WithTx(ctx context.Context, fn func(tx ImageStore) error) error
+	SearchImages(ctx context.Context, text string, albumNames []string, limit, offset int) ([]dbmodels.ImageMatch, int, error)
+	FilterImages(ctx context.Context, albumName string, filters []dbmodels.MetadataFilter) ([]dbmodels.Image, error)
+	GrantAlbumAccess(ctx context.Context, access dbmodels.AlbumAccess) error
+	RevokeAlbumAccess(ctx context.Context, albumName, principalType, principal string) error
+	DeleteAlbumAccess(ctx context.Context, albumName string) error
+	ListAlbumAccess(ctx context.Context, albumName string) ([]dbmodels.AlbumAccess, error)
+	ListPrincipalAccess(ctx context.Context, user string, groups []string) ([]dbmodels.AlbumAccess, error)
+	CreateAlbumShare(ctx context.Context, share dbmodels.AlbumShare) error
+	ListAlbumShares(ctx context.Context, albumName string) ([]dbmodels.AlbumShare, error)
+	GetAlbumShareByHash(ctx context.Context, hash string) (dbmodels.AlbumShare, error)
+	RevokeAlbumShare(ctx context.Context, albumName, id string, revokedAt time.Time) error
+	DeleteAlbumShares(ctx context.Context, albumName string) error
+	GetAlbumUsage(ctx context.Context, albumName string) (dbmodels.AlbumUsage, error)
+	ListAlbumUsage(ctx context.Context) ([]dbmodels.AlbumUsage, error)
+	GetTenantUsage(ctx context.Context) (dbmodels.Usage, error)
+	CreateAuditEvent(ctx context.Context, event dbmodels.AuditEvent) error
+}
+
+// APIKeyStore stores the API keys. The keys are not scoped by the tenant of
+// the context, each key tells the tenant it is bound to. WithAPIKeyTx is
+// WithTx for the API keys, CreateAuditEvent records their writes in the same
+// transaction.
+type APIKeyStore interface {
+	CreateAPIKey(ctx context.Context, key dbmodels.APIKey) error
+	ListAPIKeys(ctx context.Context) ([]dbmodels.APIKey, error)
+	GetAPIKeyByHash(ctx context.Context, hash string) (dbmodels.APIKey, error)
+	RevokeAPIKey(ctx context.Context, id string, revokedAt time.Time) error
+	WithAPIKeyTx(ctx context.Context, fn func(tx APIKeyStore) error) error
+	CreateAuditEvent(ctx context.Context, event dbmodels.AuditEvent) error
+}
+
+// AuditStore appends the events of the audit log and reads them back, scoped
+// by the tenant of the context. The events are never updated nor deleted.
+type AuditStore interface {
+	CreateAuditEvent(ctx context.Context, event dbmodels.AuditEvent) error
+	ListAuditEvents(ctx context.Context, filter dbmodels.AuditFilter, limit, offset int) ([]dbmodels.AuditEvent, int, error)
+	StreamAuditEvents(ctx context.Context, filter dbmodels.AuditFilter, fn func(dbmodels.AuditEvent) error) error
 }
 
 type DBHandler struct {
 	log        *log.Logger
 	connection *dbconnection.Pool
+	// envelope seals the content of the images, which is stored in plain
+	// when it is nil.
+	envelope *encryption.Envelope
+	// tx is set on the handlers passed to WithTx callbacks, which run all their
+	// statements in it.
+	tx *sqlx.Tx
 }
 
-// NewDBHandler implements DBHandler.
-func NewDBHandler(log *log.Logger, connection *dbconnection.Pool) *DBHandler {
+// NewDBHandler implements DBHandler. The content of the images is encrypted
+// at rest with envelope, unless it is nil.
+func NewDBHandler(log *log.Logger, connection *dbconnection.Pool, envelope *encryption.Envelope) *DBHandler {
 	return &DBHandler{
 		log:        log,
 		connection: connection,
+		envelope:   envelope,
 	}
 }
 
-func (db *DBHandler) CreateAlbum(album dbmodels.Album) error {
-	txn := db.connection.DB.MustBegin()
-	if _, err := txn.NamedExec(
-		`INSERT INTO Album(
-			"albumName"
-		) VALUES(
-			:albumName
-		)`,
-		album,
-	); err != nil {
-		var pqError *pq.Error
-		if errors.As(err, &pqError) {
-			if pqError.Code.Name() == "unique_violation" {
-				return ErrDuplicate
-			}
-		}
+// WithTx runs fn in a single transaction, which is committed when fn returns
+// nil and rolled back otherwise. The ImageStore passed to fn executes every
+// call in that transaction, so fn can compose several calls into one atomic
+// operation. A transaction which fails to serialize with a concurrent one is
+// retried up to constants.MaxTxRetries times, fn must therefore be safe to
+// run more than once. Nested calls join the outer transaction.
+func (db *DBHandler) WithTx(ctx context.Context, fn func(tx ImageStore) error) error {
+	return db.runTx(ctx, func(txn *sqlx.Tx) error {
+		return fn(db.inTx(txn))
+	})
+}
 
-		return fmt.Errorf("%w", handlerError(err, txn))
-	}
+// WithAPIKeyTx is WithTx for the API keys.
+func (db *DBHandler) WithAPIKeyTx(ctx context.Context, fn func(tx APIKeyStore) error) error {
+	return db.runTx(ctx, func(txn *sqlx.Tx) error {
+		return fn(db.inTx(txn))
+	})
+}
 
-	if err := handlerError(nil, txn); err != nil {
-		return fmt.Errorf("%w", err)
+// inTx returns a handler running all its statements in txn.
+func (db *DBHandler) inTx(txn *sqlx.Tx) *DBHandler {
+	return &DBHandler{
+		log:        db.log,
+		connection: db.connection,
+		envelope:   db.envelope,
+		tx:         txn,
 	}
-
-	return nil
 }
 
-func (db *DBHandler) CreateImage(image dbmodels.Image) error {
-	txn := db.connection.DB.MustBegin()
-	if _, err := txn.NamedExec(
-		`INSERT INTO Image(
-			"imageName",
-            "albumName",
-        	"image"
-		) VALUES(
-		    :imageName,
-			:albumName,
-			:image
-		)`,
-		image,
-	); err != nil {
-		var pqError *pq.Error
-		if errors.As(err, &pqError) {
-			if pqError.Code.Name() == "unique_violation" {
-				return ErrDuplicate
-			}
+// CreateAlbum creates the album, and its empty usage, in the tenant of ctx.
+// Album names are unique per tenant.
+func (db *DBHandler) CreateAlbum(ctx context.Context, album dbmodels.Album) error {
+	album.Tenant = tenant.FromContext(ctx)
+
+	return db.runTx(ctx, func(txn *sqlx.Tx) error {
+		if _, err := txn.NamedExecContext(ctx, db.query(constants.InsertAlbumQuery), album); err != nil {
+			return db.translateError(err)
 		}
 
-		return fmt.Errorf("%w", handlerError(err, txn))
-	}
+		_, err := txn.ExecContext(ctx, db.query(constants.InsertAlbumUsageQuery), album.Tenant, album.AlbumName)
 
-	if err := handlerError(nil, txn); err != nil {
-		return fmt.Errorf("%w", err)
-	}
-	return nil
+		return db.translateError(err)
+	})
 }
 
-func (db *DBHandler) DeleteAlbum(albumName string) error {
-	err := db.DeleteAllImagesOfAlbum(albumName)
-	if err != nil {
-		fmt.Errorf("%w", err)
+// CreateImage creates the image in the tenant of ctx, image names are unique
+// per tenant.
+func (db *DBHandler) CreateImage(ctx context.Context, image dbmodels.Image) error {
+	image.Tenant = tenant.FromContext(ctx)
+	size := image.Size()
+
+	if err := db.sealImage(ctx, &image); err != nil {
+		return err
 	}
 
-	tx := db.connection.DB.MustBegin()
-	_, err = db.connection.DB.Exec(constants.DeleteAlbum, albumName)
+	return db.runTx(ctx, func(txn *sqlx.Tx) error {
+		if _, err := txn.NamedExecContext(ctx, db.query(constants.InsertImageQuery), image); err != nil {
+			return db.translateError(err)
+		}
 
-	if err = handlerError(err, tx); err != nil {
-		return fmt.Errorf("%w", err)
-	}
+		return db.addAlbumUsage(ctx, txn, image.AlbumName, 1, size)
+	})
+}
 
-	return nil
+// DeleteAlbum deletes the album row and its usage only, the images of the
+// album have to be deleted first with DeleteAllImagesOfAlbum in the same
+// transaction.
+func (db *DBHandler) DeleteAlbum(ctx context.Context, albumName string) error {
+	return db.runTx(ctx, func(txn *sqlx.Tx) error {
+		if _, err := txn.ExecContext(ctx, db.query(constants.DeleteAlbumUsageQuery), tenant.FromContext(ctx), albumName); err != nil {
+			return err
+		}
+
+		_, err := txn.ExecContext(ctx, db.query(constants.DeleteAlbum), tenant.FromContext(ctx), albumName)
+
+		return err
+	})
 }
 
-func (db *DBHandler) DeleteImageWithImageName(imageName, albumName string) error {
-	tx := db.connection.DB.MustBegin()
-	_, err := db.connection.DB.Exec(constants.DeleteImageWithImageNameAndAlbumQuery,
-		imageName, albumName)
+func (db *DBHandler) DeleteImageWithImageName(ctx context.Context, imageName, albumName string) error {
+	return db.runTx(ctx, func(txn *sqlx.Tx) error {
+		size, err := db.imageSize(ctx, txn, imageName, albumName)
+		if errors.Is(err, ErrNoDataFound) {
+			return nil
+		}
 
-	if err = handlerError(err, tx); err != nil {
-		return fmt.Errorf("%w", err)
-	}
-	return nil
+		if err != nil {
+			return err
+		}
+
+		if _, err = txn.ExecContext(ctx, db.query(constants.DeleteImageWithImageNameAndAlbumQuery),
+			tenant.FromContext(ctx), imageName, albumName); err != nil {
+			return err
+		}
+
+		return db.addAlbumUsage(ctx, txn, albumName, -1, -size)
+	})
 }
 
-func (db *DBHandler) DeleteAllImagesOfAlbum(albumName string) error {
-	tx := db.connection.DB.MustBegin()
-	_, err := db.connection.DB.Exec(constants.DeleteImagesOfAlbumQuery, albumName)
+func (db *DBHandler) DeleteAllImagesOfAlbum(ctx context.Context, albumName string) error {
+	return db.runTx(ctx, func(txn *sqlx.Tx) error {
+		if _, err := txn.ExecContext(ctx, db.query(constants.DeleteImagesOfAlbumQuery), tenant.FromContext(ctx), albumName); err != nil {
+			return err
+		}
 
-	if err = handlerError(err, tx); err != nil {
-		return fmt.Errorf("%w", err)
-	}
-	return nil
+		_, err := txn.ExecContext(ctx, db.query(constants.ResetAlbumUsageQuery), tenant.FromContext(ctx), albumName)
+
+		return err
+	})
 }
 
-func (db *DBHandler) GetImageByID(imageName string) (dbmodels.Image, error) {
+func (db *DBHandler) GetImageByID(ctx context.Context, imageName string) (dbmodels.Image, error) {
 	res := dbmodels.Image{}
 
-	if err := db.connection.DB.Get(&res, constants.GetImageByIDQuery, imageName); err != nil {
+	if err := db.reader(ctx).GetContext(ctx, &res, db.query(constants.GetImageByIDQuery),
+		tenant.FromContext(ctx), imageName); err != nil {
 		// To handle row not exist
 		if errors.Is(err, sql.ErrNoRows) {
 			db.log.Errorf("image not found for the request=%s", imageName)
@@ -151,17 +245,23 @@ func (db *DBHandler) GetImageByID(imageName string) (dbmodels.Image, error) {
 		return dbmodels.Image{}, fmt.Errorf("%w", err)
 	}
 
+	if err := db.openImage(ctx, &res); err != nil {
+		return dbmodels.Image{}, err
+	}
+
 	return res, nil
 }
 
-func (db *DBHandler) GetAllImages(albumName string) ([]dbmodels.Image, error) {
+func (db *DBHandler) GetAllImages(ctx context.Context, albumName string) ([]dbmodels.Image, error) {
 	images := []dbmodels.Image{}
 
-	rows, err := db.connection.DB.Queryx(constants.GetImagesQuery, albumName)
+	rows, err := db.reader(ctx).QueryxContext(ctx, db.query(constants.GetImagesQuery), tenant.FromContext(ctx), albumName)
 	if err != nil {
-		fmt.Errorf("%w", err)
+		return nil, fmt.Errorf("%w", err)
 	}
 
+	defer rows.Close()
+
 	for rows.Next() {
 		image := dbmodels.Image{}
 
@@ -172,10 +272,296 @@ func (db *DBHandler) GetAllImages(albumName string) ([]dbmodels.Image, error) {
 			return nil, fmt.Errorf("error while scanning db data to image: %w", err)
 		}
 
+		if err = db.openImage(ctx, &image); err != nil {
+			return nil, err
+		}
+
 		images = append(images, image)
 	}
 
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("%w", err)
+	}
+
+	return images, nil
+}
+
+func (db *DBHandler) GetAlbum(ctx context.Context, albumName string) (dbmodels.Album, error) {
+	res := dbmodels.Album{}
+
+	if err := db.reader(ctx).GetContext(ctx, &res, db.query(constants.GetAlbumQuery), tenant.FromContext(ctx), albumName); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			db.log.Errorf("album not found for the request=%s", albumName)
+
+			return dbmodels.Album{}, ErrNoDataFound
+		}
+
+		db.log.Errorf("error while getting album from the table for the request=%s", albumName)
+
+		return dbmodels.Album{}, fmt.Errorf("%w", err)
+	}
+
+	return res, nil
+}
+
+// StreamImages calls fn for every image of the album, one row at a time, so
+// that the album is never held in memory as a whole. Iteration stops at the
+// first error returned by fn.
+func (db *DBHandler) StreamImages(ctx context.Context, albumName string, fn func(dbmodels.Image) error) error {
+	rows, err := db.reader(ctx).QueryxContext(ctx, db.query(constants.GetImagesQuery), tenant.FromContext(ctx), albumName)
+	if err != nil {
+		return fmt.Errorf("%w", err)
+	}
+
 	defer rows.Close()
 
+	for rows.Next() {
+		image := dbmodels.Image{}
+
+		if err = rows.StructScan(&image); err != nil {
+			db.log.Errorf("erro while converting data found from db to image struct: %v", err)
+
+			return fmt.Errorf("error while scanning db data to image: %w", err)
+		}
+
+		if err = db.openImage(ctx, &image); err != nil {
+			return err
+		}
+
+		if err = fn(image); err != nil {
+			return err
+		}
+	}
+
+	if err = rows.Err(); err != nil {
+		return fmt.Errorf("%w", err)
+	}
+
+	return nil
+}
+
+func (db *DBHandler) ListAlbums(ctx context.Context) ([]dbmodels.Album, error) {
+	albums := []dbmodels.Album{}
+
+	if err := db.reader(ctx).SelectContext(ctx, &albums, db.query(constants.ListAlbumsQuery), tenant.FromContext(ctx)); err != nil {
+		db.log.Errorf("error while listing albums: %v", err)
+
+		return nil, fmt.Errorf("%w", err)
+	}
+
+	return albums, nil
+}
+
+func (db *DBHandler) GetAlbumImage(ctx context.Context, albumName, imageName string) (dbmodels.Image, error) {
+	res := dbmodels.Image{}
+
+	if err := db.reader(ctx).GetContext(ctx, &res, db.query(constants.GetAlbumImageQuery),
+		tenant.FromContext(ctx), imageName, albumName); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			db.log.Errorf("image not found for the request=%s/%s", albumName, imageName)
+
+			return dbmodels.Image{}, ErrNoDataFound
+		}
+
+		db.log.Errorf("error while getting image from the table for the request=%s/%s", albumName, imageName)
+
+		return dbmodels.Image{}, fmt.Errorf("%w", err)
+	}
+
+	if err := db.openImage(ctx, &res); err != nil {
+		return dbmodels.Image{}, err
+	}
+
+	return res, nil
+}
+
+// UpdateImage replaces the name and content of the image identified by key.
+// The album of an image can not be changed.
+func (db *DBHandler) UpdateImage(ctx context.Context, key dbmodels.ImageKey, image dbmodels.Image) error {
+	image.Tenant = tenant.FromContext(ctx)
+	image.AlbumName = key.AlbumName
+	size := image.Size()
+
+	if err := db.sealImage(ctx, &image); err != nil {
+		return err
+	}
+
+	return db.runTx(ctx, func(txn *sqlx.Tx) error {
+		current, err := db.imageSize(ctx, txn, key.ImageName, key.AlbumName)
+		if err != nil {
+			return err
+		}
+
+		if _, err = txn.ExecContext(ctx, db.query(constants.UpdateImageQuery),
+			image.ImageName, image.Image, image.Metadata, image.KeyID, tenant.FromContext(ctx), key.ImageName, key.AlbumName); err != nil {
+			return db.translateError(err)
+		}
+
+		return db.addAlbumUsage(ctx, txn, key.AlbumName, 0, size-current)
+	})
+}
+
+// GetImagesOfAlbums returns the images of all the albums with a single query,
+// ordered by album and image name.
+func (db *DBHandler) GetImagesOfAlbums(ctx context.Context, albumNames []string) ([]dbmodels.Image, error) {
+	images := []dbmodels.Image{}
+	if len(albumNames) == 0 {
+		return images, nil
+	}
+
+	query, args, err := sqlx.In(constants.GetImagesOfAlbumsQuery, tenant.FromContext(ctx), albumNames)
+	if err != nil {
+		return nil, fmt.Errorf("%w", err)
+	}
+
+	if err = db.reader(ctx).SelectContext(ctx, &images, db.query(query), args...); err != nil {
+		db.log.Errorf("error while getting images of albums %v: %v", albumNames, err)
+
+		return nil, fmt.Errorf("%w", err)
+	}
+
+	if err = db.openImages(ctx, images); err != nil {
+		return nil, err
+	}
+
 	return images, nil
 }
+
+// FilterImages returns the images of the album selected by all the metadata
+// filters, ordered by image name. The filters the database can not query are
+// applied to the rows read.
+func (db *DBHandler) FilterImages(ctx context.Context, albumName string, filters []dbmodels.MetadataFilter) ([]dbmodels.Image, error) {
+	var (
+		conditions string
+		args       = []interface{}{tenant.FromContext(ctx), albumName}
+		remaining  []dbmodels.MetadataFilter
+	)
+
+	for _, filter := range filters {
+		if err := filter.Validate(); err != nil {
+			return nil, err
+		}
+
+		condition, conditionArgs := db.connection.Dialect.MetadataCondition(filter)
+		if condition == "" {
+			remaining = append(remaining, filter)
+
+			continue
+		}
+
+		conditions += " AND " + condition
+		args = append(args, conditionArgs...)
+	}
+
+	rows := []dbmodels.Image{}
+
+	query := db.query(fmt.Sprintf(constants.FilterImagesQuery, conditions))
+	if err := db.reader(ctx).SelectContext(ctx, &rows, query, args...); err != nil {
+		db.log.Errorf("error while filtering images of album %s: %v", albumName, err)
+
+		return nil, fmt.Errorf("%w", err)
+	}
+
+	images := rows[:0]
+
+	for _, image := range rows {
+		if image.Metadata.Match(remaining) {
+			images = append(images, image)
+		}
+	}
+
+	if err := db.openImages(ctx, images); err != nil {
+		return nil, err
+	}
+
+	return images, nil
+}
+
+// SearchImages returns a page of the images whose image or album name, or
+// the string value of a metadata key such as a description or tags, matches
+// text, best match first, and the number of matches of all pages. Postgres
+// matches the words with its full-text search, the other databases match text
+// anywhere. The number of matches is 0 when offset is past the last match. A
+// search with albumNames only matches the images of those albums.
+func (db *DBHandler) SearchImages(ctx context.Context, text string, albumNames []string,
+	limit, offset int) ([]dbmodels.ImageMatch, int, error) {
+	var (
+		query  string
+		args   []interface{}
+		refine bool
+	)
+
+	albumCondition := ""
+	if len(albumNames) > 0 {
+		albumCondition = constants.SearchAlbumsCondition
+	}
+
+	switch db.connection.Dialect.DriverName() {
+	case dialect.PostgresDriver:
+		query = fmt.Sprintf(constants.SearchImagesQuery, albumCondition)
+		args = []interface{}{text, tenant.FromContext(ctx), text}
+	default:
+		metadataCondition := constants.SearchMetadataJSONCondition
+		if db.connection.Dialect.DriverName() != dialect.MySQLDriver {
+			metadataCondition = constants.SearchMetadataTextCondition
+			refine = true
+		}
+
+		pattern := "%" + likeEscaper.Replace(strings.ToLower(text)) + "%"
+		query = fmt.Sprintf(constants.SearchImagesLikeQuery, metadataCondition, albumCondition)
+		args = []interface{}{pattern, pattern, tenant.FromContext(ctx), pattern, pattern, pattern}
+	}
+
+	if albumCondition != "" {
+		args = append(args, albumNames)
+	}
+
+	if refine {
+		// The metadata condition also matches keys, the matches are checked
+		// and paginated here.
+		args = append(args, -1, 0)
+	} else {
+		args = append(args, limit, offset)
+	}
+
+	query, args, err := sqlx.In(query, args...)
+	if err != nil {
+		return nil, 0, fmt.Errorf("%w", err)
+	}
+
+	rows := []struct {
+		dbmodels.ImageMatch
+		Total int `db:"total"`
+	}{}
+
+	if err = db.reader(ctx).SelectContext(ctx, &rows, db.query(query), args...); err != nil {
+		db.log.Errorf("error while searching images for %q: %v", text, err)
+
+		return nil, 0, fmt.Errorf("%w", err)
+	}
+
+	matches := make([]dbmodels.ImageMatch, 0, len(rows))
+	total := 0
+	for _, row := range rows {
+		if refine && row.Rank <= 1 && !row.Metadata.ContainsText(text) {
+			continue
+		}
+
+		matches = append(matches, row.ImageMatch)
+		total = row.Total
+	}
+
+	if refine {
+		total = len(matches)
+		if offset >= total {
+			return []dbmodels.ImageMatch{}, 0, nil
+		}
+
+		matches = matches[offset:]
+		if len(matches) > limit {
+			matches = matches[:limit]
+		}
+	}
+
+	return matches, total, nil
+}
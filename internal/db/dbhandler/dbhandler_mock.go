@@ -5,7 +5,9 @@
 package dbhandler
 
 import (
+	context "context"
 	reflect "reflect"
+	time "time"
 
 	gomock "github.com/golang/mock/gomock"
 	dbmodels "githum.com/anupam111/image-store/internal/db/dbmodels"
@@ -35,101 +37,613 @@ func (m *MockImageStore) EXPECT() *MockImageStoreMockRecorder {
 }
 
 // CreateAlbum mocks base method.
-func (m *MockImageStore) CreateAlbum(album dbmodels.Album) error {
+func (m *MockImageStore) CreateAlbum(ctx context.Context, album dbmodels.Album) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "CreateAlbum", album)
+	ret := m.ctrl.Call(m, "CreateAlbum", ctx, album)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
 // CreateAlbum indicates an expected call of CreateAlbum.
-func (mr *MockImageStoreMockRecorder) CreateAlbum(album interface{}) *gomock.Call {
+func (mr *MockImageStoreMockRecorder) CreateAlbum(ctx, album interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateAlbum", reflect.TypeOf((*MockImageStore)(nil).CreateAlbum), album)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateAlbum", reflect.TypeOf((*MockImageStore)(nil).CreateAlbum), ctx, album)
+}
+
+// CreateAlbumShare mocks base method.
+func (m *MockImageStore) CreateAlbumShare(ctx context.Context, share dbmodels.AlbumShare) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateAlbumShare", ctx, share)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CreateAlbumShare indicates an expected call of CreateAlbumShare.
+func (mr *MockImageStoreMockRecorder) CreateAlbumShare(ctx, share interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateAlbumShare", reflect.TypeOf((*MockImageStore)(nil).CreateAlbumShare), ctx, share)
+}
+
+// CreateAuditEvent mocks base method.
+func (m *MockImageStore) CreateAuditEvent(ctx context.Context, event dbmodels.AuditEvent) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateAuditEvent", ctx, event)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CreateAuditEvent indicates an expected call of CreateAuditEvent.
+func (mr *MockImageStoreMockRecorder) CreateAuditEvent(ctx, event interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateAuditEvent", reflect.TypeOf((*MockImageStore)(nil).CreateAuditEvent), ctx, event)
 }
 
 // CreateImage mocks base method.
-func (m *MockImageStore) CreateImage(image dbmodels.Image) error {
+func (m *MockImageStore) CreateImage(ctx context.Context, image dbmodels.Image) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "CreateImage", image)
+	ret := m.ctrl.Call(m, "CreateImage", ctx, image)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
 // CreateImage indicates an expected call of CreateImage.
-func (mr *MockImageStoreMockRecorder) CreateImage(image interface{}) *gomock.Call {
+func (mr *MockImageStoreMockRecorder) CreateImage(ctx, image interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateImage", reflect.TypeOf((*MockImageStore)(nil).CreateImage), image)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateImage", reflect.TypeOf((*MockImageStore)(nil).CreateImage), ctx, image)
 }
 
 // DeleteAlbum mocks base method.
-func (m *MockImageStore) DeleteAlbum(albumName string) error {
+func (m *MockImageStore) DeleteAlbum(ctx context.Context, albumName string) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "DeleteAlbum", albumName)
+	ret := m.ctrl.Call(m, "DeleteAlbum", ctx, albumName)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
 // DeleteAlbum indicates an expected call of DeleteAlbum.
-func (mr *MockImageStoreMockRecorder) DeleteAlbum(albumName interface{}) *gomock.Call {
+func (mr *MockImageStoreMockRecorder) DeleteAlbum(ctx, albumName interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteAlbum", reflect.TypeOf((*MockImageStore)(nil).DeleteAlbum), albumName)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteAlbum", reflect.TypeOf((*MockImageStore)(nil).DeleteAlbum), ctx, albumName)
+}
+
+// DeleteAlbumAccess mocks base method.
+func (m *MockImageStore) DeleteAlbumAccess(ctx context.Context, albumName string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteAlbumAccess", ctx, albumName)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteAlbumAccess indicates an expected call of DeleteAlbumAccess.
+func (mr *MockImageStoreMockRecorder) DeleteAlbumAccess(ctx, albumName interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteAlbumAccess", reflect.TypeOf((*MockImageStore)(nil).DeleteAlbumAccess), ctx, albumName)
+}
+
+// DeleteAlbumShares mocks base method.
+func (m *MockImageStore) DeleteAlbumShares(ctx context.Context, albumName string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteAlbumShares", ctx, albumName)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteAlbumShares indicates an expected call of DeleteAlbumShares.
+func (mr *MockImageStoreMockRecorder) DeleteAlbumShares(ctx, albumName interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteAlbumShares", reflect.TypeOf((*MockImageStore)(nil).DeleteAlbumShares), ctx, albumName)
 }
 
 // DeleteAllImagesOfAlbum mocks base method.
-func (m *MockImageStore) DeleteAllImagesOfAlbum(albumName string) error {
+func (m *MockImageStore) DeleteAllImagesOfAlbum(ctx context.Context, albumName string) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "DeleteAllImagesOfAlbum", albumName)
+	ret := m.ctrl.Call(m, "DeleteAllImagesOfAlbum", ctx, albumName)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
 // DeleteAllImagesOfAlbum indicates an expected call of DeleteAllImagesOfAlbum.
-func (mr *MockImageStoreMockRecorder) DeleteAllImagesOfAlbum(albumName interface{}) *gomock.Call {
+func (mr *MockImageStoreMockRecorder) DeleteAllImagesOfAlbum(ctx, albumName interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteAllImagesOfAlbum", reflect.TypeOf((*MockImageStore)(nil).DeleteAllImagesOfAlbum), albumName)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteAllImagesOfAlbum", reflect.TypeOf((*MockImageStore)(nil).DeleteAllImagesOfAlbum), ctx, albumName)
 }
 
 // DeleteImageWithImageName mocks base method.
-func (m *MockImageStore) DeleteImageWithImageName(imageName, albumName string) error {
+func (m *MockImageStore) DeleteImageWithImageName(ctx context.Context, imageName, albumName string) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "DeleteImageWithImageName", imageName, albumName)
+	ret := m.ctrl.Call(m, "DeleteImageWithImageName", ctx, imageName, albumName)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
 // DeleteImageWithImageName indicates an expected call of DeleteImageWithImageName.
-func (mr *MockImageStoreMockRecorder) DeleteImageWithImageName(imageName, albumName interface{}) *gomock.Call {
+func (mr *MockImageStoreMockRecorder) DeleteImageWithImageName(ctx, imageName, albumName interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteImageWithImageName", reflect.TypeOf((*MockImageStore)(nil).DeleteImageWithImageName), ctx, imageName, albumName)
+}
+
+// FilterImages mocks base method.
+func (m *MockImageStore) FilterImages(ctx context.Context, albumName string, filters []dbmodels.MetadataFilter) ([]dbmodels.Image, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FilterImages", ctx, albumName, filters)
+	ret0, _ := ret[0].([]dbmodels.Image)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// FilterImages indicates an expected call of FilterImages.
+func (mr *MockImageStoreMockRecorder) FilterImages(ctx, albumName, filters interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FilterImages", reflect.TypeOf((*MockImageStore)(nil).FilterImages), ctx, albumName, filters)
+}
+
+// GetAlbum mocks base method.
+func (m *MockImageStore) GetAlbum(ctx context.Context, albumName string) (dbmodels.Album, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetAlbum", ctx, albumName)
+	ret0, _ := ret[0].(dbmodels.Album)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetAlbum indicates an expected call of GetAlbum.
+func (mr *MockImageStoreMockRecorder) GetAlbum(ctx, albumName interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetAlbum", reflect.TypeOf((*MockImageStore)(nil).GetAlbum), ctx, albumName)
+}
+
+// GetAlbumImage mocks base method.
+func (m *MockImageStore) GetAlbumImage(ctx context.Context, albumName, imageName string) (dbmodels.Image, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetAlbumImage", ctx, albumName, imageName)
+	ret0, _ := ret[0].(dbmodels.Image)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetAlbumImage indicates an expected call of GetAlbumImage.
+func (mr *MockImageStoreMockRecorder) GetAlbumImage(ctx, albumName, imageName interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetAlbumImage", reflect.TypeOf((*MockImageStore)(nil).GetAlbumImage), ctx, albumName, imageName)
+}
+
+// GetAlbumShareByHash mocks base method.
+func (m *MockImageStore) GetAlbumShareByHash(ctx context.Context, hash string) (dbmodels.AlbumShare, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetAlbumShareByHash", ctx, hash)
+	ret0, _ := ret[0].(dbmodels.AlbumShare)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetAlbumShareByHash indicates an expected call of GetAlbumShareByHash.
+func (mr *MockImageStoreMockRecorder) GetAlbumShareByHash(ctx, hash interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetAlbumShareByHash", reflect.TypeOf((*MockImageStore)(nil).GetAlbumShareByHash), ctx, hash)
+}
+
+// GetAlbumUsage mocks base method.
+func (m *MockImageStore) GetAlbumUsage(ctx context.Context, albumName string) (dbmodels.AlbumUsage, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetAlbumUsage", ctx, albumName)
+	ret0, _ := ret[0].(dbmodels.AlbumUsage)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetAlbumUsage indicates an expected call of GetAlbumUsage.
+func (mr *MockImageStoreMockRecorder) GetAlbumUsage(ctx, albumName interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteImageWithImageName", reflect.TypeOf((*MockImageStore)(nil).DeleteImageWithImageName), imageName, albumName)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetAlbumUsage", reflect.TypeOf((*MockImageStore)(nil).GetAlbumUsage), ctx, albumName)
 }
 
 // GetAllImages mocks base method.
-func (m *MockImageStore) GetAllImages(albumName string) ([]dbmodels.Image, error) {
+func (m *MockImageStore) GetAllImages(ctx context.Context, albumName string) ([]dbmodels.Image, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "GetAllImages", albumName)
+	ret := m.ctrl.Call(m, "GetAllImages", ctx, albumName)
 	ret0, _ := ret[0].([]dbmodels.Image)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // GetAllImages indicates an expected call of GetAllImages.
-func (mr *MockImageStoreMockRecorder) GetAllImages(albumName interface{}) *gomock.Call {
+func (mr *MockImageStoreMockRecorder) GetAllImages(ctx, albumName interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetAllImages", reflect.TypeOf((*MockImageStore)(nil).GetAllImages), albumName)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetAllImages", reflect.TypeOf((*MockImageStore)(nil).GetAllImages), ctx, albumName)
 }
 
 // GetImageByID mocks base method.
-func (m *MockImageStore) GetImageByID(imageID string) (dbmodels.Image, error) {
+func (m *MockImageStore) GetImageByID(ctx context.Context, imageID string) (dbmodels.Image, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "GetImageByID", imageID)
+	ret := m.ctrl.Call(m, "GetImageByID", ctx, imageID)
 	ret0, _ := ret[0].(dbmodels.Image)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // GetImageByID indicates an expected call of GetImageByID.
-func (mr *MockImageStoreMockRecorder) GetImageByID(imageID interface{}) *gomock.Call {
+func (mr *MockImageStoreMockRecorder) GetImageByID(ctx, imageID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetImageByID", reflect.TypeOf((*MockImageStore)(nil).GetImageByID), ctx, imageID)
+}
+
+// GetImagesOfAlbums mocks base method.
+func (m *MockImageStore) GetImagesOfAlbums(ctx context.Context, albumNames []string) ([]dbmodels.Image, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetImagesOfAlbums", ctx, albumNames)
+	ret0, _ := ret[0].([]dbmodels.Image)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetImagesOfAlbums indicates an expected call of GetImagesOfAlbums.
+func (mr *MockImageStoreMockRecorder) GetImagesOfAlbums(ctx, albumNames interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetImagesOfAlbums", reflect.TypeOf((*MockImageStore)(nil).GetImagesOfAlbums), ctx, albumNames)
+}
+
+// GetTenantUsage mocks base method.
+func (m *MockImageStore) GetTenantUsage(ctx context.Context) (dbmodels.Usage, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetTenantUsage", ctx)
+	ret0, _ := ret[0].(dbmodels.Usage)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetTenantUsage indicates an expected call of GetTenantUsage.
+func (mr *MockImageStoreMockRecorder) GetTenantUsage(ctx interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetTenantUsage", reflect.TypeOf((*MockImageStore)(nil).GetTenantUsage), ctx)
+}
+
+// GrantAlbumAccess mocks base method.
+func (m *MockImageStore) GrantAlbumAccess(ctx context.Context, access dbmodels.AlbumAccess) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GrantAlbumAccess", ctx, access)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// GrantAlbumAccess indicates an expected call of GrantAlbumAccess.
+func (mr *MockImageStoreMockRecorder) GrantAlbumAccess(ctx, access interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GrantAlbumAccess", reflect.TypeOf((*MockImageStore)(nil).GrantAlbumAccess), ctx, access)
+}
+
+// ListAlbumAccess mocks base method.
+func (m *MockImageStore) ListAlbumAccess(ctx context.Context, albumName string) ([]dbmodels.AlbumAccess, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListAlbumAccess", ctx, albumName)
+	ret0, _ := ret[0].([]dbmodels.AlbumAccess)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListAlbumAccess indicates an expected call of ListAlbumAccess.
+func (mr *MockImageStoreMockRecorder) ListAlbumAccess(ctx, albumName interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListAlbumAccess", reflect.TypeOf((*MockImageStore)(nil).ListAlbumAccess), ctx, albumName)
+}
+
+// ListAlbumShares mocks base method.
+func (m *MockImageStore) ListAlbumShares(ctx context.Context, albumName string) ([]dbmodels.AlbumShare, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListAlbumShares", ctx, albumName)
+	ret0, _ := ret[0].([]dbmodels.AlbumShare)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListAlbumShares indicates an expected call of ListAlbumShares.
+func (mr *MockImageStoreMockRecorder) ListAlbumShares(ctx, albumName interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListAlbumShares", reflect.TypeOf((*MockImageStore)(nil).ListAlbumShares), ctx, albumName)
+}
+
+// ListAlbumUsage mocks base method.
+func (m *MockImageStore) ListAlbumUsage(ctx context.Context) ([]dbmodels.AlbumUsage, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListAlbumUsage", ctx)
+	ret0, _ := ret[0].([]dbmodels.AlbumUsage)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListAlbumUsage indicates an expected call of ListAlbumUsage.
+func (mr *MockImageStoreMockRecorder) ListAlbumUsage(ctx interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListAlbumUsage", reflect.TypeOf((*MockImageStore)(nil).ListAlbumUsage), ctx)
+}
+
+// ListAlbums mocks base method.
+func (m *MockImageStore) ListAlbums(ctx context.Context) ([]dbmodels.Album, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListAlbums", ctx)
+	ret0, _ := ret[0].([]dbmodels.Album)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListAlbums indicates an expected call of ListAlbums.
+func (mr *MockImageStoreMockRecorder) ListAlbums(ctx interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListAlbums", reflect.TypeOf((*MockImageStore)(nil).ListAlbums), ctx)
+}
+
+// ListPrincipalAccess mocks base method.
+func (m *MockImageStore) ListPrincipalAccess(ctx context.Context, user string, groups []string) ([]dbmodels.AlbumAccess, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListPrincipalAccess", ctx, user, groups)
+	ret0, _ := ret[0].([]dbmodels.AlbumAccess)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListPrincipalAccess indicates an expected call of ListPrincipalAccess.
+func (mr *MockImageStoreMockRecorder) ListPrincipalAccess(ctx, user, groups interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListPrincipalAccess", reflect.TypeOf((*MockImageStore)(nil).ListPrincipalAccess), ctx, user, groups)
+}
+
+// RevokeAlbumAccess mocks base method.
+func (m *MockImageStore) RevokeAlbumAccess(ctx context.Context, albumName, principalType, principal string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RevokeAlbumAccess", ctx, albumName, principalType, principal)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RevokeAlbumAccess indicates an expected call of RevokeAlbumAccess.
+func (mr *MockImageStoreMockRecorder) RevokeAlbumAccess(ctx, albumName, principalType, principal interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RevokeAlbumAccess", reflect.TypeOf((*MockImageStore)(nil).RevokeAlbumAccess), ctx, albumName, principalType, principal)
+}
+
+// RevokeAlbumShare mocks base method.
+func (m *MockImageStore) RevokeAlbumShare(ctx context.Context, albumName, id string, revokedAt time.Time) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RevokeAlbumShare", ctx, albumName, id, revokedAt)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RevokeAlbumShare indicates an expected call of RevokeAlbumShare.
+func (mr *MockImageStoreMockRecorder) RevokeAlbumShare(ctx, albumName, id, revokedAt interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RevokeAlbumShare", reflect.TypeOf((*MockImageStore)(nil).RevokeAlbumShare), ctx, albumName, id, revokedAt)
+}
+
+// SearchImages mocks base method.
+func (m *MockImageStore) SearchImages(ctx context.Context, text string, albumNames []string, limit, offset int) ([]dbmodels.ImageMatch, int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SearchImages", ctx, text, albumNames, limit, offset)
+	ret0, _ := ret[0].([]dbmodels.ImageMatch)
+	ret1, _ := ret[1].(int)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// SearchImages indicates an expected call of SearchImages.
+func (mr *MockImageStoreMockRecorder) SearchImages(ctx, text, albumNames, limit, offset interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SearchImages", reflect.TypeOf((*MockImageStore)(nil).SearchImages), ctx, text, albumNames, limit, offset)
+}
+
+// StreamImages mocks base method.
+func (m *MockImageStore) StreamImages(ctx context.Context, albumName string, fn func(dbmodels.Image) error) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "StreamImages", ctx, albumName, fn)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// StreamImages indicates an expected call of StreamImages.
+func (mr *MockImageStoreMockRecorder) StreamImages(ctx, albumName, fn interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "StreamImages", reflect.TypeOf((*MockImageStore)(nil).StreamImages), ctx, albumName, fn)
+}
+
+// UpdateImage mocks base method.
+func (m *MockImageStore) UpdateImage(ctx context.Context, key dbmodels.ImageKey, image dbmodels.Image) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateImage", ctx, key, image)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpdateImage indicates an expected call of UpdateImage.
+func (mr *MockImageStoreMockRecorder) UpdateImage(ctx, key, image interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateImage", reflect.TypeOf((*MockImageStore)(nil).UpdateImage), ctx, key, image)
+}
+
+// WithTx mocks base method.
+func (m *MockImageStore) WithTx(ctx context.Context, fn func(ImageStore) error) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "WithTx", ctx, fn)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// WithTx indicates an expected call of WithTx.
+func (mr *MockImageStoreMockRecorder) WithTx(ctx, fn interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "WithTx", reflect.TypeOf((*MockImageStore)(nil).WithTx), ctx, fn)
+}
+
+// MockAPIKeyStore is a mock of APIKeyStore interface.
+type MockAPIKeyStore struct {
+	ctrl     *gomock.Controller
+	recorder *MockAPIKeyStoreMockRecorder
+}
+
+// MockAPIKeyStoreMockRecorder is the mock recorder for MockAPIKeyStore.
+type MockAPIKeyStoreMockRecorder struct {
+	mock *MockAPIKeyStore
+}
+
+// NewMockAPIKeyStore creates a new mock instance.
+func NewMockAPIKeyStore(ctrl *gomock.Controller) *MockAPIKeyStore {
+	mock := &MockAPIKeyStore{ctrl: ctrl}
+	mock.recorder = &MockAPIKeyStoreMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockAPIKeyStore) EXPECT() *MockAPIKeyStoreMockRecorder {
+	return m.recorder
+}
+
+// CreateAPIKey mocks base method.
+func (m *MockAPIKeyStore) CreateAPIKey(ctx context.Context, key dbmodels.APIKey) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateAPIKey", ctx, key)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CreateAPIKey indicates an expected call of CreateAPIKey.
+func (mr *MockAPIKeyStoreMockRecorder) CreateAPIKey(ctx, key interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateAPIKey", reflect.TypeOf((*MockAPIKeyStore)(nil).CreateAPIKey), ctx, key)
+}
+
+// CreateAuditEvent mocks base method.
+func (m *MockAPIKeyStore) CreateAuditEvent(ctx context.Context, event dbmodels.AuditEvent) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateAuditEvent", ctx, event)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CreateAuditEvent indicates an expected call of CreateAuditEvent.
+func (mr *MockAPIKeyStoreMockRecorder) CreateAuditEvent(ctx, event interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateAuditEvent", reflect.TypeOf((*MockAPIKeyStore)(nil).CreateAuditEvent), ctx, event)
+}
+
+// GetAPIKeyByHash mocks base method.
+func (m *MockAPIKeyStore) GetAPIKeyByHash(ctx context.Context, hash string) (dbmodels.APIKey, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetAPIKeyByHash", ctx, hash)
+	ret0, _ := ret[0].(dbmodels.APIKey)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetAPIKeyByHash indicates an expected call of GetAPIKeyByHash.
+func (mr *MockAPIKeyStoreMockRecorder) GetAPIKeyByHash(ctx, hash interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetAPIKeyByHash", reflect.TypeOf((*MockAPIKeyStore)(nil).GetAPIKeyByHash), ctx, hash)
+}
+
+// ListAPIKeys mocks base method.
+func (m *MockAPIKeyStore) ListAPIKeys(ctx context.Context) ([]dbmodels.APIKey, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListAPIKeys", ctx)
+	ret0, _ := ret[0].([]dbmodels.APIKey)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListAPIKeys indicates an expected call of ListAPIKeys.
+func (mr *MockAPIKeyStoreMockRecorder) ListAPIKeys(ctx interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListAPIKeys", reflect.TypeOf((*MockAPIKeyStore)(nil).ListAPIKeys), ctx)
+}
+
+// RevokeAPIKey mocks base method.
+func (m *MockAPIKeyStore) RevokeAPIKey(ctx context.Context, id string, revokedAt time.Time) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RevokeAPIKey", ctx, id, revokedAt)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RevokeAPIKey indicates an expected call of RevokeAPIKey.
+func (mr *MockAPIKeyStoreMockRecorder) RevokeAPIKey(ctx, id, revokedAt interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RevokeAPIKey", reflect.TypeOf((*MockAPIKeyStore)(nil).RevokeAPIKey), ctx, id, revokedAt)
+}
+
+// WithAPIKeyTx mocks base method.
+func (m *MockAPIKeyStore) WithAPIKeyTx(ctx context.Context, fn func(APIKeyStore) error) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "WithAPIKeyTx", ctx, fn)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// WithAPIKeyTx indicates an expected call of WithAPIKeyTx.
+func (mr *MockAPIKeyStoreMockRecorder) WithAPIKeyTx(ctx, fn interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "WithAPIKeyTx", reflect.TypeOf((*MockAPIKeyStore)(nil).WithAPIKeyTx), ctx, fn)
+}
+
+// MockAuditStore is a mock of AuditStore interface.
+type MockAuditStore struct {
+	ctrl     *gomock.Controller
+	recorder *MockAuditStoreMockRecorder
+}
+
+// MockAuditStoreMockRecorder is the mock recorder for MockAuditStore.
+type MockAuditStoreMockRecorder struct {
+	mock *MockAuditStore
+}
+
+// NewMockAuditStore creates a new mock instance.
+func NewMockAuditStore(ctrl *gomock.Controller) *MockAuditStore {
+	mock := &MockAuditStore{ctrl: ctrl}
+	mock.recorder = &MockAuditStoreMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockAuditStore) EXPECT() *MockAuditStoreMockRecorder {
+	return m.recorder
+}
+
+// CreateAuditEvent mocks base method.
+func (m *MockAuditStore) CreateAuditEvent(ctx context.Context, event dbmodels.AuditEvent) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateAuditEvent", ctx, event)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CreateAuditEvent indicates an expected call of CreateAuditEvent.
+func (mr *MockAuditStoreMockRecorder) CreateAuditEvent(ctx, event interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateAuditEvent", reflect.TypeOf((*MockAuditStore)(nil).CreateAuditEvent), ctx, event)
+}
+
+// ListAuditEvents mocks base method.
+func (m *MockAuditStore) ListAuditEvents(ctx context.Context, filter dbmodels.AuditFilter, limit, offset int) ([]dbmodels.AuditEvent, int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListAuditEvents", ctx, filter, limit, offset)
+	ret0, _ := ret[0].([]dbmodels.AuditEvent)
+	ret1, _ := ret[1].(int)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// ListAuditEvents indicates an expected call of ListAuditEvents.
+func (mr *MockAuditStoreMockRecorder) ListAuditEvents(ctx, filter, limit, offset interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListAuditEvents", reflect.TypeOf((*MockAuditStore)(nil).ListAuditEvents), ctx, filter, limit, offset)
+}
+
+// StreamAuditEvents mocks base method.
+func (m *MockAuditStore) StreamAuditEvents(ctx context.Context, filter dbmodels.AuditFilter, fn func(dbmodels.AuditEvent) error) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "StreamAuditEvents", ctx, filter, fn)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// StreamAuditEvents indicates an expected call of StreamAuditEvents.
+func (mr *MockAuditStoreMockRecorder) StreamAuditEvents(ctx, filter, fn interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetImageByID", reflect.TypeOf((*MockImageStore)(nil).GetImageByID), imageID)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "StreamAuditEvents", reflect.TypeOf((*MockAuditStore)(nil).StreamAuditEvents), ctx, filter, fn)
 }
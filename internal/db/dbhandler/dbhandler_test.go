@@ -1,12 +1,16 @@
 package dbhandler
 
 import (
+	"context"
 	"errors"
+	"github.com/lib/pq"
 	"github.com/sirupsen/logrus"
 	"github.com/stretchr/testify/assert"
 	sqlxmock "github.com/zhashkevych/go-sqlxmock"
 	"githum.com/anupam111/image-store/internal/db/dbconnection"
 	"githum.com/anupam111/image-store/internal/db/dbmodels"
+	"githum.com/anupam111/image-store/internal/db/dialect"
+	"githum.com/anupam111/image-store/internal/tenant"
 	"reflect"
 	"testing"
 )
@@ -20,8 +24,10 @@ func getMocks(t *testing.T) (sqlxmock.Sqlmock, *DBHandler, func()) {
 	log := logrus.New()
 	dbHandler := NewDBHandler(log,
 		&dbconnection.Pool{
-			DB: sqldb,
+			DB:      sqldb,
+			Dialect: dialect.Postgres{},
 		},
+		nil,
 	)
 	finish := func() {
 		sqldb.Close()
@@ -46,6 +52,11 @@ func TestCreateAlbum(t *testing.T) {
 			name: "OK",
 			mock: func() {
 				mock.ExpectExec("(INSERT INTO Album).*").WithArgs(
+					"default",
+					"test-album",
+				).WillReturnResult(sqlxmock.NewResult(1, 1))
+				mock.ExpectExec("(INSERT INTO AlbumUsage).*").WithArgs(
+					"default",
 					"test-album",
 				).WillReturnResult(sqlxmock.NewResult(1, 1))
 				mock.ExpectCommit()
@@ -56,6 +67,7 @@ func TestCreateAlbum(t *testing.T) {
 			name: "Error",
 			mock: func() {
 				mock.ExpectExec("(INSERT INTO Album).*").WithArgs(
+					"default",
 					"test-album",
 				).WillReturnError(errors.New("SQLError"))
 				mock.ExpectRollback()
@@ -68,7 +80,7 @@ func TestCreateAlbum(t *testing.T) {
 		t.Run(tt.name, func(t *testing.T) {
 			mock.ExpectBegin()
 			tt.mock()
-			err := dbHandler.CreateAlbum(album)
+			err := dbHandler.CreateAlbum(context.Background(), album)
 			if tt.wantErr {
 				assert.NotNil(t, err)
 				assert.EqualError(t, err, tt.errString)
@@ -89,6 +101,7 @@ func TestCreateImage(t *testing.T) {
 		ImageName: "test-image",
 		AlbumName: "test-album",
 		Image:     "abc.jpg",
+		Metadata:  dbmodels.Metadata{"project": "apollo"},
 	}
 
 	tests := []struct {
@@ -101,10 +114,16 @@ func TestCreateImage(t *testing.T) {
 			name: "OK",
 			mock: func() {
 				mock.ExpectExec("(INSERT INTO Image).*").WithArgs(
+					"default",
 					"test-image",
 					"test-album",
 					"abc.jpg",
+					`{"project":"apollo"}`,
+					"",
 				).WillReturnResult(sqlxmock.NewResult(1, 1))
+				mock.ExpectExec("UPDATE AlbumUsage SET").WithArgs(
+					1, 7, "default", "test-album",
+				).WillReturnResult(sqlxmock.NewResult(0, 1))
 				mock.ExpectCommit()
 			},
 			wantErr: false,
@@ -113,9 +132,12 @@ func TestCreateImage(t *testing.T) {
 			name: "Error",
 			mock: func() {
 				mock.ExpectExec("(INSERT INTO Image).*").WithArgs(
+					"default",
 					"test-image",
 					"test-album",
 					"abc.jpg",
+					`{"project":"apollo"}`,
+					"",
 				).WillReturnError(errors.New("SQLError"))
 				mock.ExpectRollback()
 			},
@@ -127,7 +149,7 @@ func TestCreateImage(t *testing.T) {
 		t.Run(tt.name, func(t *testing.T) {
 			mock.ExpectBegin()
 			tt.mock()
-			err := dbHandler.CreateImage(image)
+			err := dbHandler.CreateImage(context.Background(), image)
 			if tt.wantErr {
 				assert.NotNil(t, err)
 				assert.EqualError(t, err, tt.errString)
@@ -154,11 +176,11 @@ func TestCreateImage(t *testing.T) {
 		{
 			name: "OK",
 			mock: func() {
-				mock.ExpectExec("DELETE FROM Image WHERE \"albumName\"=").WithArgs(
-					"test-album",
+				mock.ExpectExec("DELETE FROM Image WHERE \"tenant\"=\\$1 AND \"albumName\"=").WithArgs(
+					"default", "test-album",
 				).WillReturnResult(sqlxmock.NewResult(1, 1))
-				mock.ExpectExec("DELETE FROM Album WHERE \"albumName\"=").WithArgs(
-					"test-album",
+				mock.ExpectExec("DELETE FROM Album WHERE \"tenant\"=\\$1 AND \"albumName\"=").WithArgs(
+					"default", "test-album",
 				).WillReturnResult(sqlxmock.NewResult(1, 1))
 				mock.ExpectCommit()
 			},
@@ -170,7 +192,7 @@ func TestCreateImage(t *testing.T) {
 		t.Run(tt.name, func(t *testing.T) {
 			mock.ExpectBegin()
 			tt.mock()
-			err := dbHandler.DeleteAlbum("test-album")
+			err := dbHandler.DeleteAlbum(context.Background(), "test-album")
 			if tt.wantErr {
 				assert.NotNil(t, err)
 				assert.EqualError(t, err, tt.errString)
@@ -207,7 +229,7 @@ func TestFindSubscription(t *testing.T) {
 			name: "OK",
 			mock: func() {
 				columns := []string{"imageName", "albumName", "image"}
-				mock.ExpectQuery("SELECT \\* FROM Image WHERE \"albumName\"=").WithArgs("test-album").
+				mock.ExpectQuery("SELECT \\* FROM Image WHERE \"tenant\"=\\$1 AND \"albumName\"=").WithArgs("default", "test-album").
 					WillReturnRows(sqlxmock.NewRows(columns).AddRow(
 						"test-image",
 						"test-album",
@@ -217,15 +239,29 @@ func TestFindSubscription(t *testing.T) {
 			expectedSubscriptions: images,
 			expectedErr:           false,
 		},
+		{
+			name: "RowError",
+			mock: func() {
+				columns := []string{"imageName", "albumName", "image"}
+				mock.ExpectQuery("SELECT \\* FROM Image WHERE \"tenant\"=\\$1 AND \"albumName\"=").WithArgs("default", "test-album").
+					WillReturnRows(sqlxmock.NewRows(columns).
+						AddRow("test-image", "test-album", "abc.jpg").
+						AddRow("other-image", "test-album", "def.jpg").
+						RowError(1, errors.New("connection reset")))
+			},
+			errString:   "connection reset",
+			expectedErr: true,
+		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			tt.mock()
 			var err error
-			res, err := dbHandler.GetAllImages("test-album")
+			res, err := dbHandler.GetAllImages(context.Background(), "test-album")
 			if tt.expectedErr {
-				assert.Equal(t, tt.errString, err.Error())
+				assert.EqualError(t, err, tt.errString)
+				assert.Nil(t, res, "a broken iteration returns no partial album")
 			} else {
 				assert.Nil(t, err)
 				reflect.DeepEqual(tt.expectedSubscriptions, res)
@@ -236,3 +272,246 @@ func TestFindSubscription(t *testing.T) {
 		})
 	}
 }
+
+func TestWithTx(t *testing.T) {
+	mock, dbHandler, finish := getMocks(t)
+	defer finish()
+
+	deleteAlbum := func(tx ImageStore) error {
+		if err := tx.DeleteAllImagesOfAlbum(context.Background(), "test-album"); err != nil {
+			return err
+		}
+
+		return tx.DeleteAlbum(context.Background(), "test-album")
+	}
+
+	tests := []struct {
+		name      string
+		mock      func()
+		errString string
+		wantErr   bool
+	}{
+		{
+			name: "OK",
+			mock: func() {
+				mock.ExpectBegin()
+				mock.ExpectExec("DELETE FROM Image WHERE \"tenant\"=\\$1 AND \"albumName\"=").WithArgs(
+					"default", "test-album",
+				).WillReturnResult(sqlxmock.NewResult(1, 1))
+				mock.ExpectExec("UPDATE AlbumUsage SET").WithArgs(
+					"default", "test-album",
+				).WillReturnResult(sqlxmock.NewResult(0, 1))
+				mock.ExpectExec("DELETE FROM AlbumUsage WHERE").WithArgs(
+					"default", "test-album",
+				).WillReturnResult(sqlxmock.NewResult(0, 1))
+				mock.ExpectExec("DELETE FROM Album WHERE \"tenant\"=\\$1 AND \"albumName\"=").WithArgs(
+					"default", "test-album",
+				).WillReturnResult(sqlxmock.NewResult(1, 1))
+				mock.ExpectCommit()
+			},
+			wantErr: false,
+		},
+		{
+			name: "Error",
+			mock: func() {
+				mock.ExpectBegin()
+				mock.ExpectExec("DELETE FROM Image WHERE \"tenant\"=\\$1 AND \"albumName\"=").WithArgs(
+					"default", "test-album",
+				).WillReturnResult(sqlxmock.NewResult(1, 1))
+				mock.ExpectExec("UPDATE AlbumUsage SET").WithArgs(
+					"default", "test-album",
+				).WillReturnResult(sqlxmock.NewResult(0, 1))
+				mock.ExpectExec("DELETE FROM AlbumUsage WHERE").WithArgs(
+					"default", "test-album",
+				).WillReturnResult(sqlxmock.NewResult(0, 1))
+				mock.ExpectExec("DELETE FROM Album WHERE \"tenant\"=\\$1 AND \"albumName\"=").WithArgs(
+					"default", "test-album",
+				).WillReturnError(errors.New("SQLError"))
+				mock.ExpectRollback()
+			},
+			errString: "SQLError",
+			wantErr:   true,
+		},
+		{
+			name: "Retry serialization failure",
+			mock: func() {
+				mock.ExpectBegin()
+				mock.ExpectExec("DELETE FROM Image WHERE \"tenant\"=\\$1 AND \"albumName\"=").WithArgs(
+					"default", "test-album",
+				).WillReturnError(&pq.Error{Code: "40001"})
+				mock.ExpectRollback()
+				mock.ExpectBegin()
+				mock.ExpectExec("DELETE FROM Image WHERE \"tenant\"=\\$1 AND \"albumName\"=").WithArgs(
+					"default", "test-album",
+				).WillReturnResult(sqlxmock.NewResult(1, 1))
+				mock.ExpectExec("UPDATE AlbumUsage SET").WithArgs(
+					"default", "test-album",
+				).WillReturnResult(sqlxmock.NewResult(0, 1))
+				mock.ExpectExec("DELETE FROM AlbumUsage WHERE").WithArgs(
+					"default", "test-album",
+				).WillReturnResult(sqlxmock.NewResult(0, 1))
+				mock.ExpectExec("DELETE FROM Album WHERE \"tenant\"=\\$1 AND \"albumName\"=").WithArgs(
+					"default", "test-album",
+				).WillReturnResult(sqlxmock.NewResult(1, 1))
+				mock.ExpectCommit()
+			},
+			wantErr: false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.mock()
+			err := dbHandler.WithTx(context.Background(), deleteAlbum)
+			if tt.wantErr {
+				assert.NotNil(t, err)
+				assert.EqualError(t, err, tt.errString)
+			} else {
+				assert.Nil(t, err)
+			}
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Errorf("there were unfulfilled expections: %s", err)
+			}
+		})
+	}
+}
+
+func TestStreamImages(t *testing.T) {
+	mock, dbHandler, finish := getMocks(t)
+	defer finish()
+
+	columns := []string{"imageName", "albumName", "image"}
+
+	tests := []struct {
+		name      string
+		mock      func()
+		fnErr     error
+		expected  []string
+		errString string
+	}{
+		{
+			name: "OK",
+			mock: func() {
+				mock.ExpectQuery("SELECT \\* FROM Image WHERE \"tenant\"=\\$1 AND \"albumName\"=").WithArgs("default", "test-album").
+					WillReturnRows(sqlxmock.NewRows(columns).
+						AddRow("image-1", "test-album", "abc").
+						AddRow("image-2", "test-album", "def"))
+			},
+			expected: []string{"image-1", "image-2"},
+		},
+		{
+			name: "CallbackError",
+			mock: func() {
+				mock.ExpectQuery("SELECT \\* FROM Image WHERE \"tenant\"=\\$1 AND \"albumName\"=").WithArgs("default", "test-album").
+					WillReturnRows(sqlxmock.NewRows(columns).
+						AddRow("image-1", "test-album", "abc").
+						AddRow("image-2", "test-album", "def"))
+			},
+			fnErr:     errors.New("write error"),
+			expected:  []string{"image-1"},
+			errString: "write error",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.mock()
+			var got []string
+			err := dbHandler.StreamImages(context.Background(), "test-album", func(image dbmodels.Image) error {
+				got = append(got, image.ImageName)
+
+				return tt.fnErr
+			})
+			if tt.errString != "" {
+				assert.EqualError(t, err, tt.errString)
+			} else {
+				assert.Nil(t, err)
+			}
+			assert.Equal(t, tt.expected, got)
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Errorf("there were unfulfilled expections: %s", err)
+			}
+		})
+	}
+}
+
+func TestUpdateImage(t *testing.T) {
+	mock, dbHandler, finish := getMocks(t)
+	defer finish()
+	key := dbmodels.ImageKey{ImageName: "test-image", AlbumName: "test-album"}
+	image := dbmodels.Image{ImageName: "renamed", AlbumName: "test-album", Image: "abc"}
+
+	tests := []struct {
+		name      string
+		mock      func()
+		errString string
+		wantErr   bool
+	}{
+		{
+			name: "OK",
+			mock: func() {
+				mock.ExpectQuery("SELECT COALESCE\\(LENGTH\\(\"image\"\\), 0\\) AS \"size\", \"keyId\"").WithArgs(
+					"default", "test-image", "test-album",
+				).WillReturnRows(sqlxmock.NewRows([]string{"size", "keyId"}).AddRow(7, ""))
+				mock.ExpectExec("UPDATE Image SET").WithArgs(
+					"renamed", "abc", nil, "", "default", "test-image", "test-album",
+				).WillReturnResult(sqlxmock.NewResult(0, 1))
+				mock.ExpectExec("UPDATE AlbumUsage SET").WithArgs(
+					0, -4, "default", "test-album",
+				).WillReturnResult(sqlxmock.NewResult(0, 1))
+				mock.ExpectCommit()
+			},
+			wantErr: false,
+		},
+		{
+			name: "NotFound",
+			mock: func() {
+				mock.ExpectQuery("SELECT COALESCE\\(LENGTH\\(\"image\"\\), 0\\) AS \"size\", \"keyId\"").WithArgs(
+					"default", "test-image", "test-album",
+				).WillReturnRows(sqlxmock.NewRows([]string{"size", "keyId"}))
+				mock.ExpectRollback()
+			},
+			errString: ErrNoDataFound.Error(),
+			wantErr:   true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mock.ExpectBegin()
+			tt.mock()
+			err := dbHandler.UpdateImage(context.Background(), key, image)
+			if tt.wantErr {
+				assert.NotNil(t, err)
+				assert.EqualError(t, err, tt.errString)
+				assert.ErrorIs(t, err, ErrNoDataFound)
+			} else {
+				assert.Nil(t, err)
+			}
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Errorf("there were unfulfilled expections: %s", err)
+			}
+		})
+	}
+}
+
+func TestGetImagesOfAlbums(t *testing.T) {
+	mock, dbHandler, finish := getMocks(t)
+	defer finish()
+
+	columns := []string{"imageName", "albumName", "image"}
+	mock.ExpectQuery("SELECT \\* FROM Image WHERE \"tenant\"=\\$1 AND \"albumName\" IN \\(.+\\)").
+		WithArgs("acme", "album-1", "album-2").
+		WillReturnRows(sqlxmock.NewRows(columns).
+			AddRow("image-1", "album-1", "abc").
+			AddRow("image-2", "album-2", "def"))
+
+	ctx := tenant.NewContext(context.Background(), "acme")
+	images, err := dbHandler.GetImagesOfAlbums(ctx, []string{"album-1", "album-2"})
+	assert.Nil(t, err)
+	assert.Equal(t, []dbmodels.Image{
+		{ImageName: "image-1", AlbumName: "album-1", Image: "abc"},
+		{ImageName: "image-2", AlbumName: "album-2", Image: "def"},
+	}, images)
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expections: %s", err)
+	}
+}
@@ -0,0 +1,186 @@
+package dbhandler
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"github.com/jmoiron/sqlx"
+	"githum.com/anupam111/image-store/internal/constants"
+	"githum.com/anupam111/image-store/internal/db/dbmodels"
+	"githum.com/anupam111/image-store/internal/encryption"
+	"githum.com/anupam111/image-store/internal/tenant"
+	"strings"
+)
+
+// ErrNoEnvelope is returned when reading an encrypted image with a handler
+// which has no key provider.
+var ErrNoEnvelope = errors.New("image is encrypted and no key provider is configured")
+
+// The first byte of the sealed content of an image tells how its Image was
+// encoded, so that it is read back as it was written.
+const (
+	// contentBase64 is the content of an image whose Image is base64, it is
+	// sealed decoded.
+	contentBase64 = 'b'
+	// contentRaw is the content of an image whose Image is not base64, it is
+	// sealed as is.
+	contentRaw = 'r'
+)
+
+// imageAdditionalData binds the sealed content of an image to the image, so
+// that it can not be copied over another image.
+func imageAdditionalData(image dbmodels.Image) []byte {
+	return []byte(strings.Join([]string{image.Tenant, image.AlbumName, image.ImageName}, "\x00"))
+}
+
+// sealImage encrypts the content of the image, unless the handler has no
+// key provider, and sets the key id of the content. The tenant, album and
+// name of the image have to be set.
+func (db *DBHandler) sealImage(ctx context.Context, image *dbmodels.Image) error {
+	image.KeyID = ""
+	if db.envelope == nil {
+		return nil
+	}
+
+	plaintext := append([]byte{contentRaw}, image.Image...)
+	if !strings.ContainsAny(image.Image, "\r\n") {
+		if content, err := base64.StdEncoding.Strict().DecodeString(image.Image); err == nil {
+			plaintext = append([]byte{contentBase64}, content...)
+		}
+	}
+
+	sealed, err := db.envelope.Seal(ctx, plaintext, imageAdditionalData(*image))
+	if err != nil {
+		return fmt.Errorf("error while encrypting image %s, %w", image.ImageName, err)
+	}
+
+	image.Image = sealed
+	image.KeyID = encryption.KeyID(sealed)
+
+	return nil
+}
+
+// openImage decrypts the content of the image read from the database. The
+// content stored in plain, before encryption was enabled, is left as is.
+func (db *DBHandler) openImage(ctx context.Context, image *dbmodels.Image) error {
+	if !encryption.Sealed(image.Image) {
+		return nil
+	}
+
+	if db.envelope == nil {
+		return fmt.Errorf("error while decrypting image %s, %w", image.ImageName, ErrNoEnvelope)
+	}
+
+	plaintext, err := db.envelope.Open(ctx, image.Image, imageAdditionalData(*image))
+	if err == nil && len(plaintext) == 0 {
+		err = encryption.ErrMalformed
+	}
+
+	if err != nil {
+		db.log.Errorf("error while decrypting image %s/%s: %v", image.AlbumName, image.ImageName, err)
+
+		return fmt.Errorf("error while decrypting image %s, %w", image.ImageName, err)
+	}
+
+	switch plaintext[0] {
+	case contentBase64:
+		image.Image = base64.StdEncoding.EncodeToString(plaintext[1:])
+	case contentRaw:
+		image.Image = string(plaintext[1:])
+	default:
+		return fmt.Errorf("error while decrypting image %s, %w", image.ImageName, encryption.ErrMalformed)
+	}
+
+	image.KeyID = ""
+
+	return nil
+}
+
+func (db *DBHandler) openImages(ctx context.Context, images []dbmodels.Image) error {
+	for idx := range images {
+		if err := db.openImage(ctx, &images[idx]); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// RewrapImages encrypts the content of the images of every tenant which is
+// stored in plain, and rewraps the data keys wrapped by another master key
+// than the current one, batchSize images at a time. It returns the number of
+// images rewritten. The images which fail are logged and left as they are
+// until the next call. Concurrent calls, from several replicas, are safe
+// but duplicate the work.
+func (db *DBHandler) RewrapImages(ctx context.Context, batchSize int) (int, error) {
+	if db.envelope == nil {
+		return 0, nil
+	}
+
+	var (
+		keyID     = db.envelope.CurrentKeyID()
+		last      dbmodels.Image
+		rewritten int
+	)
+
+	for {
+		images := []dbmodels.Image{}
+
+		if err := db.connection.DB.SelectContext(ctx, &images, db.query(constants.ListImagesToRewrapQuery),
+			keyID, keyID, last.Tenant, last.Tenant, last.ImageName, batchSize); err != nil {
+			return rewritten, fmt.Errorf("error while listing images to rewrap, %w", err)
+		}
+
+		for _, image := range images {
+			if err := db.rewrapImage(ctx, image); err != nil {
+				db.log.Errorf("error while rewrapping image %s/%s of tenant %s: %v",
+					image.AlbumName, image.ImageName, image.Tenant, err)
+
+				continue
+			}
+
+			rewritten++
+		}
+
+		if len(images) < batchSize {
+			return rewritten, nil
+		}
+
+		last = images[len(images)-1]
+	}
+}
+
+// rewrapImage encrypts the content of the image of key, or rewraps its data
+// key with the current master key. The usage of its album is unchanged, it
+// counts the content in plain. An image updated meanwhile is left as is, its
+// update sealed it with the current master key.
+func (db *DBHandler) rewrapImage(ctx context.Context, key dbmodels.Image) error {
+	ctx = tenant.NewContext(ctx, key.Tenant)
+
+	return db.runTx(ctx, func(txn *sqlx.Tx) error {
+		var image dbmodels.Image
+		if err := txn.GetContext(ctx, &image, db.query(constants.GetAlbumImageQuery),
+			key.Tenant, key.ImageName, key.AlbumName); err != nil {
+			return db.translateError(err)
+		}
+
+		rewrapped := image
+		if encryption.Sealed(image.Image) {
+			content, err := db.envelope.Rewrap(ctx, image.Image)
+			if err != nil {
+				return err
+			}
+
+			rewrapped.Image = content
+			rewrapped.KeyID = encryption.KeyID(content)
+		} else if err := db.sealImage(ctx, &rewrapped); err != nil {
+			return err
+		}
+
+		_, err := txn.ExecContext(ctx, db.query(constants.RewrapImageQuery),
+			rewrapped.Image, rewrapped.KeyID, key.Tenant, key.ImageName, key.AlbumName, image.Image)
+
+		return err
+	})
+}
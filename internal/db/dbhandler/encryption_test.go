@@ -0,0 +1,185 @@
+package dbhandler_test
+
+import (
+	"context"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"githum.com/anupam111/image-store/internal/config"
+	"githum.com/anupam111/image-store/internal/db/dbconnection"
+	"githum.com/anupam111/image-store/internal/db/dbhandler"
+	"githum.com/anupam111/image-store/internal/db/dbmodels"
+	"githum.com/anupam111/image-store/internal/db/dialect"
+	"githum.com/anupam111/image-store/internal/encryption"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// newEnvelope returns the envelope of a key file with the lines.
+func newEnvelope(t *testing.T, lines ...string) *encryption.Envelope {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "keys")
+	require.NoError(t, os.WriteFile(path, []byte(strings.Join(lines, "\n")), 0o600))
+
+	keys, err := encryption.NewKeyFileProvider(path)
+	require.NoError(t, err)
+
+	return encryption.NewEnvelope(keys)
+}
+
+// storedImages returns the content of the images as stored, by image name.
+func storedImages(t *testing.T, pool *dbconnection.Pool) map[string]string {
+	t.Helper()
+
+	rows := []dbmodels.Image{}
+	require.NoError(t, pool.DB.Select(&rows, `SELECT * FROM Image`))
+
+	images := map[string]string{}
+	for _, row := range rows {
+		images[row.ImageName] = row.Image
+	}
+
+	return images
+}
+
+// storedKeyIDs returns the key id of the images as stored, by image name.
+func storedKeyIDs(t *testing.T, pool *dbconnection.Pool) map[string]string {
+	t.Helper()
+
+	rows := []dbmodels.Image{}
+	require.NoError(t, pool.DB.Select(&rows, `SELECT * FROM Image`))
+
+	keyIDs := map[string]string{}
+	for _, row := range rows {
+		keyIDs[row.ImageName] = row.KeyID
+	}
+
+	return keyIDs
+}
+
+func TestEncryption_SQLite(t *testing.T) {
+	const (
+		keyA = "2022-01 AAECAwQFBgcICQoLDA0ODxAREhMUFRYXGBkaGxwdHh8="
+		keyB = "2022_05 ICEiIyQlJicoKSorLC0uLzAxMjM0NTY3ODk6Ozw9Pj8="
+	)
+
+	ctx := context.Background()
+	pool := newSQLPool(t, config.DBConfig{DriverName: dialect.SQLiteDriver, Name: ":memory:"})
+	plain := dbhandler.NewDBHandler(logrus.New(), pool, nil)
+	store := dbhandler.NewDBHandler(logrus.New(), pool, newEnvelope(t, keyA))
+
+	require.NoError(t, store.CreateAlbum(ctx, dbmodels.Album{AlbumName: "holiday"}))
+	require.NoError(t, plain.CreateImage(ctx, dbmodels.Image{ImageName: "old.png", AlbumName: "holiday", Image: "b2xk"}))
+	require.NoError(t, store.CreateImage(ctx, dbmodels.Image{ImageName: "beach.png", AlbumName: "holiday", Image: "YmVhY2g="}))
+	require.NoError(t, store.CreateImage(ctx, dbmodels.Image{ImageName: "notes.txt", AlbumName: "holiday", Image: "not base64"}))
+
+	stored := storedImages(t, pool)
+	assert.Equal(t, "b2xk", stored["old.png"], "the images stored before encryption stay in plain")
+	assert.Equal(t, map[string]string{"old.png": "", "beach.png": "2022-01", "notes.txt": "2022-01"},
+		storedKeyIDs(t, pool))
+	assert.True(t, strings.HasPrefix(stored["beach.png"], "enc1$2022-01$"))
+	assert.True(t, strings.HasPrefix(stored["notes.txt"], "enc1$2022-01$"))
+
+	expected := map[string]string{"old.png": "b2xk", "beach.png": "YmVhY2g=", "notes.txt": "not base64"}
+	assertImages := func(store *dbhandler.DBHandler) {
+		t.Helper()
+
+		images, err := store.GetAllImages(ctx, "holiday")
+		require.NoError(t, err)
+		require.Len(t, images, len(expected))
+
+		for _, image := range images {
+			assert.Equal(t, expected[image.ImageName], image.Image)
+		}
+
+		image, err := store.GetAlbumImage(ctx, "holiday", "beach.png")
+		require.NoError(t, err)
+		assert.Equal(t, expected["beach.png"], image.Image)
+
+		image, err = store.GetImageByID(ctx, "notes.txt")
+		require.NoError(t, err)
+		assert.Equal(t, "not base64", image.Image)
+
+		images, err = store.GetImagesOfAlbums(ctx, []string{"holiday"})
+		require.NoError(t, err)
+		assert.Len(t, images, len(expected))
+
+		images, err = store.FilterImages(ctx, "holiday", nil)
+		require.NoError(t, err)
+		assert.Len(t, images, len(expected))
+
+		assert.NoError(t, store.StreamImages(ctx, "holiday", func(image dbmodels.Image) error {
+			assert.Equal(t, expected[image.ImageName], image.Image)
+
+			return nil
+		}))
+	}
+
+	assertImages(store)
+
+	// The usage counts the bytes of the content in plain, not as stored.
+	assertUsage := func() {
+		t.Helper()
+
+		var bytes int64
+		for _, image := range expected {
+			bytes += int64(len(image))
+		}
+
+		usage, err := store.GetAlbumUsage(ctx, "holiday")
+		require.NoError(t, err)
+		assert.Equal(t, dbmodels.AlbumUsage{Tenant: "default", AlbumName: "holiday",
+			Usage: dbmodels.Usage{Images: int64(len(expected)), Bytes: bytes}}, usage)
+	}
+
+	assertUsage()
+
+	// The content is bound to its image.
+	_, err := pool.DB.Exec(`UPDATE Image SET "image"=? WHERE "imageName"=?`, stored["beach.png"], "notes.txt")
+	require.NoError(t, err)
+	_, err = store.GetImageByID(ctx, "notes.txt")
+	assert.Error(t, err)
+	_, err = pool.DB.Exec(`UPDATE Image SET "image"=? WHERE "imageName"=?`, stored["notes.txt"], "notes.txt")
+	require.NoError(t, err)
+
+	// An update seals the new content.
+	require.NoError(t, store.UpdateImage(ctx, dbmodels.ImageKey{ImageName: "beach.png", AlbumName: "holiday"},
+		dbmodels.Image{ImageName: "beach.png", Image: "c2Vh"}))
+	assert.NotEqual(t, stored["beach.png"], storedImages(t, pool)["beach.png"])
+	expected["beach.png"] = "c2Vh"
+	assertUsage()
+
+	_, err = plain.GetAlbumImage(ctx, "holiday", "beach.png")
+	assert.ErrorIs(t, err, dbhandler.ErrNoEnvelope)
+
+	// After the rotation of the master key, the images are rewrapped one at a
+	// time, the plain ones encrypted.
+	rotated := dbhandler.NewDBHandler(logrus.New(), pool, newEnvelope(t, keyB, keyA))
+
+	rewritten, err := rotated.RewrapImages(ctx, 1)
+	require.NoError(t, err)
+	assert.Equal(t, 3, rewritten)
+
+	for name, image := range storedImages(t, pool) {
+		assert.True(t, strings.HasPrefix(image, "enc1$2022_05$"), name)
+	}
+
+	assert.Equal(t, map[string]string{"old.png": "2022_05", "beach.png": "2022_05", "notes.txt": "2022_05"},
+		storedKeyIDs(t, pool))
+
+	rewritten, err = rotated.RewrapImages(ctx, 1)
+	require.NoError(t, err)
+	assert.Zero(t, rewritten)
+
+	assertImages(rotated)
+	assertImages(dbhandler.NewDBHandler(logrus.New(), pool, newEnvelope(t, keyB)))
+	assertUsage()
+
+	// The deletion of a sealed image removes its size in plain.
+	require.NoError(t, rotated.DeleteImageWithImageName(ctx, "beach.png", "holiday"))
+	delete(expected, "beach.png")
+	assertUsage()
+}
@@ -0,0 +1,100 @@
+package storetest
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"githum.com/anupam111/image-store/internal/db/dbhandler"
+	"githum.com/anupam111/image-store/internal/db/dbmodels"
+	"githum.com/anupam111/image-store/internal/tenant"
+	"testing"
+)
+
+func access(albumName, principalType, principal, role string) dbmodels.AlbumAccess {
+	return dbmodels.AlbumAccess{
+		Tenant:        tenant.Default,
+		AlbumName:     albumName,
+		PrincipalType: principalType,
+		Principal:     principal,
+		Role:          role,
+	}
+}
+
+func testAlbumAccess(t *testing.T, store dbhandler.ImageStore) {
+	ctx := context.Background()
+
+	require.NoError(t, store.CreateAlbum(ctx, dbmodels.Album{AlbumName: "a-album"}))
+	require.NoError(t, store.CreateAlbum(ctx, dbmodels.Album{AlbumName: "b-album"}))
+
+	accesses, err := store.ListAlbumAccess(ctx, "a-album")
+	assert.NoError(t, err)
+	assert.Empty(t, accesses)
+
+	require.NoError(t, store.GrantAlbumAccess(ctx, access("a-album", dbmodels.PrincipalUser, "bob", dbmodels.RoleViewer)))
+	require.NoError(t, store.GrantAlbumAccess(ctx, access("a-album", dbmodels.PrincipalUser, "alice", dbmodels.RoleOwner)))
+	require.NoError(t, store.GrantAlbumAccess(ctx, access("a-album", dbmodels.PrincipalGroup, "editors", dbmodels.RoleEditor)))
+	require.NoError(t, store.GrantAlbumAccess(ctx, access("b-album", dbmodels.PrincipalUser, "bob", dbmodels.RoleOwner)))
+
+	assert.Error(t, store.GrantAlbumAccess(ctx, access("missing", dbmodels.PrincipalUser, "bob", dbmodels.RoleOwner)),
+		"album does not exist")
+
+	// Granting again replaces the role.
+	require.NoError(t, store.GrantAlbumAccess(ctx, access("a-album", dbmodels.PrincipalUser, "bob", dbmodels.RoleEditor)))
+
+	accesses, err = store.ListAlbumAccess(ctx, "a-album")
+	assert.NoError(t, err)
+	assert.Equal(t, []dbmodels.AlbumAccess{
+		access("a-album", dbmodels.PrincipalGroup, "editors", dbmodels.RoleEditor),
+		access("a-album", dbmodels.PrincipalUser, "alice", dbmodels.RoleOwner),
+		access("a-album", dbmodels.PrincipalUser, "bob", dbmodels.RoleEditor),
+	}, accesses)
+
+	require.NoError(t, store.RevokeAlbumAccess(ctx, "a-album", dbmodels.PrincipalUser, "bob"))
+	assert.ErrorIs(t, store.RevokeAlbumAccess(ctx, "a-album", dbmodels.PrincipalUser, "bob"), dbhandler.ErrNoDataFound)
+	assert.ErrorIs(t, store.RevokeAlbumAccess(ctx, "a-album", dbmodels.PrincipalGroup, "alice"), dbhandler.ErrNoDataFound,
+		"alice is a user")
+
+	assert.Error(t, store.DeleteAlbum(ctx, "a-album"), "album with accesses")
+
+	err = store.WithTx(ctx, func(tx dbhandler.ImageStore) error {
+		if err := tx.DeleteAlbumAccess(ctx, "a-album"); err != nil {
+			return err
+		}
+
+		return tx.DeleteAlbum(ctx, "a-album")
+	})
+	assert.NoError(t, err)
+
+	accesses, err = store.ListAlbumAccess(ctx, "b-album")
+	assert.NoError(t, err)
+	assert.Equal(t, []dbmodels.AlbumAccess{access("b-album", dbmodels.PrincipalUser, "bob", dbmodels.RoleOwner)}, accesses)
+}
+
+func testPrincipalAccess(t *testing.T, store dbhandler.ImageStore) {
+	ctx := context.Background()
+
+	for _, name := range []string{"a-album", "b-album", "c-album"} {
+		require.NoError(t, store.CreateAlbum(ctx, dbmodels.Album{AlbumName: name}))
+	}
+
+	require.NoError(t, store.GrantAlbumAccess(ctx, access("a-album", dbmodels.PrincipalUser, "bob", dbmodels.RoleViewer)))
+	require.NoError(t, store.GrantAlbumAccess(ctx, access("a-album", dbmodels.PrincipalGroup, "editors", dbmodels.RoleEditor)))
+	require.NoError(t, store.GrantAlbumAccess(ctx, access("b-album", dbmodels.PrincipalGroup, "bob", dbmodels.RoleOwner)))
+	require.NoError(t, store.GrantAlbumAccess(ctx, access("c-album", dbmodels.PrincipalUser, "alice", dbmodels.RoleOwner)))
+
+	accesses, err := store.ListPrincipalAccess(ctx, "bob", nil)
+	assert.NoError(t, err)
+	assert.Equal(t, []dbmodels.AlbumAccess{access("a-album", dbmodels.PrincipalUser, "bob", dbmodels.RoleViewer)}, accesses,
+		"the group bob is not the user bob")
+
+	accesses, err = store.ListPrincipalAccess(ctx, "bob", []string{"editors", "viewers"})
+	assert.NoError(t, err)
+	assert.Equal(t, []dbmodels.AlbumAccess{
+		access("a-album", dbmodels.PrincipalGroup, "editors", dbmodels.RoleEditor),
+		access("a-album", dbmodels.PrincipalUser, "bob", dbmodels.RoleViewer),
+	}, accesses)
+
+	accesses, err = store.ListPrincipalAccess(ctx, "carol", nil)
+	assert.NoError(t, err)
+	assert.Empty(t, accesses)
+}
@@ -0,0 +1,108 @@
+package storetest
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"githum.com/anupam111/image-store/internal/db/dbhandler"
+	"githum.com/anupam111/image-store/internal/db/dbmodels"
+	"githum.com/anupam111/image-store/internal/tenant"
+	"testing"
+	"time"
+)
+
+func albumShare(id, albumName string, createdAt time.Time) dbmodels.AlbumShare {
+	return dbmodels.AlbumShare{
+		ID:        id,
+		Tenant:    tenant.Default,
+		AlbumName: albumName,
+		Hash:      "hash of " + id,
+		CreatedBy: "alice",
+		CreatedAt: createdAt,
+	}
+}
+
+// assertAlbumShare compares the times with Equal, databases may read them
+// back in another location.
+func assertAlbumShare(t *testing.T, expected, actual dbmodels.AlbumShare) {
+	t.Helper()
+
+	assert.True(t, expected.CreatedAt.Equal(actual.CreatedAt), "created at %v, expected %v", actual.CreatedAt, expected.CreatedAt)
+
+	for _, times := range [][2]*time.Time{{expected.ExpiresAt, actual.ExpiresAt}, {expected.RevokedAt, actual.RevokedAt}} {
+		if times[0] == nil {
+			assert.Nil(t, times[1])
+		} else if assert.NotNil(t, times[1]) {
+			assert.True(t, times[0].Equal(*times[1]), "%v, expected %v", *times[1], *times[0])
+		}
+	}
+
+	expected.CreatedAt, actual.CreatedAt = time.Time{}, time.Time{}
+	expected.ExpiresAt, actual.ExpiresAt = nil, nil
+	expected.RevokedAt, actual.RevokedAt = nil, nil
+	assert.Equal(t, expected, actual)
+}
+
+func testAlbumShares(t *testing.T, store dbhandler.ImageStore) {
+	ctx := context.Background()
+
+	require.NoError(t, store.CreateAlbum(ctx, dbmodels.Album{AlbumName: "a-album"}))
+	require.NoError(t, store.CreateAlbum(ctx, dbmodels.Album{AlbumName: "b-album"}))
+
+	expiresAt := keyCreatedAt.Add(24 * time.Hour)
+	second := albumShare("share-2", "a-album", keyCreatedAt.Add(time.Hour))
+	second.PasswordHash = "password hash"
+	second.AllowDownload = true
+	second.ExpiresAt = &expiresAt
+	first := albumShare("share-1", "a-album", keyCreatedAt)
+
+	require.NoError(t, store.CreateAlbumShare(ctx, second))
+	require.NoError(t, store.CreateAlbumShare(ctx, first))
+	require.NoError(t, store.CreateAlbumShare(ctx, albumShare("share-3", "b-album", keyCreatedAt)))
+
+	duplicate := albumShare("share-4", "a-album", keyCreatedAt)
+	duplicate.Hash = first.Hash
+	assert.ErrorIs(t, store.CreateAlbumShare(ctx, duplicate), dbhandler.ErrDuplicate)
+	assert.Error(t, store.CreateAlbumShare(ctx, albumShare("share-5", "missing", keyCreatedAt)), "album does not exist")
+
+	share, err := store.GetAlbumShareByHash(ctx, "hash of share-2")
+	assert.NoError(t, err)
+	assertAlbumShare(t, second, share)
+
+	_, err = store.GetAlbumShareByHash(ctx, "hash of share-4")
+	assert.ErrorIs(t, err, dbhandler.ErrNoDataFound)
+
+	shares, err := store.ListAlbumShares(ctx, "a-album")
+	assert.NoError(t, err)
+
+	if assert.Len(t, shares, 2) {
+		assertAlbumShare(t, first, shares[0])
+		assertAlbumShare(t, second, shares[1])
+	}
+
+	revokedAt := keyCreatedAt.Add(time.Minute)
+	assert.ErrorIs(t, store.RevokeAlbumShare(ctx, "b-album", "share-1", revokedAt), dbhandler.ErrNoDataFound,
+		"share of another album")
+	assert.NoError(t, store.RevokeAlbumShare(ctx, "a-album", "share-1", revokedAt))
+	assert.ErrorIs(t, store.RevokeAlbumShare(ctx, "a-album", "share-1", revokedAt), dbhandler.ErrNoDataFound)
+
+	first.RevokedAt = &revokedAt
+	share, err = store.GetAlbumShareByHash(ctx, first.Hash)
+	assert.NoError(t, err)
+	assertAlbumShare(t, first, share)
+
+	assert.Error(t, store.DeleteAlbum(ctx, "a-album"), "album with shares")
+
+	err = store.WithTx(ctx, func(tx dbhandler.ImageStore) error {
+		if err := tx.DeleteAlbumShares(ctx, "a-album"); err != nil {
+			return err
+		}
+
+		return tx.DeleteAlbum(ctx, "a-album")
+	})
+	assert.NoError(t, err)
+
+	shares, err = store.ListAlbumShares(ctx, "b-album")
+	assert.NoError(t, err)
+	assert.Len(t, shares, 1)
+}
@@ -0,0 +1,91 @@
+package storetest
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"githum.com/anupam111/image-store/internal/db/dbhandler"
+	"githum.com/anupam111/image-store/internal/db/dbmodels"
+	"githum.com/anupam111/image-store/internal/tenant"
+	"testing"
+)
+
+func usage(albumName string, images, bytes int64) dbmodels.AlbumUsage {
+	return dbmodels.AlbumUsage{
+		Tenant:    tenant.Default,
+		AlbumName: albumName,
+		Usage:     dbmodels.Usage{Images: images, Bytes: bytes},
+	}
+}
+
+// testAlbumUsage follows the usage of the albums and of their tenant through
+// the image writes.
+func testAlbumUsage(t *testing.T, store dbhandler.ImageStore) {
+	ctx := context.Background()
+
+	require.NoError(t, store.CreateAlbum(ctx, dbmodels.Album{AlbumName: "a-album"}))
+	require.NoError(t, store.CreateAlbum(ctx, dbmodels.Album{AlbumName: "b-album"}))
+
+	got, err := store.GetAlbumUsage(ctx, "a-album")
+	assert.NoError(t, err)
+	assert.Equal(t, usage("a-album", 0, 0), got, "new albums are empty")
+
+	_, err = store.GetAlbumUsage(ctx, "c-album")
+	assert.ErrorIs(t, err, dbhandler.ErrNoDataFound)
+
+	// The content of image-N takes 18 bytes.
+	for _, img := range []dbmodels.Image{image("a-album", "image-1"), image("a-album", "image-2"), image("b-album", "image-3")} {
+		require.NoError(t, store.CreateImage(ctx, img))
+	}
+
+	assert.Error(t, store.CreateImage(ctx, image("a-album", "image-1")))
+
+	err = store.WithTx(ctx, func(tx dbhandler.ImageStore) error {
+		if err := tx.CreateImage(ctx, image("b-album", "image-4")); err != nil {
+			return err
+		}
+
+		return errRollback
+	})
+	assert.ErrorIs(t, err, errRollback)
+
+	usages, err := store.ListAlbumUsage(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, []dbmodels.AlbumUsage{usage("a-album", 2, 36), usage("b-album", 1, 18)}, usages,
+		"failed and rolled back writes are not counted")
+
+	tenantUsage, err := store.GetTenantUsage(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, dbmodels.Usage{Images: 3, Bytes: 54}, tenantUsage)
+
+	key := dbmodels.ImageKey{ImageName: "image-1", AlbumName: "a-album"}
+	require.NoError(t, store.UpdateImage(ctx, key, dbmodels.Image{ImageName: "image-1", AlbumName: "a-album", Image: "12345"}))
+	require.NoError(t, store.DeleteImageWithImageName(ctx, "image-2", "a-album"))
+	require.NoError(t, store.DeleteImageWithImageName(ctx, "image-2", "a-album"))
+
+	got, err = store.GetAlbumUsage(ctx, "a-album")
+	assert.NoError(t, err)
+	assert.Equal(t, usage("a-album", 1, 5), got)
+
+	require.NoError(t, store.DeleteAllImagesOfAlbum(ctx, "b-album"))
+
+	got, err = store.GetAlbumUsage(ctx, "b-album")
+	assert.NoError(t, err)
+	assert.Equal(t, usage("b-album", 0, 0), got)
+
+	require.NoError(t, store.DeleteAlbum(ctx, "b-album"))
+
+	usages, err = store.ListAlbumUsage(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, []dbmodels.AlbumUsage{usage("a-album", 1, 5)}, usages)
+
+	other := tenant.NewContext(ctx, "acme")
+
+	tenantUsage, err = store.GetTenantUsage(other)
+	assert.NoError(t, err)
+	assert.Equal(t, dbmodels.Usage{}, tenantUsage, "usage is counted per tenant")
+
+	usages, err = store.ListAlbumUsage(other)
+	assert.NoError(t, err)
+	assert.Empty(t, usages)
+}
@@ -0,0 +1,109 @@
+package storetest
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"githum.com/anupam111/image-store/internal/db/dbhandler"
+	"githum.com/anupam111/image-store/internal/db/dbmodels"
+	"testing"
+	"time"
+)
+
+// RunAPIKeys runs the conformance suite of dbhandler.APIKeyStore. newStore
+// returns an empty store for each test case.
+func RunAPIKeys(t *testing.T, newStore func(t *testing.T) dbhandler.APIKeyStore) {
+	t.Helper()
+
+	tests := []struct {
+		name string
+		test func(t *testing.T, store dbhandler.APIKeyStore)
+	}{
+		{name: "APIKeys", test: testAPIKeys},
+		{name: "RevokeAPIKey", test: testRevokeAPIKey},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			tt.test(t, newStore(t))
+		})
+	}
+}
+
+var keyCreatedAt = time.Date(2022, 5, 1, 10, 0, 0, 0, time.UTC)
+
+func apiKey(id string, createdAt time.Time) dbmodels.APIKey {
+	return dbmodels.APIKey{
+		ID:        id,
+		Name:      "name of " + id,
+		Hash:      "hash of " + id,
+		Scopes:    dbmodels.Scopes{dbmodels.ScopeAlbumsRead, dbmodels.ScopeImagesWrite},
+		CreatedAt: createdAt,
+	}
+}
+
+// assertAPIKey compares the times with Equal, databases may read them back in
+// another location.
+func assertAPIKey(t *testing.T, expected, actual dbmodels.APIKey) {
+	t.Helper()
+
+	assert.True(t, expected.CreatedAt.Equal(actual.CreatedAt), "created at %v, expected %v", actual.CreatedAt, expected.CreatedAt)
+
+	if expected.RevokedAt == nil {
+		assert.Nil(t, actual.RevokedAt)
+	} else if assert.NotNil(t, actual.RevokedAt) {
+		assert.True(t, expected.RevokedAt.Equal(*actual.RevokedAt), "revoked at %v, expected %v", *actual.RevokedAt, *expected.RevokedAt)
+	}
+
+	expected.CreatedAt, actual.CreatedAt = time.Time{}, time.Time{}
+	expected.RevokedAt, actual.RevokedAt = nil, nil
+	assert.Equal(t, expected, actual)
+}
+
+func testAPIKeys(t *testing.T, store dbhandler.APIKeyStore) {
+	ctx := context.Background()
+
+	second := apiKey("key-2", keyCreatedAt.Add(time.Hour))
+	second.Tenant = "acme"
+	first := apiKey("key-1", keyCreatedAt)
+
+	require.NoError(t, store.CreateAPIKey(ctx, second))
+	require.NoError(t, store.CreateAPIKey(ctx, first))
+
+	duplicate := apiKey("key-3", keyCreatedAt)
+	duplicate.Hash = first.Hash
+	assert.ErrorIs(t, store.CreateAPIKey(ctx, duplicate), dbhandler.ErrDuplicate)
+
+	key, err := store.GetAPIKeyByHash(ctx, "hash of key-2")
+	assert.NoError(t, err)
+	assertAPIKey(t, second, key)
+
+	_, err = store.GetAPIKeyByHash(ctx, "hash of key-3")
+	assert.ErrorIs(t, err, dbhandler.ErrNoDataFound)
+
+	keys, err := store.ListAPIKeys(ctx)
+	assert.NoError(t, err)
+
+	if assert.Len(t, keys, 2) {
+		assertAPIKey(t, first, keys[0])
+		assertAPIKey(t, second, keys[1])
+	}
+}
+
+func testRevokeAPIKey(t *testing.T, store dbhandler.APIKeyStore) {
+	ctx := context.Background()
+
+	key := apiKey("key-1", keyCreatedAt)
+	require.NoError(t, store.CreateAPIKey(ctx, key))
+
+	revokedAt := keyCreatedAt.Add(time.Minute)
+	assert.NoError(t, store.RevokeAPIKey(ctx, "key-1", revokedAt))
+	assert.ErrorIs(t, store.RevokeAPIKey(ctx, "key-1", revokedAt), dbhandler.ErrNoDataFound)
+	assert.ErrorIs(t, store.RevokeAPIKey(ctx, "key-2", revokedAt), dbhandler.ErrNoDataFound)
+
+	key.RevokedAt = &revokedAt
+	got, err := store.GetAPIKeyByHash(ctx, key.Hash)
+	assert.NoError(t, err)
+	assertAPIKey(t, key, got)
+}
@@ -0,0 +1,201 @@
+package storetest
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"githum.com/anupam111/image-store/internal/db/dbhandler"
+	"githum.com/anupam111/image-store/internal/db/dbmodels"
+	"githum.com/anupam111/image-store/internal/tenant"
+	"strings"
+	"testing"
+	"time"
+)
+
+// RunAudit runs the conformance suite of dbhandler.AuditStore. newStore
+// returns an empty store for each test case.
+func RunAudit(t *testing.T, newStore func(t *testing.T) dbhandler.AuditStore) {
+	t.Helper()
+
+	tests := []struct {
+		name string
+		test func(t *testing.T, store dbhandler.AuditStore)
+	}{
+		{name: "AuditEvents", test: testAuditEvents},
+		{name: "AuditFilter", test: testAuditFilter},
+		{name: "AuditLongNames", test: testAuditLongNames},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			tt.test(t, newStore(t))
+		})
+	}
+}
+
+var auditOccurredAt = time.Date(2022, 5, 1, 10, 0, 0, 0, time.UTC)
+
+func auditEvent(action, target string, minutes int) dbmodels.AuditEvent {
+	return dbmodels.AuditEvent{
+		OccurredAt: auditOccurredAt.Add(time.Duration(minutes) * time.Minute),
+		Actor:      "user-1",
+		Action:     action,
+		AlbumName:  "holiday",
+		Target:     target,
+		RequestID:  "request-" + target,
+		ClientIP:   "10.0.0.1",
+		Outcome:    dbmodels.OutcomeSuccess,
+	}
+}
+
+// assertAuditEvents compares the events but for their ids, and their times
+// with Equal, databases may read them back in another location.
+func assertAuditEvents(t *testing.T, expected, actual []dbmodels.AuditEvent) {
+	t.Helper()
+
+	if !assert.Len(t, actual, len(expected)) {
+		return
+	}
+
+	for idx := range expected {
+		want, got := expected[idx], actual[idx]
+		want.Tenant = tenant.Default
+
+		assert.True(t, want.OccurredAt.Equal(got.OccurredAt), "occurred at %v, expected %v", got.OccurredAt, want.OccurredAt)
+
+		want.ID, got.ID = 0, 0
+		want.OccurredAt, got.OccurredAt = time.Time{}, time.Time{}
+		assert.Equal(t, want, got)
+	}
+}
+
+func collectAuditEvents(t *testing.T, store dbhandler.AuditStore, ctx context.Context,
+	filter dbmodels.AuditFilter) []dbmodels.AuditEvent {
+	t.Helper()
+
+	var events []dbmodels.AuditEvent
+
+	require.NoError(t, store.StreamAuditEvents(ctx, filter, func(event dbmodels.AuditEvent) error {
+		events = append(events, event)
+
+		return nil
+	}))
+
+	return events
+}
+
+func testAuditEvents(t *testing.T, store dbhandler.AuditStore) {
+	ctx := context.Background()
+
+	created := auditEvent(dbmodels.AuditAlbumCreate, "holiday", 0)
+	uploaded := auditEvent(dbmodels.AuditImageCreate, "beach.png", 1)
+	deleted := auditEvent(dbmodels.AuditAlbumDelete, "holiday", 2)
+	deleted.Outcome = dbmodels.OutcomeFailure
+	deleted.Error = "album still has images"
+
+	for _, event := range []dbmodels.AuditEvent{created, uploaded, deleted} {
+		require.NoError(t, store.CreateAuditEvent(ctx, event))
+	}
+
+	other := auditEvent(dbmodels.AuditAlbumCreate, "holiday", 3)
+	require.NoError(t, store.CreateAuditEvent(tenant.NewContext(ctx, "acme"), other))
+
+	events, total, err := store.ListAuditEvents(ctx, dbmodels.AuditFilter{}, 10, 0)
+	assert.NoError(t, err)
+	assert.Equal(t, 3, total, "the events of other tenants are not listed")
+	assertAuditEvents(t, []dbmodels.AuditEvent{deleted, uploaded, created}, events)
+
+	if len(events) == 3 {
+		assert.Greater(t, events[0].ID, events[1].ID, "the events are numbered in the order they are appended")
+		assert.Greater(t, events[1].ID, events[2].ID)
+	}
+
+	events, total, err = store.ListAuditEvents(ctx, dbmodels.AuditFilter{}, 1, 1)
+	assert.NoError(t, err)
+	assert.Equal(t, 3, total)
+	assertAuditEvents(t, []dbmodels.AuditEvent{uploaded}, events)
+
+	events, total, err = store.ListAuditEvents(ctx, dbmodels.AuditFilter{}, 10, 3)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, total)
+	assert.Empty(t, events)
+
+	assertAuditEvents(t, []dbmodels.AuditEvent{created, uploaded, deleted},
+		collectAuditEvents(t, store, ctx, dbmodels.AuditFilter{}))
+}
+
+func testAuditFilter(t *testing.T, store dbhandler.AuditStore) {
+	ctx := context.Background()
+
+	created := auditEvent(dbmodels.AuditAlbumCreate, "holiday", 0)
+	uploaded := auditEvent(dbmodels.AuditImageCreate, "beach.png", 1)
+	uploaded.Actor = "user-2"
+	denied := auditEvent(dbmodels.AuditAlbumDelete, "holiday", 2)
+	denied.Actor = "user-2"
+	denied.Outcome = dbmodels.OutcomeDenied
+	moved := auditEvent(dbmodels.AuditImageMove, "beach.png", 3)
+	moved.AlbumName = "work"
+
+	for _, event := range []dbmodels.AuditEvent{created, uploaded, denied, moved} {
+		require.NoError(t, store.CreateAuditEvent(ctx, event))
+	}
+
+	tests := []struct {
+		name     string
+		filter   dbmodels.AuditFilter
+		expected []dbmodels.AuditEvent
+	}{
+		{name: "actor", filter: dbmodels.AuditFilter{Actor: "user-2"}, expected: []dbmodels.AuditEvent{uploaded, denied}},
+		{name: "action", filter: dbmodels.AuditFilter{Action: dbmodels.AuditAlbumDelete}, expected: []dbmodels.AuditEvent{denied}},
+		{name: "album", filter: dbmodels.AuditFilter{AlbumName: "holiday"}, expected: []dbmodels.AuditEvent{created, uploaded, denied}},
+		{name: "target", filter: dbmodels.AuditFilter{Target: "beach.png"}, expected: []dbmodels.AuditEvent{uploaded, moved}},
+		{name: "outcome", filter: dbmodels.AuditFilter{Outcome: dbmodels.OutcomeDenied}, expected: []dbmodels.AuditEvent{denied}},
+		{name: "request", filter: dbmodels.AuditFilter{RequestID: "request-holiday"}, expected: []dbmodels.AuditEvent{created, denied}},
+		{
+			name: "period",
+			filter: dbmodels.AuditFilter{
+				From: auditOccurredAt.Add(time.Minute),
+				To:   auditOccurredAt.Add(3 * time.Minute),
+			},
+			expected: []dbmodels.AuditEvent{uploaded, denied},
+		},
+		{
+			name:     "all fields",
+			filter:   dbmodels.AuditFilter{Actor: "user-2", AlbumName: "holiday", Outcome: dbmodels.OutcomeSuccess},
+			expected: []dbmodels.AuditEvent{uploaded},
+		},
+		{name: "none", filter: dbmodels.AuditFilter{Actor: "user-3"}},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			assertAuditEvents(t, tt.expected, collectAuditEvents(t, store, ctx, tt.filter))
+
+			latestFirst := make([]dbmodels.AuditEvent, len(tt.expected))
+			for idx, event := range tt.expected {
+				latestFirst[len(tt.expected)-1-idx] = event
+			}
+
+			events, total, err := store.ListAuditEvents(ctx, tt.filter, 10, 0)
+			assert.NoError(t, err)
+			assert.Equal(t, len(tt.expected), total)
+			assertAuditEvents(t, latestFirst, events)
+		})
+	}
+}
+
+// testAuditLongNames records the event of an image with a long name, by a
+// caller with a long subject. Neither is bounded, and a failure to record
+// the event would fail the write it audits.
+func testAuditLongNames(t *testing.T, store dbhandler.AuditStore) {
+	ctx := context.Background()
+
+	event := auditEvent(dbmodels.AuditImageCreate, strings.Repeat("beach", 400)+".png", 0)
+	event.Actor = strings.Repeat("user", 300)
+	event.RequestID = "request-long"
+
+	require.NoError(t, store.CreateAuditEvent(ctx, event))
+	assertAuditEvents(t, []dbmodels.AuditEvent{event}, collectAuditEvents(t, store, ctx, dbmodels.AuditFilter{}))
+}
@@ -0,0 +1,435 @@
+// Package storetest is the conformance suite of dbhandler.ImageStore. Every
+// implementation runs it from its tests, so that they all behave alike.
+package storetest
+
+import (
+	"context"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"githum.com/anupam111/image-store/internal/db/dbhandler"
+	"githum.com/anupam111/image-store/internal/db/dbmodels"
+	"githum.com/anupam111/image-store/internal/tenant"
+	"testing"
+)
+
+var errRollback = errors.New("rollback")
+
+// Run runs the conformance suite. newStore returns an empty store for each
+// test case.
+func Run(t *testing.T, newStore func(t *testing.T) dbhandler.ImageStore) {
+	t.Helper()
+
+	tests := []struct {
+		name string
+		test func(t *testing.T, store dbhandler.ImageStore)
+	}{
+		{name: "Albums", test: testAlbums},
+		{name: "Images", test: testImages},
+		{name: "UpdateImage", test: testUpdateImage},
+		{name: "DeleteImage", test: testDeleteImage},
+		{name: "CascadeDelete", test: testCascadeDelete},
+		{name: "Rollback", test: testRollback},
+		{name: "Search", test: testSearch},
+		{name: "Metadata", test: testMetadata},
+		{name: "AlbumAccess", test: testAlbumAccess},
+		{name: "PrincipalAccess", test: testPrincipalAccess},
+		{name: "AlbumShares", test: testAlbumShares},
+		{name: "Tenants", test: testTenants},
+		{name: "AlbumUsage", test: testAlbumUsage},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			tt.test(t, newStore(t))
+		})
+	}
+}
+
+// album and image return the album and image as the store reads them back in
+// the default tenant.
+func album(albumName string) dbmodels.Album {
+	return dbmodels.Album{Tenant: tenant.Default, AlbumName: albumName}
+}
+
+func image(albumName, imageName string) dbmodels.Image {
+	return dbmodels.Image{
+		Tenant:    tenant.Default,
+		ImageName: imageName,
+		AlbumName: albumName,
+		Image:     "content of " + imageName,
+	}
+}
+
+func testAlbums(t *testing.T, store dbhandler.ImageStore) {
+	ctx := context.Background()
+
+	for _, name := range []string{"b-album", "c-album", "a-album"} {
+		require.NoError(t, store.CreateAlbum(ctx, dbmodels.Album{AlbumName: name}))
+	}
+
+	assert.ErrorIs(t, store.CreateAlbum(ctx, dbmodels.Album{AlbumName: "b-album"}), dbhandler.ErrDuplicate)
+
+	got, err := store.GetAlbum(ctx, "b-album")
+	assert.NoError(t, err)
+	assert.Equal(t, album("b-album"), got)
+
+	_, err = store.GetAlbum(ctx, "d-album")
+	assert.ErrorIs(t, err, dbhandler.ErrNoDataFound)
+
+	albums, err := store.ListAlbums(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, []dbmodels.Album{album("a-album"), album("b-album"), album("c-album")}, albums)
+}
+
+func testImages(t *testing.T, store dbhandler.ImageStore) {
+	ctx := context.Background()
+
+	require.NoError(t, store.CreateAlbum(ctx, dbmodels.Album{AlbumName: "a-album"}))
+	require.NoError(t, store.CreateAlbum(ctx, dbmodels.Album{AlbumName: "b-album"}))
+
+	assert.Error(t, store.CreateImage(ctx, image("c-album", "image-1")), "image of missing album")
+
+	for _, img := range []dbmodels.Image{
+		image("b-album", "image-2"),
+		image("a-album", "image-3"),
+		image("b-album", "image-1"),
+	} {
+		require.NoError(t, store.CreateImage(ctx, img))
+	}
+
+	assert.ErrorIs(t, store.CreateImage(ctx, image("b-album", "image-1")), dbhandler.ErrDuplicate)
+	assert.ErrorIs(t, store.CreateImage(ctx, image("a-album", "image-1")), dbhandler.ErrDuplicate,
+		"image names are unique across albums")
+
+	got, err := store.GetImageByID(ctx, "image-1")
+	assert.NoError(t, err)
+	assert.Equal(t, image("b-album", "image-1"), got)
+
+	_, err = store.GetImageByID(ctx, "image-4")
+	assert.ErrorIs(t, err, dbhandler.ErrNoDataFound)
+
+	got, err = store.GetAlbumImage(ctx, "a-album", "image-3")
+	assert.NoError(t, err)
+	assert.Equal(t, image("a-album", "image-3"), got)
+
+	_, err = store.GetAlbumImage(ctx, "a-album", "image-1")
+	assert.ErrorIs(t, err, dbhandler.ErrNoDataFound)
+
+	images, err := store.GetAllImages(ctx, "b-album")
+	assert.NoError(t, err)
+	assert.ElementsMatch(t, []dbmodels.Image{image("b-album", "image-1"), image("b-album", "image-2")}, images)
+
+	images, err = store.GetAllImages(ctx, "c-album")
+	assert.NoError(t, err)
+	assert.Empty(t, images)
+
+	var streamed []dbmodels.Image
+	err = store.StreamImages(ctx, "b-album", func(img dbmodels.Image) error {
+		streamed = append(streamed, img)
+
+		return nil
+	})
+	assert.NoError(t, err)
+	assert.ElementsMatch(t, []dbmodels.Image{image("b-album", "image-1"), image("b-album", "image-2")}, streamed)
+
+	err = store.StreamImages(ctx, "b-album", func(img dbmodels.Image) error {
+		return errRollback
+	})
+	assert.ErrorIs(t, err, errRollback)
+
+	images, err = store.GetImagesOfAlbums(ctx, []string{"b-album", "a-album", "c-album"})
+	assert.NoError(t, err)
+	assert.Equal(t, []dbmodels.Image{
+		image("a-album", "image-3"),
+		image("b-album", "image-1"),
+		image("b-album", "image-2"),
+	}, images)
+
+	images, err = store.GetImagesOfAlbums(ctx, nil)
+	assert.NoError(t, err)
+	assert.Empty(t, images)
+}
+
+func testUpdateImage(t *testing.T, store dbhandler.ImageStore) {
+	ctx := context.Background()
+
+	require.NoError(t, store.CreateAlbum(ctx, dbmodels.Album{AlbumName: "a-album"}))
+	require.NoError(t, store.CreateImage(ctx, image("a-album", "image-1")))
+	require.NoError(t, store.CreateImage(ctx, image("a-album", "image-2")))
+
+	key := dbmodels.ImageKey{ImageName: "image-1", AlbumName: "a-album"}
+	renamed := dbmodels.Image{Tenant: tenant.Default, ImageName: "image-3", AlbumName: "a-album", Image: "new content"}
+	assert.NoError(t, store.UpdateImage(ctx, key, renamed))
+
+	_, err := store.GetImageByID(ctx, "image-1")
+	assert.ErrorIs(t, err, dbhandler.ErrNoDataFound)
+
+	got, err := store.GetImageByID(ctx, "image-3")
+	assert.NoError(t, err)
+	assert.Equal(t, renamed, got)
+
+	assert.ErrorIs(t, store.UpdateImage(ctx, key, renamed), dbhandler.ErrNoDataFound)
+
+	key = dbmodels.ImageKey{ImageName: "image-2", AlbumName: "a-album"}
+	assert.ErrorIs(t, store.UpdateImage(ctx, key, renamed), dbhandler.ErrDuplicate)
+}
+
+func testDeleteImage(t *testing.T, store dbhandler.ImageStore) {
+	ctx := context.Background()
+
+	require.NoError(t, store.CreateAlbum(ctx, dbmodels.Album{AlbumName: "a-album"}))
+	require.NoError(t, store.CreateImage(ctx, image("a-album", "image-1")))
+
+	assert.NoError(t, store.DeleteImageWithImageName(ctx, "image-1", "b-album"), "delete is idempotent")
+	_, err := store.GetImageByID(ctx, "image-1")
+	assert.NoError(t, err, "image of another album is kept")
+
+	assert.NoError(t, store.DeleteImageWithImageName(ctx, "image-1", "a-album"))
+	_, err = store.GetImageByID(ctx, "image-1")
+	assert.ErrorIs(t, err, dbhandler.ErrNoDataFound)
+
+	assert.NoError(t, store.DeleteImageWithImageName(ctx, "image-1", "a-album"), "delete is idempotent")
+}
+
+func testCascadeDelete(t *testing.T, store dbhandler.ImageStore) {
+	ctx := context.Background()
+
+	require.NoError(t, store.CreateAlbum(ctx, dbmodels.Album{AlbumName: "a-album"}))
+	require.NoError(t, store.CreateAlbum(ctx, dbmodels.Album{AlbumName: "b-album"}))
+	require.NoError(t, store.CreateImage(ctx, image("a-album", "image-1")))
+	require.NoError(t, store.CreateImage(ctx, image("a-album", "image-2")))
+	require.NoError(t, store.CreateImage(ctx, image("b-album", "image-3")))
+
+	assert.Error(t, store.DeleteAlbum(ctx, "a-album"), "album with images")
+
+	err := store.WithTx(ctx, func(tx dbhandler.ImageStore) error {
+		if err := tx.DeleteAllImagesOfAlbum(ctx, "a-album"); err != nil {
+			return err
+		}
+
+		return tx.DeleteAlbum(ctx, "a-album")
+	})
+	assert.NoError(t, err)
+
+	_, err = store.GetAlbum(ctx, "a-album")
+	assert.ErrorIs(t, err, dbhandler.ErrNoDataFound)
+
+	_, err = store.GetImageByID(ctx, "image-1")
+	assert.ErrorIs(t, err, dbhandler.ErrNoDataFound)
+
+	images, err := store.GetImagesOfAlbums(ctx, []string{"a-album", "b-album"})
+	assert.NoError(t, err)
+	assert.Equal(t, []dbmodels.Image{image("b-album", "image-3")}, images)
+}
+
+func testRollback(t *testing.T, store dbhandler.ImageStore) {
+	ctx := context.Background()
+
+	require.NoError(t, store.CreateAlbum(ctx, dbmodels.Album{AlbumName: "a-album"}))
+
+	err := store.WithTx(ctx, func(tx dbhandler.ImageStore) error {
+		if err := tx.CreateAlbum(ctx, dbmodels.Album{AlbumName: "b-album"}); err != nil {
+			return err
+		}
+
+		if err := tx.CreateImage(ctx, image("b-album", "image-1")); err != nil {
+			return err
+		}
+
+		if _, err := tx.GetImageByID(ctx, "image-1"); err != nil {
+			return err
+		}
+
+		return errRollback
+	})
+	assert.ErrorIs(t, err, errRollback)
+
+	albums, err := store.ListAlbums(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, []dbmodels.Album{album("a-album")}, albums)
+
+	_, err = store.GetImageByID(ctx, "image-1")
+	assert.ErrorIs(t, err, dbhandler.ErrNoDataFound)
+}
+
+func matchNames(matches []dbmodels.ImageMatch) []string {
+	names := make([]string, len(matches))
+	for idx, match := range matches {
+		names[idx] = match.AlbumName + "/" + match.ImageName
+	}
+
+	return names
+}
+
+// testSearch only searches whole words of the names and of the string
+// metadata values, which every implementation matches alike.
+func testSearch(t *testing.T, store dbhandler.ImageStore) {
+	ctx := context.Background()
+
+	require.NoError(t, store.CreateAlbum(ctx, dbmodels.Album{AlbumName: "holiday"}))
+	require.NoError(t, store.CreateAlbum(ctx, dbmodels.Album{AlbumName: "work"}))
+	require.NoError(t, store.CreateImage(ctx, image("holiday", "beach-sunset.png")))
+	require.NoError(t, store.CreateImage(ctx, image("holiday", "city.png")))
+	require.NoError(t, store.CreateImage(ctx, image("work", "sunset-report.png")))
+	require.NoError(t, store.CreateImage(ctx, image("work", "slides.png")))
+
+	described := image("holiday", "coast.png")
+	described.Metadata = dbmodels.Metadata{"description": "The lighthouse at dawn", "tags": []interface{}{"Harbour"}, "rating": 4.0}
+	require.NoError(t, store.CreateImage(ctx, described))
+
+	matches, total, err := store.SearchImages(ctx, "Sunset", nil, 10, 0)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, total)
+	assert.ElementsMatch(t, []string{"holiday/beach-sunset.png", "work/sunset-report.png"}, matchNames(matches))
+
+	matches, total, err = store.SearchImages(ctx, "holiday", nil, 10, 0)
+	assert.NoError(t, err)
+	assert.Equal(t, 3, total)
+	assert.ElementsMatch(t, []string{"holiday/beach-sunset.png", "holiday/city.png", "holiday/coast.png"}, matchNames(matches))
+
+	for _, text := range []string{"lighthouse", "harbour"} {
+		matches, total, err = store.SearchImages(ctx, text, nil, 10, 0)
+		assert.NoError(t, err)
+		assert.Equal(t, 1, total, text)
+		assert.Equal(t, []string{"holiday/coast.png"}, matchNames(matches), text)
+		assert.Equal(t, described.Metadata, matches[0].Metadata, text)
+	}
+
+	// The keys of the metadata are not searched.
+	matches, total, err = store.SearchImages(ctx, "description", nil, 10, 0)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, total)
+	assert.Empty(t, matches)
+
+	first, total, err := store.SearchImages(ctx, "sunset", nil, 1, 0)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, total)
+	assert.Len(t, first, 1)
+
+	second, total, err := store.SearchImages(ctx, "sunset", nil, 1, 1)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, total)
+	assert.Len(t, second, 1)
+	assert.NotEqual(t, matchNames(first), matchNames(second))
+
+	matches, total, err = store.SearchImages(ctx, "sunset", nil, 10, 2)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, total)
+	assert.Empty(t, matches)
+
+	matches, total, err = store.SearchImages(ctx, "mountain", nil, 10, 0)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, total)
+	assert.Empty(t, matches)
+
+	matches, total, err = store.SearchImages(ctx, "sunset", []string{"work", "other"}, 10, 0)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, total)
+	assert.Equal(t, []string{"work/sunset-report.png"}, matchNames(matches))
+}
+
+func imageNames(images []dbmodels.Image) []string {
+	names := make([]string, len(images))
+	for idx, image := range images {
+		names[idx] = image.ImageName
+	}
+
+	return names
+}
+
+// testMetadata uses float64 numbers, which is what JSON numbers read back as.
+func testMetadata(t *testing.T, store dbhandler.ImageStore) {
+	ctx := context.Background()
+
+	require.NoError(t, store.CreateAlbum(ctx, dbmodels.Album{AlbumName: "a-album"}))
+	require.NoError(t, store.CreateAlbum(ctx, dbmodels.Album{AlbumName: "b-album"}))
+
+	images := []dbmodels.Image{
+		{ImageName: "image-1", AlbumName: "a-album", Metadata: dbmodels.Metadata{"project": "apollo", "rating": 4.0}},
+		{ImageName: "image-2", AlbumName: "a-album", Metadata: dbmodels.Metadata{"project": "apollo", "rating": 2.5, "published": true}},
+		{ImageName: "image-3", AlbumName: "a-album", Metadata: dbmodels.Metadata{"project": "gemini", "rating": 5.0}},
+		{ImageName: "image-4", AlbumName: "a-album", Metadata: dbmodels.Metadata{"rating": "4", "tags": []interface{}{"apollo"}}},
+		{ImageName: "image-5", AlbumName: "a-album"},
+		{ImageName: "image-6", AlbumName: "b-album", Metadata: dbmodels.Metadata{"project": "apollo", "rating": 4.0}},
+	}
+	for idx := range images {
+		require.NoError(t, store.CreateImage(ctx, images[idx]))
+		images[idx].Tenant = tenant.Default
+	}
+
+	got, err := store.GetAlbumImage(ctx, "a-album", "image-2")
+	assert.NoError(t, err)
+	assert.Equal(t, images[1], got)
+
+	got, err = store.GetAlbumImage(ctx, "a-album", "image-5")
+	assert.NoError(t, err)
+	assert.Nil(t, got.Metadata)
+
+	tests := []struct {
+		name     string
+		filters  []dbmodels.MetadataFilter
+		expected []string
+	}{
+		{
+			name:     "no_filter",
+			expected: []string{"image-1", "image-2", "image-3", "image-4", "image-5"},
+		},
+		{
+			name:     "equal_string",
+			filters:  []dbmodels.MetadataFilter{{Key: "project", Op: "=", Value: "apollo"}},
+			expected: []string{"image-1", "image-2"},
+		},
+		{
+			name:     "equal_number_or_string",
+			filters:  []dbmodels.MetadataFilter{{Key: "rating", Op: "=", Value: "4"}},
+			expected: []string{"image-1", "image-4"},
+		},
+		{
+			name:     "equal_bool",
+			filters:  []dbmodels.MetadataFilter{{Key: "published", Op: "=", Value: "true"}},
+			expected: []string{"image-2"},
+		},
+		{
+			name:     "greater_or_equal",
+			filters:  []dbmodels.MetadataFilter{{Key: "rating", Op: ">=", Value: "4"}},
+			expected: []string{"image-1", "image-3"},
+		},
+		{
+			name:     "less",
+			filters:  []dbmodels.MetadataFilter{{Key: "rating", Op: "<", Value: "4.5"}},
+			expected: []string{"image-1", "image-2"},
+		},
+		{
+			name: "all_filters",
+			filters: []dbmodels.MetadataFilter{
+				{Key: "project", Op: "=", Value: "apollo"},
+				{Key: "rating", Op: ">", Value: "3"},
+			},
+			expected: []string{"image-1"},
+		},
+		{
+			name:     "array_value",
+			filters:  []dbmodels.MetadataFilter{{Key: "tags", Op: "=", Value: "apollo"}},
+			expected: []string{},
+		},
+	}
+
+	for _, tt := range tests {
+		got, err := store.FilterImages(ctx, "a-album", tt.filters)
+		assert.NoError(t, err, tt.name)
+		assert.Equal(t, tt.expected, imageNames(got), tt.name)
+	}
+
+	_, err = store.FilterImages(ctx, "a-album", []dbmodels.MetadataFilter{{Key: "rating", Op: ">", Value: "high"}})
+	assert.Error(t, err)
+
+	updated := images[0]
+	updated.Metadata = dbmodels.Metadata{"project": "gemini"}
+	require.NoError(t, store.UpdateImage(ctx, dbmodels.ImageKey{ImageName: "image-1", AlbumName: "a-album"}, updated))
+
+	got, err = store.GetAlbumImage(ctx, "a-album", "image-1")
+	assert.NoError(t, err)
+	assert.Equal(t, updated, got)
+}
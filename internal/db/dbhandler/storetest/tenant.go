@@ -0,0 +1,102 @@
+package storetest
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"githum.com/anupam111/image-store/internal/db/dbhandler"
+	"githum.com/anupam111/image-store/internal/db/dbmodels"
+	"githum.com/anupam111/image-store/internal/tenant"
+	"testing"
+)
+
+// testTenants gives two tenants an album and an image of the same names, each
+// tenant only ever sees its own.
+func testTenants(t *testing.T, store dbhandler.ImageStore) {
+	acme := tenant.NewContext(context.Background(), "acme")
+	globex := tenant.NewContext(context.Background(), "globex")
+
+	for _, ctx := range []context.Context{acme, globex} {
+		require.NoError(t, store.CreateAlbum(ctx, dbmodels.Album{AlbumName: "holiday"}), "album names are unique per tenant")
+		require.NoError(t, store.CreateImage(ctx, image("holiday", "beach.png")), "image names are unique per tenant")
+	}
+
+	require.NoError(t, store.CreateAlbum(acme, dbmodels.Album{AlbumName: "work"}))
+	assert.ErrorIs(t, store.CreateAlbum(acme, dbmodels.Album{AlbumName: "holiday"}), dbhandler.ErrDuplicate)
+	assert.ErrorIs(t, store.CreateImage(acme, image("holiday", "beach.png")), dbhandler.ErrDuplicate)
+	assert.Error(t, store.CreateImage(globex, image("work", "report.png")), "album of another tenant")
+
+	albums, err := store.ListAlbums(globex)
+	assert.NoError(t, err)
+	assert.Equal(t, []dbmodels.Album{{Tenant: "globex", AlbumName: "holiday"}}, albums)
+
+	_, err = store.GetAlbum(globex, "work")
+	assert.ErrorIs(t, err, dbhandler.ErrNoDataFound)
+
+	got, err := store.GetImageByID(globex, "beach.png")
+	assert.NoError(t, err)
+	assert.Equal(t, "globex", got.Tenant)
+
+	// Writes of one tenant leave the images of the same name of the other
+	// one alone.
+	key := dbmodels.ImageKey{ImageName: "beach.png", AlbumName: "holiday"}
+	require.NoError(t, store.UpdateImage(globex, key, image("holiday", "sunset.png")))
+
+	got, err = store.GetAlbumImage(acme, "holiday", "beach.png")
+	assert.NoError(t, err)
+	assert.Equal(t, "acme", got.Tenant)
+
+	images, err := store.GetImagesOfAlbums(globex, []string{"holiday", "work"})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"sunset.png"}, imageNames(images))
+
+	images, err = store.FilterImages(acme, "holiday", nil)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"beach.png"}, imageNames(images))
+
+	matches, total, err := store.SearchImages(acme, "holiday", nil, 10, 0)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, total)
+	assert.Equal(t, []string{"holiday/beach.png"}, matchNames(matches))
+
+	require.NoError(t, store.GrantAlbumAccess(acme, access("holiday", dbmodels.PrincipalUser, "alice", dbmodels.RoleOwner)))
+
+	accesses, err := store.ListPrincipalAccess(globex, "alice", nil)
+	assert.NoError(t, err)
+	assert.Empty(t, accesses)
+
+	accesses, err = store.ListAlbumAccess(globex, "holiday")
+	assert.NoError(t, err)
+	assert.Empty(t, accesses)
+	assert.ErrorIs(t, store.RevokeAlbumAccess(globex, "holiday", dbmodels.PrincipalUser, "alice"), dbhandler.ErrNoDataFound)
+
+	// Shares are found by the hash of their token in every tenant, and tell
+	// the tenant of their album.
+	require.NoError(t, store.CreateAlbumShare(acme, albumShare("share-1", "holiday", keyCreatedAt)))
+
+	share, err := store.GetAlbumShareByHash(globex, "hash of share-1")
+	assert.NoError(t, err)
+	assert.Equal(t, "acme", share.Tenant)
+
+	shares, err := store.ListAlbumShares(globex, "holiday")
+	assert.NoError(t, err)
+	assert.Empty(t, shares)
+	assert.ErrorIs(t, store.RevokeAlbumShare(globex, "holiday", "share-1", keyCreatedAt), dbhandler.ErrNoDataFound)
+
+	err = store.WithTx(acme, func(tx dbhandler.ImageStore) error {
+		for _, deleteFn := range []func(context.Context, string) error{
+			tx.DeleteAllImagesOfAlbum, tx.DeleteAlbumAccess, tx.DeleteAlbumShares, tx.DeleteAlbum,
+		} {
+			if err := deleteFn(acme, "holiday"); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+	assert.NoError(t, err)
+
+	images, err = store.GetAllImages(globex, "holiday")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"sunset.png"}, imageNames(images))
+}
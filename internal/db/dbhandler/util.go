@@ -1,11 +1,85 @@
 package dbhandler
 
 import (
+	"context"
+	"database/sql"
+	"errors"
 	"fmt"
 	"github.com/jmoiron/sqlx"
 	log "github.com/sirupsen/logrus"
+	"githum.com/anupam111/image-store/internal/constants"
+	"githum.com/anupam111/image-store/internal/db/dbconnection"
+	"strings"
+	"time"
 )
 
+// likeEscaper escapes the LIKE wildcards with the ! escape character of the
+// queries.
+var likeEscaper = strings.NewReplacer("!", "!!", "%", "!%", "_", "!_")
+
+// reader returns the transaction of a handler passed to WithTx, or else the
+// database of the pool to read from.
+func (db *DBHandler) reader(ctx context.Context) dbconnection.Queryer {
+	if db.tx != nil {
+		return db.tx
+	}
+
+	return db.connection.Reader(ctx)
+}
+
+// query rewrites query for the dialect of the database.
+func (db *DBHandler) query(query string) string {
+	return db.connection.Dialect.Query(query)
+}
+
+// runTx runs fn in the transaction of the handler, or else in a transaction
+// of its own which is committed when fn succeeds and retried on serialization
+// failures.
+func (db *DBHandler) runTx(ctx context.Context, fn func(txn *sqlx.Tx) error) error {
+	if db.tx != nil {
+		return fn(db.tx)
+	}
+
+	for attempt := 0; ; attempt++ {
+		txn, err := db.beginTx(ctx)
+		if err != nil {
+			return err
+		}
+
+		err = handlerError(fn(txn), txn)
+		if err == nil {
+			dbconnection.MarkWritten(ctx)
+
+			return nil
+		}
+
+		if !db.connection.Dialect.IsSerializationFailure(err) || attempt >= constants.MaxTxRetries {
+			return err
+		}
+
+		db.log.Warnf("retrying transaction after serialization failure, %v", err)
+
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("%w", ctx.Err())
+		case <-time.After(time.Duration(attempt+1) * 10 * time.Millisecond):
+		}
+	}
+}
+
+// beginTx starts a serializable transaction bound to ctx, so that concurrent
+// transactions which would conflict fail with a serialization failure and are
+// retried by runTx. Unlike MustBegin it reports a lost database connection as
+// an error instead of panicking.
+func (db *DBHandler) beginTx(ctx context.Context) (*sqlx.Tx, error) {
+	txn, err := db.connection.DB.BeginTxx(ctx, &sql.TxOptions{Isolation: sql.LevelSerializable})
+	if err != nil {
+		return nil, fmt.Errorf("error while starting transaction, %w", err)
+	}
+
+	return txn, nil
+}
+
 func handlerError(err error, txn *sqlx.Tx) error {
 	if err != nil {
 		err1 := txn.Rollback()
@@ -29,3 +103,18 @@ func handlerError(err error, txn *sqlx.Tx) error {
 
 	return nil
 }
+
+// translateError maps the driver specific errors of a statement to the errors
+// of this package, so that callers see the same errors for every driver.
+func (db *DBHandler) translateError(err error) error {
+	switch {
+	case err == nil:
+		return nil
+	case errors.Is(err, sql.ErrNoRows):
+		return ErrNoDataFound
+	case db.connection.Dialect.IsUniqueViolation(err):
+		return ErrDuplicate
+	default:
+		return err
+	}
+}
@@ -0,0 +1,40 @@
+package dbmodels
+
+// The roles on an album, each granting the rights of the previous ones:
+// viewers read the album and its images, editors add, change and delete its
+// images, owners delete the album and manage its access.
+const (
+	RoleViewer = "viewer"
+	RoleEditor = "editor"
+	RoleOwner  = "owner"
+)
+
+// The types of principal an album access is granted to.
+const (
+	PrincipalUser  = "user"
+	PrincipalGroup = "group"
+)
+
+// AlbumAccess grants a role on an album to a user, identified by its subject,
+// or to the members of a group.
+type AlbumAccess struct {
+	Tenant        string `db:"tenant"`
+	AlbumName     string `db:"albumName"`
+	PrincipalType string `db:"principalType"`
+	Principal     string `db:"principal"`
+	Role          string `db:"role"`
+}
+
+// RoleRank orders the roles, 0 being no role at all.
+func RoleRank(role string) int {
+	switch role {
+	case RoleViewer:
+		return 1
+	case RoleEditor:
+		return 2
+	case RoleOwner:
+		return 3
+	default:
+		return 0
+	}
+}
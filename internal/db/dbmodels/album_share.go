@@ -0,0 +1,25 @@
+package dbmodels
+
+import "time"
+
+// AlbumShare is a public link to an album. Only the hash of its token is
+// stored, the token itself is shown once when the share is created. A share
+// with a PasswordHash also requires its password, and only a share which
+// AllowDownload lets its visitors download the images.
+type AlbumShare struct {
+	ID            string     `db:"id"`
+	Tenant        string     `db:"tenant"`
+	AlbumName     string     `db:"albumName"`
+	Hash          string     `db:"hash"`
+	PasswordHash  string     `db:"passwordHash"`
+	AllowDownload bool       `db:"allowDownload"`
+	CreatedBy     string     `db:"createdBy"`
+	CreatedAt     time.Time  `db:"createdAt"`
+	ExpiresAt     *time.Time `db:"expiresAt"`
+	RevokedAt     *time.Time `db:"revokedAt"`
+}
+
+// Active reports whether the share is neither revoked nor expired at now.
+func (s AlbumShare) Active(now time.Time) bool {
+	return s.RevokedAt == nil && (s.ExpiresAt == nil || now.Before(*s.ExpiresAt))
+}
@@ -0,0 +1,16 @@
+package dbmodels
+
+// Usage counts images and the bytes of their content, as Image.Size counts
+// them.
+type Usage struct {
+	Images int64 `db:"imageCount"`
+	Bytes  int64 `db:"byteCount"`
+}
+
+// AlbumUsage is the usage of an album. The stores keep it up to date with
+// every image write, instead of counting the images when it is read.
+type AlbumUsage struct {
+	Tenant    string `db:"tenant"`
+	AlbumName string `db:"albumName"`
+	Usage
+}
@@ -0,0 +1,74 @@
+package dbmodels
+
+import (
+	"database/sql/driver"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// The scopes of an API key. ScopeAdmin grants every scope.
+const (
+	ScopeAlbumsRead  = "albums:read"
+	ScopeAlbumsWrite = "albums:write"
+	ScopeImagesRead  = "images:read"
+	ScopeImagesWrite = "images:write"
+	ScopeAuditRead   = "audit:read"
+	ScopeAdmin       = "admin"
+)
+
+// ValidScope reports whether scope is one of the scopes of an API key.
+func ValidScope(scope string) bool {
+	switch scope {
+	case ScopeAlbumsRead, ScopeAlbumsWrite, ScopeImagesRead, ScopeImagesWrite, ScopeAuditRead, ScopeAdmin:
+		return true
+	default:
+		return false
+	}
+}
+
+// APIKey is an API key. Only the hash of the key is stored, the key itself is
+// shown once when it is created. A key with a Tenant only acts on that
+// tenant, a key without one may name any tenant.
+type APIKey struct {
+	ID        string     `db:"id"`
+	Name      string     `db:"name"`
+	Tenant    string     `db:"tenant"`
+	Hash      string     `db:"hash"`
+	Scopes    Scopes     `db:"scopes"`
+	CreatedAt time.Time  `db:"createdAt"`
+	RevokedAt *time.Time `db:"revokedAt"`
+}
+
+// Scopes are the scopes granted to an API key, stored space separated.
+type Scopes []string
+
+// Value stores the scopes space separated.
+func (s Scopes) Value() (driver.Value, error) {
+	return strings.Join(s, " "), nil
+}
+
+// Scan reads space separated scopes.
+func (s *Scopes) Scan(src interface{}) error {
+	switch src := src.(type) {
+	case []byte:
+		*s = strings.Fields(string(src))
+	case string:
+		*s = strings.Fields(src)
+	default:
+		return fmt.Errorf("unsupported type %T of api key scopes", src)
+	}
+
+	return nil
+}
+
+// Allows reports whether the scopes grant scope.
+func (s Scopes) Allows(scope string) bool {
+	for _, granted := range s {
+		if granted == scope || granted == ScopeAdmin {
+			return true
+		}
+	}
+
+	return false
+}
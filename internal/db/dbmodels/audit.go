@@ -0,0 +1,85 @@
+package dbmodels
+
+import "time"
+
+// The actions of the audit events.
+const (
+	AuditAlbumCreate  = "album.create"
+	AuditAlbumDelete  = "album.delete"
+	AuditImageCreate  = "image.create"
+	AuditImageUpdate  = "image.update"
+	AuditImageMove    = "image.move"
+	AuditImageDelete  = "image.delete"
+	AuditAccessGrant  = "access.grant"
+	AuditAccessRevoke = "access.revoke"
+	AuditShareCreate  = "share.create"
+	AuditShareRevoke  = "share.revoke"
+	AuditAPIKeyCreate = "apikey.create"
+	AuditAPIKeyRevoke = "apikey.revoke"
+)
+
+// The outcomes of the audit events.
+const (
+	OutcomeSuccess = "success"
+	// OutcomeDenied is the outcome of the operations the caller had no access
+	// for.
+	OutcomeDenied  = "denied"
+	OutcomeFailure = "failure"
+)
+
+// AuditEvent records an operation changing the store, successful or not. The
+// audit log is append-only, its events are never updated nor deleted.
+//
+// Target is the object acted on: the album, the image, the principal of an
+// access as "type:principal", the id of a share or of an API key. AlbumName
+// is the album it belongs to, empty for the API keys. Details tell what the
+// operation did beyond its target, Error why it failed.
+type AuditEvent struct {
+	ID         int64     `db:"id"`
+	Tenant     string    `db:"tenant"`
+	OccurredAt time.Time `db:"occurredAt"`
+	Actor      string    `db:"actor"`
+	Action     string    `db:"action"`
+	AlbumName  string    `db:"albumName"`
+	Target     string    `db:"target"`
+	Details    string    `db:"details"`
+	RequestID  string    `db:"requestId"`
+	ClientIP   string    `db:"clientIp"`
+	Outcome    string    `db:"outcome"`
+	Error      string    `db:"error"`
+}
+
+// AuditFilter selects audit events, an empty field selects any event. From
+// and To bound OccurredAt, To excluded.
+type AuditFilter struct {
+	Actor     string
+	Action    string
+	AlbumName string
+	Target    string
+	Outcome   string
+	RequestID string
+	From      time.Time
+	To        time.Time
+}
+
+// Match reports whether the filter selects the event.
+func (f AuditFilter) Match(event AuditEvent) bool {
+	for _, field := range [][2]string{
+		{f.Actor, event.Actor},
+		{f.Action, event.Action},
+		{f.AlbumName, event.AlbumName},
+		{f.Target, event.Target},
+		{f.Outcome, event.Outcome},
+		{f.RequestID, event.RequestID},
+	} {
+		if field[0] != "" && field[0] != field[1] {
+			return false
+		}
+	}
+
+	if !f.From.IsZero() && event.OccurredAt.Before(f.From) {
+		return false
+	}
+
+	return f.To.IsZero() || event.OccurredAt.Before(f.To)
+}
@@ -1,11 +1,63 @@
 package dbmodels
 
+import "encoding/base64"
+
+// Album is an album of images. Tenant is set from the request context and
+// never read from nor written to the v1 payloads.
 type Album struct {
+	Tenant    string `db:"tenant" json:"-"`
 	AlbumName string `db:"albumName"`
 }
 
+// Image is an image of an album. Tenant is set from the request context;
+// Tenant and Metadata are never read from nor written to the v1 payloads,
+// the v2 models carry the metadata. KeyID is the id of the master key of the
+// content as stored, empty when stored in plain, and is only used by the
+// database.
 type Image struct {
+	Tenant    string   `db:"tenant" json:"-"`
+	ImageName string   `db:"imageName"`
+	AlbumName string   `db:"albumName"`
+	Image     string   `db:"image"`
+	Metadata  Metadata `db:"metadata" json:"-"`
+	KeyID     string   `db:"keyId" json:"-"`
+}
+
+// Content returns the raw bytes of the image. Images are stored base64
+// encoded; content which is not valid base64 is returned as is.
+func (i Image) Content() []byte {
+	content, err := base64.StdEncoding.DecodeString(i.Image)
+	if err != nil {
+		return []byte(i.Image)
+	}
+
+	return content
+}
+
+// Size returns the number of bytes of the content of the image, which is
+// base64 encoded. The usage and the quotas count this size, also when the
+// content is encrypted at rest.
+func (i Image) Size() int64 {
+	return int64(len(i.Image))
+}
+
+// SetContent stores the raw bytes of the image base64 encoded.
+func (i *Image) SetContent(content []byte) {
+	i.Image = base64.StdEncoding.EncodeToString(content)
+}
+
+// ImageMatch is an image found by a search, without its content but with its
+// metadata, which the search matches too. Matches with a higher rank are more
+// relevant.
+type ImageMatch struct {
+	ImageName string   `db:"imageName"`
+	AlbumName string   `db:"albumName"`
+	Metadata  Metadata `db:"metadata"`
+	Rank      float64  `db:"rank"`
+}
+
+// ImageKey identifies an image within an album.
+type ImageKey struct {
 	ImageName string `db:"imageName"`
 	AlbumName string `db:"albumName"`
-	Image     string `db:"image"`
 }
@@ -0,0 +1,194 @@
+package dbmodels
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Metadata is the custom key/value metadata of an image, stored as a JSON
+// object.
+type Metadata map[string]interface{}
+
+// Value stores the metadata as a JSON object, nil metadata as NULL.
+func (m Metadata) Value() (driver.Value, error) {
+	if m == nil {
+		return nil, nil
+	}
+
+	data, err := json.Marshal(m)
+	if err != nil {
+		return nil, fmt.Errorf("error while encoding image metadata: %w", err)
+	}
+
+	return string(data), nil
+}
+
+// Scan reads the metadata from a JSON object column.
+func (m *Metadata) Scan(src interface{}) error {
+	var data []byte
+
+	switch src := src.(type) {
+	case nil:
+		*m = nil
+
+		return nil
+	case []byte:
+		data = src
+	case string:
+		data = []byte(src)
+	default:
+		return fmt.Errorf("unsupported type %T of image metadata", src)
+	}
+
+	if err := json.Unmarshal(data, m); err != nil {
+		return fmt.Errorf("error while decoding image metadata: %w", err)
+	}
+
+	return nil
+}
+
+// Match reports whether the metadata is selected by all the filters.
+func (m Metadata) Match(filters []MetadataFilter) bool {
+	for _, filter := range filters {
+		if !filter.Match(m) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Strings returns the string values of the metadata, such as a description
+// or tags, including the strings nested in arrays and objects.
+func (m Metadata) Strings() []string {
+	var values []string
+
+	var walk func(value interface{})
+	walk = func(value interface{}) {
+		switch value := value.(type) {
+		case string:
+			values = append(values, value)
+		case []interface{}:
+			for _, item := range value {
+				walk(item)
+			}
+		case map[string]interface{}:
+			for _, item := range value {
+				walk(item)
+			}
+		}
+	}
+
+	for _, value := range m {
+		walk(value)
+	}
+
+	return values
+}
+
+// ContainsText reports whether one of the string values of the metadata
+// contains text, ignoring case. Keys, numbers and booleans are not searched.
+func (m Metadata) ContainsText(text string) bool {
+	text = strings.ToLower(text)
+
+	for _, value := range m.Strings() {
+		if strings.Contains(strings.ToLower(value), text) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// The comparison operators of a MetadataFilter.
+const (
+	MetadataEqual          = "="
+	MetadataGreater        = ">"
+	MetadataGreaterOrEqual = ">="
+	MetadataLess           = "<"
+	MetadataLessOrEqual    = "<="
+)
+
+// MetadataFilter selects the images whose metadata value of Key compares to
+// Value with Op. Equality matches a string, number or boolean metadata value
+// written as Value, the other operators only match number metadata values.
+type MetadataFilter struct {
+	Key   string
+	Op    string
+	Value string
+}
+
+// Validate reports an unknown operator, or a value which is not a number for
+// an operator which compares numbers.
+func (f MetadataFilter) Validate() error {
+	switch f.Op {
+	case MetadataEqual:
+		return nil
+	case MetadataGreater, MetadataGreaterOrEqual, MetadataLess, MetadataLessOrEqual:
+		if _, ok := f.Number(); !ok {
+			return fmt.Errorf("metadata filter %s%s%s does not compare to a number", f.Key, f.Op, f.Value)
+		}
+
+		return nil
+	default:
+		return fmt.Errorf("unknown metadata filter operator %q", f.Op)
+	}
+}
+
+// Number returns Value as a number, and whether it is a finite number.
+func (f MetadataFilter) Number() (float64, bool) {
+	number, err := strconv.ParseFloat(f.Value, 64)
+	if err != nil || math.IsInf(number, 0) || math.IsNaN(number) {
+		return 0, false
+	}
+
+	return number, true
+}
+
+// Bool returns Value as a boolean, and whether it is true or false.
+func (f MetadataFilter) Bool() (bool, bool) {
+	switch f.Value {
+	case "true":
+		return true, true
+	case "false":
+		return false, true
+	default:
+		return false, false
+	}
+}
+
+// Match reports whether the metadata is selected by the filter.
+func (f MetadataFilter) Match(m Metadata) bool {
+	switch value := m[f.Key].(type) {
+	case string:
+		return f.Op == MetadataEqual && value == f.Value
+	case bool:
+		b, ok := f.Bool()
+
+		return f.Op == MetadataEqual && ok && value == b
+	case float64:
+		number, ok := f.Number()
+		if !ok {
+			return false
+		}
+
+		switch f.Op {
+		case MetadataEqual:
+			return value == number
+		case MetadataGreater:
+			return value > number
+		case MetadataGreaterOrEqual:
+			return value >= number
+		case MetadataLess:
+			return value < number
+		case MetadataLessOrEqual:
+			return value <= number
+		}
+	}
+
+	return false
+}
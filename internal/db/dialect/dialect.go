@@ -0,0 +1,108 @@
+// Package dialect hides the differences between the SQL databases supported
+// by the image store: connection strings, identifier quoting, placeholders
+// and error codes.
+package dialect
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"githum.com/anupam111/image-store/internal/config"
+	"githum.com/anupam111/image-store/internal/db/dbmodels"
+	"io"
+	"net"
+	"strings"
+)
+
+// Dialect adapts the queries of the image store to one database.
+//
+// Queries are written once with "double quoted" identifiers and ? bind vars;
+// Query rewrites them for the database.
+type Dialect interface {
+	// DriverName is the name the database/sql driver is registered with.
+	DriverName() string
+	// DataSourceName builds the connection string of the database.
+	DataSourceName(dbConfig *config.DBConfig) string
+	// Query rewrites the identifiers and bind vars of query for the database.
+	Query(query string) string
+	// IsUniqueViolation reports whether err was caused by a unique or primary
+	// key constraint.
+	IsUniqueViolation(err error) bool
+	// IsSerializationFailure reports whether err aborted a transaction which
+	// can succeed when retried.
+	IsSerializationFailure(err error) bool
+	// IsConnectionFailure reports whether err was caused by the connection to
+	// the database rather than by the statement, so that the statement can
+	// succeed on another database.
+	IsConnectionFailure(err error) bool
+	// MetadataCondition returns the WHERE condition of the filter on the
+	// "metadata" column of the Image table and its bind vars. It returns an
+	// empty condition when the database can not query JSON, the filter is then
+	// applied to the rows with MetadataFilter.Match.
+	MetadataCondition(filter dbmodels.MetadataFilter) (string, []interface{})
+}
+
+// New returns the Dialect of the database/sql driver driverName.
+func New(driverName string) (Dialect, error) {
+	switch driverName {
+	case PostgresDriver:
+		return Postgres{}, nil
+	case SQLiteDriver:
+		return SQLite{}, nil
+	case MySQLDriver:
+		return MySQL{}, nil
+	default:
+		return nil, fmt.Errorf("unsupported database driver %q", driverName)
+	}
+}
+
+// isNetworkFailure reports whether err was caused by a connection which is
+// broken or can not be established, whatever the driver.
+func isNetworkFailure(err error) bool {
+	var netError net.Error
+
+	return errors.Is(err, driver.ErrBadConn) || errors.Is(err, io.EOF) ||
+		errors.Is(err, io.ErrUnexpectedEOF) || errors.As(err, &netError)
+}
+
+// quoteIdentifiers replaces the double quotes around identifiers by quote.
+// The string literals of the queries are single quoted, so every double
+// quote delimits an identifier.
+func quoteIdentifiers(query, quote string) string {
+	return strings.ReplaceAll(query, `"`, quote)
+}
+
+// metadataValues returns the JSON values an equality filter matches: the
+// filter value as a string, and as a number or a boolean when it is one.
+func metadataValues(filter dbmodels.MetadataFilter) []interface{} {
+	values := []interface{}{filter.Value}
+
+	if number, ok := filter.Number(); ok {
+		values = append(values, number)
+	}
+
+	if b, ok := filter.Bool(); ok {
+		values = append(values, b)
+	}
+
+	return values
+}
+
+// jsonText encodes a metadata value, which is always a string, number or
+// boolean and can not fail to encode.
+func jsonText(value interface{}) string {
+	data, _ := json.Marshal(value)
+
+	return string(data)
+}
+
+// orConditions joins the same condition repeated n times with OR.
+func orConditions(condition string, n int) string {
+	conditions := make([]string, n)
+	for idx := range conditions {
+		conditions[idx] = condition
+	}
+
+	return "(" + strings.Join(conditions, " OR ") + ")"
+}
@@ -0,0 +1,220 @@
+package dialect
+
+import (
+	"errors"
+	"github.com/go-sql-driver/mysql"
+	"github.com/lib/pq"
+	"github.com/mattn/go-sqlite3"
+	"github.com/stretchr/testify/assert"
+	"githum.com/anupam111/image-store/internal/config"
+	"githum.com/anupam111/image-store/internal/db/dbmodels"
+	"net"
+	"testing"
+)
+
+func TestQuery(t *testing.T) {
+	t.Parallel()
+
+	query := `SELECT * FROM Image WHERE "imageName"=? AND "albumName"=?`
+
+	tests := []struct {
+		name    string
+		dialect Dialect
+		want    string
+	}{
+		{
+			name:    "postgres",
+			dialect: Postgres{},
+			want:    `SELECT * FROM Image WHERE "imageName"=$1 AND "albumName"=$2`,
+		},
+		{
+			name:    "sqlite3",
+			dialect: SQLite{},
+			want:    `SELECT * FROM Image WHERE "imageName"=? AND "albumName"=?`,
+		},
+		{
+			name:    "mysql",
+			dialect: MySQL{},
+			want:    "SELECT * FROM Image WHERE `imageName`=? AND `albumName`=?",
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			assert.Equal(t, tt.want, tt.dialect.Query(query))
+		})
+	}
+}
+
+func TestErrors(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name                 string
+		dialect              Dialect
+		err                  error
+		uniqueViolation      bool
+		serializationFailure bool
+		connectionFailure    bool
+	}{
+		{
+			name:            "postgres unique violation",
+			dialect:         Postgres{},
+			err:             &pq.Error{Code: "23505"},
+			uniqueViolation: true,
+		},
+		{
+			name:                 "postgres serialization failure",
+			dialect:              Postgres{},
+			err:                  &pq.Error{Code: "40001"},
+			serializationFailure: true,
+		},
+		{
+			name:            "sqlite3 unique violation",
+			dialect:         SQLite{},
+			err:             sqlite3.Error{Code: sqlite3.ErrConstraint, ExtendedCode: sqlite3.ErrConstraintUnique},
+			uniqueViolation: true,
+		},
+		{
+			name:                 "sqlite3 busy",
+			dialect:              SQLite{},
+			err:                  sqlite3.Error{Code: sqlite3.ErrBusy},
+			serializationFailure: true,
+		},
+		{
+			name:            "mysql duplicate entry",
+			dialect:         MySQL{},
+			err:             &mysql.MySQLError{Number: 1062},
+			uniqueViolation: true,
+		},
+		{
+			name:                 "mysql deadlock",
+			dialect:              MySQL{},
+			err:                  &mysql.MySQLError{Number: 1213},
+			serializationFailure: true,
+		},
+		{
+			name:              "postgres admin shutdown",
+			dialect:           Postgres{},
+			err:               &pq.Error{Code: "57P01"},
+			connectionFailure: true,
+		},
+		{
+			name:              "postgres connection refused",
+			dialect:           Postgres{},
+			err:               &net.OpError{Op: "dial", Err: errors.New("connection refused")},
+			connectionFailure: true,
+		},
+		{
+			name:              "mysql invalid connection",
+			dialect:           MySQL{},
+			err:               mysql.ErrInvalidConn,
+			connectionFailure: true,
+		},
+		{
+			name:    "other error",
+			dialect: MySQL{},
+			err:     errors.New("error"),
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			assert.Equal(t, tt.uniqueViolation, tt.dialect.IsUniqueViolation(tt.err))
+			assert.Equal(t, tt.serializationFailure, tt.dialect.IsSerializationFailure(tt.err))
+			assert.Equal(t, tt.connectionFailure, tt.dialect.IsConnectionFailure(tt.err))
+		})
+	}
+}
+
+func TestDataSourceName(t *testing.T) {
+	t.Parallel()
+
+	dbConfig := &config.DBConfig{
+		Host:     "host",
+		Port:     3306,
+		Name:     "dummy",
+		Username: "dummy",
+		Password: "dummy",
+	}
+
+	assert.Equal(t, "dummy:dummy@tcp(host:3306)/dummy?multiStatements=true&parseTime=true", MySQL{}.DataSourceName(dbConfig))
+	assert.Equal(t, "file:dummy?_foreign_keys=on&_busy_timeout=5000", SQLite{}.DataSourceName(dbConfig))
+
+	_, err := New("oracle")
+	assert.Error(t, err)
+}
+
+func TestMetadataCondition(t *testing.T) {
+	t.Parallel()
+
+	equal := dbmodels.MetadataFilter{Key: "rating", Op: "=", Value: "4"}
+	greater := dbmodels.MetadataFilter{Key: "rating", Op: ">=", Value: "4"}
+
+	tests := []struct {
+		name      string
+		dialect   Dialect
+		filter    dbmodels.MetadataFilter
+		condition string
+		args      []interface{}
+	}{
+		{
+			name:      "postgres_equal",
+			dialect:   Postgres{},
+			filter:    equal,
+			condition: `("metadata" @> ?::jsonb OR "metadata" @> ?::jsonb)`,
+			args:      []interface{}{`{"rating":"4"}`, `{"rating":4}`},
+		},
+		{
+			name:      "postgres_compare",
+			dialect:   Postgres{},
+			filter:    greater,
+			condition: `CASE WHEN jsonb_typeof("metadata"->?::text) = 'number' THEN ("metadata"->>?::text)::numeric END >= ?`,
+			args:      []interface{}{"rating", "rating", 4.0},
+		},
+		{
+			name:    "mysql_equal",
+			dialect: MySQL{},
+			filter:  dbmodels.MetadataFilter{Key: "draft", Op: "=", Value: "true"},
+			condition: `(JSON_EXTRACT("metadata", ?) = JSON_EXTRACT(?, '$')` +
+				` OR JSON_EXTRACT("metadata", ?) = JSON_EXTRACT(?, '$'))`,
+			args: []interface{}{`$."draft"`, `"true"`, `$."draft"`, `true`},
+		},
+		{
+			name:    "mysql_equal_number",
+			dialect: MySQL{},
+			filter:  equal,
+			condition: `(JSON_EXTRACT("metadata", ?) = JSON_EXTRACT(?, '$')` +
+				` OR (JSON_TYPE(JSON_EXTRACT("metadata", ?)) IN ('INTEGER', 'UNSIGNED INTEGER', 'DOUBLE', 'DECIMAL')
+			AND JSON_EXTRACT("metadata", ?) = ?))`,
+			args: []interface{}{`$."rating"`, `"4"`, `$."rating"`, `$."rating"`, 4.0},
+		},
+		{
+			name:    "mysql_compare",
+			dialect: MySQL{},
+			filter:  greater,
+			condition: `(JSON_TYPE(JSON_EXTRACT("metadata", ?)) IN ('INTEGER', 'UNSIGNED INTEGER', 'DOUBLE', 'DECIMAL')
+			AND JSON_EXTRACT("metadata", ?) >= ?)`,
+			args: []interface{}{`$."rating"`, `$."rating"`, 4.0},
+		},
+		{
+			name:    "sqlite3",
+			dialect: SQLite{},
+			filter:  equal,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			condition, args := tt.dialect.MetadataCondition(tt.filter)
+			assert.Equal(t, tt.condition, condition)
+			assert.Equal(t, tt.args, args)
+		})
+	}
+}
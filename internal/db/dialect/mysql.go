@@ -0,0 +1,118 @@
+package dialect
+
+import (
+	"errors"
+	"github.com/go-sql-driver/mysql"
+	"githum.com/anupam111/image-store/internal/config"
+	"githum.com/anupam111/image-store/internal/db/dbmodels"
+	"strconv"
+	"strings"
+)
+
+const MySQLDriver = "mysql"
+
+// MySQL error numbers, see
+// https://dev.mysql.com/doc/mysql-errors/8.0/en/server-error-reference.html
+const (
+	mysqlDuplicateEntry = 1062
+	mysqlDeadlock       = 1213
+	mysqlServerShutdown = 1053
+)
+
+// MySQL is the Dialect of MySQL and MariaDB.
+type MySQL struct{}
+
+func (MySQL) DriverName() string {
+	return MySQLDriver
+}
+
+// DataSourceName enables multi statements, which the schema migrations need,
+// and reads DATETIME columns as time.Time.
+func (MySQL) DataSourceName(dbConfig *config.DBConfig) string {
+	dsn := mysql.NewConfig()
+	dsn.User = dbConfig.Username
+	dsn.Passwd = dbConfig.Password
+	dsn.Net = "tcp"
+	dsn.Addr = dbConfig.Host + ":" + strconv.Itoa(dbConfig.Port)
+	dsn.DBName = dbConfig.Name
+	dsn.MultiStatements = true
+	dsn.ParseTime = true
+
+	return dsn.FormatDSN()
+}
+
+// Query quotes the identifiers with backticks, MySQL reads double quotes as
+// string literals unless ANSI_QUOTES is set.
+func (MySQL) Query(query string) string {
+	return quoteIdentifiers(query, "`")
+}
+
+func (MySQL) IsUniqueViolation(err error) bool {
+	var mysqlError *mysql.MySQLError
+	if errors.As(err, &mysqlError) {
+		return mysqlError.Number == mysqlDuplicateEntry
+	}
+
+	return false
+}
+
+func (MySQL) IsSerializationFailure(err error) bool {
+	var mysqlError *mysql.MySQLError
+	if errors.As(err, &mysqlError) {
+		return mysqlError.Number == mysqlDeadlock
+	}
+
+	return false
+}
+
+// IsConnectionFailure also reports the connections the driver found invalid,
+// and the errors of a server which shuts down.
+func (MySQL) IsConnectionFailure(err error) bool {
+	var mysqlError *mysql.MySQLError
+	if errors.As(err, &mysqlError) {
+		return mysqlError.Number == mysqlServerShutdown
+	}
+
+	return errors.Is(err, mysql.ErrInvalidConn) || isNetworkFailure(err)
+}
+
+// MetadataCondition compares the JSON value of the key. MariaDB has no JSON
+// type, so the value of the filter is parsed with JSON_EXTRACT rather than
+// cast, and numbers are compared as numbers, after checking their type, since
+// MariaDB compares the extracted JSON as text.
+func (MySQL) MetadataCondition(filter dbmodels.MetadataFilter) (string, []interface{}) {
+	path := metadataPath(filter.Key)
+	number, isNumber := filter.Number()
+
+	if filter.Op != dbmodels.MetadataEqual {
+		return mysqlNumberCondition(filter.Op), []interface{}{path, path, number}
+	}
+
+	equal := `JSON_EXTRACT("metadata", ?) = JSON_EXTRACT(?, '$')`
+	conditions := []string{equal}
+	args := []interface{}{path, jsonText(filter.Value)}
+
+	if b, ok := filter.Bool(); ok {
+		conditions = append(conditions, equal)
+		args = append(args, path, jsonText(b))
+	}
+
+	if isNumber {
+		conditions = append(conditions, mysqlNumberCondition(filter.Op))
+		args = append(args, path, path, number)
+	}
+
+	return "(" + strings.Join(conditions, " OR ") + ")", args
+}
+
+// mysqlNumberCondition compares the value of the key, when it is a number,
+// with op to a number bind var.
+func mysqlNumberCondition(op string) string {
+	return `(JSON_TYPE(JSON_EXTRACT("metadata", ?)) IN ('INTEGER', 'UNSIGNED INTEGER', 'DOUBLE', 'DECIMAL')
+			AND JSON_EXTRACT("metadata", ?) ` + op + ` ?)`
+}
+
+// metadataPath is the JSON path of a top level key of the metadata.
+func metadataPath(key string) string {
+	return "$." + strconv.Quote(key)
+}
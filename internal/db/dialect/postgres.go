@@ -0,0 +1,94 @@
+package dialect
+
+import (
+	"errors"
+	"fmt"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+	"githum.com/anupam111/image-store/internal/config"
+	"githum.com/anupam111/image-store/internal/db/dbmodels"
+)
+
+const PostgresDriver = "postgres"
+
+// Postgres is the Dialect of PostgreSQL.
+type Postgres struct{}
+
+func (Postgres) DriverName() string {
+	return PostgresDriver
+}
+
+func (Postgres) DataSourceName(dbConfig *config.DBConfig) string {
+	return fmt.Sprintf(
+		"host=%s port=%d user=%s password='%s' dbname=%s sslmode=disable",
+		dbConfig.Host,
+		dbConfig.Port,
+		dbConfig.Username,
+		dbConfig.Password,
+		dbConfig.Name,
+	)
+}
+
+// Query keeps the double quoted identifiers, which preserve the case of the
+// camelCase column names, and numbers the bind vars as $1, $2, ...
+func (Postgres) Query(query string) string {
+	return sqlx.Rebind(sqlx.DOLLAR, query)
+}
+
+func (Postgres) IsUniqueViolation(err error) bool {
+	var pqError *pq.Error
+	if errors.As(err, &pqError) {
+		return pqError.Code.Name() == "unique_violation"
+	}
+
+	return false
+}
+
+func (Postgres) IsSerializationFailure(err error) bool {
+	var pqError *pq.Error
+	if errors.As(err, &pqError) {
+		switch pqError.Code.Name() {
+		case "serialization_failure", "deadlock_detected":
+			return true
+		}
+	}
+
+	return false
+}
+
+// IsConnectionFailure also reports the connection exceptions, class 08, and
+// the errors of a server which shuts down or is starting.
+func (Postgres) IsConnectionFailure(err error) bool {
+	var pqError *pq.Error
+	if errors.As(err, &pqError) {
+		switch pqError.Code {
+		case "57P01", "57P02", "57P03":
+			return true
+		}
+
+		return pqError.Code.Class() == "08"
+	}
+
+	return isNetworkFailure(err)
+}
+
+// MetadataCondition tests equality with JSONB containment, which the GIN
+// index on the metadata column serves. Number comparisons cast the value,
+// after checking its type.
+func (Postgres) MetadataCondition(filter dbmodels.MetadataFilter) (string, []interface{}) {
+	if filter.Op == dbmodels.MetadataEqual {
+		values := metadataValues(filter)
+		args := make([]interface{}, len(values))
+
+		for idx, value := range values {
+			args[idx] = jsonText(map[string]interface{}{filter.Key: value})
+		}
+
+		return orConditions(`"metadata" @> ?::jsonb`, len(args)), args
+	}
+
+	number, _ := filter.Number()
+
+	return `CASE WHEN jsonb_typeof("metadata"->?::text) = 'number' THEN ("metadata"->>?::text)::numeric END ` + filter.Op + ` ?`,
+		[]interface{}{filter.Key, filter.Key, number}
+}
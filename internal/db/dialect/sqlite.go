@@ -0,0 +1,59 @@
+package dialect
+
+import (
+	"errors"
+	"fmt"
+	"github.com/mattn/go-sqlite3"
+	"githum.com/anupam111/image-store/internal/config"
+	"githum.com/anupam111/image-store/internal/db/dbmodels"
+)
+
+const SQLiteDriver = "sqlite3"
+
+// SQLite is the Dialect of SQLite, which accepts the double quoted
+// identifiers and ? bind vars of the queries as they are.
+type SQLite struct{}
+
+func (SQLite) DriverName() string {
+	return SQLiteDriver
+}
+
+// DataSourceName uses the database name as the path of the database file.
+func (SQLite) DataSourceName(dbConfig *config.DBConfig) string {
+	return fmt.Sprintf("file:%s?_foreign_keys=on&_busy_timeout=5000", dbConfig.Name)
+}
+
+func (SQLite) Query(query string) string {
+	return query
+}
+
+func (SQLite) IsUniqueViolation(err error) bool {
+	var sqliteError sqlite3.Error
+	if errors.As(err, &sqliteError) {
+		return sqliteError.ExtendedCode == sqlite3.ErrConstraintUnique ||
+			sqliteError.ExtendedCode == sqlite3.ErrConstraintPrimaryKey
+	}
+
+	return false
+}
+
+func (SQLite) IsSerializationFailure(err error) bool {
+	var sqliteError sqlite3.Error
+	if errors.As(err, &sqliteError) {
+		return sqliteError.Code == sqlite3.ErrBusy || sqliteError.Code == sqlite3.ErrLocked
+	}
+
+	return false
+}
+
+// IsConnectionFailure never reports the errors of SQLite, which opens its
+// database file in process.
+func (SQLite) IsConnectionFailure(error) bool {
+	return false
+}
+
+// MetadataCondition returns no condition, the JSON functions are not built
+// into the bundled SQLite.
+func (SQLite) MetadataCondition(dbmodels.MetadataFilter) (string, []interface{}) {
+	return "", nil
+}
@@ -0,0 +1,112 @@
+package memstore
+
+import (
+	"context"
+	"githum.com/anupam111/image-store/internal/db/dbhandler"
+	"githum.com/anupam111/image-store/internal/db/dbmodels"
+	"githum.com/anupam111/image-store/internal/tenant"
+	"sort"
+)
+
+func (m *MemStore) GrantAlbumAccess(ctx context.Context, access dbmodels.AlbumAccess) error {
+	access.Tenant = tenant.FromContext(ctx)
+
+	return m.write(ctx, func(d *data) error {
+		if _, ok := d.albums[nameKey{access.Tenant, access.AlbumName}]; !ok {
+			return ErrAlbumNotFound
+		}
+
+		d.albumAccess[accessKey{access.Tenant, access.AlbumName, access.PrincipalType, access.Principal}] = access
+
+		return nil
+	})
+}
+
+func (m *MemStore) RevokeAlbumAccess(ctx context.Context, albumName, principalType, principal string) error {
+	key := accessKey{tenant.FromContext(ctx), albumName, principalType, principal}
+
+	return m.write(ctx, func(d *data) error {
+		if _, ok := d.albumAccess[key]; !ok {
+			return dbhandler.ErrNoDataFound
+		}
+
+		delete(d.albumAccess, key)
+
+		return nil
+	})
+}
+
+func (m *MemStore) DeleteAlbumAccess(ctx context.Context, albumName string) error {
+	id := tenant.FromContext(ctx)
+
+	return m.write(ctx, func(d *data) error {
+		for key := range d.albumAccess {
+			if key.tenant == id && key.albumName == albumName {
+				delete(d.albumAccess, key)
+			}
+		}
+
+		return nil
+	})
+}
+
+// ListAlbumAccess returns the accesses of the album ordered by principal type
+// and principal.
+func (m *MemStore) ListAlbumAccess(ctx context.Context, albumName string) ([]dbmodels.AlbumAccess, error) {
+	return m.listAccess(ctx, func(access dbmodels.AlbumAccess) bool {
+		return access.AlbumName == albumName
+	})
+}
+
+// ListPrincipalAccess returns the accesses of the user and of the groups
+// ordered by album, principal type and principal.
+func (m *MemStore) ListPrincipalAccess(ctx context.Context, user string, groups []string) ([]dbmodels.AlbumAccess, error) {
+	inGroups := make(map[string]bool, len(groups))
+	for _, group := range groups {
+		inGroups[group] = true
+	}
+
+	return m.listAccess(ctx, func(access dbmodels.AlbumAccess) bool {
+		switch access.PrincipalType {
+		case dbmodels.PrincipalUser:
+			return access.Principal == user
+		case dbmodels.PrincipalGroup:
+			return inGroups[access.Principal]
+		default:
+			return false
+		}
+	})
+}
+
+func (m *MemStore) listAccess(ctx context.Context, selected func(dbmodels.AlbumAccess) bool) ([]dbmodels.AlbumAccess, error) {
+	accesses := []dbmodels.AlbumAccess{}
+	id := tenant.FromContext(ctx)
+
+	err := m.read(ctx, func(d *data) error {
+		for _, access := range d.albumAccess {
+			if access.Tenant == id && selected(access) {
+				accesses = append(accesses, access)
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Slice(accesses, func(i, j int) bool {
+		a, b := accesses[i], accesses[j]
+		if a.AlbumName != b.AlbumName {
+			return a.AlbumName < b.AlbumName
+		}
+
+		if a.PrincipalType != b.PrincipalType {
+			return a.PrincipalType < b.PrincipalType
+		}
+
+		return a.Principal < b.Principal
+	})
+
+	return accesses, nil
+}
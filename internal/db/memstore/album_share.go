@@ -0,0 +1,108 @@
+package memstore
+
+import (
+	"context"
+	"githum.com/anupam111/image-store/internal/db/dbhandler"
+	"githum.com/anupam111/image-store/internal/db/dbmodels"
+	"githum.com/anupam111/image-store/internal/tenant"
+	"sort"
+	"time"
+)
+
+func (m *MemStore) CreateAlbumShare(ctx context.Context, share dbmodels.AlbumShare) error {
+	share.Tenant = tenant.FromContext(ctx)
+
+	return m.write(ctx, func(d *data) error {
+		if _, ok := d.albums[nameKey{share.Tenant, share.AlbumName}]; !ok {
+			return ErrAlbumNotFound
+		}
+
+		for _, existing := range d.albumShares {
+			if existing.ID == share.ID || existing.Hash == share.Hash {
+				return dbhandler.ErrDuplicate
+			}
+		}
+
+		d.albumShares[share.ID] = share
+
+		return nil
+	})
+}
+
+// ListAlbumShares returns the shares of the album ordered by creation time
+// and id.
+func (m *MemStore) ListAlbumShares(ctx context.Context, albumName string) ([]dbmodels.AlbumShare, error) {
+	shares := []dbmodels.AlbumShare{}
+	id := tenant.FromContext(ctx)
+
+	err := m.read(ctx, func(d *data) error {
+		for _, share := range d.albumShares {
+			if share.Tenant == id && share.AlbumName == albumName {
+				shares = append(shares, share)
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Slice(shares, func(i, j int) bool {
+		if !shares[i].CreatedAt.Equal(shares[j].CreatedAt) {
+			return shares[i].CreatedAt.Before(shares[j].CreatedAt)
+		}
+
+		return shares[i].ID < shares[j].ID
+	})
+
+	return shares, nil
+}
+
+func (m *MemStore) GetAlbumShareByHash(ctx context.Context, hash string) (dbmodels.AlbumShare, error) {
+	var res dbmodels.AlbumShare
+
+	err := m.read(ctx, func(d *data) error {
+		for _, share := range d.albumShares {
+			if share.Hash == hash {
+				res = share
+
+				return nil
+			}
+		}
+
+		return dbhandler.ErrNoDataFound
+	})
+
+	return res, err
+}
+
+func (m *MemStore) RevokeAlbumShare(ctx context.Context, albumName, id string, revokedAt time.Time) error {
+	shareTenant := tenant.FromContext(ctx)
+
+	return m.write(ctx, func(d *data) error {
+		share, ok := d.albumShares[id]
+		if !ok || share.Tenant != shareTenant || share.AlbumName != albumName || share.RevokedAt != nil {
+			return dbhandler.ErrNoDataFound
+		}
+
+		share.RevokedAt = &revokedAt
+		d.albumShares[id] = share
+
+		return nil
+	})
+}
+
+func (m *MemStore) DeleteAlbumShares(ctx context.Context, albumName string) error {
+	shareTenant := tenant.FromContext(ctx)
+
+	return m.write(ctx, func(d *data) error {
+		for id, share := range d.albumShares {
+			if share.Tenant == shareTenant && share.AlbumName == albumName {
+				delete(d.albumShares, id)
+			}
+		}
+
+		return nil
+	})
+}
@@ -0,0 +1,87 @@
+package memstore
+
+import (
+	"context"
+	"githum.com/anupam111/image-store/internal/db/dbhandler"
+	"githum.com/anupam111/image-store/internal/db/dbmodels"
+	"githum.com/anupam111/image-store/internal/tenant"
+	"sort"
+)
+
+// The memory store counts the usage when it is read, which is cheap enough
+// in memory.
+
+func (m *MemStore) GetAlbumUsage(ctx context.Context, albumName string) (dbmodels.AlbumUsage, error) {
+	var res dbmodels.AlbumUsage
+
+	id := tenant.FromContext(ctx)
+
+	err := m.read(ctx, func(d *data) error {
+		if _, ok := d.albums[nameKey{id, albumName}]; !ok {
+			return dbhandler.ErrNoDataFound
+		}
+
+		res = albumUsage(d, id, albumName)
+
+		return nil
+	})
+
+	return res, err
+}
+
+// ListAlbumUsage returns the usage of every album of the tenant, ordered by
+// album name.
+func (m *MemStore) ListAlbumUsage(ctx context.Context) ([]dbmodels.AlbumUsage, error) {
+	usages := []dbmodels.AlbumUsage{}
+
+	id := tenant.FromContext(ctx)
+
+	err := m.read(ctx, func(d *data) error {
+		for key := range d.albums {
+			if key.tenant == id {
+				usages = append(usages, albumUsage(d, id, key.name))
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Slice(usages, func(i, j int) bool {
+		return usages[i].AlbumName < usages[j].AlbumName
+	})
+
+	return usages, nil
+}
+
+// GetTenantUsage returns the usage of all the albums of the tenant.
+func (m *MemStore) GetTenantUsage(ctx context.Context) (dbmodels.Usage, error) {
+	var res dbmodels.Usage
+
+	id := tenant.FromContext(ctx)
+
+	err := m.read(ctx, func(d *data) error {
+		for key, image := range d.images {
+			if key.tenant == id {
+				res.Images++
+				res.Bytes += image.Size()
+			}
+		}
+
+		return nil
+	})
+
+	return res, err
+}
+
+func albumUsage(d *data, id, albumName string) dbmodels.AlbumUsage {
+	usage := dbmodels.AlbumUsage{Tenant: id, AlbumName: albumName}
+	for _, image := range imagesOfAlbums(d, id, albumName) {
+		usage.Images++
+		usage.Bytes += image.Size()
+	}
+
+	return usage
+}
@@ -0,0 +1,81 @@
+package memstore
+
+import (
+	"context"
+	"githum.com/anupam111/image-store/internal/db/dbhandler"
+	"githum.com/anupam111/image-store/internal/db/dbmodels"
+	"sort"
+	"time"
+)
+
+func (m *MemStore) CreateAPIKey(ctx context.Context, key dbmodels.APIKey) error {
+	return m.write(ctx, func(d *data) error {
+		for _, existing := range d.apiKeys {
+			if existing.ID == key.ID || existing.Hash == key.Hash {
+				return dbhandler.ErrDuplicate
+			}
+		}
+
+		d.apiKeys[key.ID] = key
+
+		return nil
+	})
+}
+
+// ListAPIKeys returns the API keys ordered by creation time and id.
+func (m *MemStore) ListAPIKeys(ctx context.Context) ([]dbmodels.APIKey, error) {
+	keys := []dbmodels.APIKey{}
+
+	err := m.read(ctx, func(d *data) error {
+		for _, key := range d.apiKeys {
+			keys = append(keys, key)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Slice(keys, func(i, j int) bool {
+		if !keys[i].CreatedAt.Equal(keys[j].CreatedAt) {
+			return keys[i].CreatedAt.Before(keys[j].CreatedAt)
+		}
+
+		return keys[i].ID < keys[j].ID
+	})
+
+	return keys, nil
+}
+
+func (m *MemStore) GetAPIKeyByHash(ctx context.Context, hash string) (dbmodels.APIKey, error) {
+	var res dbmodels.APIKey
+
+	err := m.read(ctx, func(d *data) error {
+		for _, key := range d.apiKeys {
+			if key.Hash == hash {
+				res = key
+
+				return nil
+			}
+		}
+
+		return dbhandler.ErrNoDataFound
+	})
+
+	return res, err
+}
+
+func (m *MemStore) RevokeAPIKey(ctx context.Context, id string, revokedAt time.Time) error {
+	return m.write(ctx, func(d *data) error {
+		key, ok := d.apiKeys[id]
+		if !ok || key.RevokedAt != nil {
+			return dbhandler.ErrNoDataFound
+		}
+
+		key.RevokedAt = &revokedAt
+		d.apiKeys[id] = key
+
+		return nil
+	})
+}
@@ -0,0 +1,85 @@
+package memstore
+
+import (
+	"context"
+	"githum.com/anupam111/image-store/internal/db/dbmodels"
+	"githum.com/anupam111/image-store/internal/tenant"
+)
+
+func (m *MemStore) CreateAuditEvent(ctx context.Context, event dbmodels.AuditEvent) error {
+	event.Tenant = tenant.FromContext(ctx)
+
+	return m.write(ctx, func(d *data) error {
+		event.ID = int64(len(d.auditEvents) + 1)
+		d.auditEvents = append(d.auditEvents, event)
+
+		return nil
+	})
+}
+
+// ListAuditEvents returns a page of the events selected by the filter, latest
+// first, and the number of events of all pages. Like the SQL stores, the
+// number of events is 0 when offset is past the last event.
+func (m *MemStore) ListAuditEvents(ctx context.Context, filter dbmodels.AuditFilter,
+	limit, offset int) ([]dbmodels.AuditEvent, int, error) {
+	var selected []dbmodels.AuditEvent
+
+	err := m.read(ctx, func(d *data) error {
+		selected = auditEvents(d, tenant.FromContext(ctx), filter)
+
+		return nil
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+
+	if offset >= len(selected) {
+		return []dbmodels.AuditEvent{}, 0, nil
+	}
+
+	events := []dbmodels.AuditEvent{}
+	for idx := len(selected) - 1 - offset; idx >= 0 && len(events) < limit; idx-- {
+		events = append(events, selected[idx])
+	}
+
+	return events, len(selected), nil
+}
+
+// StreamAuditEvents calls fn with every event selected by the filter, oldest
+// first. The events are read before fn is called, so that fn may use the
+// store.
+func (m *MemStore) StreamAuditEvents(ctx context.Context, filter dbmodels.AuditFilter,
+	fn func(dbmodels.AuditEvent) error) error {
+	var selected []dbmodels.AuditEvent
+
+	err := m.read(ctx, func(d *data) error {
+		selected = auditEvents(d, tenant.FromContext(ctx), filter)
+
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, event := range selected {
+		if err = fn(event); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// auditEvents returns the events of the tenant selected by the filter, oldest
+// first.
+func auditEvents(d *data, id string, filter dbmodels.AuditFilter) []dbmodels.AuditEvent {
+	var selected []dbmodels.AuditEvent
+
+	for _, event := range d.auditEvents {
+		if event.Tenant == id && filter.Match(event) {
+			selected = append(selected, event)
+		}
+	}
+
+	return selected
+}
@@ -0,0 +1,540 @@
+// Package memstore is an in-memory dbhandler.ImageStore. It keeps the
+// constraints of the SQL schema, names unique per tenant and images belonging
+// to an existing album, so it stands in for a database in tests and demos.
+package memstore
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"githum.com/anupam111/image-store/internal/db/dbhandler"
+	"githum.com/anupam111/image-store/internal/db/dbmodels"
+	"githum.com/anupam111/image-store/internal/tenant"
+	"sort"
+	"strings"
+	"sync"
+)
+
+var (
+	// ErrAlbumNotFound is returned for an image of an album which does not
+	// exist, like the foreign key of the SQL schema.
+	ErrAlbumNotFound = errors.New("album does not exist")
+	// ErrAlbumNotEmpty is returned when deleting an album which still has
+	// images, like the foreign key of the SQL schema.
+	ErrAlbumNotEmpty = errors.New("album still has images")
+	// ErrAlbumShared is returned when deleting an album which still has
+	// accesses or shares, like the foreign keys of the SQL schema.
+	ErrAlbumShared = errors.New("album still has accesses or shares")
+)
+
+type data struct {
+	// albums are keyed by tenant and name.
+	albums map[nameKey]dbmodels.Album
+	// images are keyed by tenant and name, which is unique across the albums
+	// of a tenant.
+	images map[nameKey]dbmodels.Image
+	// apiKeys are keyed by id.
+	apiKeys map[string]dbmodels.APIKey
+	// albumAccess is keyed by tenant, album, principal type and principal.
+	albumAccess map[accessKey]dbmodels.AlbumAccess
+	// albumShares are keyed by id.
+	albumShares map[string]dbmodels.AlbumShare
+	// auditEvents are in the order they were appended, their ids numbering
+	// them from 1.
+	auditEvents []dbmodels.AuditEvent
+}
+
+type nameKey struct {
+	tenant, name string
+}
+
+type accessKey struct {
+	tenant, albumName, principalType, principal string
+}
+
+func (d *data) clone() *data {
+	c := &data{
+		albums:  make(map[nameKey]dbmodels.Album, len(d.albums)),
+		images:  make(map[nameKey]dbmodels.Image, len(d.images)),
+		apiKeys: make(map[string]dbmodels.APIKey, len(d.apiKeys)),
+
+		albumAccess: make(map[accessKey]dbmodels.AlbumAccess, len(d.albumAccess)),
+		albumShares: make(map[string]dbmodels.AlbumShare, len(d.albumShares)),
+		auditEvents: append([]dbmodels.AuditEvent(nil), d.auditEvents...),
+	}
+
+	for key, album := range d.albums {
+		c.albums[key] = album
+	}
+
+	for key, image := range d.images {
+		c.images[key] = image
+	}
+
+	for id, key := range d.apiKeys {
+		c.apiKeys[id] = key
+	}
+
+	for key, access := range d.albumAccess {
+		c.albumAccess[key] = access
+	}
+
+	for id, share := range d.albumShares {
+		c.albumShares[id] = share
+	}
+
+	return c
+}
+
+// MemStore implements dbhandler.ImageStore in memory. Every write and every
+// WithTx works on a copy of the data which replaces the data when it
+// succeeds, so failed operations leave no trace.
+type MemStore struct {
+	mu   *sync.Mutex
+	data *data
+	// inTx is set on the stores passed to WithTx callbacks, which already hold
+	// mu and work on the copy of the transaction.
+	inTx bool
+}
+
+// NewMemStore implements MemStore.
+func NewMemStore() *MemStore {
+	return &MemStore{
+		mu: &sync.Mutex{},
+		data: &data{
+			albums:  map[nameKey]dbmodels.Album{},
+			images:  map[nameKey]dbmodels.Image{},
+			apiKeys: map[string]dbmodels.APIKey{},
+
+			albumAccess: map[accessKey]dbmodels.AlbumAccess{},
+			albumShares: map[string]dbmodels.AlbumShare{},
+		},
+	}
+}
+
+var (
+	_ dbhandler.ImageStore  = (*MemStore)(nil)
+	_ dbhandler.APIKeyStore = (*MemStore)(nil)
+	_ dbhandler.AuditStore  = (*MemStore)(nil)
+)
+
+func (m *MemStore) read(ctx context.Context, fn func(d *data) error) error {
+	if err := ctx.Err(); err != nil {
+		return fmt.Errorf("%w", err)
+	}
+
+	if m.inTx {
+		return fn(m.data)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return fn(m.data)
+}
+
+func (m *MemStore) write(ctx context.Context, fn func(d *data) error) error {
+	if err := ctx.Err(); err != nil {
+		return fmt.Errorf("%w", err)
+	}
+
+	if m.inTx {
+		return fn(m.data)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	c := m.data.clone()
+	if err := fn(c); err != nil {
+		return err
+	}
+
+	m.data = c
+
+	return nil
+}
+
+// WithTx runs fn on a copy of the data, which replaces the data when fn
+// returns nil. Transactions are serialized, nested calls join the outer one.
+func (m *MemStore) WithTx(ctx context.Context, fn func(tx dbhandler.ImageStore) error) error {
+	return m.write(ctx, func(d *data) error {
+		return fn(m.inTxOn(d))
+	})
+}
+
+// WithAPIKeyTx is WithTx for the API keys.
+func (m *MemStore) WithAPIKeyTx(ctx context.Context, fn func(tx dbhandler.APIKeyStore) error) error {
+	return m.write(ctx, func(d *data) error {
+		return fn(m.inTxOn(d))
+	})
+}
+
+// inTxOn returns a store acting on d, the data of a transaction.
+func (m *MemStore) inTxOn(d *data) *MemStore {
+	return &MemStore{
+		mu:   m.mu,
+		data: d,
+		inTx: true,
+	}
+}
+
+func (m *MemStore) CreateAlbum(ctx context.Context, album dbmodels.Album) error {
+	album.Tenant = tenant.FromContext(ctx)
+
+	return m.write(ctx, func(d *data) error {
+		key := nameKey{album.Tenant, album.AlbumName}
+		if _, ok := d.albums[key]; ok {
+			return dbhandler.ErrDuplicate
+		}
+
+		d.albums[key] = album
+
+		return nil
+	})
+}
+
+func (m *MemStore) CreateImage(ctx context.Context, image dbmodels.Image) error {
+	image.Tenant = tenant.FromContext(ctx)
+
+	return m.write(ctx, func(d *data) error {
+		if _, ok := d.albums[nameKey{image.Tenant, image.AlbumName}]; !ok {
+			return ErrAlbumNotFound
+		}
+
+		key := nameKey{image.Tenant, image.ImageName}
+		if _, ok := d.images[key]; ok {
+			return dbhandler.ErrDuplicate
+		}
+
+		d.images[key] = image
+
+		return nil
+	})
+}
+
+func (m *MemStore) DeleteAlbum(ctx context.Context, albumName string) error {
+	id := tenant.FromContext(ctx)
+
+	return m.write(ctx, func(d *data) error {
+		if len(imagesOfAlbums(d, id, albumName)) > 0 {
+			return ErrAlbumNotEmpty
+		}
+
+		for key := range d.albumAccess {
+			if key.tenant == id && key.albumName == albumName {
+				return ErrAlbumShared
+			}
+		}
+
+		for _, share := range d.albumShares {
+			if share.Tenant == id && share.AlbumName == albumName {
+				return ErrAlbumShared
+			}
+		}
+
+		delete(d.albums, nameKey{id, albumName})
+
+		return nil
+	})
+}
+
+func (m *MemStore) DeleteAllImagesOfAlbum(ctx context.Context, albumName string) error {
+	id := tenant.FromContext(ctx)
+
+	return m.write(ctx, func(d *data) error {
+		for _, image := range imagesOfAlbums(d, id, albumName) {
+			delete(d.images, nameKey{id, image.ImageName})
+		}
+
+		return nil
+	})
+}
+
+func (m *MemStore) DeleteImageWithImageName(ctx context.Context, imageName, albumName string) error {
+	key := nameKey{tenant.FromContext(ctx), imageName}
+
+	return m.write(ctx, func(d *data) error {
+		if image, ok := d.images[key]; ok && image.AlbumName == albumName {
+			delete(d.images, key)
+		}
+
+		return nil
+	})
+}
+
+func (m *MemStore) GetImageByID(ctx context.Context, imageName string) (dbmodels.Image, error) {
+	var res dbmodels.Image
+
+	err := m.read(ctx, func(d *data) error {
+		image, ok := d.images[nameKey{tenant.FromContext(ctx), imageName}]
+		if !ok {
+			return dbhandler.ErrNoDataFound
+		}
+
+		res = image
+
+		return nil
+	})
+
+	return res, err
+}
+
+func (m *MemStore) GetAllImages(ctx context.Context, albumName string) ([]dbmodels.Image, error) {
+	images := []dbmodels.Image{}
+
+	err := m.read(ctx, func(d *data) error {
+		images = append(images, imagesOfAlbums(d, tenant.FromContext(ctx), albumName)...)
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return images, nil
+}
+
+func (m *MemStore) GetAlbum(ctx context.Context, albumName string) (dbmodels.Album, error) {
+	var res dbmodels.Album
+
+	err := m.read(ctx, func(d *data) error {
+		album, ok := d.albums[nameKey{tenant.FromContext(ctx), albumName}]
+		if !ok {
+			return dbhandler.ErrNoDataFound
+		}
+
+		res = album
+
+		return nil
+	})
+
+	return res, err
+}
+
+// StreamImages calls fn for every image of the album. The images are copied
+// first, so fn may call the store.
+func (m *MemStore) StreamImages(ctx context.Context, albumName string, fn func(dbmodels.Image) error) error {
+	images, err := m.GetAllImages(ctx, albumName)
+	if err != nil {
+		return err
+	}
+
+	for _, image := range images {
+		if err = fn(image); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (m *MemStore) ListAlbums(ctx context.Context) ([]dbmodels.Album, error) {
+	albums := []dbmodels.Album{}
+
+	id := tenant.FromContext(ctx)
+
+	err := m.read(ctx, func(d *data) error {
+		for key, album := range d.albums {
+			if key.tenant == id {
+				albums = append(albums, album)
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Slice(albums, func(i, j int) bool {
+		return albums[i].AlbumName < albums[j].AlbumName
+	})
+
+	return albums, nil
+}
+
+func (m *MemStore) GetAlbumImage(ctx context.Context, albumName, imageName string) (dbmodels.Image, error) {
+	var res dbmodels.Image
+
+	err := m.read(ctx, func(d *data) error {
+		image, ok := d.images[nameKey{tenant.FromContext(ctx), imageName}]
+		if !ok || image.AlbumName != albumName {
+			return dbhandler.ErrNoDataFound
+		}
+
+		res = image
+
+		return nil
+	})
+
+	return res, err
+}
+
+func (m *MemStore) UpdateImage(ctx context.Context, key dbmodels.ImageKey, image dbmodels.Image) error {
+	id := tenant.FromContext(ctx)
+
+	return m.write(ctx, func(d *data) error {
+		current, ok := d.images[nameKey{id, key.ImageName}]
+		if !ok || current.AlbumName != key.AlbumName {
+			return dbhandler.ErrNoDataFound
+		}
+
+		if _, ok = d.images[nameKey{id, image.ImageName}]; ok && image.ImageName != key.ImageName {
+			return dbhandler.ErrDuplicate
+		}
+
+		delete(d.images, nameKey{id, key.ImageName})
+		current.ImageName = image.ImageName
+		current.Image = image.Image
+		current.Metadata = image.Metadata
+		d.images[nameKey{id, current.ImageName}] = current
+
+		return nil
+	})
+}
+
+// GetImagesOfAlbums returns the images of all the albums ordered by album and
+// image name.
+func (m *MemStore) GetImagesOfAlbums(ctx context.Context, albumNames []string) ([]dbmodels.Image, error) {
+	images := []dbmodels.Image{}
+
+	err := m.read(ctx, func(d *data) error {
+		images = append(images, imagesOfAlbums(d, tenant.FromContext(ctx), albumNames...)...)
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Slice(images, func(i, j int) bool {
+		if images[i].AlbumName != images[j].AlbumName {
+			return images[i].AlbumName < images[j].AlbumName
+		}
+
+		return images[i].ImageName < images[j].ImageName
+	})
+
+	return images, nil
+}
+
+// FilterImages returns the images of the album selected by all the metadata
+// filters, ordered by image name.
+func (m *MemStore) FilterImages(ctx context.Context, albumName string, filters []dbmodels.MetadataFilter) ([]dbmodels.Image, error) {
+	for _, filter := range filters {
+		if err := filter.Validate(); err != nil {
+			return nil, err
+		}
+	}
+
+	images := []dbmodels.Image{}
+
+	err := m.read(ctx, func(d *data) error {
+		for _, image := range imagesOfAlbums(d, tenant.FromContext(ctx), albumName) {
+			if image.Metadata.Match(filters) {
+				images = append(images, image)
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Slice(images, func(i, j int) bool {
+		return images[i].ImageName < images[j].ImageName
+	})
+
+	return images, nil
+}
+
+// SearchImages matches text anywhere in the image and album names and in the
+// string values of the metadata, ignoring case, like the databases without
+// full-text search. Image name matches rank above album name matches, which
+// rank above metadata matches. A search with albumNames only matches the
+// images of those albums.
+func (m *MemStore) SearchImages(ctx context.Context, text string, albumNames []string,
+	limit, offset int) ([]dbmodels.ImageMatch, int, error) {
+	matches := []dbmodels.ImageMatch{}
+	text = strings.ToLower(text)
+	id := tenant.FromContext(ctx)
+
+	err := m.read(ctx, func(d *data) error {
+		var images []dbmodels.Image
+		if len(albumNames) > 0 {
+			images = imagesOfAlbums(d, id, albumNames...)
+		} else {
+			for key, image := range d.images {
+				if key.tenant == id {
+					images = append(images, image)
+				}
+			}
+		}
+
+		for _, image := range images {
+			match := dbmodels.ImageMatch{ImageName: image.ImageName, AlbumName: image.AlbumName, Metadata: image.Metadata}
+
+			switch {
+			case strings.Contains(strings.ToLower(image.ImageName), text):
+				match.Rank = 3
+			case strings.Contains(strings.ToLower(image.AlbumName), text):
+				match.Rank = 2
+			case image.Metadata.ContainsText(text):
+				match.Rank = 1
+			default:
+				continue
+			}
+
+			matches = append(matches, match)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+
+	sort.Slice(matches, func(i, j int) bool {
+		if matches[i].Rank != matches[j].Rank {
+			return matches[i].Rank > matches[j].Rank
+		}
+
+		if matches[i].AlbumName != matches[j].AlbumName {
+			return matches[i].AlbumName < matches[j].AlbumName
+		}
+
+		return matches[i].ImageName < matches[j].ImageName
+	})
+
+	if offset >= len(matches) {
+		return []dbmodels.ImageMatch{}, 0, nil
+	}
+
+	total := len(matches)
+	matches = matches[offset:]
+
+	if limit < len(matches) {
+		matches = matches[:limit]
+	}
+
+	return matches, total, nil
+}
+
+// imagesOfAlbums returns the images of the albums of the tenant id.
+func imagesOfAlbums(d *data, id string, albumNames ...string) []dbmodels.Image {
+	wanted := make(map[string]bool, len(albumNames))
+	for _, name := range albumNames {
+		wanted[name] = true
+	}
+
+	var images []dbmodels.Image
+	for key, image := range d.images {
+		if key.tenant == id && wanted[image.AlbumName] {
+			images = append(images, image)
+		}
+	}
+
+	return images
+}
@@ -0,0 +1,19 @@
+package memstore
+
+import (
+	"githum.com/anupam111/image-store/internal/db/dbhandler"
+	"githum.com/anupam111/image-store/internal/db/dbhandler/storetest"
+	"testing"
+)
+
+func TestConformance(t *testing.T) {
+	storetest.Run(t, func(t *testing.T) dbhandler.ImageStore {
+		return NewMemStore()
+	})
+	storetest.RunAPIKeys(t, func(t *testing.T) dbhandler.APIKeyStore {
+		return NewMemStore()
+	})
+	storetest.RunAudit(t, func(t *testing.T) dbhandler.AuditStore {
+		return NewMemStore()
+	})
+}
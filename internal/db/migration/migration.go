@@ -0,0 +1,239 @@
+// Package migration embeds the SQL schema migrations of the image store and
+// applies them with golang-migrate.
+package migration
+
+import (
+	"context"
+	"embed"
+	"errors"
+	"fmt"
+	"github.com/golang-migrate/migrate/v4"
+	"github.com/golang-migrate/migrate/v4/database"
+	"github.com/golang-migrate/migrate/v4/database/mysql"
+	"github.com/golang-migrate/migrate/v4/database/postgres"
+	"github.com/golang-migrate/migrate/v4/database/sqlite3"
+	"github.com/golang-migrate/migrate/v4/source"
+	"github.com/golang-migrate/migrate/v4/source/iofs"
+	"github.com/jmoiron/sqlx"
+	log "github.com/sirupsen/logrus"
+	"githum.com/anupam111/image-store/internal/db/dialect"
+)
+
+// autoMigrateLockID is the key of the lock held while migrating on start, so
+// that only one replica migrates at a time.
+const autoMigrateLockID = 7_263_420_151
+
+// autoMigrateLocks holds the statements taking and releasing that lock, per
+// driver. SQLite databases are local to a single node and need no lock.
+var autoMigrateLocks = map[string][2]string{
+	dialect.PostgresDriver: {`SELECT pg_advisory_lock($1)`, `SELECT pg_advisory_unlock($1)`},
+	dialect.MySQLDriver:    {`SELECT GET_LOCK(?, -1)`, `SELECT RELEASE_LOCK(?)`},
+}
+
+// migrations holds one directory of migrations per database driver.
+//
+//go:embed postgres/*.sql sqlite3/*.sql mysql/*.sql
+var migrations embed.FS
+
+// Migrator applies the embedded migrations to a database.
+type Migrator struct {
+	log    *log.Logger
+	db     *sqlx.DB
+	m      *migrate.Migrate
+	source source.Driver
+	driver database.Driver
+}
+
+// NewMigrator implements Migrator for the database of db.
+func NewMigrator(log *log.Logger, db *sqlx.DB) (*Migrator, error) {
+	source, err := iofs.New(migrations, db.DriverName())
+	if err != nil {
+		return nil, fmt.Errorf("error while reading embedded migrations, %w", err)
+	}
+
+	driver, err := databaseDriver(db)
+	if err != nil {
+		return nil, err
+	}
+
+	m, err := migrate.NewWithInstance("iofs", source, db.DriverName(), driver)
+	if err != nil {
+		return nil, fmt.Errorf("error while setting up migrations, %w", err)
+	}
+
+	return &Migrator{
+		log:    log,
+		db:     db,
+		m:      m,
+		source: source,
+		driver: driver,
+	}, nil
+}
+
+// databaseDriver returns the migration driver of db. The postgres and mysql
+// drivers are given a connection of their own, which is all they close,
+// while given the *sql.DB itself they close the whole pool.
+func databaseDriver(db *sqlx.DB) (database.Driver, error) {
+	ctx := context.Background()
+
+	switch db.DriverName() {
+	case dialect.PostgresDriver:
+		conn, err := db.DB.Conn(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("error while getting connection for postgres migrations, %w", err)
+		}
+
+		driver, err := postgres.WithConnection(ctx, conn, &postgres.Config{})
+		if err != nil {
+			conn.Close()
+
+			return nil, fmt.Errorf("error while setting up postgres migrations, %w", err)
+		}
+
+		return driver, nil
+	case dialect.SQLiteDriver:
+		driver, err := sqlite3.WithInstance(db.DB, &sqlite3.Config{})
+		if err != nil {
+			return nil, fmt.Errorf("error while setting up sqlite3 migrations, %w", err)
+		}
+
+		return driver, nil
+	case dialect.MySQLDriver:
+		conn, err := db.DB.Conn(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("error while getting connection for mysql migrations, %w", err)
+		}
+
+		driver, err := mysql.WithConnection(ctx, conn, &mysql.Config{})
+		if err != nil {
+			conn.Close()
+
+			return nil, fmt.Errorf("error while setting up mysql migrations, %w", err)
+		}
+
+		return driver, nil
+	default:
+		return nil, fmt.Errorf("migrations are not supported for database driver %q", db.DriverName())
+	}
+}
+
+// Up applies the next steps migrations, or all pending ones when steps is 0.
+func (mg *Migrator) Up(steps int) error {
+	var err error
+	if steps > 0 {
+		err = mg.m.Steps(steps)
+	} else {
+		err = mg.m.Up()
+	}
+
+	if err != nil && !errors.Is(err, migrate.ErrNoChange) {
+		return fmt.Errorf("error while applying migrations, %w", err)
+	}
+
+	return nil
+}
+
+// Down reverts the last steps migrations, or all of them when steps is 0.
+func (mg *Migrator) Down(steps int) error {
+	var err error
+	if steps > 0 {
+		err = mg.m.Steps(-steps)
+	} else {
+		err = mg.m.Down()
+	}
+
+	if err != nil && !errors.Is(err, migrate.ErrNoChange) {
+		return fmt.Errorf("error while reverting migrations, %w", err)
+	}
+
+	return nil
+}
+
+// Status returns the current schema version and whether the last migration
+// failed half way. The version is 0 when no migration was applied.
+func (mg *Migrator) Status() (uint, bool, error) {
+	version, dirty, err := mg.m.Version()
+	if errors.Is(err, migrate.ErrNilVersion) {
+		return 0, false, nil
+	}
+
+	if err != nil {
+		return 0, false, fmt.Errorf("error while reading schema version, %w", err)
+	}
+
+	return version, dirty, nil
+}
+
+// Force sets the schema version without running any migration, to recover
+// from a dirty schema after it was repaired by hand.
+func (mg *Migrator) Force(version int) error {
+	if err := mg.m.Force(version); err != nil {
+		return fmt.Errorf("error while forcing schema version %d, %w", version, err)
+	}
+
+	return nil
+}
+
+// AutoMigrate applies all pending migrations while holding a database lock.
+// Replicas starting together wait for the one holding the lock and then find
+// nothing left to apply.
+func (mg *Migrator) AutoMigrate(ctx context.Context) error {
+	lock, ok := autoMigrateLocks[mg.db.DriverName()]
+	if !ok {
+		return mg.up()
+	}
+
+	conn, err := mg.db.Connx(ctx)
+	if err != nil {
+		return fmt.Errorf("error while getting connection for migration lock, %w", err)
+	}
+
+	defer conn.Close()
+
+	if _, err = conn.ExecContext(ctx, lock[0], autoMigrateLockID); err != nil {
+		return fmt.Errorf("error while taking migration lock, %w", err)
+	}
+
+	defer func() {
+		if _, err := conn.ExecContext(context.Background(), lock[1], autoMigrateLockID); err != nil {
+			mg.log.Errorf("error while releasing migration lock, %v", err)
+		}
+	}()
+
+	return mg.up()
+}
+
+func (mg *Migrator) up() error {
+	if err := mg.Up(0); err != nil {
+		return err
+	}
+
+	version, _, err := mg.Status()
+	if err != nil {
+		return err
+	}
+
+	mg.log.Infof("database schema is at version %d", version)
+
+	return nil
+}
+
+// Close releases the connection held by the Migrator, the database itself
+// stays open.
+func (mg *Migrator) Close() error {
+	if err := mg.source.Close(); err != nil {
+		return fmt.Errorf("error while closing migration source, %w", err)
+	}
+
+	// The sqlite3 driver has no connection of its own, its Close closes the
+	// whole database.
+	if mg.db.DriverName() == dialect.SQLiteDriver {
+		return nil
+	}
+
+	if err := mg.driver.Close(); err != nil {
+		return fmt.Errorf("error while closing migration database, %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,119 @@
+package migration
+
+import (
+	"context"
+	"errors"
+	"github.com/golang-migrate/migrate/v4/source/iofs"
+	"github.com/jmoiron/sqlx"
+	"github.com/kelseyhightower/envconfig"
+	_ "github.com/mattn/go-sqlite3"
+	log "github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"githum.com/anupam111/image-store/internal/config"
+	"githum.com/anupam111/image-store/internal/db/dbconnection"
+	"os"
+	"testing"
+)
+
+func TestEmbeddedMigrations(t *testing.T) {
+	for _, driver := range []string{"postgres", "sqlite3", "mysql"} {
+		driver := driver
+		t.Run(driver, func(t *testing.T) {
+			source, err := iofs.New(migrations, driver)
+			assert.NoError(t, err)
+
+			defer source.Close()
+
+			version, err := source.First()
+			assert.NoError(t, err)
+
+			for {
+				up, _, err := source.ReadUp(version)
+				assert.NoError(t, err, "version %d has no up migration", version)
+				up.Close()
+
+				down, _, err := source.ReadDown(version)
+				assert.NoError(t, err, "version %d has no down migration", version)
+				down.Close()
+
+				next, err := source.Next(version)
+				if errors.Is(err, os.ErrNotExist) {
+					break
+				}
+
+				assert.NoError(t, err)
+				version = next
+			}
+		})
+	}
+}
+
+func TestMigrator_SQLite(t *testing.T) {
+	db, err := sqlx.Connect("sqlite3", "file::memory:?_foreign_keys=on")
+	assert.NoError(t, err)
+
+	db.SetMaxOpenConns(1)
+	defer db.Close()
+
+	migrator, err := NewMigrator(log.New(), db)
+	require.NoError(t, err)
+
+	assert.NoError(t, migrator.Up(8))
+	version, _, err := migrator.Status()
+	assert.NoError(t, err)
+	assert.Equal(t, uint(10), version)
+
+	_, err = db.Exec(`INSERT INTO Album("albumName") VALUES('test-album')`)
+	assert.NoError(t, err)
+
+	_, err = db.Exec(`INSERT INTO Image("imageName", "albumName", "image", "metadata")
+		VALUES('sealed-image', 'test-album', 'enc1$2022-01$a2V5$Y29udGVudA==', '{}')`)
+	assert.NoError(t, err)
+
+	assert.NoError(t, migrator.Up(0))
+	version, dirty, err := migrator.Status()
+	assert.NoError(t, err)
+	assert.Equal(t, uint(12), version)
+	assert.False(t, dirty)
+
+	var keyID string
+	assert.NoError(t, db.Get(&keyID, `SELECT "keyId" FROM Image WHERE "imageName"='sealed-image'`))
+	assert.Equal(t, "2022-01", keyID, "the key id of the sealed images is backfilled")
+
+	_, err = db.Exec(`INSERT INTO Image("imageName", "albumName", "metadata") VALUES('test-image', 'test-album', '{}')`)
+	assert.NoError(t, err)
+
+	assert.NoError(t, migrator.Down(0))
+	version, _, err = migrator.Status()
+	assert.NoError(t, err)
+	assert.Equal(t, uint(0), version)
+
+	// Closing the Migrator leaves the database open.
+	assert.NoError(t, migrator.Close())
+	assert.NoError(t, db.Ping())
+}
+
+// TestMigrator_External migrates the database of the TEST_DB_* variables and
+// checks that closing the Migrator leaves the pool it was given open.
+func TestMigrator_External(t *testing.T) {
+	if os.Getenv("TEST_DB_DRIVER") == "" {
+		t.Skip("TEST_DB_DRIVER not set")
+	}
+
+	var dbConfig config.DBConfig
+	require.NoError(t, envconfig.Process("TEST", &dbConfig))
+
+	pool, err := dbconnection.New(&dbConfig)
+	require.NoError(t, err)
+
+	defer pool.DB.Close()
+
+	migrator, err := NewMigrator(log.New(), pool.DB)
+	require.NoError(t, err)
+	require.NoError(t, migrator.AutoMigrate(context.Background()))
+	require.NoError(t, migrator.Close())
+
+	var count int
+	assert.NoError(t, pool.DB.Get(&count, `SELECT COUNT(*) FROM Album`))
+}
@@ -1,3 +1,3 @@
-DROP TABLE IF EXISTS Album;
-
 DROP TABLE IF EXISTS Image;
+
+DROP TABLE IF EXISTS Album;
@@ -0,0 +1,10 @@
+CREATE TABLE IF NOT EXISTS Album (
+    `albumName` VARCHAR(100) PRIMARY KEY
+    );
+
+CREATE TABLE IF NOT EXISTS Image (
+    `imageName` VARCHAR(255) NOT NULL UNIQUE,
+    `albumName` VARCHAR(100) NOT NULL,
+    `image` LONGTEXT,
+    FOREIGN KEY (`albumName`) REFERENCES Album (`albumName`)
+);
@@ -0,0 +1 @@
+ALTER TABLE Image DROP COLUMN `metadata`;
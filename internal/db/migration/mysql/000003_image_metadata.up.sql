@@ -0,0 +1 @@
+ALTER TABLE Image ADD COLUMN `metadata` JSON;
@@ -0,0 +1,9 @@
+CREATE TABLE IF NOT EXISTS AlbumAccess (
+    `albumName` VARCHAR(100) NOT NULL,
+    `principalType` VARCHAR(10) NOT NULL,
+    `principal` VARCHAR(255) NOT NULL,
+    `role` VARCHAR(10) NOT NULL,
+    PRIMARY KEY (`albumName`, `principalType`, `principal`),
+    INDEX album_access_principal_idx (`principalType`, `principal`),
+    FOREIGN KEY (`albumName`) REFERENCES Album (`albumName`)
+);
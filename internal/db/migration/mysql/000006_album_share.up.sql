@@ -0,0 +1,13 @@
+CREATE TABLE IF NOT EXISTS AlbumShare (
+    `id` VARCHAR(32) PRIMARY KEY,
+    `albumName` VARCHAR(100) NOT NULL,
+    `hash` VARCHAR(64) NOT NULL UNIQUE,
+    `passwordHash` VARCHAR(100) NOT NULL,
+    `allowDownload` BOOLEAN NOT NULL,
+    `createdBy` VARCHAR(255) NOT NULL,
+    `createdAt` DATETIME(6) NOT NULL,
+    `expiresAt` DATETIME(6),
+    `revokedAt` DATETIME(6),
+    INDEX album_share_album_idx (`albumName`),
+    FOREIGN KEY (`albumName`) REFERENCES Album (`albumName`)
+);
@@ -0,0 +1,36 @@
+DELETE FROM AlbumShare WHERE `tenant` <> 'default';
+DELETE FROM AlbumAccess WHERE `tenant` <> 'default';
+DELETE FROM Image WHERE `tenant` <> 'default';
+DELETE FROM Album WHERE `tenant` <> 'default';
+DELETE FROM ApiKey WHERE `tenant` <> '';
+
+ALTER TABLE Image DROP FOREIGN KEY image_album_fk;
+ALTER TABLE AlbumAccess DROP FOREIGN KEY album_access_album_fk;
+ALTER TABLE AlbumShare DROP FOREIGN KEY album_share_album_fk;
+
+ALTER TABLE Album
+    DROP PRIMARY KEY,
+    DROP COLUMN `tenant`,
+    ADD PRIMARY KEY (`albumName`);
+
+ALTER TABLE Image
+    DROP INDEX image_name_idx,
+    DROP COLUMN `tenant`,
+    ADD UNIQUE INDEX `imageName` (`imageName`),
+    ADD FOREIGN KEY (`albumName`) REFERENCES Album (`albumName`);
+
+ALTER TABLE AlbumAccess
+    DROP PRIMARY KEY,
+    DROP INDEX album_access_principal_idx,
+    DROP COLUMN `tenant`,
+    ADD PRIMARY KEY (`albumName`, `principalType`, `principal`),
+    ADD INDEX album_access_principal_idx (`principalType`, `principal`),
+    ADD FOREIGN KEY (`albumName`) REFERENCES Album (`albumName`);
+
+ALTER TABLE AlbumShare
+    DROP INDEX album_share_album_idx,
+    DROP COLUMN `tenant`,
+    ADD INDEX album_share_album_idx (`albumName`),
+    ADD FOREIGN KEY (`albumName`) REFERENCES Album (`albumName`);
+
+ALTER TABLE ApiKey DROP COLUMN `tenant`;
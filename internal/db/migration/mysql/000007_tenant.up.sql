@@ -0,0 +1,30 @@
+ALTER TABLE Image DROP FOREIGN KEY Image_ibfk_1;
+ALTER TABLE AlbumAccess DROP FOREIGN KEY AlbumAccess_ibfk_1;
+ALTER TABLE AlbumShare DROP FOREIGN KEY AlbumShare_ibfk_1;
+
+ALTER TABLE Album
+    ADD COLUMN `tenant` VARCHAR(100) NOT NULL DEFAULT 'default',
+    DROP PRIMARY KEY,
+    ADD PRIMARY KEY (`tenant`, `albumName`);
+
+ALTER TABLE Image
+    ADD COLUMN `tenant` VARCHAR(100) NOT NULL DEFAULT 'default',
+    DROP INDEX `imageName`,
+    ADD UNIQUE INDEX image_name_idx (`tenant`, `imageName`),
+    ADD CONSTRAINT image_album_fk FOREIGN KEY (`tenant`, `albumName`) REFERENCES Album (`tenant`, `albumName`);
+
+ALTER TABLE AlbumAccess
+    ADD COLUMN `tenant` VARCHAR(100) NOT NULL DEFAULT 'default',
+    DROP PRIMARY KEY,
+    ADD PRIMARY KEY (`tenant`, `albumName`, `principalType`, `principal`),
+    DROP INDEX album_access_principal_idx,
+    ADD INDEX album_access_principal_idx (`tenant`, `principalType`, `principal`),
+    ADD CONSTRAINT album_access_album_fk FOREIGN KEY (`tenant`, `albumName`) REFERENCES Album (`tenant`, `albumName`);
+
+ALTER TABLE AlbumShare
+    ADD COLUMN `tenant` VARCHAR(100) NOT NULL DEFAULT 'default',
+    DROP INDEX album_share_album_idx,
+    ADD INDEX album_share_album_idx (`tenant`, `albumName`),
+    ADD CONSTRAINT album_share_album_fk FOREIGN KEY (`tenant`, `albumName`) REFERENCES Album (`tenant`, `albumName`);
+
+ALTER TABLE ApiKey ADD COLUMN `tenant` VARCHAR(100) NOT NULL DEFAULT 'default';
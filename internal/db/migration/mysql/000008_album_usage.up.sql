@@ -0,0 +1,13 @@
+CREATE TABLE IF NOT EXISTS AlbumUsage (
+    `tenant` VARCHAR(100) NOT NULL,
+    `albumName` VARCHAR(100) NOT NULL,
+    `imageCount` BIGINT NOT NULL DEFAULT 0,
+    `byteCount` BIGINT NOT NULL DEFAULT 0,
+    PRIMARY KEY (`tenant`, `albumName`),
+    FOREIGN KEY (`tenant`, `albumName`) REFERENCES Album (`tenant`, `albumName`)
+);
+
+INSERT INTO AlbumUsage (`tenant`, `albumName`, `imageCount`, `byteCount`)
+    SELECT Album.`tenant`, Album.`albumName`, COUNT(Image.`imageName`), COALESCE(SUM(LENGTH(Image.`image`)), 0)
+    FROM Album LEFT JOIN Image ON Image.`tenant` = Album.`tenant` AND Image.`albumName` = Album.`albumName`
+    GROUP BY Album.`tenant`, Album.`albumName`;
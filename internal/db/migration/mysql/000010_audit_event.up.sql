@@ -0,0 +1,23 @@
+CREATE TABLE IF NOT EXISTS AuditEvent (
+    `id` BIGINT AUTO_INCREMENT PRIMARY KEY,
+    `tenant` VARCHAR(100) NOT NULL,
+    `occurredAt` DATETIME(6) NOT NULL,
+    `actor` VARCHAR(255) NOT NULL,
+    `action` VARCHAR(32) NOT NULL,
+    `albumName` VARCHAR(100) NOT NULL,
+    `target` VARCHAR(512) NOT NULL,
+    `details` TEXT NOT NULL,
+    `requestId` VARCHAR(128) NOT NULL,
+    `clientIp` VARCHAR(64) NOT NULL,
+    `outcome` VARCHAR(16) NOT NULL,
+    `error` TEXT NOT NULL,
+    INDEX audit_event_tenant_idx (`tenant`, `id`),
+    INDEX audit_event_album_idx (`tenant`, `albumName`)
+);
+
+-- The audit log is append-only.
+CREATE TRIGGER audit_event_no_update BEFORE UPDATE ON AuditEvent FOR EACH ROW
+    SIGNAL SQLSTATE '45000' SET MESSAGE_TEXT = 'the audit log is append-only';
+
+CREATE TRIGGER audit_event_no_delete BEFORE DELETE ON AuditEvent FOR EACH ROW
+    SIGNAL SQLSTATE '45000' SET MESSAGE_TEXT = 'the audit log is append-only';
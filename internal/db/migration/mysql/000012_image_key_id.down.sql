@@ -0,0 +1,2 @@
+DROP INDEX image_key_id_idx ON Image;
+ALTER TABLE Image DROP COLUMN `keyId`;
@@ -0,0 +1,8 @@
+-- The id of the master key which wraps the data key of the content of the
+-- image, empty when the content is stored in plain. The rewrap selects the
+-- images of the other keys on its index instead of scanning their content.
+ALTER TABLE Image ADD COLUMN `keyId` VARCHAR(64) NOT NULL DEFAULT '';
+
+UPDATE Image SET `keyId` = SUBSTRING_INDEX(SUBSTRING_INDEX(`image`, '$', 2), '$', -1) WHERE `image` LIKE 'enc1$%';
+
+CREATE INDEX image_key_id_idx ON Image (`keyId`, `tenant`, `imageName`);
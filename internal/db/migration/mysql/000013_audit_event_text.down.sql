@@ -0,0 +1 @@
+ALTER TABLE AuditEvent MODIFY `actor` VARCHAR(255) NOT NULL, MODIFY `target` VARCHAR(512) NOT NULL;
@@ -0,0 +1,2 @@
+-- Image names and subjects are not bounded, their events have to fit.
+ALTER TABLE AuditEvent MODIFY `actor` TEXT NOT NULL, MODIFY `target` TEXT NOT NULL;
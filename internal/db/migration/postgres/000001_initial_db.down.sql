@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS Image;
+
+DROP TABLE IF EXISTS Album;
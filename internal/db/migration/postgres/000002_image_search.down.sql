@@ -0,0 +1 @@
+DROP INDEX IF EXISTS image_search_idx;
@@ -0,0 +1,3 @@
+CREATE INDEX IF NOT EXISTS image_search_idx ON Image USING GIN (
+    to_tsvector('simple', regexp_replace("imageName" || ' ' || "albumName", '[^[:alnum:]]+', ' ', 'g'))
+);
@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS image_metadata_idx;
+
+ALTER TABLE Image DROP COLUMN IF EXISTS "metadata";
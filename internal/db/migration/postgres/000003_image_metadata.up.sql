@@ -0,0 +1,3 @@
+ALTER TABLE Image ADD COLUMN IF NOT EXISTS "metadata" JSONB;
+
+CREATE INDEX IF NOT EXISTS image_metadata_idx ON Image USING GIN ("metadata" jsonb_path_ops);
@@ -0,0 +1,13 @@
+CREATE TABLE IF NOT EXISTS AlbumShare (
+    "id" VARCHAR(32) PRIMARY KEY,
+    "albumName" VARCHAR(100) NOT NULL REFERENCES Album ("albumName"),
+    "hash" VARCHAR(64) NOT NULL UNIQUE,
+    "passwordHash" VARCHAR(100) NOT NULL,
+    "allowDownload" BOOLEAN NOT NULL,
+    "createdBy" VARCHAR(255) NOT NULL,
+    "createdAt" TIMESTAMPTZ NOT NULL,
+    "expiresAt" TIMESTAMPTZ,
+    "revokedAt" TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS album_share_album_idx ON AlbumShare ("albumName");
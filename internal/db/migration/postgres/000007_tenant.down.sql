@@ -0,0 +1,27 @@
+DELETE FROM AlbumShare WHERE "tenant" <> 'default';
+DELETE FROM AlbumAccess WHERE "tenant" <> 'default';
+DELETE FROM Image WHERE "tenant" <> 'default';
+DELETE FROM Album WHERE "tenant" <> 'default';
+DELETE FROM ApiKey WHERE "tenant" <> '';
+
+ALTER TABLE Image DROP CONSTRAINT IF EXISTS "image_albumName_fkey";
+ALTER TABLE AlbumAccess DROP CONSTRAINT IF EXISTS "albumaccess_albumName_fkey";
+ALTER TABLE AlbumShare DROP CONSTRAINT IF EXISTS "albumshare_albumName_fkey";
+
+ALTER TABLE Album DROP COLUMN IF EXISTS "tenant";
+ALTER TABLE Album ADD PRIMARY KEY ("albumName");
+
+ALTER TABLE Image DROP COLUMN IF EXISTS "tenant";
+ALTER TABLE Image ADD CONSTRAINT "image_imageName_key" UNIQUE ("imageName");
+ALTER TABLE Image ADD FOREIGN KEY ("albumName") REFERENCES Album ("albumName");
+
+ALTER TABLE AlbumAccess DROP COLUMN IF EXISTS "tenant";
+ALTER TABLE AlbumAccess ADD PRIMARY KEY ("albumName", "principalType", "principal");
+ALTER TABLE AlbumAccess ADD FOREIGN KEY ("albumName") REFERENCES Album ("albumName");
+CREATE INDEX IF NOT EXISTS album_access_principal_idx ON AlbumAccess ("principalType", "principal");
+
+ALTER TABLE AlbumShare DROP COLUMN IF EXISTS "tenant";
+ALTER TABLE AlbumShare ADD FOREIGN KEY ("albumName") REFERENCES Album ("albumName");
+CREATE INDEX IF NOT EXISTS album_share_album_idx ON AlbumShare ("albumName");
+
+ALTER TABLE ApiKey DROP COLUMN IF EXISTS "tenant";
@@ -0,0 +1,29 @@
+ALTER TABLE Image DROP CONSTRAINT IF EXISTS "image_albumName_fkey";
+ALTER TABLE AlbumAccess DROP CONSTRAINT IF EXISTS "albumaccess_albumName_fkey";
+ALTER TABLE AlbumShare DROP CONSTRAINT IF EXISTS "albumshare_albumName_fkey";
+
+ALTER TABLE Album ADD COLUMN IF NOT EXISTS "tenant" VARCHAR(100) NOT NULL DEFAULT 'default';
+ALTER TABLE Album DROP CONSTRAINT IF EXISTS album_pkey;
+ALTER TABLE Album ADD PRIMARY KEY ("tenant", "albumName");
+
+ALTER TABLE Image ADD COLUMN IF NOT EXISTS "tenant" VARCHAR(100) NOT NULL DEFAULT 'default';
+ALTER TABLE Image DROP CONSTRAINT IF EXISTS "image_imageName_key";
+ALTER TABLE Image ADD CONSTRAINT "image_tenant_imageName_key" UNIQUE ("tenant", "imageName");
+ALTER TABLE Image ADD CONSTRAINT "image_albumName_fkey"
+    FOREIGN KEY ("tenant", "albumName") REFERENCES Album ("tenant", "albumName");
+
+ALTER TABLE AlbumAccess ADD COLUMN IF NOT EXISTS "tenant" VARCHAR(100) NOT NULL DEFAULT 'default';
+ALTER TABLE AlbumAccess DROP CONSTRAINT IF EXISTS albumaccess_pkey;
+ALTER TABLE AlbumAccess ADD PRIMARY KEY ("tenant", "albumName", "principalType", "principal");
+ALTER TABLE AlbumAccess ADD CONSTRAINT "albumaccess_albumName_fkey"
+    FOREIGN KEY ("tenant", "albumName") REFERENCES Album ("tenant", "albumName");
+DROP INDEX IF EXISTS album_access_principal_idx;
+CREATE INDEX IF NOT EXISTS album_access_principal_idx ON AlbumAccess ("tenant", "principalType", "principal");
+
+ALTER TABLE AlbumShare ADD COLUMN IF NOT EXISTS "tenant" VARCHAR(100) NOT NULL DEFAULT 'default';
+ALTER TABLE AlbumShare ADD CONSTRAINT "albumshare_albumName_fkey"
+    FOREIGN KEY ("tenant", "albumName") REFERENCES Album ("tenant", "albumName");
+DROP INDEX IF EXISTS album_share_album_idx;
+CREATE INDEX IF NOT EXISTS album_share_album_idx ON AlbumShare ("tenant", "albumName");
+
+ALTER TABLE ApiKey ADD COLUMN IF NOT EXISTS "tenant" VARCHAR(100) NOT NULL DEFAULT 'default';
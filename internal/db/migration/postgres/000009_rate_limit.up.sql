@@ -0,0 +1,8 @@
+-- The buckets are only worth their last minutes, they are not written to the
+-- WAL and are lost on a crash.
+CREATE UNLOGGED TABLE IF NOT EXISTS RateLimitBucket (
+    "bucketKey" VARCHAR(512) PRIMARY KEY,
+    "tokens" DOUBLE PRECISION NOT NULL,
+    "allowed" BOOLEAN NOT NULL,
+    "updatedAt" TIMESTAMPTZ NOT NULL
+);
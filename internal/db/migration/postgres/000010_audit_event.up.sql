@@ -0,0 +1,27 @@
+CREATE TABLE IF NOT EXISTS AuditEvent (
+    "id" BIGSERIAL PRIMARY KEY,
+    "tenant" VARCHAR(100) NOT NULL,
+    "occurredAt" TIMESTAMPTZ NOT NULL,
+    "actor" VARCHAR(255) NOT NULL,
+    "action" VARCHAR(32) NOT NULL,
+    "albumName" VARCHAR(100) NOT NULL,
+    "target" VARCHAR(512) NOT NULL,
+    "details" TEXT NOT NULL,
+    "requestId" VARCHAR(128) NOT NULL,
+    "clientIp" VARCHAR(64) NOT NULL,
+    "outcome" VARCHAR(16) NOT NULL,
+    "error" TEXT NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS audit_event_tenant_idx ON AuditEvent ("tenant", "id");
+CREATE INDEX IF NOT EXISTS audit_event_album_idx ON AuditEvent ("tenant", "albumName");
+
+-- The audit log is append-only.
+CREATE OR REPLACE FUNCTION audit_event_append_only() RETURNS trigger AS $$
+BEGIN
+    RAISE EXCEPTION 'the audit log is append-only';
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER audit_event_append_only BEFORE UPDATE OR DELETE ON AuditEvent
+    FOR EACH ROW EXECUTE PROCEDURE audit_event_append_only();
@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS image_search_idx;
+CREATE INDEX IF NOT EXISTS image_search_idx ON Image USING GIN (
+    (to_tsvector('simple', regexp_replace("imageName" || ' ' || "albumName", '[^[:alnum:]]+', ' ', 'g'))
+        || jsonb_to_tsvector('simple', COALESCE("metadata", '{}'::jsonb), '["string"]'))
+);
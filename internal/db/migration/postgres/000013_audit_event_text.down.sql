@@ -0,0 +1 @@
+ALTER TABLE AuditEvent ALTER COLUMN "actor" TYPE VARCHAR(255), ALTER COLUMN "target" TYPE VARCHAR(512);
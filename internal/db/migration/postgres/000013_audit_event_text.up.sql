@@ -0,0 +1,2 @@
+-- Image names and subjects are not bounded, their events have to fit.
+ALTER TABLE AuditEvent ALTER COLUMN "actor" TYPE TEXT, ALTER COLUMN "target" TYPE TEXT;
@@ -0,0 +1,9 @@
+CREATE TABLE IF NOT EXISTS Album (
+    "albumName" VARCHAR(100) PRIMARY KEY
+    );
+
+CREATE TABLE IF NOT EXISTS Image (
+    "imageName" TEXT NOT NULL UNIQUE,
+    "albumName" VARCHAR(100) NOT NULL REFERENCES Album ("albumName"),
+    "image" TEXT
+);
@@ -0,0 +1,8 @@
+CREATE TABLE IF NOT EXISTS ApiKey (
+    "id" VARCHAR(32) PRIMARY KEY,
+    "name" VARCHAR(100) NOT NULL,
+    "hash" VARCHAR(64) NOT NULL UNIQUE,
+    "scopes" TEXT NOT NULL,
+    "createdAt" TIMESTAMP NOT NULL,
+    "revokedAt" TIMESTAMP
+);
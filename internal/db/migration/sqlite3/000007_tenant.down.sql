@@ -0,0 +1,60 @@
+CREATE TABLE Album_old (
+    "albumName" VARCHAR(100) PRIMARY KEY
+);
+
+INSERT INTO Album_old ("albumName") SELECT "albumName" FROM Album WHERE "tenant"='default';
+
+CREATE TABLE Image_old (
+    "imageName" TEXT NOT NULL UNIQUE,
+    "albumName" VARCHAR(100) NOT NULL REFERENCES Album_old ("albumName"),
+    "image" TEXT,
+    "metadata" TEXT
+);
+
+INSERT INTO Image_old ("imageName", "albumName", "image", "metadata")
+    SELECT "imageName", "albumName", "image", "metadata" FROM Image WHERE "tenant"='default';
+
+CREATE TABLE AlbumAccess_old (
+    "albumName" VARCHAR(100) NOT NULL REFERENCES Album_old ("albumName"),
+    "principalType" VARCHAR(10) NOT NULL,
+    "principal" VARCHAR(255) NOT NULL,
+    "role" VARCHAR(10) NOT NULL,
+    PRIMARY KEY ("albumName", "principalType", "principal")
+);
+
+INSERT INTO AlbumAccess_old ("albumName", "principalType", "principal", "role")
+    SELECT "albumName", "principalType", "principal", "role" FROM AlbumAccess WHERE "tenant"='default';
+
+CREATE TABLE AlbumShare_old (
+    "id" VARCHAR(32) PRIMARY KEY,
+    "albumName" VARCHAR(100) NOT NULL REFERENCES Album_old ("albumName"),
+    "hash" VARCHAR(64) NOT NULL UNIQUE,
+    "passwordHash" VARCHAR(100) NOT NULL,
+    "allowDownload" BOOLEAN NOT NULL,
+    "createdBy" VARCHAR(255) NOT NULL,
+    "createdAt" TIMESTAMP NOT NULL,
+    "expiresAt" TIMESTAMP,
+    "revokedAt" TIMESTAMP
+);
+
+INSERT INTO AlbumShare_old ("id", "albumName", "hash", "passwordHash", "allowDownload", "createdBy", "createdAt",
+        "expiresAt", "revokedAt")
+    SELECT "id", "albumName", "hash", "passwordHash", "allowDownload", "createdBy", "createdAt",
+        "expiresAt", "revokedAt" FROM AlbumShare WHERE "tenant"='default';
+
+DROP TABLE AlbumShare;
+DROP TABLE AlbumAccess;
+DROP TABLE Image;
+DROP TABLE Album;
+
+ALTER TABLE Album_old RENAME TO Album;
+ALTER TABLE Image_old RENAME TO Image;
+ALTER TABLE AlbumAccess_old RENAME TO AlbumAccess;
+ALTER TABLE AlbumShare_old RENAME TO AlbumShare;
+
+CREATE INDEX IF NOT EXISTS album_access_principal_idx ON AlbumAccess ("principalType", "principal");
+CREATE INDEX IF NOT EXISTS album_share_album_idx ON AlbumShare ("albumName");
+
+DELETE FROM ApiKey WHERE "tenant" <> '';
+
+ALTER TABLE ApiKey DROP COLUMN "tenant";
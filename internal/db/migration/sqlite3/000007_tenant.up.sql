@@ -0,0 +1,67 @@
+CREATE TABLE Album_new (
+    "tenant" VARCHAR(100) NOT NULL DEFAULT 'default',
+    "albumName" VARCHAR(100) NOT NULL,
+    PRIMARY KEY ("tenant", "albumName")
+);
+
+INSERT INTO Album_new ("albumName") SELECT "albumName" FROM Album;
+
+CREATE TABLE Image_new (
+    "tenant" VARCHAR(100) NOT NULL DEFAULT 'default',
+    "imageName" TEXT NOT NULL,
+    "albumName" VARCHAR(100) NOT NULL,
+    "image" TEXT,
+    "metadata" TEXT,
+    UNIQUE ("tenant", "imageName"),
+    FOREIGN KEY ("tenant", "albumName") REFERENCES Album_new ("tenant", "albumName")
+);
+
+INSERT INTO Image_new ("imageName", "albumName", "image", "metadata")
+    SELECT "imageName", "albumName", "image", "metadata" FROM Image;
+
+CREATE TABLE AlbumAccess_new (
+    "tenant" VARCHAR(100) NOT NULL DEFAULT 'default',
+    "albumName" VARCHAR(100) NOT NULL,
+    "principalType" VARCHAR(10) NOT NULL,
+    "principal" VARCHAR(255) NOT NULL,
+    "role" VARCHAR(10) NOT NULL,
+    PRIMARY KEY ("tenant", "albumName", "principalType", "principal"),
+    FOREIGN KEY ("tenant", "albumName") REFERENCES Album_new ("tenant", "albumName")
+);
+
+INSERT INTO AlbumAccess_new ("albumName", "principalType", "principal", "role")
+    SELECT "albumName", "principalType", "principal", "role" FROM AlbumAccess;
+
+CREATE TABLE AlbumShare_new (
+    "id" VARCHAR(32) PRIMARY KEY,
+    "tenant" VARCHAR(100) NOT NULL DEFAULT 'default',
+    "albumName" VARCHAR(100) NOT NULL,
+    "hash" VARCHAR(64) NOT NULL UNIQUE,
+    "passwordHash" VARCHAR(100) NOT NULL,
+    "allowDownload" BOOLEAN NOT NULL,
+    "createdBy" VARCHAR(255) NOT NULL,
+    "createdAt" TIMESTAMP NOT NULL,
+    "expiresAt" TIMESTAMP,
+    "revokedAt" TIMESTAMP,
+    FOREIGN KEY ("tenant", "albumName") REFERENCES Album_new ("tenant", "albumName")
+);
+
+INSERT INTO AlbumShare_new ("id", "albumName", "hash", "passwordHash", "allowDownload", "createdBy", "createdAt",
+        "expiresAt", "revokedAt")
+    SELECT "id", "albumName", "hash", "passwordHash", "allowDownload", "createdBy", "createdAt",
+        "expiresAt", "revokedAt" FROM AlbumShare;
+
+DROP TABLE AlbumShare;
+DROP TABLE AlbumAccess;
+DROP TABLE Image;
+DROP TABLE Album;
+
+ALTER TABLE Album_new RENAME TO Album;
+ALTER TABLE Image_new RENAME TO Image;
+ALTER TABLE AlbumAccess_new RENAME TO AlbumAccess;
+ALTER TABLE AlbumShare_new RENAME TO AlbumShare;
+
+CREATE INDEX IF NOT EXISTS album_access_principal_idx ON AlbumAccess ("tenant", "principalType", "principal");
+CREATE INDEX IF NOT EXISTS album_share_album_idx ON AlbumShare ("tenant", "albumName");
+
+ALTER TABLE ApiKey ADD COLUMN "tenant" VARCHAR(100) NOT NULL DEFAULT 'default';
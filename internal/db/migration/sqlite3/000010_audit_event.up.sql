@@ -0,0 +1,28 @@
+CREATE TABLE IF NOT EXISTS AuditEvent (
+    "id" INTEGER PRIMARY KEY AUTOINCREMENT,
+    "tenant" VARCHAR(100) NOT NULL,
+    "occurredAt" TIMESTAMP NOT NULL,
+    "actor" VARCHAR(255) NOT NULL,
+    "action" VARCHAR(32) NOT NULL,
+    "albumName" VARCHAR(100) NOT NULL,
+    "target" VARCHAR(512) NOT NULL,
+    "details" TEXT NOT NULL,
+    "requestId" VARCHAR(128) NOT NULL,
+    "clientIp" VARCHAR(64) NOT NULL,
+    "outcome" VARCHAR(16) NOT NULL,
+    "error" TEXT NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS audit_event_tenant_idx ON AuditEvent ("tenant", "id");
+CREATE INDEX IF NOT EXISTS audit_event_album_idx ON AuditEvent ("tenant", "albumName");
+
+-- The audit log is append-only.
+CREATE TRIGGER IF NOT EXISTS audit_event_no_update BEFORE UPDATE ON AuditEvent
+BEGIN
+    SELECT RAISE(ABORT, 'the audit log is append-only');
+END;
+
+CREATE TRIGGER IF NOT EXISTS audit_event_no_delete BEFORE DELETE ON AuditEvent
+BEGIN
+    SELECT RAISE(ABORT, 'the audit log is append-only');
+END;
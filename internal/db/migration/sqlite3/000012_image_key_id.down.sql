@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS image_key_id_idx;
+ALTER TABLE Image DROP COLUMN "keyId";
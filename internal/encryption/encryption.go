@@ -0,0 +1,221 @@
+// Package encryption seals the image content at rest with envelope
+// encryption: every object is encrypted with a data key of its own, which is
+// wrapped by a master key of a KeyProvider.
+package encryption
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// KeyFile reads the master keys from a local file.
+const KeyFile = "keyfile"
+
+const (
+	// prefix starts every sealed object, which base64 content never does.
+	prefix = "enc1$"
+	// separator separates the master key id, the wrapped data key and the
+	// ciphertext of a sealed object.
+	separator = "$"
+	// dataKeySize is the size of the AES-256 data keys.
+	dataKeySize = 32
+)
+
+var (
+	// ErrUnknownKey is returned for a master key the provider does not have.
+	ErrUnknownKey = errors.New("unknown master key")
+	// ErrMalformed is returned when opening an object which is not sealed,
+	// or whose envelope is damaged.
+	ErrMalformed = errors.New("malformed sealed object")
+)
+
+// KeyProvider wraps the data keys with its master keys. It keeps the retired
+// master keys, so that the data keys they wrapped can still be unwrapped
+// until they are rewrapped with the current one.
+type KeyProvider interface {
+	// KeyID returns the id of the current master key, which wraps the new
+	// data keys. Key ids never contain "$".
+	KeyID() string
+	// WrapKey encrypts dataKey with the current master key, and returns the
+	// id of that key.
+	WrapKey(ctx context.Context, dataKey []byte) (string, []byte, error)
+	// UnwrapKey decrypts a data key wrapped by the master key keyID.
+	UnwrapKey(ctx context.Context, keyID string, wrapped []byte) ([]byte, error)
+}
+
+// Envelope seals objects as "enc1$<key id>$<wrapped data key>$<ciphertext>",
+// the data key and the ciphertext base64 encoded. The ciphertext is the
+// AES-GCM encryption of the object, prefixed with its nonce and bound to
+// the additional data given when sealing.
+type Envelope struct {
+	keys KeyProvider
+}
+
+// NewEnvelope implements Envelope.
+func NewEnvelope(keys KeyProvider) *Envelope {
+	return &Envelope{keys: keys}
+}
+
+// Sealed reports whether value is a sealed object.
+func Sealed(value string) bool {
+	return strings.HasPrefix(value, prefix)
+}
+
+// KeyID returns the id of the master key which wraps the data key of the
+// sealed object, empty when sealed is not a sealed object.
+func KeyID(sealed string) string {
+	keyID, _, _, err := split(sealed)
+	if err != nil {
+		return ""
+	}
+
+	return keyID
+}
+
+// CurrentKeyID returns the id of the master key which wraps the data keys of
+// the objects sealed now.
+func (e *Envelope) CurrentKeyID() string {
+	return e.keys.KeyID()
+}
+
+// Seal encrypts plaintext with a new data key bound to additionalData, which
+// has to be given again to open it.
+func (e *Envelope) Seal(ctx context.Context, plaintext, additionalData []byte) (string, error) {
+	dataKey := make([]byte, dataKeySize)
+	if _, err := rand.Read(dataKey); err != nil {
+		return "", fmt.Errorf("error while generating data key, %w", err)
+	}
+
+	ciphertext, err := seal(dataKey, plaintext, additionalData)
+	if err != nil {
+		return "", err
+	}
+
+	keyID, wrapped, err := e.keys.WrapKey(ctx, dataKey)
+	if err != nil {
+		return "", fmt.Errorf("error while wrapping data key, %w", err)
+	}
+
+	return join(keyID, wrapped, ciphertext), nil
+}
+
+// Open decrypts the sealed object, which was sealed with additionalData.
+func (e *Envelope) Open(ctx context.Context, sealed string, additionalData []byte) ([]byte, error) {
+	keyID, wrapped, ciphertext, err := split(sealed)
+	if err != nil {
+		return nil, err
+	}
+
+	dataKey, err := e.keys.UnwrapKey(ctx, keyID, wrapped)
+	if err != nil {
+		return nil, fmt.Errorf("error while unwrapping data key, %w", err)
+	}
+
+	return open(dataKey, ciphertext, additionalData)
+}
+
+// Rewrap wraps the data key of the sealed object with the current master
+// key. The ciphertext is left as is.
+func (e *Envelope) Rewrap(ctx context.Context, sealed string) (string, error) {
+	keyID, wrapped, ciphertext, err := split(sealed)
+	if err != nil {
+		return "", err
+	}
+
+	dataKey, err := e.keys.UnwrapKey(ctx, keyID, wrapped)
+	if err != nil {
+		return "", fmt.Errorf("error while unwrapping data key, %w", err)
+	}
+
+	keyID, wrapped, err = e.keys.WrapKey(ctx, dataKey)
+	if err != nil {
+		return "", fmt.Errorf("error while wrapping data key, %w", err)
+	}
+
+	return join(keyID, wrapped, ciphertext), nil
+}
+
+func join(keyID string, wrapped, ciphertext []byte) string {
+	return prefix + keyID + separator + base64.StdEncoding.EncodeToString(wrapped) +
+		separator + base64.StdEncoding.EncodeToString(ciphertext)
+}
+
+func split(sealed string) (string, []byte, []byte, error) {
+	if !Sealed(sealed) {
+		return "", nil, nil, ErrMalformed
+	}
+
+	parts := strings.Split(strings.TrimPrefix(sealed, prefix), separator)
+	if len(parts) != 3 || parts[0] == "" {
+		return "", nil, nil, ErrMalformed
+	}
+
+	wrapped, err := base64.StdEncoding.DecodeString(parts[1])
+	if err != nil {
+		return "", nil, nil, ErrMalformed
+	}
+
+	ciphertext, err := base64.StdEncoding.DecodeString(parts[2])
+	if err != nil {
+		return "", nil, nil, ErrMalformed
+	}
+
+	return parts[0], wrapped, ciphertext, nil
+}
+
+// seal encrypts plaintext with AES-GCM under key, and prefixes the result
+// with its random nonce.
+func seal(key, plaintext, additionalData []byte) ([]byte, error) {
+	aead, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+
+	nonce := make([]byte, aead.NonceSize(), aead.NonceSize()+len(plaintext)+aead.Overhead())
+	if _, err = rand.Read(nonce); err != nil {
+		return nil, fmt.Errorf("error while generating nonce, %w", err)
+	}
+
+	return aead.Seal(nonce, nonce, plaintext, additionalData), nil
+}
+
+// open decrypts the output of seal.
+func open(key, ciphertext, additionalData []byte) ([]byte, error) {
+	aead, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(ciphertext) < aead.NonceSize() {
+		return nil, ErrMalformed
+	}
+
+	nonce, ciphertext := ciphertext[:aead.NonceSize()], ciphertext[aead.NonceSize():]
+
+	plaintext, err := aead.Open(nil, nonce, ciphertext, additionalData)
+	if err != nil {
+		return nil, fmt.Errorf("error while decrypting, %w", err)
+	}
+
+	return plaintext, nil
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("error while creating cipher, %w", err)
+	}
+
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, fmt.Errorf("error while creating cipher, %w", err)
+	}
+
+	return aead, nil
+}
@@ -0,0 +1,72 @@
+package encryption
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"strings"
+	"testing"
+)
+
+func TestEnvelope(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	plaintext := []byte("the content of beach.png")
+	additionalData := []byte("default/holiday/beach.png")
+
+	old, err := NewKeyFileProvider(writeKeyFile(t, "2022-01 "+testKeyA))
+	require.NoError(t, err)
+
+	envelope := NewEnvelope(old)
+
+	sealed, err := envelope.Seal(ctx, plaintext, additionalData)
+	require.NoError(t, err)
+	assert.True(t, Sealed(sealed))
+	assert.Equal(t, "2022-01", KeyID(sealed))
+	assert.Equal(t, envelope.CurrentKeyID(), KeyID(sealed))
+	assert.NotContains(t, sealed, string(plaintext))
+
+	again, err := envelope.Seal(ctx, plaintext, additionalData)
+	require.NoError(t, err)
+	assert.NotEqual(t, sealed, again, "every seal has a data key and a nonce of its own")
+
+	opened, err := envelope.Open(ctx, sealed, additionalData)
+	require.NoError(t, err)
+	assert.Equal(t, plaintext, opened)
+
+	_, err = envelope.Open(ctx, sealed, []byte("default/holiday/sunset.png"))
+	assert.Error(t, err, "the content is bound to its additional data")
+
+	_, err = envelope.Open(ctx, "aGVsbG8=", additionalData)
+	assert.ErrorIs(t, err, ErrMalformed)
+	assert.False(t, Sealed("aGVsbG8="))
+	assert.Empty(t, KeyID("aGVsbG8="))
+
+	_, err = envelope.Open(ctx, "enc1$2022-01$!$!", additionalData)
+	assert.ErrorIs(t, err, ErrMalformed)
+
+	// After a rotation the old data keys are rewrapped, the ciphertext kept.
+	rotated, err := NewKeyFileProvider(writeKeyFile(t, "2022-05 "+testKeyB, "2022-01 "+testKeyA))
+	require.NoError(t, err)
+
+	envelope = NewEnvelope(rotated)
+	assert.NotEqual(t, envelope.CurrentKeyID(), KeyID(sealed))
+
+	opened, err = envelope.Open(ctx, sealed, additionalData)
+	require.NoError(t, err)
+	assert.Equal(t, plaintext, opened)
+
+	rewrapped, err := envelope.Rewrap(ctx, sealed)
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(rewrapped, "enc1$2022-05$"))
+	assert.Equal(t, sealed[strings.LastIndex(sealed, "$"):], rewrapped[strings.LastIndex(rewrapped, "$"):])
+
+	opened, err = envelope.Open(ctx, rewrapped, additionalData)
+	require.NoError(t, err)
+	assert.Equal(t, plaintext, opened)
+
+	// Once the old master key is dropped its data keys can not be unwrapped.
+	_, err = NewEnvelope(old).Open(ctx, rewrapped, additionalData)
+	assert.ErrorIs(t, err, ErrUnknownKey)
+}
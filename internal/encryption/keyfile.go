@@ -0,0 +1,100 @@
+package encryption
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/base64"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+)
+
+var keyIDPattern = regexp.MustCompile(`^[A-Za-z0-9_.:/-]{1,64}$`)
+
+// KeyFileProvider wraps the data keys with the master keys of a local file.
+// Every line of the file is a key id and a base64 AES-256 key, separated by
+// spaces, such as "2022-05 q5dr...=". The first key is the current one, the
+// others are retired. Empty lines and lines starting with # are ignored.
+//
+// A master key is rotated by adding the new key on the first line, keeping
+// the previous one until every data key is rewrapped.
+type KeyFileProvider struct {
+	current string
+	keys    map[string][]byte
+}
+
+// NewKeyFileProvider reads the master keys of the file at path.
+func NewKeyFileProvider(path string) (*KeyFileProvider, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("error while reading key file, %w", err)
+	}
+
+	provider := &KeyFileProvider{keys: map[string][]byte{}}
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+
+		fields := strings.Fields(text)
+		if len(fields) != 2 || !keyIDPattern.MatchString(fields[0]) {
+			return nil, fmt.Errorf("line %d of key file is not a key id and a base64 key", line)
+		}
+
+		key, err := base64.StdEncoding.DecodeString(fields[1])
+		if err != nil || len(key) != dataKeySize {
+			return nil, fmt.Errorf("key %s of key file is not a base64 %d bytes key", fields[0], dataKeySize)
+		}
+
+		if _, ok := provider.keys[fields[0]]; ok {
+			return nil, fmt.Errorf("key %s is repeated in key file", fields[0])
+		}
+
+		if provider.current == "" {
+			provider.current = fields[0]
+		}
+
+		provider.keys[fields[0]] = key
+	}
+
+	if err = scanner.Err(); err != nil {
+		return nil, fmt.Errorf("error while reading key file, %w", err)
+	}
+
+	if provider.current == "" {
+		return nil, fmt.Errorf("key file %s has no key", path)
+	}
+
+	return provider, nil
+}
+
+// KeyID implements KeyProvider.
+func (k *KeyFileProvider) KeyID() string {
+	return k.current
+}
+
+// WrapKey implements KeyProvider. The data key is bound to the id of the
+// master key.
+func (k *KeyFileProvider) WrapKey(_ context.Context, dataKey []byte) (string, []byte, error) {
+	wrapped, err := seal(k.keys[k.current], dataKey, []byte(k.current))
+	if err != nil {
+		return "", nil, err
+	}
+
+	return k.current, wrapped, nil
+}
+
+// UnwrapKey implements KeyProvider.
+func (k *KeyFileProvider) UnwrapKey(_ context.Context, keyID string, wrapped []byte) ([]byte, error) {
+	key, ok := k.keys[keyID]
+	if !ok {
+		return nil, fmt.Errorf("%w %s", ErrUnknownKey, keyID)
+	}
+
+	return open(key, wrapped, []byte(keyID))
+}
@@ -0,0 +1,114 @@
+package encryption
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const (
+	testKeyA = "AAECAwQFBgcICQoLDA0ODxAREhMUFRYXGBkaGxwdHh8="
+	testKeyB = "ICEiIyQlJicoKSorLC0uLzAxMjM0NTY3ODk6Ozw9Pj8="
+)
+
+// writeKeyFile writes the lines to a key file and returns its path.
+func writeKeyFile(t *testing.T, lines ...string) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "keys")
+	require.NoError(t, os.WriteFile(path, []byte(strings.Join(lines, "\n")), 0o600))
+
+	return path
+}
+
+func TestNewKeyFileProvider(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name      string
+		lines     []string
+		keyID     string
+		errString string
+	}{
+		{
+			name:  "first_key_is_current",
+			lines: []string{"# rotated 2022-05", "", "2022-05 " + testKeyB, "2022-01 " + testKeyA},
+			keyID: "2022-05",
+		},
+		{
+			name:      "empty",
+			lines:     []string{"# no key yet"},
+			errString: "has no key",
+		},
+		{
+			name:      "missing_key",
+			lines:     []string{"2022-05"},
+			errString: "line 1 of key file is not a key id and a base64 key",
+		},
+		{
+			name:      "invalid_key_id",
+			lines:     []string{"2022$05 " + testKeyA},
+			errString: "line 1 of key file is not a key id and a base64 key",
+		},
+		{
+			name:      "short_key",
+			lines:     []string{"2022-05 AAECAwQFBgcICQoLDA0ODw=="},
+			errString: "key 2022-05 of key file is not a base64 32 bytes key",
+		},
+		{
+			name:      "repeated_key",
+			lines:     []string{"2022-05 " + testKeyA, "2022-05 " + testKeyB},
+			errString: "key 2022-05 is repeated in key file",
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			provider, err := NewKeyFileProvider(writeKeyFile(t, tt.lines...))
+			if tt.errString != "" {
+				assert.ErrorContains(t, err, tt.errString)
+
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.keyID, provider.KeyID())
+		})
+	}
+}
+
+func TestKeyFileProvider_WrapKey(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	dataKey := []byte(strings.Repeat("k", dataKeySize))
+
+	old, err := NewKeyFileProvider(writeKeyFile(t, "2022-01 "+testKeyA))
+	require.NoError(t, err)
+
+	keyID, wrapped, err := old.WrapKey(ctx, dataKey)
+	require.NoError(t, err)
+	assert.Equal(t, "2022-01", keyID)
+	assert.NotContains(t, string(wrapped), string(dataKey))
+
+	rotated, err := NewKeyFileProvider(writeKeyFile(t, "2022-05 "+testKeyB, "2022-01 "+testKeyA))
+	require.NoError(t, err)
+
+	unwrapped, err := rotated.UnwrapKey(ctx, keyID, wrapped)
+	require.NoError(t, err)
+	assert.Equal(t, dataKey, unwrapped)
+
+	// The wrapped key is bound to the id of its master key.
+	_, err = rotated.UnwrapKey(ctx, "2022-05", wrapped)
+	assert.Error(t, err)
+
+	_, err = rotated.UnwrapKey(ctx, "2021-12", wrapped)
+	assert.ErrorIs(t, err, ErrUnknownKey)
+}
@@ -0,0 +1,56 @@
+package graphqlhandler
+
+import (
+	_ "embed"
+	"github.com/gin-gonic/gin"
+	"github.com/graph-gophers/graphql-go"
+	log "github.com/sirupsen/logrus"
+	"githum.com/anupam111/image-store/internal/controller"
+	"githum.com/anupam111/image-store/internal/models"
+	"net/http"
+)
+
+//go:embed schema.graphql
+var schema string
+
+// maxQueryDepth bounds the nesting of album -> images -> album queries.
+const maxQueryDepth = 8
+
+// GraphQLHandler serves the read only graphql api.
+type GraphQLHandler struct {
+	log        *log.Logger
+	imageStore controller.ImageStore
+	schema     *graphql.Schema
+}
+
+// NewGraphQLHandler implements GraphQLHandler.
+func NewGraphQLHandler(logger *log.Logger, imageStore controller.ImageStore) *GraphQLHandler {
+	return &GraphQLHandler{
+		log:        logger,
+		imageStore: imageStore,
+		schema: graphql.MustParseSchema(schema, &queryResolver{imageStore: imageStore},
+			graphql.MaxDepth(maxQueryDepth)),
+	}
+}
+
+func (g *GraphQLHandler) Query(ginCtx *gin.Context) {
+	var request models.GraphQLRequest
+	if err := ginCtx.BindJSON(&request); err != nil {
+		ginCtx.JSON(http.StatusBadRequest, models.ResponseError{
+			HTTPStatusCode: http.StatusBadRequest,
+			ErrorCode:      "BAD-REQUEST",
+			MessageDetails: err.Error(),
+		})
+
+		return
+	}
+
+	g.log.Debugf("graphql request got operation %q", request.OperationName)
+
+	// Every request gets its own loader, so batched lookups never leak data
+	// between requests.
+	ctx := withImagesLoader(ginCtx.Request.Context(), newImagesLoader(g.imageStore))
+	response := g.schema.Exec(ctx, request.Query, request.OperationName, request.Variables)
+
+	ginCtx.JSON(http.StatusOK, response)
+}
@@ -0,0 +1,133 @@
+package graphqlhandler
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	log "github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"githum.com/anupam111/image-store/internal/controller"
+	"githum.com/anupam111/image-store/internal/db/dbhandler"
+	"githum.com/anupam111/image-store/internal/db/dbmodels"
+	"githum.com/anupam111/image-store/internal/models"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+var errFake = errors.New("error")
+
+func Test_Query(t *testing.T) {
+	t.Parallel()
+
+	albums := []dbmodels.Album{{AlbumName: "album-1"}, {AlbumName: "album-2"}, {AlbumName: "album-3"}}
+
+	tests := []struct {
+		name      string
+		query     string
+		variables map[string]interface{}
+		prepare   func(
+			subs *controller.MockImageStore,
+		)
+		statusCode int
+		expected   string
+		hasErrors  bool
+	}{
+		{
+			name:  "albums_with_images_batched",
+			query: `{ albums(first: 2) { totalCount pageInfo { hasNextPage } edges { node { name images { totalCount edges { node { name size } } } } } } }`,
+			prepare: func(subs *controller.MockImageStore) {
+				subs.EXPECT().ListImageAlbums(gomock.Any()).Return(albums, nil)
+				subs.EXPECT().GetImagesOfAlbums(gomock.Any(), gomock.InAnyOrder([]string{"album-1", "album-2"})).Return(
+					map[string][]dbmodels.Image{
+						"album-1": {{AlbumName: "album-1", ImageName: "image-1", Image: "YWJj"}},
+					}, nil).Times(1)
+			},
+			statusCode: 200,
+			expected: `{"data":{"albums":{"totalCount":3,"pageInfo":{"hasNextPage":true},"edges":[` +
+				`{"node":{"name":"album-1","images":{"totalCount":1,"edges":[{"node":{"name":"image-1","size":3}}]}}},` +
+				`{"node":{"name":"album-2","images":{"totalCount":0,"edges":[]}}}]}}}`,
+		},
+		{
+			name:      "albums_after_cursor",
+			query:     `query($after: String) { albums(first: 5, after: $after) { edges { node { name } } } }`,
+			variables: map[string]interface{}{"after": encodeCursor("album-1")},
+			prepare: func(subs *controller.MockImageStore) {
+				subs.EXPECT().ListImageAlbums(gomock.Any()).Return(albums, nil)
+			},
+			statusCode: 200,
+			expected:   `{"data":{"albums":{"edges":[{"node":{"name":"album-2"}},{"node":{"name":"album-3"}}]}}}`,
+		},
+		{
+			name:  "missing_album",
+			query: `{ album(name: "missing") { name } }`,
+			prepare: func(subs *controller.MockImageStore) {
+				subs.EXPECT().GetImageAlbum(gomock.Any(), "missing").Return(dbmodels.Album{}, dbhandler.ErrNoDataFound)
+			},
+			statusCode: 200,
+			expected:   `{"data":{"album":null}}`,
+		},
+		{
+			name:  "image_with_album",
+			query: `{ image(album: "album-1", name: "image-1") { name content album { name } } }`,
+			prepare: func(subs *controller.MockImageStore) {
+				subs.EXPECT().GetAlbumImage(gomock.Any(), "album-1", "image-1").Return(
+					dbmodels.Image{AlbumName: "album-1", ImageName: "image-1", Image: "YWJj"}, nil)
+			},
+			statusCode: 200,
+			expected:   `{"data":{"image":{"name":"image-1","content":"YWJj","album":{"name":"album-1"}}}}`,
+		},
+		{
+			name:  "controller_error",
+			query: `{ albums { totalCount } }`,
+			prepare: func(subs *controller.MockImageStore) {
+				subs.EXPECT().ListImageAlbums(gomock.Any()).Return(nil, errFake)
+			},
+			statusCode: 200,
+			hasErrors:  true,
+		},
+		{
+			name:       "mutation_not_supported",
+			query:      `mutation { deleteAlbum(name: "album-1") }`,
+			statusCode: 200,
+			hasErrors:  true,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			mockCtrl := gomock.NewController(t)
+			mockController := controller.NewMockImageStore(mockCtrl)
+			if tt.prepare != nil {
+				tt.prepare(mockController)
+			}
+
+			router := gin.New()
+			router.POST("/graphql", NewGraphQLHandler(log.New(), mockController).Query)
+
+			payload, _ := json.Marshal(models.GraphQLRequest{Query: tt.query, Variables: tt.variables})
+			req, _ := http.NewRequestWithContext(context.Background(), http.MethodPost, "/graphql", bytes.NewReader(payload))
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+			assert.Equal(t, tt.statusCode, w.Code)
+
+			if tt.hasErrors {
+				var response struct {
+					Errors []interface{} `json:"errors"`
+				}
+				assert.Nil(t, json.Unmarshal(w.Body.Bytes(), &response))
+				assert.NotEmpty(t, response.Errors)
+
+				return
+			}
+
+			assert.JSONEq(t, tt.expected, w.Body.String())
+		})
+	}
+}
@@ -0,0 +1,74 @@
+package graphqlhandler
+
+import (
+	"context"
+	"githum.com/anupam111/image-store/internal/controller"
+	"githum.com/anupam111/image-store/internal/db/dbmodels"
+	"sync"
+)
+
+type loaderKey struct{}
+
+// imagesLoader batches the image lookups of a single graphql request. Albums
+// are registered with prime when they are resolved, and the first load of any
+// of them fetches the images of every registered album with one query.
+type imagesLoader struct {
+	imageStore controller.ImageStore
+	mu         sync.Mutex
+	pending    map[string]struct{}
+	loaded     map[string][]dbmodels.Image
+}
+
+func newImagesLoader(imageStore controller.ImageStore) *imagesLoader {
+	return &imagesLoader{
+		imageStore: imageStore,
+		pending:    map[string]struct{}{},
+		loaded:     map[string][]dbmodels.Image{},
+	}
+}
+
+func withImagesLoader(ctx context.Context, loader *imagesLoader) context.Context {
+	return context.WithValue(ctx, loaderKey{}, loader)
+}
+
+func imagesLoaderFrom(ctx context.Context) *imagesLoader {
+	return ctx.Value(loaderKey{}).(*imagesLoader)
+}
+
+func (l *imagesLoader) prime(albumNames ...string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	for _, albumName := range albumNames {
+		if _, ok := l.loaded[albumName]; !ok {
+			l.pending[albumName] = struct{}{}
+		}
+	}
+}
+
+func (l *imagesLoader) load(ctx context.Context, albumName string) ([]dbmodels.Image, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if images, ok := l.loaded[albumName]; ok {
+		return images, nil
+	}
+
+	l.pending[albumName] = struct{}{}
+	albumNames := make([]string, 0, len(l.pending))
+	for name := range l.pending {
+		albumNames = append(albumNames, name)
+	}
+
+	byAlbum, err := l.imageStore.GetImagesOfAlbums(ctx, albumNames)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, name := range albumNames {
+		l.loaded[name] = byAlbum[name]
+	}
+	l.pending = map[string]struct{}{}
+
+	return l.loaded[albumName], nil
+}
@@ -0,0 +1,262 @@
+package graphqlhandler
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"githum.com/anupam111/image-store/internal/controller"
+	"githum.com/anupam111/image-store/internal/db/dbhandler"
+	"githum.com/anupam111/image-store/internal/db/dbmodels"
+)
+
+const maxPageSize = 100
+
+type connectionArgs struct {
+	First int32
+	After *string
+}
+
+type queryResolver struct {
+	imageStore controller.ImageStore
+}
+
+func (q *queryResolver) Albums(ctx context.Context, args connectionArgs) (*albumConnectionResolver, error) {
+	albums, err := q.imageStore.ListImageAlbums(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	start, end, err := page(len(albums), func(i int) string { return albums[i].AlbumName }, args)
+	if err != nil {
+		return nil, err
+	}
+
+	loader := imagesLoaderFrom(ctx)
+	connection := &albumConnectionResolver{
+		total:   len(albums),
+		hasNext: end < len(albums),
+	}
+	for _, album := range albums[start:end] {
+		loader.prime(album.AlbumName)
+		connection.albums = append(connection.albums, &albumResolver{album: album, loader: loader})
+	}
+
+	return connection, nil
+}
+
+func (q *queryResolver) Album(ctx context.Context, args struct{ Name string }) (*albumResolver, error) {
+	album, err := q.imageStore.GetImageAlbum(ctx, args.Name)
+	if err != nil {
+		if errors.Is(err, dbhandler.ErrNoDataFound) {
+			return nil, nil
+		}
+
+		return nil, err
+	}
+
+	return &albumResolver{album: album, loader: imagesLoaderFrom(ctx)}, nil
+}
+
+func (q *queryResolver) Image(ctx context.Context, args struct{ Album, Name string }) (*imageResolver, error) {
+	image, err := q.imageStore.GetAlbumImage(ctx, args.Album, args.Name)
+	if err != nil {
+		if errors.Is(err, dbhandler.ErrNoDataFound) {
+			return nil, nil
+		}
+
+		return nil, err
+	}
+
+	return &imageResolver{image: image, loader: imagesLoaderFrom(ctx)}, nil
+}
+
+type albumResolver struct {
+	album  dbmodels.Album
+	loader *imagesLoader
+}
+
+func (a *albumResolver) Name() string {
+	return a.album.AlbumName
+}
+
+func (a *albumResolver) Images(ctx context.Context, args connectionArgs) (*imageConnectionResolver, error) {
+	images, err := a.loader.load(ctx, a.album.AlbumName)
+	if err != nil {
+		return nil, err
+	}
+
+	start, end, err := page(len(images), func(i int) string { return images[i].ImageName }, args)
+	if err != nil {
+		return nil, err
+	}
+
+	connection := &imageConnectionResolver{
+		total:   len(images),
+		hasNext: end < len(images),
+	}
+	for _, image := range images[start:end] {
+		connection.images = append(connection.images, &imageResolver{image: image, loader: a.loader})
+	}
+
+	return connection, nil
+}
+
+type imageResolver struct {
+	image  dbmodels.Image
+	loader *imagesLoader
+}
+
+func (i *imageResolver) Name() string {
+	return i.image.ImageName
+}
+
+func (i *imageResolver) Album() *albumResolver {
+	return &albumResolver{
+		album:  dbmodels.Album{AlbumName: i.image.AlbumName},
+		loader: i.loader,
+	}
+}
+
+func (i *imageResolver) Content() string {
+	return i.image.Image
+}
+
+func (i *imageResolver) Size() int32 {
+	return int32(len(i.image.Content()))
+}
+
+type albumConnectionResolver struct {
+	albums  []*albumResolver
+	total   int
+	hasNext bool
+}
+
+func (c *albumConnectionResolver) Edges() []*albumEdgeResolver {
+	edges := make([]*albumEdgeResolver, len(c.albums))
+	for idx, album := range c.albums {
+		edges[idx] = &albumEdgeResolver{node: album}
+	}
+
+	return edges
+}
+
+func (c *albumConnectionResolver) PageInfo() *pageInfoResolver {
+	info := &pageInfoResolver{hasNext: c.hasNext}
+	if len(c.albums) > 0 {
+		cursor := encodeCursor(c.albums[len(c.albums)-1].Name())
+		info.endCursor = &cursor
+	}
+
+	return info
+}
+
+func (c *albumConnectionResolver) TotalCount() int32 {
+	return int32(c.total)
+}
+
+type albumEdgeResolver struct {
+	node *albumResolver
+}
+
+func (e *albumEdgeResolver) Cursor() string {
+	return encodeCursor(e.node.Name())
+}
+
+func (e *albumEdgeResolver) Node() *albumResolver {
+	return e.node
+}
+
+type imageConnectionResolver struct {
+	images  []*imageResolver
+	total   int
+	hasNext bool
+}
+
+func (c *imageConnectionResolver) Edges() []*imageEdgeResolver {
+	edges := make([]*imageEdgeResolver, len(c.images))
+	for idx, image := range c.images {
+		edges[idx] = &imageEdgeResolver{node: image}
+	}
+
+	return edges
+}
+
+func (c *imageConnectionResolver) PageInfo() *pageInfoResolver {
+	info := &pageInfoResolver{hasNext: c.hasNext}
+	if len(c.images) > 0 {
+		cursor := encodeCursor(c.images[len(c.images)-1].Name())
+		info.endCursor = &cursor
+	}
+
+	return info
+}
+
+func (c *imageConnectionResolver) TotalCount() int32 {
+	return int32(c.total)
+}
+
+type imageEdgeResolver struct {
+	node *imageResolver
+}
+
+func (e *imageEdgeResolver) Cursor() string {
+	return encodeCursor(e.node.Name())
+}
+
+func (e *imageEdgeResolver) Node() *imageResolver {
+	return e.node
+}
+
+type pageInfoResolver struct {
+	hasNext   bool
+	endCursor *string
+}
+
+func (p *pageInfoResolver) HasNextPage() bool {
+	return p.hasNext
+}
+
+func (p *pageInfoResolver) EndCursor() *string {
+	return p.endCursor
+}
+
+// page returns the bounds of the requested page of a list sorted by name. The
+// cursor is the encoded name of the last item of the previous page.
+func page(size int, nameAt func(int) string, args connectionArgs) (int, int, error) {
+	if args.First < 0 || args.First > maxPageSize {
+		return 0, 0, fmt.Errorf("first must be between 0 and %d", maxPageSize)
+	}
+
+	start := 0
+	if args.After != nil {
+		after, err := decodeCursor(*args.After)
+		if err != nil {
+			return 0, 0, err
+		}
+
+		for start < size && nameAt(start) <= after {
+			start++
+		}
+	}
+
+	end := start + int(args.First)
+	if end > size {
+		end = size
+	}
+
+	return start, end, nil
+}
+
+func encodeCursor(name string) string {
+	return base64.URLEncoding.EncodeToString([]byte(name))
+}
+
+func decodeCursor(cursor string) (string, error) {
+	name, err := base64.URLEncoding.DecodeString(cursor)
+	if err != nil {
+		return "", fmt.Errorf("invalid cursor %q", cursor)
+	}
+
+	return string(name), nil
+}
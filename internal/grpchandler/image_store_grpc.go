@@ -0,0 +1,271 @@
+package grpchandler
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	log "github.com/sirupsen/logrus"
+	imagestorev1 "githum.com/anupam111/image-store/api/imagestore/v1"
+	"githum.com/anupam111/image-store/internal/constants"
+	"githum.com/anupam111/image-store/internal/controller"
+	"githum.com/anupam111/image-store/internal/db/dbhandler"
+	"githum.com/anupam111/image-store/internal/db/dbmodels"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"io"
+)
+
+// downloadChunkSize is the size of the content chunks sent by DownloadImage.
+const downloadChunkSize = 64 << 10
+
+// MethodScopes maps the full name of every method of the service to the API
+// key scopes it requires.
+var MethodScopes = map[string][]string{
+	methodName("CreateAlbum"):   {dbmodels.ScopeAlbumsWrite},
+	methodName("GetAlbum"):      {dbmodels.ScopeAlbumsRead},
+	methodName("DeleteAlbum"):   {dbmodels.ScopeAlbumsWrite},
+	methodName("ListAlbums"):    {dbmodels.ScopeAlbumsRead},
+	methodName("UploadImages"):  {dbmodels.ScopeImagesWrite},
+	methodName("DownloadImage"): {dbmodels.ScopeImagesRead},
+	methodName("ListImages"):    {dbmodels.ScopeImagesRead},
+	methodName("DeleteImage"):   {dbmodels.ScopeImagesWrite},
+}
+
+func methodName(method string) string {
+	return "/" + imagestorev1.ImageStore_ServiceDesc.ServiceName + "/" + method
+}
+
+// GRPCHandler implements the imagestorev1.ImageStoreServer on top of the same
+// controller as the REST api.
+type GRPCHandler struct {
+	imagestorev1.UnimplementedImageStoreServer
+	log        *log.Logger
+	imageStore controller.ImageStore
+}
+
+// NewGRPCHandler implements GRPCHandler.
+func NewGRPCHandler(logger *log.Logger, imageStore controller.ImageStore) *GRPCHandler {
+	return &GRPCHandler{
+		log:        logger,
+		imageStore: imageStore,
+	}
+}
+
+func (g *GRPCHandler) CreateAlbum(ctx context.Context, req *imagestorev1.CreateAlbumRequest) (*imagestorev1.Album, error) {
+	if req.GetAlbumName() == "" {
+		return nil, status.Error(codes.InvalidArgument, "album_name is empty")
+	}
+
+	if err := g.imageStore.CreateImageAlbum(ctx, dbmodels.Album{AlbumName: req.GetAlbumName()}); err != nil {
+		return nil, toStatus(err)
+	}
+
+	return &imagestorev1.Album{AlbumName: req.GetAlbumName()}, nil
+}
+
+func (g *GRPCHandler) GetAlbum(ctx context.Context, req *imagestorev1.GetAlbumRequest) (*imagestorev1.Album, error) {
+	album, err := g.imageStore.GetImageAlbum(ctx, req.GetAlbumName())
+	if err != nil {
+		return nil, toStatus(err)
+	}
+
+	return &imagestorev1.Album{AlbumName: album.AlbumName}, nil
+}
+
+func (g *GRPCHandler) DeleteAlbum(ctx context.Context, req *imagestorev1.DeleteAlbumRequest) (*imagestorev1.DeleteAlbumResponse, error) {
+	if _, err := g.imageStore.GetImageAlbum(ctx, req.GetAlbumName()); err != nil {
+		return nil, toStatus(err)
+	}
+
+	if err := g.imageStore.DeleteImageAlbum(ctx, req.GetAlbumName()); err != nil {
+		return nil, toStatus(err)
+	}
+
+	return &imagestorev1.DeleteAlbumResponse{}, nil
+}
+
+func (g *GRPCHandler) ListAlbums(_ *imagestorev1.ListAlbumsRequest, stream imagestorev1.ImageStore_ListAlbumsServer) error {
+	albums, err := g.imageStore.ListImageAlbums(stream.Context())
+	if err != nil {
+		return toStatus(err)
+	}
+
+	for _, album := range albums {
+		if err = stream.Send(&imagestorev1.Album{AlbumName: album.AlbumName}); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// UploadImages creates the images sent on the stream one by one and reports
+// the outcome of each of them once the client closes the stream.
+func (g *GRPCHandler) UploadImages(stream imagestorev1.ImageStore_UploadImagesServer) error {
+	ctx := stream.Context()
+	response := &imagestorev1.UploadImagesResponse{}
+	var current *imagestorev1.ImageInfo
+	var content bytes.Buffer
+	var skip bool
+
+	addResult := func(info *imagestorev1.ImageInfo, err error) {
+		result := &imagestorev1.UploadResult{Image: info}
+		if err != nil {
+			st := status.Convert(toStatus(err))
+			result.Code = int32(st.Code())
+			result.Message = st.Message()
+			response.Failed++
+		} else {
+			response.Succeeded++
+		}
+		response.Results = append(response.Results, result)
+	}
+
+	flush := func() {
+		if current == nil || skip {
+			return
+		}
+
+		image := dbmodels.Image{
+			ImageName: current.GetImageName(),
+			AlbumName: current.GetAlbumName(),
+		}
+		image.SetContent(content.Bytes())
+		addResult(current, g.imageStore.CreateImage(ctx, image))
+	}
+
+	for {
+		req, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			flush()
+
+			return stream.SendAndClose(response)
+		}
+
+		if err != nil {
+			return err
+		}
+
+		switch payload := req.GetPayload().(type) {
+		case *imagestorev1.UploadImagesRequest_Info:
+			flush()
+			current, skip = payload.Info, false
+			content.Reset()
+
+			if current.GetAlbumName() == "" || current.GetImageName() == "" {
+				addResult(current, status.Error(codes.InvalidArgument, "album_name and image_name are required"))
+				skip = true
+			}
+		case *imagestorev1.UploadImagesRequest_Chunk:
+			if current == nil {
+				return status.Error(codes.InvalidArgument, "chunk sent before image info")
+			}
+
+			if skip {
+				continue
+			}
+
+			if content.Len()+len(payload.Chunk) > constants.MaxImageSize {
+				addResult(current, status.Errorf(codes.InvalidArgument,
+					"image exceeds the maximum image size of %d bytes", constants.MaxImageSize))
+				skip = true
+
+				continue
+			}
+
+			content.Write(payload.Chunk)
+		default:
+			return status.Error(codes.InvalidArgument, "upload message has no payload")
+		}
+	}
+}
+
+// DownloadImage sends the info of the image followed by its content.
+func (g *GRPCHandler) DownloadImage(req *imagestorev1.DownloadImageRequest, stream imagestorev1.ImageStore_DownloadImageServer) error {
+	ctx := stream.Context()
+	image, err := g.imageStore.GetAlbumImage(ctx, req.GetAlbumName(), req.GetImageName())
+	if err != nil {
+		return toStatus(err)
+	}
+
+	if err = stream.Send(&imagestorev1.DownloadImageResponse{
+		Payload: &imagestorev1.DownloadImageResponse_Info{Info: imageInfo(image)},
+	}); err != nil {
+		return err
+	}
+
+	content := image.Content()
+	for len(content) > 0 {
+		size := downloadChunkSize
+		if len(content) < size {
+			size = len(content)
+		}
+
+		if err = stream.Send(&imagestorev1.DownloadImageResponse{
+			Payload: &imagestorev1.DownloadImageResponse_Chunk{Chunk: content[:size]},
+		}); err != nil {
+			return err
+		}
+
+		content = content[size:]
+	}
+
+	return nil
+}
+
+// ListImages streams the images of the album as they are read from the
+// database.
+func (g *GRPCHandler) ListImages(req *imagestorev1.ListImagesRequest, stream imagestorev1.ImageStore_ListImagesServer) error {
+	ctx := stream.Context()
+	if _, err := g.imageStore.GetImageAlbum(ctx, req.GetAlbumName()); err != nil {
+		return toStatus(err)
+	}
+
+	err := g.imageStore.ExportAlbumImages(ctx, req.GetAlbumName(), func(image dbmodels.Image) error {
+		return stream.Send(imageInfo(image))
+	})
+	if err != nil {
+		return toStatus(err)
+	}
+
+	return nil
+}
+
+func (g *GRPCHandler) DeleteImage(ctx context.Context, req *imagestorev1.DeleteImageRequest) (*imagestorev1.DeleteImageResponse, error) {
+	if _, err := g.imageStore.GetAlbumImage(ctx, req.GetAlbumName(), req.GetImageName()); err != nil {
+		return nil, toStatus(err)
+	}
+
+	if err := g.imageStore.DeleteImage(ctx, req.GetImageName(), req.GetAlbumName()); err != nil {
+		return nil, toStatus(err)
+	}
+
+	return &imagestorev1.DeleteImageResponse{}, nil
+}
+
+func imageInfo(image dbmodels.Image) *imagestorev1.ImageInfo {
+	return &imagestorev1.ImageInfo{
+		AlbumName: image.AlbumName,
+		ImageName: image.ImageName,
+	}
+}
+
+// toStatus converts the errors of the controller to gRPC status errors.
+func toStatus(err error) error {
+	if _, ok := status.FromError(err); ok {
+		return err
+	}
+
+	switch {
+	case errors.Is(err, dbhandler.ErrNoDataFound):
+		return status.Error(codes.NotFound, err.Error())
+	case errors.Is(err, dbhandler.ErrDuplicate):
+		return status.Error(codes.AlreadyExists, err.Error())
+	case errors.Is(err, controller.ErrAccessDenied):
+		return status.Error(codes.PermissionDenied, err.Error())
+	case errors.Is(err, controller.ErrImageQuota), errors.Is(err, controller.ErrStorageQuota):
+		return status.Error(codes.ResourceExhausted, err.Error())
+	default:
+		return status.Error(codes.Internal, err.Error())
+	}
+}
@@ -0,0 +1,268 @@
+package grpchandler
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"github.com/golang/mock/gomock"
+	log "github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	imagestorev1 "githum.com/anupam111/image-store/api/imagestore/v1"
+	"githum.com/anupam111/image-store/internal/controller"
+	"githum.com/anupam111/image-store/internal/db/dbhandler"
+	"githum.com/anupam111/image-store/internal/db/dbmodels"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"io"
+	"net"
+	"testing"
+)
+
+var errFake = errors.New("error")
+
+func setupTestEnv(t *testing.T) (imagestorev1.ImageStoreClient, *controller.MockImageStore) {
+	t.Helper()
+	mockCtrl := gomock.NewController(t)
+	mockController := controller.NewMockImageStore(mockCtrl)
+
+	listener := bufconn.Listen(1 << 20)
+	server := grpc.NewServer()
+	imagestorev1.RegisterImageStoreServer(server, NewGRPCHandler(log.New(), mockController))
+	go func() {
+		_ = server.Serve(listener)
+	}()
+
+	conn, err := grpc.DialContext(context.Background(), "bufnet",
+		grpc.WithContextDialer(func(context.Context, string) (net.Conn, error) {
+			return listener.Dial()
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if err != nil {
+		t.Fatalf("error while dialing bufconn: %v", err)
+	}
+
+	t.Cleanup(func() {
+		conn.Close()
+		server.Stop()
+	})
+
+	return imagestorev1.NewImageStoreClient(conn), mockController
+}
+
+func TestCreateAlbum(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name      string
+		albumName string
+		prepare   func(
+			subs *controller.MockImageStore,
+		)
+		code codes.Code
+	}{
+		{
+			name:      "success",
+			albumName: "test-album",
+			prepare: func(subs *controller.MockImageStore) {
+				subs.EXPECT().CreateImageAlbum(gomock.Any(), dbmodels.Album{AlbumName: "test-album"}).Return(nil)
+			},
+			code: codes.OK,
+		},
+		{
+			name:      "already_exists",
+			albumName: "test-album",
+			prepare: func(subs *controller.MockImageStore) {
+				subs.EXPECT().CreateImageAlbum(gomock.Any(), gomock.Any()).Return(dbhandler.ErrDuplicate)
+			},
+			code: codes.AlreadyExists,
+		},
+		{
+			name:      "internal",
+			albumName: "test-album",
+			prepare: func(subs *controller.MockImageStore) {
+				subs.EXPECT().CreateImageAlbum(gomock.Any(), gomock.Any()).Return(errFake)
+			},
+			code: codes.Internal,
+		},
+		{
+			name: "invalid_argument",
+			code: codes.InvalidArgument,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			client, controller := setupTestEnv(t)
+			if tt.prepare != nil {
+				tt.prepare(controller)
+			}
+
+			_, err := client.CreateAlbum(context.Background(), &imagestorev1.CreateAlbumRequest{AlbumName: tt.albumName})
+			assert.Equal(t, tt.code, status.Code(err))
+		})
+	}
+}
+
+func TestUploadImages(t *testing.T) {
+	t.Parallel()
+
+	client, controller := setupTestEnv(t)
+
+	first := dbmodels.Image{ImageName: "image-1", AlbumName: "test-album"}
+	first.SetContent([]byte("hello world"))
+	second := dbmodels.Image{ImageName: "image-2", AlbumName: "test-album"}
+	second.SetContent(nil)
+
+	controller.EXPECT().CreateImage(gomock.Any(), first).Return(nil)
+	controller.EXPECT().CreateImage(gomock.Any(), second).Return(dbhandler.ErrDuplicate)
+
+	stream, err := client.UploadImages(context.Background())
+	assert.Nil(t, err)
+
+	requests := []*imagestorev1.UploadImagesRequest{
+		{Payload: &imagestorev1.UploadImagesRequest_Info{Info: &imagestorev1.ImageInfo{AlbumName: "test-album", ImageName: "image-1"}}},
+		{Payload: &imagestorev1.UploadImagesRequest_Chunk{Chunk: []byte("hello ")}},
+		{Payload: &imagestorev1.UploadImagesRequest_Chunk{Chunk: []byte("world")}},
+		{Payload: &imagestorev1.UploadImagesRequest_Info{Info: &imagestorev1.ImageInfo{AlbumName: "test-album"}}},
+		{Payload: &imagestorev1.UploadImagesRequest_Chunk{Chunk: []byte("skipped")}},
+		{Payload: &imagestorev1.UploadImagesRequest_Info{Info: &imagestorev1.ImageInfo{AlbumName: "test-album", ImageName: "image-2"}}},
+	}
+	for _, req := range requests {
+		assert.Nil(t, stream.Send(req))
+	}
+
+	response, err := stream.CloseAndRecv()
+	assert.Nil(t, err)
+	assert.Equal(t, int32(1), response.GetSucceeded())
+	assert.Equal(t, int32(2), response.GetFailed())
+	assert.Equal(t, int32(codes.OK), response.GetResults()[0].GetCode())
+	assert.Equal(t, int32(codes.InvalidArgument), response.GetResults()[1].GetCode())
+	assert.Equal(t, int32(codes.AlreadyExists), response.GetResults()[2].GetCode())
+}
+
+func TestDownloadImage(t *testing.T) {
+	t.Parallel()
+
+	client, controller := setupTestEnv(t)
+
+	content := bytes.Repeat([]byte("a"), downloadChunkSize+10)
+	image := dbmodels.Image{ImageName: "test-image", AlbumName: "test-album"}
+	image.SetContent(content)
+	controller.EXPECT().GetAlbumImage(gomock.Any(), "test-album", "test-image").Return(image, nil)
+
+	stream, err := client.DownloadImage(context.Background(), &imagestorev1.DownloadImageRequest{
+		AlbumName: "test-album",
+		ImageName: "test-image",
+	})
+	assert.Nil(t, err)
+
+	first, err := stream.Recv()
+	assert.Nil(t, err)
+	assert.Equal(t, "test-image", first.GetInfo().GetImageName())
+
+	var got []byte
+	chunks := 0
+	for {
+		msg, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		assert.Nil(t, err)
+		got = append(got, msg.GetChunk()...)
+		chunks++
+	}
+	assert.Equal(t, content, got)
+	assert.Equal(t, 2, chunks)
+}
+
+func TestListImages(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		prepare func(
+			subs *controller.MockImageStore,
+		)
+		expected []string
+		code     codes.Code
+	}{
+		{
+			name: "success",
+			prepare: func(subs *controller.MockImageStore) {
+				subs.EXPECT().GetImageAlbum(gomock.Any(), "test-album").Return(dbmodels.Album{AlbumName: "test-album"}, nil)
+				subs.EXPECT().ExportAlbumImages(gomock.Any(), "test-album", gomock.Any()).DoAndReturn(
+					func(_ context.Context, albumName string, fn func(dbmodels.Image) error) error {
+						for _, name := range []string{"image-1", "image-2"} {
+							if err := fn(dbmodels.Image{AlbumName: albumName, ImageName: name}); err != nil {
+								return err
+							}
+						}
+
+						return nil
+					})
+			},
+			expected: []string{"image-1", "image-2"},
+			code:     codes.OK,
+		},
+		{
+			name: "not_found",
+			prepare: func(subs *controller.MockImageStore) {
+				subs.EXPECT().GetImageAlbum(gomock.Any(), "test-album").Return(dbmodels.Album{}, dbhandler.ErrNoDataFound)
+			},
+			code: codes.NotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			client, controller := setupTestEnv(t)
+			tt.prepare(controller)
+
+			stream, err := client.ListImages(context.Background(), &imagestorev1.ListImagesRequest{AlbumName: "test-album"})
+			assert.Nil(t, err)
+
+			var got []string
+			for {
+				msg, err := stream.Recv()
+				if err != nil {
+					if !errors.Is(err, io.EOF) {
+						assert.Equal(t, tt.code, status.Code(err))
+					}
+
+					break
+				}
+				got = append(got, msg.GetImageName())
+			}
+			assert.Equal(t, tt.expected, got)
+		})
+	}
+}
+
+func TestMethodScopes(t *testing.T) {
+	t.Parallel()
+
+	desc := imagestorev1.ImageStore_ServiceDesc
+	methods := map[string]bool{}
+
+	for _, method := range desc.Methods {
+		methods["/"+desc.ServiceName+"/"+method.MethodName] = true
+	}
+
+	for _, stream := range desc.Streams {
+		methods["/"+desc.ServiceName+"/"+stream.StreamName] = true
+	}
+
+	for method := range methods {
+		assert.NotEmpty(t, MethodScopes[method], "method %s has no scope", method)
+	}
+
+	assert.Len(t, MethodScopes, len(methods))
+}
@@ -0,0 +1,296 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	log "github.com/sirupsen/logrus"
+	"githum.com/anupam111/image-store/internal/auth"
+	"githum.com/anupam111/image-store/internal/controller"
+	"githum.com/anupam111/image-store/internal/db/dbmodels"
+	"githum.com/anupam111/image-store/internal/models"
+	"githum.com/anupam111/image-store/internal/tenant"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"net/http"
+	"strings"
+)
+
+// apiKeyHeader carries the API key of clients which can not send an
+// Authorization header.
+const apiKeyHeader = "X-API-Key"
+
+// Authenticator returns the API key of a key, controller.ErrInvalidAPIKey
+// when it is unknown or revoked.
+type Authenticator interface {
+	Authenticate(ctx context.Context, key string) (dbmodels.APIKey, error)
+}
+
+// TokenVerifier returns the principal of a JWT bearer token,
+// auth.ErrInvalidToken when it is not acceptable.
+type TokenVerifier interface {
+	Verify(ctx context.Context, token string) (auth.Principal, error)
+}
+
+// Auth authenticates the requests with the API key sent as
+// "Authorization: Bearer <key>" or in the X-API-Key header, or with a JWT
+// bearer token. The principal of the request is then available with
+// auth.FromContext. Auth also resolves the tenant of the request, available
+// with tenant.FromContext: the tenant the principal is bound to, or else the
+// one of the X-Tenant-ID header, or else tenant.Default. Only the principals
+// with the admin scope can be bound to no tenant, the others are bound to
+// tenant.Default.
+type Auth struct {
+	log           *log.Logger
+	authenticator Authenticator
+	tokens        TokenVerifier
+}
+
+// NewAuth implements Auth. A nil tokens only accepts API keys, a nil
+// authenticator only accepts tokens. Authentication is disabled when both are
+// nil, every request is then let through.
+func NewAuth(logger *log.Logger, authenticator Authenticator, tokens TokenVerifier) *Auth {
+	return &Auth{
+		log:           logger,
+		authenticator: authenticator,
+		tokens:        tokens,
+	}
+}
+
+func (a *Auth) disabled() bool {
+	return a.authenticator == nil && a.tokens == nil
+}
+
+// Require answers 401 to the requests without a valid API key or token and
+// 403 when the caller lacks one of the scopes or names a tenant it is not
+// bound to. An invalid tenant is answered 400. When authentication is
+// disabled only the tenant is resolved.
+func (a *Auth) Require(scopes ...string) gin.HandlerFunc {
+	return func(ginCtx *gin.Context) {
+		var (
+			ctx       context.Context
+			err       error
+			requested = ginCtx.GetHeader(tenant.Header)
+		)
+
+		if a.disabled() {
+			ctx, err = withTenant(ginCtx.Request.Context(), "", requested)
+		} else {
+			ctx, err = a.authorize(ginCtx.Request.Context(),
+				bearerToken(ginCtx.GetHeader("Authorization")), ginCtx.GetHeader(apiKeyHeader), requested, scopes)
+		}
+
+		if err != nil {
+			status, errorCode := http.StatusInternalServerError, "INTERNAL-SERVER-ERROR"
+
+			switch {
+			case errors.Is(err, errUnauthenticated):
+				status, errorCode = http.StatusUnauthorized, "UNAUTHORIZED"
+				ginCtx.Header("WWW-Authenticate", "Bearer")
+			case errors.Is(err, errForbidden), errors.Is(err, errTenantDenied):
+				status, errorCode = http.StatusForbidden, "FORBIDDEN"
+			case errors.Is(err, errInvalidTenant):
+				status, errorCode = http.StatusBadRequest, "BAD-REQUEST"
+			}
+
+			ginCtx.AbortWithStatusJSON(status, models.ResponseError{
+				HTTPStatusCode: status,
+				ErrorCode:      errorCode,
+				MessageDetails: err.Error(),
+			})
+
+			return
+		}
+
+		ginCtx.Request = ginCtx.Request.WithContext(ctx)
+		ginCtx.Next()
+	}
+}
+
+// UnaryInterceptor is Require for unary gRPC calls, methodScopes maps the
+// full method names to their scopes. Methods missing from it are denied.
+func (a *Auth) UnaryInterceptor(methodScopes map[string][]string) grpc.UnaryServerInterceptor {
+	return func(
+		ctx context.Context,
+		req interface{},
+		info *grpc.UnaryServerInfo,
+		handler grpc.UnaryHandler,
+	) (interface{}, error) {
+		ctx, err := a.authorizeGRPC(ctx, info.FullMethod, methodScopes)
+		if err != nil {
+			return nil, err
+		}
+
+		return handler(ctx, req)
+	}
+}
+
+// StreamInterceptor is Require for streaming gRPC calls.
+func (a *Auth) StreamInterceptor(methodScopes map[string][]string) grpc.StreamServerInterceptor {
+	return func(
+		srv interface{},
+		stream grpc.ServerStream,
+		info *grpc.StreamServerInfo,
+		handler grpc.StreamHandler,
+	) error {
+		ctx, err := a.authorizeGRPC(stream.Context(), info.FullMethod, methodScopes)
+		if err != nil {
+			return err
+		}
+
+		return handler(srv, &sessionStream{
+			ServerStream: stream,
+			ctx:          ctx,
+		})
+	}
+}
+
+var (
+	errUnauthenticated = errors.New("missing or invalid credentials")
+	errForbidden       = errors.New("caller lacks scope")
+	errTenantDenied    = errors.New("caller is bound to tenant")
+	errInvalidTenant   = errors.New("invalid tenant")
+)
+
+// authorize authenticates the bearer token, or else the API key, and checks
+// the scopes of the principal. It returns ctx with the principal and the
+// tenant of the request, requested being the tenant the request names.
+func (a *Auth) authorize(ctx context.Context, bearer, apiKey, requested string, scopes []string) (context.Context, error) {
+	principal, err := a.authenticate(ctx, bearer, apiKey)
+	if err != nil {
+		return nil, err
+	}
+
+	if principal.Tenant == "" && !principal.Scopes.Allows(dbmodels.ScopeAdmin) {
+		principal.Tenant = tenant.Default
+	}
+
+	for _, scope := range scopes {
+		if !principal.Scopes.Allows(scope) {
+			return nil, fmt.Errorf("%w %s", errForbidden, scope)
+		}
+	}
+
+	ctx, err = withTenant(ctx, principal.Tenant, requested)
+	if err != nil {
+		return nil, err
+	}
+
+	return auth.NewContext(ctx, principal), nil
+}
+
+// withTenant returns ctx with the tenant of the request: bound, the tenant of
+// the principal, when it is not empty, or else requested, or else
+// tenant.Default. A principal bound to a tenant can not request another one.
+func withTenant(ctx context.Context, bound, requested string) (context.Context, error) {
+	switch {
+	case requested != "" && !tenant.Valid(requested):
+		return nil, fmt.Errorf("%w %q", errInvalidTenant, requested)
+	case bound != "" && requested != "" && requested != bound:
+		return nil, fmt.Errorf("%w %s", errTenantDenied, bound)
+	case bound != "":
+		requested = bound
+	case requested == "":
+		requested = tenant.Default
+	}
+
+	return tenant.NewContext(ctx, requested), nil
+}
+
+// authenticate returns the principal of the credentials. A bearer JWT is
+// verified as a token, any other bearer is an API key.
+func (a *Auth) authenticate(ctx context.Context, bearer, apiKey string) (auth.Principal, error) {
+	if bearer != "" && a.tokens != nil && auth.IsJWT(bearer) {
+		principal, err := a.tokens.Verify(ctx, bearer)
+		if errors.Is(err, auth.ErrInvalidToken) {
+			a.log.Debugf("rejected bearer token: %v", err)
+
+			return auth.Principal{}, errUnauthenticated
+		}
+
+		if err != nil {
+			a.log.Errorf("error while verifying bearer token: %v", err)
+
+			return auth.Principal{}, err
+		}
+
+		return principal, nil
+	}
+
+	if bearer != "" {
+		apiKey = bearer
+	}
+
+	if apiKey == "" || a.authenticator == nil {
+		return auth.Principal{}, errUnauthenticated
+	}
+
+	key, err := a.authenticator.Authenticate(ctx, apiKey)
+	if errors.Is(err, controller.ErrInvalidAPIKey) {
+		return auth.Principal{}, errUnauthenticated
+	}
+
+	if err != nil {
+		a.log.Errorf("error while authenticating api key: %v", err)
+
+		return auth.Principal{}, err
+	}
+
+	return auth.APIKeyPrincipal(key), nil
+}
+
+func (a *Auth) authorizeGRPC(ctx context.Context, method string, methodScopes map[string][]string) (context.Context, error) {
+	var bearer, apiKey, requested string
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if values := md.Get("authorization"); len(values) > 0 {
+			bearer = bearerToken(values[0])
+		}
+
+		if values := md.Get(apiKeyHeader); len(values) > 0 {
+			apiKey = values[0]
+		}
+
+		if values := md.Get(tenant.Header); len(values) > 0 {
+			requested = values[0]
+		}
+	}
+
+	var err error
+
+	if a.disabled() {
+		ctx, err = withTenant(ctx, "", requested)
+	} else {
+		scopes, ok := methodScopes[method]
+		if !ok {
+			return nil, status.Errorf(codes.PermissionDenied, "no scope allows %s", method)
+		}
+
+		ctx, err = a.authorize(ctx, bearer, apiKey, requested, scopes)
+	}
+
+	switch {
+	case errors.Is(err, errUnauthenticated):
+		return nil, status.Error(codes.Unauthenticated, err.Error())
+	case errors.Is(err, errForbidden), errors.Is(err, errTenantDenied):
+		return nil, status.Error(codes.PermissionDenied, err.Error())
+	case errors.Is(err, errInvalidTenant):
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	case err != nil:
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+
+	return ctx, nil
+}
+
+// bearerToken returns the token of an "Authorization: Bearer <token>" header.
+func bearerToken(header string) string {
+	const prefix = "bearer "
+	if len(header) > len(prefix) && strings.EqualFold(header[:len(prefix)], prefix) {
+		return strings.TrimSpace(header[len(prefix):])
+	}
+
+	return ""
+}
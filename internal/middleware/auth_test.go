@@ -0,0 +1,345 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"github.com/gin-gonic/gin"
+	log "github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"githum.com/anupam111/image-store/internal/auth"
+	"githum.com/anupam111/image-store/internal/controller"
+	"githum.com/anupam111/image-store/internal/db/dbmodels"
+	"githum.com/anupam111/image-store/internal/tenant"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+type fakeAuthenticator map[string]dbmodels.APIKey
+
+func (f fakeAuthenticator) Authenticate(_ context.Context, key string) (dbmodels.APIKey, error) {
+	if key == "broken" {
+		return dbmodels.APIKey{}, errors.New("database down")
+	}
+
+	apiKey, ok := f[key]
+	if !ok {
+		return dbmodels.APIKey{}, controller.ErrInvalidAPIKey
+	}
+
+	return apiKey, nil
+}
+
+type fakeTokenVerifier map[string]auth.Principal
+
+func (f fakeTokenVerifier) Verify(_ context.Context, token string) (auth.Principal, error) {
+	principal, ok := f[token]
+	if !ok {
+		return auth.Principal{}, auth.ErrInvalidToken
+	}
+
+	return principal, nil
+}
+
+var testTokenVerifier = fakeTokenVerifier{
+	"user.token.sig":  {Subject: "user-1", Groups: []string{"editors"}, Scopes: dbmodels.Scopes{dbmodels.ScopeImagesRead}},
+	"other.token.sig": {Subject: "user-2", Scopes: dbmodels.Scopes{"openid"}},
+}
+
+var testAuthenticator = fakeAuthenticator{
+	"reader": {ID: "reader", Scopes: dbmodels.Scopes{dbmodels.ScopeImagesRead}},
+	"admin":  {ID: "admin", Scopes: dbmodels.Scopes{dbmodels.ScopeAdmin}},
+	"acme":   {ID: "acme", Scopes: dbmodels.Scopes{dbmodels.ScopeImagesRead, dbmodels.ScopeImagesWrite}, Tenant: "acme"},
+}
+
+func Test_Auth_Require(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name           string
+		authenticator  Authenticator
+		tokens         TokenVerifier
+		headers        map[string]string
+		expectedStatus int
+		expectedID     string
+		expectedTenant string
+	}{
+		{
+			name:           "disabled",
+			expectedStatus: http.StatusOK,
+			expectedTenant: "default",
+		},
+		{
+			name:           "disabled with tenant",
+			headers:        map[string]string{"X-Tenant-ID": "globex"},
+			expectedStatus: http.StatusOK,
+			expectedTenant: "globex",
+		},
+		{
+			name:           "disabled with invalid tenant",
+			headers:        map[string]string{"X-Tenant-ID": "../globex"},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "missing key",
+			authenticator:  testAuthenticator,
+			expectedStatus: http.StatusUnauthorized,
+		},
+		{
+			name:           "invalid key",
+			authenticator:  testAuthenticator,
+			headers:        map[string]string{"Authorization": "Bearer unknown"},
+			expectedStatus: http.StatusUnauthorized,
+		},
+		{
+			name:           "bearer key with scope",
+			authenticator:  testAuthenticator,
+			headers:        map[string]string{"Authorization": "bearer reader"},
+			expectedStatus: http.StatusOK,
+			expectedID:     "apikey:reader",
+		},
+		{
+			name:           "header key with scope",
+			authenticator:  testAuthenticator,
+			headers:        map[string]string{"X-API-Key": "reader"},
+			expectedStatus: http.StatusOK,
+			expectedID:     "apikey:reader",
+		},
+		{
+			name:           "unbound key without tenant header",
+			authenticator:  testAuthenticator,
+			headers:        map[string]string{"X-API-Key": "reader"},
+			expectedStatus: http.StatusOK,
+			expectedID:     "apikey:reader",
+			expectedTenant: "default",
+		},
+		{
+			name:           "unbound key with tenant header",
+			authenticator:  testAuthenticator,
+			headers:        map[string]string{"X-API-Key": "reader", "X-Tenant-ID": "globex"},
+			expectedStatus: http.StatusForbidden,
+		},
+		{
+			name:           "admin key with tenant header",
+			authenticator:  testAuthenticator,
+			headers:        map[string]string{"X-API-Key": "admin", "X-Tenant-ID": "globex"},
+			expectedStatus: http.StatusOK,
+			expectedID:     "apikey:admin",
+			expectedTenant: "globex",
+		},
+		{
+			name:           "token without tenant claim with tenant header",
+			tokens:         testTokenVerifier,
+			headers:        map[string]string{"Authorization": "Bearer user.token.sig", "X-Tenant-ID": "globex"},
+			expectedStatus: http.StatusForbidden,
+		},
+		{
+			name:           "key bound to tenant",
+			authenticator:  testAuthenticator,
+			headers:        map[string]string{"X-API-Key": "acme"},
+			expectedStatus: http.StatusOK,
+			expectedID:     "apikey:acme",
+			expectedTenant: "acme",
+		},
+		{
+			name:           "key bound to tenant with its tenant",
+			authenticator:  testAuthenticator,
+			headers:        map[string]string{"X-API-Key": "acme", "X-Tenant-ID": "acme"},
+			expectedStatus: http.StatusOK,
+			expectedID:     "apikey:acme",
+			expectedTenant: "acme",
+		},
+		{
+			name:           "key bound to tenant with other tenant",
+			authenticator:  testAuthenticator,
+			headers:        map[string]string{"X-API-Key": "acme", "X-Tenant-ID": "globex"},
+			expectedStatus: http.StatusForbidden,
+		},
+		{
+			name:           "admin has every scope",
+			authenticator:  testAuthenticator,
+			headers:        map[string]string{"X-API-Key": "admin"},
+			expectedStatus: http.StatusOK,
+			expectedID:     "apikey:admin",
+		},
+		{
+			name:           "token with scope",
+			authenticator:  testAuthenticator,
+			tokens:         testTokenVerifier,
+			headers:        map[string]string{"Authorization": "Bearer user.token.sig"},
+			expectedStatus: http.StatusOK,
+			expectedID:     "user-1",
+		},
+		{
+			name:           "token without scope",
+			tokens:         testTokenVerifier,
+			headers:        map[string]string{"Authorization": "Bearer other.token.sig"},
+			expectedStatus: http.StatusForbidden,
+		},
+		{
+			name:           "invalid token",
+			authenticator:  testAuthenticator,
+			tokens:         testTokenVerifier,
+			headers:        map[string]string{"Authorization": "Bearer forged.token.sig", "X-API-Key": "reader"},
+			expectedStatus: http.StatusUnauthorized,
+		},
+		{
+			name:           "api key with token verifier",
+			authenticator:  testAuthenticator,
+			tokens:         testTokenVerifier,
+			headers:        map[string]string{"Authorization": "Bearer reader"},
+			expectedStatus: http.StatusOK,
+			expectedID:     "apikey:reader",
+		},
+		{
+			name:           "api key without authenticator",
+			tokens:         testTokenVerifier,
+			headers:        map[string]string{"X-API-Key": "reader"},
+			expectedStatus: http.StatusUnauthorized,
+		},
+		{
+			name:           "authenticator error",
+			authenticator:  testAuthenticator,
+			headers:        map[string]string{"X-API-Key": "broken"},
+			expectedStatus: http.StatusInternalServerError,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			authMiddleware := NewAuth(log.New(), tt.authenticator, tt.tokens)
+			router := gin.New()
+
+			var gotID, gotTenant string
+			router.GET("/images", authMiddleware.Require(dbmodels.ScopeImagesRead), func(ginCtx *gin.Context) {
+				principal, _ := auth.FromContext(ginCtx.Request.Context())
+				gotID = principal.Subject
+				gotTenant = tenant.FromContext(ginCtx.Request.Context())
+				ginCtx.Status(http.StatusOK)
+			})
+
+			req := httptest.NewRequest(http.MethodGet, "/images", nil)
+			for name, value := range tt.headers {
+				req.Header.Set(name, value)
+			}
+
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, req)
+
+			assert.Equal(t, tt.expectedStatus, rec.Code)
+			assert.Equal(t, tt.expectedID, gotID)
+
+			if tt.expectedTenant != "" {
+				assert.Equal(t, tt.expectedTenant, gotTenant)
+			}
+		})
+	}
+}
+
+func Test_Auth_RequireScope(t *testing.T) {
+	t.Parallel()
+
+	authMiddleware := NewAuth(log.New(), testAuthenticator, nil)
+	router := gin.New()
+	router.POST("/images", authMiddleware.Require(dbmodels.ScopeImagesWrite), func(ginCtx *gin.Context) {
+		ginCtx.Status(http.StatusCreated)
+	})
+
+	req := httptest.NewRequest(http.MethodPost, "/images", nil)
+	req.Header.Set("X-API-Key", "reader")
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusForbidden, rec.Code)
+}
+
+func Test_Auth_UnaryInterceptor(t *testing.T) {
+	t.Parallel()
+
+	methodScopes := map[string][]string{"/svc/Read": {dbmodels.ScopeImagesRead}, "/svc/Write": {dbmodels.ScopeImagesWrite}}
+
+	tests := []struct {
+		name          string
+		authenticator Authenticator
+		tokens        TokenVerifier
+		method        string
+		md            metadata.MD
+		expectedCode  codes.Code
+	}{
+		{
+			name:         "disabled",
+			method:       "/svc/Write",
+			expectedCode: codes.OK,
+		},
+		{
+			name:         "disabled with invalid tenant",
+			method:       "/svc/Write",
+			md:           metadata.Pairs("x-tenant-id", "../globex"),
+			expectedCode: codes.InvalidArgument,
+		},
+		{
+			name:          "missing key",
+			authenticator: testAuthenticator,
+			method:        "/svc/Read",
+			expectedCode:  codes.Unauthenticated,
+		},
+		{
+			name:          "key bound to tenant with other tenant",
+			authenticator: testAuthenticator,
+			method:        "/svc/Read",
+			md:            metadata.Pairs("x-api-key", "acme", "x-tenant-id", "globex"),
+			expectedCode:  codes.PermissionDenied,
+		},
+		{
+			name:          "bearer key with scope",
+			authenticator: testAuthenticator,
+			method:        "/svc/Read",
+			md:            metadata.Pairs("authorization", "Bearer reader"),
+			expectedCode:  codes.OK,
+		},
+		{
+			name:          "header key without scope",
+			authenticator: testAuthenticator,
+			method:        "/svc/Write",
+			md:            metadata.Pairs("x-api-key", "reader"),
+			expectedCode:  codes.PermissionDenied,
+		},
+		{
+			name:          "token with scope",
+			authenticator: testAuthenticator,
+			tokens:        testTokenVerifier,
+			method:        "/svc/Read",
+			md:            metadata.Pairs("authorization", "Bearer user.token.sig"),
+			expectedCode:  codes.OK,
+		},
+		{
+			name:          "unknown method",
+			authenticator: testAuthenticator,
+			method:        "/svc/Other",
+			md:            metadata.Pairs("x-api-key", "admin"),
+			expectedCode:  codes.PermissionDenied,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			interceptor := NewAuth(log.New(), tt.authenticator, tt.tokens).UnaryInterceptor(methodScopes)
+			ctx := metadata.NewIncomingContext(context.Background(), tt.md)
+
+			_, err := interceptor(ctx, nil, &grpc.UnaryServerInfo{FullMethod: tt.method},
+				func(context.Context, interface{}) (interface{}, error) {
+					return nil, nil
+				})
+			assert.Equal(t, tt.expectedCode, status.Code(err))
+		})
+	}
+}
@@ -0,0 +1,49 @@
+package middleware
+
+import (
+	"context"
+	"github.com/gin-gonic/gin"
+	"githum.com/anupam111/image-store/internal/db/dbconnection"
+	"google.golang.org/grpc"
+)
+
+// DBSession gives every request a database session, so that the request
+// reads its own writes even when reads are served by replicas.
+func DBSession() gin.HandlerFunc {
+	return func(ginCtx *gin.Context) {
+		ginCtx.Request = ginCtx.Request.WithContext(dbconnection.WithSession(ginCtx.Request.Context()))
+		ginCtx.Next()
+	}
+}
+
+// DBSessionUnaryInterceptor is DBSession for unary gRPC calls.
+func DBSessionUnaryInterceptor(
+	ctx context.Context,
+	req interface{},
+	_ *grpc.UnaryServerInfo,
+	handler grpc.UnaryHandler,
+) (interface{}, error) {
+	return handler(dbconnection.WithSession(ctx), req)
+}
+
+// DBSessionStreamInterceptor is DBSession for streaming gRPC calls.
+func DBSessionStreamInterceptor(
+	srv interface{},
+	stream grpc.ServerStream,
+	_ *grpc.StreamServerInfo,
+	handler grpc.StreamHandler,
+) error {
+	return handler(srv, &sessionStream{
+		ServerStream: stream,
+		ctx:          dbconnection.WithSession(stream.Context()),
+	})
+}
+
+type sessionStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *sessionStream) Context() context.Context {
+	return s.ctx
+}
@@ -0,0 +1,239 @@
+package middleware
+
+import (
+	"context"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	log "github.com/sirupsen/logrus"
+	"githum.com/anupam111/image-store/internal/auth"
+	"githum.com/anupam111/image-store/internal/config"
+	"githum.com/anupam111/image-store/internal/models"
+	"githum.com/anupam111/image-store/internal/ratelimit"
+	"githum.com/anupam111/image-store/internal/tenant"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const (
+	// defaultBucket names the bucket of the routes without a limit of their
+	// own.
+	defaultBucket = "*"
+	// ipBucket names the bucket every request of an IP takes from before it
+	// is authenticated.
+	ipBucket = "ip"
+)
+
+// allowFunc takes a token from a bucket of the client for route.
+type allowFunc func(ctx context.Context, route, clientIP string) (config.RateLimit, ratelimit.Result, bool)
+
+// RateLimit limits the requests of every client with a token bucket. The
+// client is the principal of the request, set by Auth, or else its IP; Auth
+// must therefore come first. A route with an entry in routeLimits has a
+// bucket of its own, the others share the bucket of defaultLimit. Every IP
+// has besides a bucket of ipLimit, taken from before Auth so that the
+// requests failing authentication are limited too.
+type RateLimit struct {
+	log          *log.Logger
+	limiter      ratelimit.Limiter
+	defaultLimit config.RateLimit
+	routeLimits  config.RouteRateLimits
+	ipLimit      config.RateLimit
+}
+
+// NewRateLimit implements RateLimit.
+func NewRateLimit(
+	logger *log.Logger,
+	limiter ratelimit.Limiter,
+	defaultLimit config.RateLimit,
+	routeLimits config.RouteRateLimits,
+	ipLimit config.RateLimit,
+) *RateLimit {
+	return &RateLimit{
+		log:          logger,
+		limiter:      limiter,
+		defaultLimit: defaultLimit,
+		routeLimits:  routeLimits,
+		ipLimit:      ipLimit,
+	}
+}
+
+// Handler answers 429 to the requests of a client whose bucket is empty,
+// with the seconds to wait in Retry-After. Every limited response carries
+// the RateLimit-Limit, RateLimit-Remaining, RateLimit-Reset and
+// RateLimit-Policy headers.
+func (r *RateLimit) Handler() gin.HandlerFunc {
+	return r.handler(r.allow)
+}
+
+// IPHandler is Handler for the bucket of the IP of the request, whatever
+// its route. It comes before Auth.
+func (r *RateLimit) IPHandler() gin.HandlerFunc {
+	return r.handler(r.allowIP)
+}
+
+func (r *RateLimit) handler(allow allowFunc) gin.HandlerFunc {
+	return func(ginCtx *gin.Context) {
+		ctx := ginCtx.Request.Context()
+
+		limit, result, ok := allow(ctx, ginCtx.Request.Method+" "+ginCtx.FullPath(), ginCtx.ClientIP())
+		if !ok {
+			ginCtx.Next()
+
+			return
+		}
+
+		header := ginCtx.Writer.Header()
+		header.Set("RateLimit-Limit", strconv.Itoa(result.Limit))
+		header.Set("RateLimit-Remaining", strconv.Itoa(result.Remaining))
+		header.Set("RateLimit-Reset", seconds(result.Reset))
+		header.Set("RateLimit-Policy", fmt.Sprintf("%d;w=%s", limit.Requests, seconds(limit.Period)))
+
+		if !result.Allowed {
+			header.Set("Retry-After", seconds(result.RetryAfter))
+			ginCtx.AbortWithStatusJSON(http.StatusTooManyRequests, models.ResponseError{
+				HTTPStatusCode: http.StatusTooManyRequests,
+				ErrorCode:      "TOO-MANY-REQUESTS",
+				MessageDetails: "rate limit of " + limit.String() + " exceeded",
+			})
+
+			return
+		}
+
+		ginCtx.Next()
+	}
+}
+
+// UnaryInterceptor is Handler for unary gRPC calls, routes being the full
+// method names. A denied call fails with codes.ResourceExhausted and the
+// seconds to wait in the retry-after header.
+func (r *RateLimit) UnaryInterceptor() grpc.UnaryServerInterceptor {
+	return r.unaryInterceptor(r.allow)
+}
+
+// IPUnaryInterceptor is IPHandler for unary gRPC calls.
+func (r *RateLimit) IPUnaryInterceptor() grpc.UnaryServerInterceptor {
+	return r.unaryInterceptor(r.allowIP)
+}
+
+func (r *RateLimit) unaryInterceptor(allow allowFunc) grpc.UnaryServerInterceptor {
+	return func(
+		ctx context.Context,
+		req interface{},
+		info *grpc.UnaryServerInfo,
+		handler grpc.UnaryHandler,
+	) (interface{}, error) {
+		if err := r.allowGRPC(ctx, allow, info.FullMethod, func(md metadata.MD) error {
+			return grpc.SetHeader(ctx, md)
+		}); err != nil {
+			return nil, err
+		}
+
+		return handler(ctx, req)
+	}
+}
+
+// StreamInterceptor is Handler for streaming gRPC calls, limiting the calls
+// and not the messages of the streams.
+func (r *RateLimit) StreamInterceptor() grpc.StreamServerInterceptor {
+	return r.streamInterceptor(r.allow)
+}
+
+// IPStreamInterceptor is IPHandler for streaming gRPC calls.
+func (r *RateLimit) IPStreamInterceptor() grpc.StreamServerInterceptor {
+	return r.streamInterceptor(r.allowIP)
+}
+
+func (r *RateLimit) streamInterceptor(allow allowFunc) grpc.StreamServerInterceptor {
+	return func(
+		srv interface{},
+		stream grpc.ServerStream,
+		info *grpc.StreamServerInfo,
+		handler grpc.StreamHandler,
+	) error {
+		if err := r.allowGRPC(stream.Context(), allow, info.FullMethod, stream.SetHeader); err != nil {
+			return err
+		}
+
+		return handler(srv, stream)
+	}
+}
+
+func (r *RateLimit) allowGRPC(ctx context.Context, allow allowFunc, method string, setHeader func(metadata.MD) error) error {
+	limit, result, ok := allow(ctx, method, peerIP(ctx))
+	if !ok || result.Allowed {
+		return nil
+	}
+
+	if err := setHeader(metadata.Pairs("retry-after", seconds(result.RetryAfter))); err != nil {
+		r.log.Warnf("error while setting retry-after header: %v", err)
+	}
+
+	return status.Errorf(codes.ResourceExhausted, "rate limit of %s exceeded, retry in %s",
+		limit.String(), result.RetryAfter.Round(time.Millisecond))
+}
+
+// allow takes a token from the bucket of the client for route, false when
+// the route is unlimited.
+func (r *RateLimit) allow(ctx context.Context, route, clientIP string) (config.RateLimit, ratelimit.Result, bool) {
+	name, limit := defaultBucket, r.defaultLimit
+	if routeLimit, ok := r.routeLimits[route]; ok {
+		name, limit = route, routeLimit
+	}
+
+	client := "ip:" + clientIP
+	if principal, ok := auth.FromContext(ctx); ok {
+		client = principalClient(ctx, principal)
+	}
+
+	return r.take(ctx, route, client+" "+name, limit)
+}
+
+// allowIP takes a token from the bucket of clientIP, false when the IPs are
+// unlimited.
+func (r *RateLimit) allowIP(ctx context.Context, route, clientIP string) (config.RateLimit, ratelimit.Result, bool) {
+	return r.take(ctx, route, "ip:"+clientIP+" "+ipBucket, r.ipLimit)
+}
+
+// take takes a token from bucket, false when limit is unlimited. A request is
+// let through when the limiter fails, the rate limit is not worth failing the
+// requests.
+func (r *RateLimit) take(
+	ctx context.Context,
+	route, bucket string,
+	limit config.RateLimit,
+) (config.RateLimit, ratelimit.Result, bool) {
+	if limit.Unlimited() {
+		return limit, ratelimit.Result{}, false
+	}
+
+	result, err := r.limiter.Allow(ctx, bucket, limit)
+	if err != nil {
+		r.log.Warnf("error while rate limiting %s, letting the request through: %v", route, err)
+
+		return limit, ratelimit.Result{}, false
+	}
+
+	return limit, result, true
+}
+
+// principalClient identifies the principal within the tenant of the request,
+// an API key by its id and the others by their subject, so that no token
+// subject can name the bucket of an API key.
+func principalClient(ctx context.Context, principal auth.Principal) string {
+	if principal.APIKeyID != "" {
+		return tenant.FromContext(ctx) + "/apikey:" + principal.APIKeyID
+	}
+
+	return tenant.FromContext(ctx) + "/user:" + principal.Subject
+}
+
+// seconds formats d as whole seconds, rounded up.
+func seconds(d time.Duration) string {
+	return strconv.FormatInt(int64(math.Ceil(d.Seconds())), 10)
+}
@@ -0,0 +1,201 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"github.com/gin-gonic/gin"
+	log "github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"githum.com/anupam111/image-store/internal/auth"
+	"githum.com/anupam111/image-store/internal/config"
+	"githum.com/anupam111/image-store/internal/ratelimit"
+	"githum.com/anupam111/image-store/internal/tenant"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+type failingLimiter struct{}
+
+func (failingLimiter) Allow(context.Context, string, config.RateLimit) (ratelimit.Result, error) {
+	return ratelimit.Result{}, errors.New("connection refused")
+}
+
+func (failingLimiter) Sweep(context.Context, time.Duration) error {
+	return nil
+}
+
+func Test_RateLimit(t *testing.T) {
+	t.Parallel()
+
+	type request struct {
+		method, path, subject, apiKeyID, tenant, ip string
+		expectedStatus                              int
+	}
+
+	perMinute := func(requests int) config.RateLimit {
+		return config.RateLimit{Requests: requests, Period: time.Minute}
+	}
+
+	tests := []struct {
+		name         string
+		limiter      ratelimit.Limiter
+		defaultLimit config.RateLimit
+		routeLimits  config.RouteRateLimits
+		ipLimit      config.RateLimit
+		requests     []request
+	}{
+		{
+			name:         "default bucket shared by the routes",
+			defaultLimit: perMinute(2),
+			requests: []request{
+				{method: http.MethodGet, path: "/images/1", ip: "10.0.0.1", expectedStatus: http.StatusOK},
+				{method: http.MethodPost, path: "/images", ip: "10.0.0.1", expectedStatus: http.StatusOK},
+				{method: http.MethodGet, path: "/images/2", ip: "10.0.0.1", expectedStatus: http.StatusTooManyRequests},
+				{method: http.MethodGet, path: "/images/2", ip: "10.0.0.2", expectedStatus: http.StatusOK},
+			},
+		},
+		{
+			name:         "route with a bucket of its own",
+			defaultLimit: perMinute(100),
+			routeLimits:  config.RouteRateLimits{"POST /images": perMinute(1)},
+			requests: []request{
+				{method: http.MethodPost, path: "/images", ip: "10.0.0.1", expectedStatus: http.StatusOK},
+				{method: http.MethodPost, path: "/images", ip: "10.0.0.1", expectedStatus: http.StatusTooManyRequests},
+				{method: http.MethodGet, path: "/images/1", ip: "10.0.0.1", expectedStatus: http.StatusOK},
+			},
+		},
+		{
+			name:        "principal limited across addresses",
+			routeLimits: config.RouteRateLimits{"POST /images": perMinute(1)},
+			requests: []request{
+				{method: http.MethodPost, path: "/images", subject: "apikey:1", ip: "10.0.0.1", expectedStatus: http.StatusOK},
+				{method: http.MethodPost, path: "/images", subject: "apikey:1", ip: "10.0.0.2", expectedStatus: http.StatusTooManyRequests},
+				{method: http.MethodPost, path: "/images", subject: "apikey:2", ip: "10.0.0.1", expectedStatus: http.StatusOK},
+				{method: http.MethodPost, path: "/images", ip: "10.0.0.1", expectedStatus: http.StatusOK},
+			},
+		},
+		{
+			name:        "api key not limited by a token of the same subject",
+			routeLimits: config.RouteRateLimits{"POST /images": perMinute(1)},
+			requests: []request{
+				{method: http.MethodPost, path: "/images", subject: "apikey:1", apiKeyID: "1", ip: "10.0.0.1", expectedStatus: http.StatusOK},
+				{method: http.MethodPost, path: "/images", subject: "apikey:1", ip: "10.0.0.2", expectedStatus: http.StatusOK},
+				{method: http.MethodPost, path: "/images", subject: "apikey:1", apiKeyID: "1", ip: "10.0.0.1", expectedStatus: http.StatusTooManyRequests},
+			},
+		},
+		{
+			name:        "principal limited per tenant",
+			routeLimits: config.RouteRateLimits{"POST /images": perMinute(1)},
+			requests: []request{
+				{method: http.MethodPost, path: "/images", subject: "admin", tenant: "acme", ip: "10.0.0.1", expectedStatus: http.StatusOK},
+				{method: http.MethodPost, path: "/images", subject: "admin", tenant: "globex", ip: "10.0.0.1", expectedStatus: http.StatusOK},
+				{method: http.MethodPost, path: "/images", subject: "admin", tenant: "acme", ip: "10.0.0.1", expectedStatus: http.StatusTooManyRequests},
+			},
+		},
+		{
+			name:         "ip limited before authentication",
+			defaultLimit: perMinute(100),
+			ipLimit:      perMinute(2),
+			requests: []request{
+				{method: http.MethodGet, path: "/images/1", subject: "apikey:1", apiKeyID: "1", ip: "10.0.0.1", expectedStatus: http.StatusOK},
+				{method: http.MethodGet, path: "/images/1", subject: "apikey:2", apiKeyID: "2", ip: "10.0.0.1", expectedStatus: http.StatusOK},
+				{method: http.MethodGet, path: "/images/1", subject: "apikey:3", apiKeyID: "3", ip: "10.0.0.1", expectedStatus: http.StatusTooManyRequests},
+				{method: http.MethodGet, path: "/images/1", subject: "apikey:1", apiKeyID: "1", ip: "10.0.0.2", expectedStatus: http.StatusOK},
+			},
+		},
+		{
+			name: "unlimited",
+			requests: []request{
+				{method: http.MethodPost, path: "/images", ip: "10.0.0.1", expectedStatus: http.StatusOK},
+				{method: http.MethodPost, path: "/images", ip: "10.0.0.1", expectedStatus: http.StatusOK},
+			},
+		},
+		{
+			name:         "failing limiter lets requests through",
+			limiter:      failingLimiter{},
+			defaultLimit: perMinute(1),
+			requests: []request{
+				{method: http.MethodPost, path: "/images", ip: "10.0.0.1", expectedStatus: http.StatusOK},
+				{method: http.MethodPost, path: "/images", ip: "10.0.0.1", expectedStatus: http.StatusOK},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			limiter := tt.limiter
+			if limiter == nil {
+				limiter = ratelimit.NewMemoryLimiter()
+			}
+
+			rateLimit := NewRateLimit(log.New(), limiter, tt.defaultLimit, tt.routeLimits, tt.ipLimit)
+
+			authenticate := func(ginCtx *gin.Context) {
+				if subject := ginCtx.GetHeader("X-Subject"); subject != "" {
+					principal := auth.Principal{Subject: subject, APIKeyID: ginCtx.GetHeader("X-API-Key-ID")}
+					ctx := auth.NewContext(ginCtx.Request.Context(), principal)
+					ctx = tenant.NewContext(ctx, ginCtx.GetHeader(tenant.Header))
+					ginCtx.Request = ginCtx.Request.WithContext(ctx)
+				}
+			}
+
+			ok := func(ginCtx *gin.Context) { ginCtx.Status(http.StatusOK) }
+
+			router := gin.New()
+			router.GET("/images/:id", rateLimit.IPHandler(), authenticate, rateLimit.Handler(), ok)
+			router.POST("/images", rateLimit.IPHandler(), authenticate, rateLimit.Handler(), ok)
+
+			for _, r := range tt.requests {
+				req := httptest.NewRequest(r.method, r.path, nil)
+				req.RemoteAddr = r.ip + ":1234"
+				req.Header.Set("X-Subject", r.subject)
+				req.Header.Set("X-API-Key-ID", r.apiKeyID)
+				req.Header.Set(tenant.Header, r.tenant)
+
+				rec := httptest.NewRecorder()
+				router.ServeHTTP(rec, req)
+
+				assert.Equal(t, r.expectedStatus, rec.Code, "%s %s", r.method, r.path)
+
+				if r.expectedStatus == http.StatusTooManyRequests {
+					assert.NotEmpty(t, rec.Header().Get("Retry-After"))
+					assert.Equal(t, "0", rec.Header().Get("RateLimit-Remaining"))
+					assert.NotEmpty(t, rec.Header().Get("RateLimit-Limit"))
+					assert.NotEmpty(t, rec.Header().Get("RateLimit-Reset"))
+					assert.NotEmpty(t, rec.Header().Get("RateLimit-Policy"))
+				}
+			}
+		})
+	}
+}
+
+func Test_RateLimitUnaryInterceptor(t *testing.T) {
+	t.Parallel()
+
+	rateLimit := NewRateLimit(log.New(), ratelimit.NewMemoryLimiter(), config.RateLimit{},
+		config.RouteRateLimits{"/imagestore.v1.ImageStore/UploadImages": {Requests: 1, Period: time.Minute}},
+		config.RateLimit{})
+	interceptor := rateLimit.UnaryInterceptor()
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) { return "ok", nil }
+
+	ctx := auth.NewContext(context.Background(), auth.Principal{Subject: "apikey:1"})
+	upload := &grpc.UnaryServerInfo{FullMethod: "/imagestore.v1.ImageStore/UploadImages"}
+	list := &grpc.UnaryServerInfo{FullMethod: "/imagestore.v1.ImageStore/ListImages"}
+
+	_, err := interceptor(ctx, nil, upload, handler)
+	assert.NoError(t, err)
+
+	_, err = interceptor(ctx, nil, upload, handler)
+	assert.Equal(t, codes.ResourceExhausted, status.Code(err))
+
+	_, err = interceptor(ctx, nil, list, handler)
+	assert.NoError(t, err)
+}
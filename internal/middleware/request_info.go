@@ -0,0 +1,96 @@
+package middleware
+
+import (
+	"context"
+	"github.com/gin-gonic/gin"
+	"githum.com/anupam111/image-store/internal/requestinfo"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"net"
+)
+
+// RequestInfo gives every request an id, the one of its X-Request-ID header
+// when it is valid or else a new one, echoed in the X-Request-ID response
+// header. The id and the client IP are then available with
+// requestinfo.FromContext.
+func RequestInfo() gin.HandlerFunc {
+	return func(ginCtx *gin.Context) {
+		id := ginCtx.GetHeader(requestinfo.Header)
+		if !requestinfo.Valid(id) {
+			id = requestinfo.NewID()
+		}
+
+		ginCtx.Header(requestinfo.Header, id)
+		ginCtx.Request = ginCtx.Request.WithContext(requestinfo.NewContext(ginCtx.Request.Context(), requestinfo.Info{
+			ID:       id,
+			ClientIP: ginCtx.ClientIP(),
+		}))
+		ginCtx.Next()
+	}
+}
+
+// RequestInfoUnaryInterceptor is RequestInfo for unary gRPC calls.
+func RequestInfoUnaryInterceptor(
+	ctx context.Context,
+	req interface{},
+	_ *grpc.UnaryServerInfo,
+	handler grpc.UnaryHandler,
+) (interface{}, error) {
+	ctx, md := withRequestInfo(ctx)
+	// Echoing the id is best effort, the call goes on without it.
+	_ = grpc.SetHeader(ctx, md)
+
+	return handler(ctx, req)
+}
+
+// RequestInfoStreamInterceptor is RequestInfo for streaming gRPC calls.
+func RequestInfoStreamInterceptor(
+	srv interface{},
+	stream grpc.ServerStream,
+	_ *grpc.StreamServerInfo,
+	handler grpc.StreamHandler,
+) error {
+	ctx, md := withRequestInfo(stream.Context())
+	_ = stream.SetHeader(md)
+
+	return handler(srv, &sessionStream{
+		ServerStream: stream,
+		ctx:          ctx,
+	})
+}
+
+// withRequestInfo returns the context of a gRPC call with its request info,
+// and the header echoing its id.
+func withRequestInfo(ctx context.Context) (context.Context, metadata.MD) {
+	var id string
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if values := md.Get(requestinfo.Header); len(values) > 0 {
+			id = values[0]
+		}
+	}
+
+	if !requestinfo.Valid(id) {
+		id = requestinfo.NewID()
+	}
+
+	return requestinfo.NewContext(ctx, requestinfo.Info{
+		ID:       id,
+		ClientIP: peerIP(ctx),
+	}), metadata.Pairs(requestinfo.Header, id)
+}
+
+// peerIP returns the IP of the client of a gRPC call.
+func peerIP(ctx context.Context) string {
+	p, ok := peer.FromContext(ctx)
+	if !ok {
+		return ""
+	}
+
+	addr := p.Addr.String()
+	if host, _, err := net.SplitHostPort(addr); err == nil {
+		return host
+	}
+
+	return addr
+}
@@ -0,0 +1,75 @@
+package middleware
+
+import (
+	"context"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"githum.com/anupam111/image-store/internal/requestinfo"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func Test_RequestInfo(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name       string
+		header     string
+		expectedID string
+	}{
+		{name: "id of the request", header: "client-1234", expectedID: "client-1234"},
+		{name: "new id without header"},
+		{name: "new id for invalid header", header: "no spaces allowed"},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			var info requestinfo.Info
+
+			router := gin.New()
+			router.Use(RequestInfo())
+			router.GET("/", func(ginCtx *gin.Context) {
+				info = requestinfo.FromContext(ginCtx.Request.Context())
+			})
+
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.RemoteAddr = "10.0.0.1:1234"
+			req.Header.Set(requestinfo.Header, tt.header)
+
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, req)
+
+			if tt.expectedID != "" {
+				assert.Equal(t, tt.expectedID, info.ID)
+			} else {
+				assert.Len(t, info.ID, 32)
+			}
+
+			assert.Equal(t, info.ID, rec.Header().Get(requestinfo.Header))
+			assert.Equal(t, "10.0.0.1", info.ClientIP)
+		})
+	}
+}
+
+func Test_RequestInfoUnaryInterceptor(t *testing.T) {
+	t.Parallel()
+
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs(requestinfo.Header, "client-1234"))
+
+	var info requestinfo.Info
+
+	_, err := RequestInfoUnaryInterceptor(ctx, nil, &grpc.UnaryServerInfo{},
+		func(ctx context.Context, req interface{}) (interface{}, error) {
+			info = requestinfo.FromContext(ctx)
+
+			return nil, nil
+		})
+	assert.NoError(t, err)
+	assert.Equal(t, "client-1234", info.ID)
+}
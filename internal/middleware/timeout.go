@@ -0,0 +1,45 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"github.com/gin-gonic/gin"
+	"githum.com/anupam111/image-store/internal/config"
+	"githum.com/anupam111/image-store/internal/models"
+	"net/http"
+	"time"
+)
+
+// Timeout puts a deadline on the context of every request. The deadline of a
+// route is looked up in routeTimeouts by method and full path, and defaults to
+// defaultTimeout; a timeout of 0 leaves the request without deadline. When the
+// deadline expires before the handler responded, the request is answered with
+// 504.
+func Timeout(defaultTimeout time.Duration, routeTimeouts config.RouteTimeouts) gin.HandlerFunc {
+	return func(ginCtx *gin.Context) {
+		timeout := defaultTimeout
+		if routeTimeout, ok := routeTimeouts[ginCtx.Request.Method+" "+ginCtx.FullPath()]; ok {
+			timeout = routeTimeout
+		}
+
+		if timeout <= 0 {
+			ginCtx.Next()
+
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(ginCtx.Request.Context(), timeout)
+		defer cancel()
+
+		ginCtx.Request = ginCtx.Request.WithContext(ctx)
+		ginCtx.Next()
+
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) && !ginCtx.Writer.Written() {
+			ginCtx.AbortWithStatusJSON(http.StatusGatewayTimeout, models.ResponseError{
+				HTTPStatusCode: http.StatusGatewayTimeout,
+				ErrorCode:      "GATEWAY-TIMEOUT",
+				MessageDetails: "request exceeded its deadline of " + timeout.String(),
+			})
+		}
+	}
+}
@@ -0,0 +1,62 @@
+package middleware
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"githum.com/anupam111/image-store/internal/config"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func Test_Timeout(t *testing.T) {
+	t.Parallel()
+
+	slowHandler := func(ginCtx *gin.Context) {
+		select {
+		case <-ginCtx.Request.Context().Done():
+		case <-time.After(time.Second):
+			ginCtx.Status(http.StatusOK)
+		}
+	}
+
+	tests := []struct {
+		name           string
+		defaultTimeout time.Duration
+		routeTimeouts  config.RouteTimeouts
+		expectedStatus int
+	}{
+		{
+			name:           "default deadline exceeded",
+			defaultTimeout: 10 * time.Millisecond,
+			expectedStatus: http.StatusGatewayTimeout,
+		},
+		{
+			name:           "route deadline overrides default",
+			defaultTimeout: 10 * time.Millisecond,
+			routeTimeouts:  config.RouteTimeouts{"GET /slow/:id": 5 * time.Second},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "zero timeout disables deadline",
+			expectedStatus: http.StatusOK,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			router := gin.New()
+			router.Use(Timeout(tt.defaultTimeout, tt.routeTimeouts))
+			router.GET("/slow/:id", slowHandler)
+
+			req := httptest.NewRequest(http.MethodGet, "/slow/1", nil)
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, req)
+
+			assert.Equal(t, tt.expectedStatus, rec.Code)
+		})
+	}
+}
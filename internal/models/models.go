@@ -1,5 +1,10 @@
 package models
 
+import (
+	"githum.com/anupam111/image-store/internal/db/dbmodels"
+	"time"
+)
+
 // ResponseError model for err response.
 type ResponseError struct {
 	HTTPStatusCode int      `json:"httpStatusCode"`
@@ -7,3 +12,253 @@ type ResponseError struct {
 	Recommendation []string `json:"recommendation"`
 	MessageDetails string   `json:"messageDetails"`
 }
+
+// ImageRef identifies an image of an album in a request payload.
+type ImageRef struct {
+	ImageName string `json:"imageName"`
+	AlbumName string `json:"albumName"`
+}
+
+// BatchCreateImagesRequest model for batch image upload request.
+type BatchCreateImagesRequest struct {
+	AllOrNothing bool             `json:"allOrNothing"`
+	Images       []dbmodels.Image `json:"images"`
+}
+
+// BatchDeleteImagesRequest model for batch image delete request.
+type BatchDeleteImagesRequest struct {
+	AllOrNothing bool       `json:"allOrNothing"`
+	Images       []ImageRef `json:"images"`
+}
+
+// BatchItemResult model for the outcome of a single item of a batch request.
+type BatchItemResult struct {
+	Index          int    `json:"index"`
+	ImageName      string `json:"imageName"`
+	AlbumName      string `json:"albumName"`
+	HTTPStatusCode int    `json:"httpStatusCode"`
+	ErrorCode      string `json:"errorCode,omitempty"`
+	MessageDetails string `json:"messageDetails,omitempty"`
+}
+
+// BatchResponse model for batch response.
+type BatchResponse struct {
+	Succeeded int               `json:"succeeded"`
+	Failed    int               `json:"failed"`
+	Results   []BatchItemResult `json:"results"`
+}
+
+// Envelope model wrapping every v2 response.
+type Envelope struct {
+	Data  interface{}       `json:"data,omitempty"`
+	Links map[string]string `json:"links,omitempty"`
+	Error *ResponseError    `json:"error,omitempty"`
+}
+
+// AlbumResource model for the v2 album representation.
+type AlbumResource struct {
+	Name  string            `json:"name"`
+	Links map[string]string `json:"links"`
+}
+
+// ImageResource model for the v2 image representation.
+type ImageResource struct {
+	Name     string            `json:"name"`
+	Album    string            `json:"album"`
+	Image    string            `json:"image"`
+	Metadata dbmodels.Metadata `json:"metadata,omitempty"`
+	Links    map[string]string `json:"links"`
+}
+
+// AlbumRequest model for v2 album create request.
+type AlbumRequest struct {
+	Name string `json:"name"`
+}
+
+// ImageRequest model for v2 image replace request.
+type ImageRequest struct {
+	Image    string            `json:"image"`
+	Metadata dbmodels.Metadata `json:"metadata"`
+}
+
+// ImagePatchRequest model for v2 image partial update request. Absent fields
+// are left unchanged. Metadata is merged into the metadata of the image, a
+// null value removes its key.
+type ImagePatchRequest struct {
+	Name     *string                `json:"name"`
+	Image    *string                `json:"image"`
+	Metadata map[string]interface{} `json:"metadata"`
+}
+
+// GraphQLRequest model for graphql request.
+type GraphQLRequest struct {
+	Query         string                 `json:"query"`
+	OperationName string                 `json:"operationName"`
+	Variables     map[string]interface{} `json:"variables"`
+}
+
+// HealthStatus model for the service status response.
+type HealthStatus struct {
+	Status   string `json:"status"`
+	Database string `json:"database"`
+	Details  string `json:"details,omitempty"`
+}
+
+// SearchResponse is a page of the images matching a search.
+type SearchResponse struct {
+	Query  string      `json:"query"`
+	Total  int         `json:"total"`
+	Limit  int         `json:"limit"`
+	Offset int         `json:"offset"`
+	Hits   []SearchHit `json:"hits"`
+}
+
+// SearchHit is an image matching a search. Highlights hold the names, and the
+// string values of the metadata keys which match as "metadata.<key>", HTML
+// escaped, with the matched words wrapped in <mark>.
+type SearchHit struct {
+	ImageName  string            `json:"imageName"`
+	AlbumName  string            `json:"albumName"`
+	Rank       float64           `json:"rank"`
+	Highlights map[string]string `json:"highlights"`
+}
+
+// AlbumAccess model for an access to an album, granting role to a user or a
+// group.
+type AlbumAccess struct {
+	PrincipalType string `json:"principalType"`
+	Principal     string `json:"principal"`
+	Role          string `json:"role"`
+}
+
+// AlbumShareRequest model for the album share create request. The share never
+// expires without ExpiresAt and needs no password without Password.
+type AlbumShareRequest struct {
+	ExpiresAt     *time.Time `json:"expiresAt"`
+	Password      string     `json:"password"`
+	AllowDownload bool       `json:"allowDownload"`
+}
+
+// AlbumShare model for an album share, which never shows its token nor its
+// password.
+type AlbumShare struct {
+	ID            string     `json:"id"`
+	AlbumName     string     `json:"albumName"`
+	HasPassword   bool       `json:"hasPassword"`
+	AllowDownload bool       `json:"allowDownload"`
+	CreatedBy     string     `json:"createdBy,omitempty"`
+	CreatedAt     time.Time  `json:"createdAt"`
+	ExpiresAt     *time.Time `json:"expiresAt,omitempty"`
+	RevokedAt     *time.Time `json:"revokedAt,omitempty"`
+}
+
+// CreatedAlbumShare model for the album share create response, the only
+// response with the token of the share and its public url.
+type CreatedAlbumShare struct {
+	AlbumShare
+	Token string `json:"token"`
+	URL   string `json:"url"`
+}
+
+// SharedAlbum model for the public view of a shared album.
+type SharedAlbum struct {
+	AlbumName     string        `json:"albumName"`
+	AllowDownload bool          `json:"allowDownload"`
+	ExpiresAt     *time.Time    `json:"expiresAt,omitempty"`
+	Images        []SharedImage `json:"images"`
+}
+
+// SharedImage model for an image of a shared album, URL serving its
+// content.
+type SharedImage struct {
+	ImageName string            `json:"imageName"`
+	Metadata  dbmodels.Metadata `json:"metadata,omitempty"`
+	URL       string            `json:"url"`
+}
+
+// SignedURLRequest model for the signed url request. ExpiresIn is the
+// lifetime of the url in seconds, Transform the optional derivative spec the
+// url is bound to, such as "w=200,h=100" to scale the image down to fit.
+type SignedURLRequest struct {
+	AlbumName string `json:"albumName"`
+	ImageName string `json:"imageName"`
+	Transform string `json:"transform"`
+	ExpiresIn int    `json:"expiresIn"`
+}
+
+// SignedURL model for an url of image content which needs no credentials
+// until it expires.
+type SignedURL struct {
+	URL       string    `json:"url"`
+	ExpiresAt time.Time `json:"expiresAt"`
+}
+
+// APIKeyRequest model for the api key create request.
+type APIKeyRequest struct {
+	Name   string   `json:"name"`
+	Tenant string   `json:"tenant,omitempty"`
+	Scopes []string `json:"scopes"`
+}
+
+// APIKey model for an api key, which never shows the key itself.
+type APIKey struct {
+	ID        string     `json:"id"`
+	Name      string     `json:"name"`
+	Tenant    string     `json:"tenant,omitempty"`
+	Scopes    []string   `json:"scopes"`
+	CreatedAt time.Time  `json:"createdAt"`
+	RevokedAt *time.Time `json:"revokedAt,omitempty"`
+}
+
+// CreatedAPIKey model for the api key create response, the only response
+// with the key.
+type CreatedAPIKey struct {
+	APIKey
+	Key string `json:"key"`
+}
+
+// QuotaUsage model for what is used of a quota. Limit is left out when there
+// is no quota.
+type QuotaUsage struct {
+	Used  int64 `json:"used"`
+	Limit int64 `json:"limit,omitempty"`
+}
+
+// AlbumUsage model for the images and bytes stored in an album.
+type AlbumUsage struct {
+	AlbumName string     `json:"albumName"`
+	Images    QuotaUsage `json:"images"`
+	Bytes     QuotaUsage `json:"bytes"`
+}
+
+// Usage model for the usage response, the images and bytes stored by the
+// tenant and by the albums the caller can view.
+type Usage struct {
+	Tenant string       `json:"tenant"`
+	Images QuotaUsage   `json:"images"`
+	Bytes  QuotaUsage   `json:"bytes"`
+	Albums []AlbumUsage `json:"albums"`
+}
+
+// AuditEvent model for an event of the audit log.
+type AuditEvent struct {
+	ID         int64     `json:"id"`
+	OccurredAt time.Time `json:"occurredAt"`
+	Actor      string    `json:"actor"`
+	Action     string    `json:"action"`
+	AlbumName  string    `json:"albumName,omitempty"`
+	Target     string    `json:"target"`
+	Details    string    `json:"details,omitempty"`
+	RequestID  string    `json:"requestId,omitempty"`
+	ClientIP   string    `json:"clientIp,omitempty"`
+	Outcome    string    `json:"outcome"`
+	Error      string    `json:"error,omitempty"`
+}
+
+// AuditLog model for a page of the audit log, latest event first.
+type AuditLog struct {
+	Total  int          `json:"total"`
+	Limit  int          `json:"limit"`
+	Offset int          `json:"offset"`
+	Events []AuditEvent `json:"events"`
+}
@@ -0,0 +1,71 @@
+package ratelimit
+
+import (
+	"context"
+	"githum.com/anupam111/image-store/internal/config"
+	"math"
+	"sync"
+	"time"
+)
+
+type bucket struct {
+	tokens    float64
+	updatedAt time.Time
+}
+
+// MemoryLimiter keeps the buckets in memory. Every replica limits on its own
+// the requests it serves.
+type MemoryLimiter struct {
+	mu      sync.Mutex
+	buckets map[string]*bucket
+	now     func() time.Time
+}
+
+// NewMemoryLimiter implements MemoryLimiter.
+func NewMemoryLimiter() *MemoryLimiter {
+	return &MemoryLimiter{
+		buckets: map[string]*bucket{},
+		now:     time.Now,
+	}
+}
+
+// Allow implements Limiter.
+func (m *MemoryLimiter) Allow(_ context.Context, key string, limit config.RateLimit) (Result, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := m.now()
+	size := float64(limit.Requests)
+
+	b, ok := m.buckets[key]
+	if !ok {
+		b = &bucket{tokens: size}
+		m.buckets[key] = b
+	} else {
+		b.tokens = math.Min(size, b.tokens+now.Sub(b.updatedAt).Seconds()*limit.PerSecond())
+	}
+
+	b.updatedAt = now
+
+	allowed := b.tokens >= 1
+	if allowed {
+		b.tokens--
+	}
+
+	return newResult(allowed, b.tokens, limit), nil
+}
+
+// Sweep implements Limiter.
+func (m *MemoryLimiter) Sweep(_ context.Context, idle time.Duration) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := m.now()
+	for key, b := range m.buckets {
+		if now.Sub(b.updatedAt) > idle {
+			delete(m.buckets, key)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,65 @@
+package ratelimit
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"githum.com/anupam111/image-store/internal/config"
+	"testing"
+	"time"
+)
+
+func TestMemoryLimiter(t *testing.T) {
+	t.Parallel()
+
+	limit := config.RateLimit{Requests: 2, Period: 2 * time.Second}
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	limiter := NewMemoryLimiter()
+	limiter.now = func() time.Time { return now }
+
+	ctx := context.Background()
+
+	result, err := limiter.Allow(ctx, "client", limit)
+	assert.NoError(t, err)
+	assert.Equal(t, Result{Allowed: true, Limit: 2, Remaining: 1, Reset: time.Second}, result)
+
+	result, err = limiter.Allow(ctx, "client", limit)
+	assert.NoError(t, err)
+	assert.Equal(t, Result{Allowed: true, Limit: 2, Remaining: 0, RetryAfter: time.Second, Reset: 2 * time.Second}, result)
+
+	result, err = limiter.Allow(ctx, "client", limit)
+	assert.NoError(t, err)
+	assert.False(t, result.Allowed)
+	assert.Equal(t, time.Second, result.RetryAfter)
+
+	// Another key has a bucket of its own.
+	result, err = limiter.Allow(ctx, "other", limit)
+	assert.NoError(t, err)
+	assert.True(t, result.Allowed)
+
+	// Half a second refills half a token.
+	now = now.Add(500 * time.Millisecond)
+	result, err = limiter.Allow(ctx, "client", limit)
+	assert.NoError(t, err)
+	assert.False(t, result.Allowed)
+	assert.Equal(t, 500*time.Millisecond, result.RetryAfter)
+
+	now = now.Add(500 * time.Millisecond)
+	result, err = limiter.Allow(ctx, "client", limit)
+	assert.NoError(t, err)
+	assert.True(t, result.Allowed)
+
+	// The bucket never holds more than its size.
+	now = now.Add(time.Hour)
+	result, err = limiter.Allow(ctx, "client", limit)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, result.Remaining)
+
+	now = now.Add(time.Second)
+	assert.NoError(t, limiter.Sweep(ctx, 2*time.Second))
+	assert.Len(t, limiter.buckets, 1)
+
+	now = now.Add(2 * time.Second)
+	assert.NoError(t, limiter.Sweep(ctx, 2*time.Second))
+	assert.Empty(t, limiter.buckets)
+}
@@ -0,0 +1,49 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"github.com/jmoiron/sqlx"
+	"githum.com/anupam111/image-store/internal/config"
+	"githum.com/anupam111/image-store/internal/constants"
+	"time"
+)
+
+// PostgresLimiter keeps the buckets in the RateLimitBucket table, so that the
+// replicas share them. Each request takes its token with a single statement,
+// timed by the clock of the database.
+type PostgresLimiter struct {
+	db *sqlx.DB
+}
+
+// NewPostgresLimiter implements PostgresLimiter on db, a Postgres database.
+func NewPostgresLimiter(db *sqlx.DB) *PostgresLimiter {
+	return &PostgresLimiter{
+		db: db,
+	}
+}
+
+// Allow implements Limiter.
+func (p *PostgresLimiter) Allow(ctx context.Context, key string, limit config.RateLimit) (Result, error) {
+	var (
+		tokens  float64
+		allowed bool
+	)
+
+	err := p.db.QueryRowxContext(ctx, constants.TakeRateLimitTokenQuery, key, limit.Requests, limit.PerSecond()).
+		Scan(&tokens, &allowed)
+	if err != nil {
+		return Result{}, fmt.Errorf("error while taking rate limit token, %w", err)
+	}
+
+	return newResult(allowed, tokens, limit), nil
+}
+
+// Sweep implements Limiter.
+func (p *PostgresLimiter) Sweep(ctx context.Context, idle time.Duration) error {
+	if _, err := p.db.ExecContext(ctx, constants.SweepRateLimitBucketsQuery, idle.Seconds()); err != nil {
+		return fmt.Errorf("error while sweeping rate limit buckets, %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,72 @@
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	sqlxmock "github.com/zhashkevych/go-sqlxmock"
+	"githum.com/anupam111/image-store/internal/config"
+	"testing"
+	"time"
+)
+
+func TestPostgresLimiter(t *testing.T) {
+	t.Parallel()
+
+	limit := config.RateLimit{Requests: 60, Period: time.Minute}
+
+	tests := []struct {
+		name     string
+		mock     func(mock sqlxmock.Sqlmock)
+		expected Result
+		wantErr  bool
+	}{
+		{
+			name: "allowed",
+			mock: func(mock sqlxmock.Sqlmock) {
+				mock.ExpectQuery("(INSERT INTO RateLimitBucket).*").WithArgs("client", 60, 1.0).
+					WillReturnRows(sqlxmock.NewRows([]string{"tokens", "allowed"}).AddRow(59.5, true))
+			},
+			expected: Result{Allowed: true, Limit: 60, Remaining: 59, Reset: 500 * time.Millisecond},
+		},
+		{
+			name: "denied",
+			mock: func(mock sqlxmock.Sqlmock) {
+				mock.ExpectQuery("(INSERT INTO RateLimitBucket).*").WithArgs("client", 60, 1.0).
+					WillReturnRows(sqlxmock.NewRows([]string{"tokens", "allowed"}).AddRow(0.25, false))
+			},
+			expected: Result{Limit: 60, RetryAfter: 750 * time.Millisecond, Reset: 59750 * time.Millisecond},
+		},
+		{
+			name: "error",
+			mock: func(mock sqlxmock.Sqlmock) {
+				mock.ExpectQuery("(INSERT INTO RateLimitBucket).*").WillReturnError(errors.New("connection refused"))
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			db, mock, err := sqlxmock.Newx()
+			assert.NoError(t, err)
+
+			defer db.Close()
+
+			tt.mock(mock)
+
+			result, err := NewPostgresLimiter(db).Allow(context.Background(), "client", limit)
+			if tt.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, tt.expected, result)
+			}
+
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
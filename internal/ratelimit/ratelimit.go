@@ -0,0 +1,66 @@
+// Package ratelimit limits the requests of the clients with token buckets,
+// kept in memory or in Postgres.
+package ratelimit
+
+import (
+	"context"
+	"githum.com/anupam111/image-store/internal/config"
+	"math"
+	"time"
+)
+
+const (
+	// MemoryStore keeps the buckets in the memory of each replica.
+	MemoryStore = "memory"
+	// PostgresStore keeps the buckets in Postgres, shared by the replicas.
+	PostgresStore = "postgres"
+)
+
+// Limiter takes a token from the bucket of a key for every request.
+type Limiter interface {
+	// Allow takes a token from the bucket of key, which holds up to
+	// limit.Requests tokens and refills at the rate of limit. The request is
+	// denied when the bucket is empty.
+	Allow(ctx context.Context, key string, limit config.RateLimit) (Result, error)
+	// Sweep forgets the buckets left alone for longer than idle. A bucket
+	// refills in the period of its limit, so that forgetting it once that
+	// long has passed changes nothing.
+	Sweep(ctx context.Context, idle time.Duration) error
+}
+
+// Result is the state of a bucket after a request.
+type Result struct {
+	Allowed bool
+	// Limit is the size of the bucket, Remaining the tokens it holds.
+	Limit     int
+	Remaining int
+	// RetryAfter is the time until the bucket holds a token again, 0 when
+	// it does. Reset is the time until the bucket is full.
+	RetryAfter time.Duration
+	Reset      time.Duration
+}
+
+// newResult returns the result of a request leaving tokens in the bucket.
+func newResult(allowed bool, tokens float64, limit config.RateLimit) Result {
+	result := Result{
+		Allowed:   allowed,
+		Limit:     limit.Requests,
+		Remaining: int(math.Floor(tokens)),
+		Reset:     refillTime(float64(limit.Requests)-tokens, limit),
+	}
+
+	if tokens < 1 {
+		result.RetryAfter = refillTime(1-tokens, limit)
+	}
+
+	return result
+}
+
+// refillTime is the time the bucket takes to refill tokens.
+func refillTime(tokens float64, limit config.RateLimit) time.Duration {
+	if tokens <= 0 {
+		return 0
+	}
+
+	return time.Duration(math.Ceil(tokens / limit.PerSecond() * float64(time.Second)))
+}
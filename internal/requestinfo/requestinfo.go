@@ -0,0 +1,53 @@
+// Package requestinfo carries the id and the client address of a request,
+// which the audit log records.
+package requestinfo
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"regexp"
+)
+
+// Header carries the id of an HTTP request, the response echoes it. gRPC
+// requests carry it in the metadata key of the same name.
+const Header = "X-Request-ID"
+
+var pattern = regexp.MustCompile(`^[A-Za-z0-9_.:-]{1,128}$`)
+
+// Valid reports whether id can identify a request: up to 128 letters, digits,
+// dots, colons, dashes and underscores.
+func Valid(id string) bool {
+	return pattern.MatchString(id)
+}
+
+// NewID returns a random request id.
+func NewID() string {
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		// The request id only correlates logs, a fixed one is no harm.
+		return "unknown"
+	}
+
+	return hex.EncodeToString(id)
+}
+
+// Info is the id of a request and the IP of its client.
+type Info struct {
+	ID       string
+	ClientIP string
+}
+
+type infoKey struct{}
+
+// NewContext returns a context carrying the request info.
+func NewContext(ctx context.Context, info Info) context.Context {
+	return context.WithValue(ctx, infoKey{}, info)
+}
+
+// FromContext returns the request info of ctx, empty when it carries none.
+func FromContext(ctx context.Context) Info {
+	info, _ := ctx.Value(infoKey{}).(Info)
+
+	return info
+}
@@ -4,11 +4,24 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	imagestorev1 "githum.com/anupam111/image-store/api/imagestore/v1"
 	"githum.com/anupam111/image-store/internal/apihandler"
+	"githum.com/anupam111/image-store/internal/auth"
 	"githum.com/anupam111/image-store/internal/config"
+	"githum.com/anupam111/image-store/internal/constants"
 	"githum.com/anupam111/image-store/internal/controller"
 	"githum.com/anupam111/image-store/internal/db/dbconnection"
 	"githum.com/anupam111/image-store/internal/db/dbhandler"
+	"githum.com/anupam111/image-store/internal/db/dbmodels"
+	"githum.com/anupam111/image-store/internal/db/dialect"
+	"githum.com/anupam111/image-store/internal/db/migration"
+	"githum.com/anupam111/image-store/internal/encryption"
+	"githum.com/anupam111/image-store/internal/graphqlhandler"
+	"githum.com/anupam111/image-store/internal/grpchandler"
+	"githum.com/anupam111/image-store/internal/middleware"
+	"githum.com/anupam111/image-store/internal/ratelimit"
+	"google.golang.org/grpc"
+	"net"
 	"net/http"
 	"os"
 	"os/signal"
@@ -21,9 +34,14 @@ import (
 
 // AppServer ...
 type AppServer struct {
-	log    *log.Logger
-	router *gin.Engine
-	server *http.Server
+	log        *log.Logger
+	router     *gin.Engine
+	server     *http.Server
+	grpcServer *grpc.Server
+
+	stopDBMonitor      context.CancelFunc
+	stopRateLimitSweep context.CancelFunc
+	stopImageRewrap    context.CancelFunc
 }
 
 // NewAppServer implements AppServer.
@@ -35,8 +53,17 @@ func NewAppServer() *AppServer {
 func (app *AppServer) ConfigureAndStart(config *config.ImageStoreServiceConfig) {
 	app.log = configureLogger(config.ServiceConfig.LogLevel)
 	app.router = gin.New()
+	// Without trusted proxies the client IP, which the rate limits rely on,
+	// is the peer address and not a header the client can forge.
+	if err := app.router.SetTrustedProxies(config.ServiceConfig.TrustedProxies); err != nil {
+		log.Fatalf("error while configuring trusted proxies: %v", err)
+	}
+
 	gin.EnableJsonDecoderDisallowUnknownFields()
 	app.router.Use(gin.Recovery())
+	app.router.Use(middleware.RequestInfo())
+	app.router.Use(middleware.Timeout(config.ServiceConfig.RequestTimeout, config.ServiceConfig.RouteTimeouts))
+	app.router.Use(middleware.DBSession())
 	app.router.HandleMethodNotAllowed = true
 
 	base := app.router.Group("")
@@ -44,30 +71,349 @@ func (app *AppServer) ConfigureAndStart(config *config.ImageStoreServiceConfig)
 		base.Use(gin.Logger())
 	}
 
-	app.setupRouter(config.DBConfig)
+	logger := log.New()
+	dbConnection, err := dbconnection.New(&config.DBConfig)
+	if err != nil {
+		log.Fatalf("error while connecting to database: %v", err)
+	}
+
+	if config.DBConfig.AutoMigrate {
+		if err = migrateDB(logger, dbConnection); err != nil {
+			log.Fatalf("error while migrating database: %v", err)
+		}
+	}
+
+	app.startDBMonitor(dbConnection, config.DBConfig.HealthCheckInterval)
+
+	envelope, err := newEnvelope(config.ServiceConfig)
+	if err != nil {
+		log.Fatalf("error while configuring encryption: %v", err)
+	}
+
+	dbHandler := dbhandler.NewDBHandler(logger, dbConnection, envelope)
+	if envelope != nil {
+		app.startImageRewrap(logger, dbHandler, config.ServiceConfig.EncryptionRewrapInterval)
+	} else {
+		logger.Warn("encryption is disabled, the image content is stored in plain")
+	}
+
+	imageController := controller.NewImageController(logger, dbHandler, dbHandler, config.ServiceConfig.Quotas)
+	apiKeyController := controller.NewAPIKeyController(logger, dbHandler, dbHandler)
+	auditController := controller.NewAuditController(logger, dbHandler)
+
+	var (
+		authenticator middleware.Authenticator
+		tokens        middleware.TokenVerifier
+	)
+
+	if config.ServiceConfig.AuthEnabled {
+		authenticator = apiKeyController
+
+		if config.ServiceConfig.OIDCIssuer != "" {
+			verifier, err := newJWTVerifier(config.ServiceConfig)
+			if err != nil {
+				log.Fatalf("error while configuring oidc authentication: %v", err)
+			}
+
+			tokens = verifier
+		}
+	} else {
+		logger.Warn("authentication is disabled, every request is allowed")
+	}
+
+	authMiddleware := middleware.NewAuth(logger, authenticator, tokens)
+
+	var signedURLs *apihandler.SignedURLHandler
+	if key := config.ServiceConfig.SignedURLKey; key != "" {
+		signedURLs = apihandler.NewSignedURLHandler(logger, imageController, auth.NewURLSigner([]byte(key)),
+			config.ServiceConfig.SignedURLBase, config.ServiceConfig.SignedURLMaxTTL)
+	}
+
+	limiter, err := newRateLimiter(config.ServiceConfig.RateLimitStore, dbConnection)
+	if err != nil {
+		log.Fatalf("error while configuring rate limiting: %v", err)
+	}
+
+	app.startRateLimitSweep(logger, limiter, config.ServiceConfig)
+	rateLimit := middleware.NewRateLimit(logger, limiter, config.ServiceConfig.RateLimit,
+		config.ServiceConfig.RouteRateLimits, config.ServiceConfig.IPRateLimit)
+
+	app.setupRouter(logger, imageController, apiKeyController, auditController, authMiddleware, rateLimit, signedURLs, dbConnection)
+	app.setupGRPCServer(logger, imageController, authMiddleware, rateLimit)
 	app.Start(config.ServiceConfig)
 }
 
-func (app *AppServer) setupRouter(dbConfig config.DBConfig) {
-	logger := log.New()
-	v1router := app.router.Group("/v1")
-	dbConnection := dbconnection.New(&dbConfig)
-	dbHandler := dbhandler.NewDBHandler(logger, dbConnection)
-	controller := controller.NewImageController(logger, dbHandler)
+// setupRouter registers the routes, each with the scopes it requires and
+// rate limited but for /status. The IP rate limit comes before the
+// authentication, the others after. The signed url routes are only
+// registered with signedURLs.
+func (app *AppServer) setupRouter(
+	logger *log.Logger,
+	controller controller.ImageStore,
+	apiKeys controller.APIKeys,
+	auditLog controller.AuditLog,
+	authMiddleware *middleware.Auth,
+	rateLimit *middleware.RateLimit,
+	signedURLs *apihandler.SignedURLHandler,
+	dbStatus apihandler.DBStatus,
+) {
+	healthHandler := apihandler.NewHealthHandler(logger, dbStatus)
+	app.router.GET("/status", healthHandler.Status)
+
+	var (
+		albumsRead  = authMiddleware.Require(dbmodels.ScopeAlbumsRead)
+		albumsWrite = authMiddleware.Require(dbmodels.ScopeAlbumsWrite)
+		imagesRead  = authMiddleware.Require(dbmodels.ScopeImagesRead)
+		imagesWrite = authMiddleware.Require(dbmodels.ScopeImagesWrite)
+		limit       = rateLimit.Handler()
+		limitIP     = rateLimit.IPHandler()
+	)
+
+	v1router := app.router.Group("/v1", limitIP)
 	handler := apihandler.NewAPIHandler(logger, controller)
 
-	v1router.POST("/album", handler.CreateImageAlbum)
-	v1router.POST("/album/images", handler.CreateImage)
-	v1router.DELETE("/album/:albumName", handler.DeleteImageAlbum)
-	v1router.DELETE("/album/images/:imageName", handler.DeleteImage)
-	v1router.GET("/album/images/:imageName", handler.GetImageByID)
-	v1router.GET("/album/images", handler.GetAlbumImages)
+	v1router.POST("/album", albumsWrite, limit, handler.CreateImageAlbum)
+	v1router.POST("/album/images", imagesWrite, limit, handler.CreateImage)
+	v1router.POST("/album/images:action", imagesWrite, limit, handler.ImagesAction)
+	v1router.POST("/album/import", authMiddleware.Require(dbmodels.ScopeAlbumsWrite, dbmodels.ScopeImagesWrite), limit, handler.ImportAlbum)
+	v1router.DELETE("/album/:albumName", albumsWrite, limit, handler.DeleteImageAlbum)
+	v1router.DELETE("/album/images/:imageName", imagesWrite, limit, handler.DeleteImage)
+	v1router.GET("/album/images/:imageName", imagesRead, limit, handler.GetImageByID)
+	v1router.GET("/album/images", imagesRead, limit, handler.GetAlbumImages)
+	v1router.GET("/album/:albumName/export.zip", imagesRead, limit, handler.ExportAlbum)
+	v1router.GET("/album/:albumName/access", albumsRead, limit, handler.GetAlbumAccess)
+	v1router.PUT("/album/:albumName/access", albumsWrite, limit, handler.GrantAlbumAccess)
+	v1router.DELETE("/album/:albumName/access", albumsWrite, limit, handler.RevokeAlbumAccess)
+	v1router.GET("/album/:albumName/shares", albumsRead, limit, handler.ListAlbumShares)
+	v1router.POST("/album/:albumName/shares", albumsWrite, limit, handler.CreateAlbumShare)
+	v1router.DELETE("/album/:albumName/shares/:id", albumsWrite, limit, handler.RevokeAlbumShare)
+	v1router.GET("/search", imagesRead, limit, handler.SearchImages)
+	v1router.GET("/usage", albumsRead, limit, handler.GetUsage)
+
+	if signedURLs != nil {
+		v1router.POST("/signed-urls", imagesRead, limit, signedURLs.CreateSignedURL)
+		// The signature of the url authorizes the content, without credentials.
+		v1router.GET("/content/:albumName/:imageName", limit, signedURLs.GetContent)
+	}
+
+	// The share token, and its password, authorize the public share routes.
+	shareRouter := app.router.Group("/s", limitIP, limit)
+	shareRouter.GET("/:token", handler.GetSharedAlbum)
+	shareRouter.GET("/:token/images/:imageName", handler.GetSharedImage)
+	shareRouter.GET("/:token/download.zip", handler.ExportSharedAlbum)
+
+	graphqlHandler := graphqlhandler.NewGraphQLHandler(logger, controller)
+	v1router.POST("/graphql", authMiddleware.Require(dbmodels.ScopeAlbumsRead, dbmodels.ScopeImagesRead), limit, graphqlHandler.Query)
+
+	auditRead := authMiddleware.Require(dbmodels.ScopeAuditRead)
+	auditHandler := apihandler.NewAuditHandler(logger, auditLog)
+
+	v1router.GET("/audit", auditRead, limit, auditHandler.ListAuditEvents)
+	v1router.GET("/audit/export", auditRead, limit, auditHandler.ExportAuditEvents)
+
+	adminRouter := v1router.Group("/admin", authMiddleware.Require(dbmodels.ScopeAdmin), limit)
+	apiKeyHandler := apihandler.NewAPIKeyHandler(logger, apiKeys)
+
+	adminRouter.POST("/keys", apiKeyHandler.CreateAPIKey)
+	adminRouter.GET("/keys", apiKeyHandler.ListAPIKeys)
+	adminRouter.DELETE("/keys/:id", apiKeyHandler.RevokeAPIKey)
+
+	v2router := app.router.Group("/v2", limitIP)
+	handlerV2 := apihandler.NewAPIHandlerV2(logger, controller)
+
+	v2router.GET("/albums", albumsRead, limit, handlerV2.ListAlbums)
+	v2router.POST("/albums", albumsWrite, limit, handlerV2.CreateAlbum)
+	v2router.GET("/albums/:album", albumsRead, limit, handlerV2.GetAlbum)
+	v2router.PUT("/albums/:album", albumsWrite, limit, handlerV2.PutAlbum)
+	v2router.DELETE("/albums/:album", albumsWrite, limit, handlerV2.DeleteAlbum)
+	v2router.GET("/albums/:album/images", imagesRead, limit, handlerV2.ListImages)
+	v2router.GET("/albums/:album/images/:image", imagesRead, limit, handlerV2.GetImage)
+	v2router.PUT("/albums/:album/images/:image", imagesWrite, limit, handlerV2.PutImage)
+	v2router.PATCH("/albums/:album/images/:image", imagesWrite, limit, handlerV2.PatchImage)
+	v2router.DELETE("/albums/:album/images/:image", imagesWrite, limit, handlerV2.DeleteImage)
+}
+
+func (app *AppServer) setupGRPCServer(
+	logger *log.Logger,
+	controller controller.ImageStore,
+	authMiddleware *middleware.Auth,
+	rateLimit *middleware.RateLimit,
+) {
+	app.grpcServer = grpc.NewServer(
+		grpc.ChainUnaryInterceptor(
+			middleware.RequestInfoUnaryInterceptor,
+			middleware.DBSessionUnaryInterceptor,
+			rateLimit.IPUnaryInterceptor(),
+			authMiddleware.UnaryInterceptor(grpchandler.MethodScopes),
+			rateLimit.UnaryInterceptor(),
+		),
+		grpc.ChainStreamInterceptor(
+			middleware.RequestInfoStreamInterceptor,
+			middleware.DBSessionStreamInterceptor,
+			rateLimit.IPStreamInterceptor(),
+			authMiddleware.StreamInterceptor(grpchandler.MethodScopes),
+			rateLimit.StreamInterceptor(),
+		),
+	)
+	imagestorev1.RegisterImageStoreServer(app.grpcServer, grpchandler.NewGRPCHandler(logger, controller))
+}
+
+// newJWTVerifier returns the verifier of the tokens of the OIDC provider,
+// with its keys loaded.
+func newJWTVerifier(conf config.ServiceConfig) (*auth.JWTVerifier, error) {
+	if conf.OIDCAudience == "" || conf.OIDCJWKS == "" {
+		return nil, errors.New("OIDC_AUDIENCE and OIDC_JWKS are required with OIDC_ISSUER")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	keys := auth.NewKeySet(conf.OIDCJWKS)
+	if err := keys.Load(ctx); err != nil {
+		return nil, err
+	}
+
+	return auth.NewJWTVerifier(conf.OIDCIssuer, conf.OIDCAudience, conf.OIDCGroupsClaim, conf.OIDCTenantClaim, keys), nil
+}
+
+// migrateDB applies the pending schema migrations, serialized across replicas.
+func migrateDB(logger *log.Logger, dbConnection *dbconnection.Pool) error {
+	migrator, err := migration.NewMigrator(logger, dbConnection.DB)
+	if err != nil {
+		return err
+	}
+
+	defer migrator.Close()
+
+	return migrator.AutoMigrate(context.Background())
+}
+
+// startDBMonitor keeps checking the database connectivity reported on /status
+// until the server stops. An interval of 0 disables the monitor.
+func (app *AppServer) startDBMonitor(pool *dbconnection.Pool, interval time.Duration) {
+	if interval <= 0 {
+		return
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	app.stopDBMonitor = cancel
+
+	go pool.Monitor(ctx, interval)
+}
+
+// newEnvelope returns the envelope encrypting the image content with the
+// master keys of the key provider, nil without provider.
+func newEnvelope(conf config.ServiceConfig) (*encryption.Envelope, error) {
+	switch conf.EncryptionKeyProvider {
+	case "":
+		return nil, nil
+	case encryption.KeyFile:
+		keys, err := encryption.NewKeyFileProvider(conf.EncryptionKeyFile)
+		if err != nil {
+			return nil, err
+		}
+
+		return encryption.NewEnvelope(keys), nil
+	default:
+		return nil, fmt.Errorf("unknown encryption key provider %q", conf.EncryptionKeyProvider)
+	}
+}
+
+// startImageRewrap rewraps the image content stored in plain or under a
+// retired master key, on start and then every interval until the server
+// stops. An interval of 0 disables it.
+func (app *AppServer) startImageRewrap(logger *log.Logger, dbHandler *dbhandler.DBHandler, interval time.Duration) {
+	if interval <= 0 {
+		return
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	app.stopImageRewrap = cancel
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			rewritten, err := dbHandler.RewrapImages(ctx, constants.RewrapBatchSize)
+			if err != nil && ctx.Err() == nil {
+				logger.Warnf("error while rewrapping images: %v", err)
+			}
+
+			if rewritten > 0 {
+				logger.Infof("rewrapped %d images with the current master key", rewritten)
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// newRateLimiter returns the limiter of the store, which is "memory" or
+// "postgres". The postgres store needs a Postgres database.
+func newRateLimiter(store string, pool *dbconnection.Pool) (ratelimit.Limiter, error) {
+	switch store {
+	case "", ratelimit.MemoryStore:
+		return ratelimit.NewMemoryLimiter(), nil
+	case ratelimit.PostgresStore:
+		if pool.DB.DriverName() != dialect.PostgresDriver {
+			return nil, fmt.Errorf("rate limit store %q needs DB_DRIVER %q", store, dialect.PostgresDriver)
+		}
+
+		return ratelimit.NewPostgresLimiter(pool.DB), nil
+	default:
+		return nil, fmt.Errorf("unknown rate limit store %q", store)
+	}
+}
+
+// startRateLimitSweep regularly forgets the buckets which refilled, those
+// left alone for longer than the longest period of the rate limits, until
+// the server stops.
+func (app *AppServer) startRateLimitSweep(logger *log.Logger, limiter ratelimit.Limiter, conf config.ServiceConfig) {
+	idle := conf.RateLimit.Period
+	if conf.IPRateLimit.Period > idle {
+		idle = conf.IPRateLimit.Period
+	}
+
+	for _, limit := range conf.RouteRateLimits {
+		if limit.Period > idle {
+			idle = limit.Period
+		}
+	}
+
+	if idle <= 0 {
+		return
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	app.stopRateLimitSweep = cancel
+
+	go func() {
+		ticker := time.NewTicker(idle)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if err := limiter.Sweep(ctx, idle); err != nil {
+					logger.Warnf("error while sweeping rate limit buckets: %v", err)
+				}
+			}
+		}
+	}()
 }
 
 // Start starts the Server for real.
 func (app *AppServer) Start(conf config.ServiceConfig) {
 	log.Info("Starting image-store server...")
 	app.startGinServer(conf)
+	app.startGRPCServer(conf)
 	log.Info("image-store server started successfully ...")
 
 	// Wait for interrupt signal to gracefully shutdown the server with
@@ -93,6 +439,27 @@ func (app *AppServer) startGinServer(conf config.ServiceConfig) {
 	}()
 }
 
+// startGRPCServer serves the gRPC api beside the gin server. A GRPCPort of 0
+// disables it.
+func (app *AppServer) startGRPCServer(conf config.ServiceConfig) {
+	if conf.GRPCPort == 0 {
+		return
+	}
+
+	listener, err := net.Listen("tcp", fmt.Sprintf(":%d", conf.GRPCPort))
+	if err != nil {
+		log.Errorf("grpc listen: %s\n", err)
+		os.Exit(1)
+	}
+
+	go func() {
+		if err := app.grpcServer.Serve(listener); err != nil {
+			log.Errorf("grpc serve: %s\n", err)
+			os.Exit(1)
+		}
+	}()
+}
+
 func (app *AppServer) StopServer() {
 	// The context is used to inform the server it has 20 seconds to finish
 	// the request it is currently handling
@@ -103,6 +470,20 @@ func (app *AppServer) StopServer() {
 		log.Errorf("Server Shutdown: %v", err)
 	}
 
+	app.grpcServer.GracefulStop()
+
+	if app.stopDBMonitor != nil {
+		app.stopDBMonitor()
+	}
+
+	if app.stopRateLimitSweep != nil {
+		app.stopRateLimitSweep()
+	}
+
+	if app.stopImageRewrap != nil {
+		app.stopImageRewrap()
+	}
+
 	log.Info("Server stopped successfully")
 }
 
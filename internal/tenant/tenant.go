@@ -0,0 +1,44 @@
+// Package tenant carries the tenant of a request, which scopes all the albums
+// and images the request reads and writes.
+package tenant
+
+import (
+	"context"
+	"regexp"
+)
+
+const (
+	// Default is the tenant of the requests which name none, and of the data
+	// stored before tenants existed.
+	Default = "default"
+	// Header names the tenant of an HTTP request whose principal is not bound
+	// to one. gRPC requests name it in the metadata key of the same name.
+	Header = "X-Tenant-ID"
+	// Any is given instead of a tenant to create an admin API key bound to no
+	// tenant, which can name any tenant in Header.
+	Any = "*"
+)
+
+var pattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_.-]{0,99}$`)
+
+// Valid reports whether id can name a tenant: up to 100 letters, digits,
+// dots, dashes and underscores, starting with a letter or digit.
+func Valid(id string) bool {
+	return pattern.MatchString(id)
+}
+
+type tenantKey struct{}
+
+// NewContext returns a context carrying the tenant.
+func NewContext(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, tenantKey{}, id)
+}
+
+// FromContext returns the tenant of ctx, Default when it carries none.
+func FromContext(ctx context.Context) string {
+	if id, ok := ctx.Value(tenantKey{}).(string); ok && id != "" {
+		return id
+	}
+
+	return Default
+}
@@ -0,0 +1,197 @@
+// Package transform renders the derivatives of images: copies of their
+// content scaled down to fit a size.
+package transform
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	// The GIF decoder is registered for image.Decode.
+	_ "image/gif"
+	"image/jpeg"
+	"image/png"
+	"strconv"
+	"strings"
+)
+
+const (
+	// MaxSize is the largest width or height of a derivative.
+	MaxSize = 4096
+	// maxSourcePixels bounds the images which are decoded, so that a small
+	// file declaring a huge image can not exhaust the memory.
+	maxSourcePixels = 50_000_000
+	jpegQuality     = 85
+)
+
+var (
+	// ErrInvalidSpec is returned for a transform spec which can not be parsed.
+	ErrInvalidSpec = errors.New("invalid transform spec")
+	// ErrUnsupportedImage is returned for content which is not a PNG, JPEG or
+	// GIF image, or is too large to transform.
+	ErrUnsupportedImage = errors.New("image can not be transformed")
+)
+
+// Spec is a parsed transform spec, such as "w=200,h=100". The derivative fits
+// in Width by Height, keeping the aspect ratio of the image; a zero bound
+// does not constrain. Images are scaled down, never up.
+type Spec struct {
+	Width  int
+	Height int
+}
+
+// Parse parses a transform spec: comma separated w=<width> and h=<height>
+// pairs, at least one of them, each between 1 and MaxSize.
+func Parse(spec string) (Spec, error) {
+	var parsed Spec
+
+	for _, pair := range strings.Split(spec, ",") {
+		key, value, ok := cut(strings.TrimSpace(pair), "=")
+		if !ok {
+			return Spec{}, fmt.Errorf("%w %q, %q is not a key=value pair", ErrInvalidSpec, spec, pair)
+		}
+
+		var bound *int
+
+		switch key {
+		case "w":
+			bound = &parsed.Width
+		case "h":
+			bound = &parsed.Height
+		default:
+			return Spec{}, fmt.Errorf("%w %q, unknown key %q", ErrInvalidSpec, spec, key)
+		}
+
+		size, err := strconv.Atoi(value)
+		if err != nil || size < 1 || size > MaxSize || *bound != 0 {
+			return Spec{}, fmt.Errorf("%w %q, %s must be given once, between 1 and %d", ErrInvalidSpec, spec, key, MaxSize)
+		}
+
+		*bound = size
+	}
+
+	return parsed, nil
+}
+
+// Apply renders the derivative of the PNG, JPEG or GIF content for the spec.
+// It returns the derivative and its content type, PNG for a GIF image and
+// the format of the image otherwise.
+func Apply(content []byte, spec Spec) ([]byte, string, error) {
+	config, format, err := image.DecodeConfig(bytes.NewReader(content))
+	if err != nil {
+		return nil, "", fmt.Errorf("%w, %v", ErrUnsupportedImage, err)
+	}
+
+	if config.Width*config.Height > maxSourcePixels {
+		return nil, "", fmt.Errorf("%w, %dx%d pixels", ErrUnsupportedImage, config.Width, config.Height)
+	}
+
+	src, _, err := image.Decode(bytes.NewReader(content))
+	if err != nil {
+		return nil, "", fmt.Errorf("%w, %v", ErrUnsupportedImage, err)
+	}
+
+	dst := scale(src, spec)
+
+	var out bytes.Buffer
+
+	if format == "jpeg" {
+		if err = jpeg.Encode(&out, dst, &jpeg.Options{Quality: jpegQuality}); err != nil {
+			return nil, "", fmt.Errorf("error while encoding image, %w", err)
+		}
+
+		return out.Bytes(), "image/jpeg", nil
+	}
+
+	if err = png.Encode(&out, dst); err != nil {
+		return nil, "", fmt.Errorf("error while encoding image, %w", err)
+	}
+
+	return out.Bytes(), "image/png", nil
+}
+
+// scale returns src scaled down to fit the spec, each pixel the average of
+// the pixels of src it covers.
+func scale(src image.Image, spec Spec) image.Image {
+	bounds := src.Bounds()
+	width, height := fit(bounds.Dx(), bounds.Dy(), spec)
+
+	if width == bounds.Dx() && height == bounds.Dy() {
+		return src
+	}
+
+	dst := image.NewNRGBA(image.Rect(0, 0, width, height))
+
+	for y := 0; y < height; y++ {
+		y0 := bounds.Min.Y + y*bounds.Dy()/height
+		y1 := bounds.Min.Y + (y+1)*bounds.Dy()/height
+
+		for x := 0; x < width; x++ {
+			x0 := bounds.Min.X + x*bounds.Dx()/width
+			x1 := bounds.Min.X + (x+1)*bounds.Dx()/width
+
+			var r, g, b, a, n uint64
+
+			for sy := y0; sy < y1; sy++ {
+				for sx := x0; sx < x1; sx++ {
+					pr, pg, pb, pa := src.At(sx, sy).RGBA()
+					r, g, b, a, n = r+uint64(pr), g+uint64(pg), b+uint64(pb), a+uint64(pa), n+1
+				}
+			}
+
+			offset := dst.PixOffset(x, y)
+			pixel := dst.Pix[offset : offset+4 : offset+4]
+
+			if a == 0 {
+				pixel[0], pixel[1], pixel[2], pixel[3] = 0, 0, 0, 0
+
+				continue
+			}
+
+			// The colors are premultiplied by alpha, NRGBA is not.
+			pixel[0] = uint8(r * 0xff / a)
+			pixel[1] = uint8(g * 0xff / a)
+			pixel[2] = uint8(b * 0xff / a)
+			pixel[3] = uint8((a / n) >> 8)
+		}
+	}
+
+	return dst
+}
+
+// fit returns the size of a width by height image scaled down to fit the
+// spec, keeping its aspect ratio and at least one pixel wide and high.
+func fit(width, height int, spec Spec) (int, int) {
+	ratio := 1.0
+
+	if spec.Width > 0 && width > spec.Width {
+		ratio = float64(spec.Width) / float64(width)
+	}
+
+	if spec.Height > 0 && float64(height)*ratio > float64(spec.Height) {
+		ratio = float64(spec.Height) / float64(height)
+	}
+
+	if ratio == 1 {
+		return width, height
+	}
+
+	return max(1, int(float64(width)*ratio+0.5)), max(1, int(float64(height)*ratio+0.5))
+}
+
+func max(a, b int) int {
+	if a > b {
+		return a
+	}
+
+	return b
+}
+
+// cut slices s around the first sep, like strings.Cut of later Go versions.
+func cut(s, sep string) (string, string, bool) {
+	if idx := strings.Index(s, sep); idx >= 0 {
+		return s[:idx], s[idx+len(sep):], true
+	}
+
+	return s, "", false
+}
@@ -0,0 +1,113 @@
+package transform
+
+import (
+	"bytes"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"image"
+	"image/color"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name      string
+		spec      string
+		want      Spec
+		errString string
+	}{
+		{name: "width", spec: "w=100", want: Spec{Width: 100}},
+		{name: "width_and_height", spec: "w=100, h=50", want: Spec{Width: 100, Height: 50}},
+		{name: "height", spec: "h=4096", want: Spec{Height: 4096}},
+		{name: "empty", spec: "", errString: `"" is not a key=value pair`},
+		{name: "unknown_key", spec: "w=100,q=80", errString: `unknown key "q"`},
+		{name: "not_a_number", spec: "w=wide", errString: "w must be given once, between 1 and 4096"},
+		{name: "zero", spec: "h=0", errString: "h must be given once, between 1 and 4096"},
+		{name: "too_large", spec: "w=4097", errString: "w must be given once, between 1 and 4096"},
+		{name: "repeated", spec: "w=100,w=200", errString: "w must be given once, between 1 and 4096"},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := Parse(tt.spec)
+			if tt.errString != "" {
+				assert.ErrorIs(t, err, ErrInvalidSpec)
+				assert.ErrorContains(t, err, tt.errString)
+
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestApply(t *testing.T) {
+	t.Parallel()
+
+	src := image.NewNRGBA(image.Rect(0, 0, 40, 20))
+	for y := 0; y < 20; y++ {
+		for x := 0; x < 40; x++ {
+			src.SetNRGBA(x, y, color.NRGBA{R: 200, G: 100, B: 50, A: 255})
+		}
+	}
+
+	encode := func(encoder func(*bytes.Buffer) error) []byte {
+		var buf bytes.Buffer
+		require.NoError(t, encoder(&buf))
+
+		return buf.Bytes()
+	}
+
+	pngContent := encode(func(buf *bytes.Buffer) error { return png.Encode(buf, src) })
+	jpegContent := encode(func(buf *bytes.Buffer) error { return jpeg.Encode(buf, src, nil) })
+	gifContent := encode(func(buf *bytes.Buffer) error { return gif.Encode(buf, src, nil) })
+
+	tests := []struct {
+		name        string
+		content     []byte
+		spec        Spec
+		contentType string
+		width       int
+		height      int
+	}{
+		{name: "png_width", content: pngContent, spec: Spec{Width: 10}, contentType: "image/png", width: 10, height: 5},
+		{name: "png_height_bounds", content: pngContent, spec: Spec{Width: 30, Height: 5}, contentType: "image/png", width: 10, height: 5},
+		{name: "png_not_upscaled", content: pngContent, spec: Spec{Width: 100}, contentType: "image/png", width: 40, height: 20},
+		{name: "jpeg", content: jpegContent, spec: Spec{Height: 10}, contentType: "image/jpeg", width: 20, height: 10},
+		{name: "gif_as_png", content: gifContent, spec: Spec{Width: 4}, contentType: "image/png", width: 4, height: 2},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			derivative, contentType, err := Apply(tt.content, tt.spec)
+			require.NoError(t, err)
+			assert.Equal(t, tt.contentType, contentType)
+
+			decoded, _, err := image.Decode(bytes.NewReader(derivative))
+			require.NoError(t, err)
+			assert.Equal(t, image.Rect(0, 0, tt.width, tt.height), decoded.Bounds())
+
+			// JPEG and GIF do not keep the exact colors.
+			if bytes.Equal(tt.content, pngContent) {
+				assert.Equal(t, color.NRGBA{R: 200, G: 100, B: 50, A: 255},
+					color.NRGBAModel.Convert(decoded.At(tt.width/2, tt.height/2)))
+			}
+		})
+	}
+
+	_, _, err := Apply([]byte("not an image"), Spec{Width: 10})
+	assert.ErrorIs(t, err, ErrUnsupportedImage)
+}